@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// CachePolicy maps route path templates, as registered in RegisterRoutes, to a Cache-Control max-age
+// Zero sends no-cache; routes missing from the policy get no Cache-Control header at all
+type CachePolicy map[string]time.Duration
+
+const (
+	// realtimeMaxAge is half the default feed update interval, so a cached response is at most one update behind
+	realtimeMaxAge = 30 * time.Second
+	// alertsMaxAge covers alerts and responses that count them; alerts change less often than arrivals
+	alertsMaxAge = time.Minute
+	// staticMaxAge covers data derived only from static GTFS, which is refreshed every few hours
+	staticMaxAge = time.Hour
+)
+
+// DefaultCachePolicy returns max-ages matched to how often each endpoint's data changes
+// Operational endpoints (/stats, /feed-info, /coverage) are no-cache since they're for watching changes as they happen
+func DefaultCachePolicy() CachePolicy {
+	return CachePolicy{
+		"/":                             staticMaxAge,
+		"/by-location":                  realtimeMaxAge,
+		"/reachable":                    realtimeMaxAge,
+		"/by-route/{route}":             realtimeMaxAge,
+		"/by-trunk/{trunk}":             realtimeMaxAge,
+		"/by-id/{ids}":                  realtimeMaxAge,
+		"/by-id-prefix/{prefix}":        realtimeMaxAge,
+		"/station/{id}/by-route":        realtimeMaxAge,
+		"/search":                       realtimeMaxAge,
+		"/stations":                     realtimeMaxAge,
+		"/route/{route}/arrivals":       realtimeMaxAge,
+		"/routes/status":                realtimeMaxAge,
+		"/trip/{tripID}/progress":       realtimeMaxAge,
+		"/alerts":                       alertsMaxAge,
+		"/alerts.ics":                   alertsMaxAge,
+		"/routes/{route}":               alertsMaxAge, // Includes the active alert count
+		"/routes":                       staticMaxAge,
+		"/routes/nearby":                staticMaxAge,
+		"/routes/{route}/shape.geojson": staticMaxAge,
+		"/routes/{route}/directions":    staticMaxAge,
+		"/fares":                        staticMaxAge,
+		"/stations.geojson":             staticMaxAge,
+		"/bounds":                       staticMaxAge,
+		"/stats":                        0,
+		"/feed-info":                    0,
+		"/coverage":                     0,
+	}
+}
+
+// SetCachePolicy replaces the per-endpoint Cache-Control policy; nil sends no Cache-Control headers
+func (h *Handler) SetCachePolicy(policy CachePolicy) {
+	h.cachePolicy = policy
+}
+
+// setCacheControl sets Cache-Control for the matched route from the cache policy
+// Only called on successful responses so CDNs never hold on to an error
+func (h *Handler) setCacheControl(w http.ResponseWriter, r *http.Request) {
+	route := mux.CurrentRoute(r)
+	if route == nil {
+		return
+	}
+	template, err := route.GetPathTemplate()
+	if err != nil {
+		return
+	}
+	maxAge, ok := h.cachePolicy[template]
+	if !ok {
+		return
+	}
+
+	if maxAge <= 0 {
+		w.Header().Set("Cache-Control", "no-cache")
+		return
+	}
+	w.Header().Set("Cache-Control", "public, max-age="+strconv.Itoa(int(maxAge.Seconds())))
+}
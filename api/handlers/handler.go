@@ -2,13 +2,19 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"io"
+	"iter"
 	"log"
+	"math"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/jusunglee/mta-go/internal/clock"
 	"github.com/jusunglee/mta-go/internal/models"
 	"github.com/jusunglee/mta-go/pkg/mta"
 )
@@ -16,31 +22,169 @@ import (
 // Handler handles HTTP requests
 // Wraps MTA client with REST API endpoints
 type Handler struct {
-	client mta.Client
+	client      mta.Client
+	clock       clock.Clock
+	maxStations int
+	maxPoints   int // Points accepted by one /by-locations request; see SetMaxBatchLocations
+	cachePolicy CachePolicy
+	timeZone    *time.Location // Zone timestamps are written in; see SetTimeZone
+	staleAfter  time.Duration  // Age of a station's data at which it is marked stale; see SetStaleAfter
+	walkSpeed   float64        // Metres per minute /reachable assumes; see SetWalkingSpeed
 }
 
+// DefaultMaxStations caps stations per response; comfortably above the longest route
+// but small enough that no single request can produce an unbounded payload
+const DefaultMaxStations = 500
+
+// DefaultMaxBatchLocations caps the points in one /by-locations request
+// Each point is a full nearest-station search, so the cap bounds the work one request can cause
+const DefaultMaxBatchLocations = 500
+
+// DefaultStaleAfter marks a station stale once its arrivals are this old
+// Five missed one-minute update cycles: long enough to ride out a slow or failed fetch or two
+const DefaultStaleAfter = 5 * time.Minute
+
+// DefaultWalkingSpeed is the metres per minute /reachable assumes, an unhurried adult pace
+const DefaultWalkingSpeed = 80.0
+
+// ArrivalDisplayLimit caps arrivals per direction in responses
+// The store retains more (see mta.DefaultArrivalRetention) for headway and schedule features
+const ArrivalDisplayLimit = 10
+
 func NewHandler(client mta.Client) *Handler {
-	return &Handler{client: client}
+	return &Handler{client: client, clock: clock.Real{}, maxStations: DefaultMaxStations, maxPoints: DefaultMaxBatchLocations, cachePolicy: DefaultCachePolicy(), timeZone: time.UTC, staleAfter: DefaultStaleAfter, walkSpeed: DefaultWalkingSpeed}
+}
+
+// SetWalkingSpeed sets the metres per minute /reachable turns walk times into distances with
+// Values <= 0 restore DefaultWalkingSpeed
+func (h *Handler) SetWalkingSpeed(metersPerMinute float64) {
+	if metersPerMinute <= 0 {
+		metersPerMinute = DefaultWalkingSpeed
+	}
+	h.walkSpeed = metersPerMinute
+}
+
+// SetStaleAfter sets how old a station's last update may get before responses mark it "stale"
+// Values <= 0 never mark stations stale
+func (h *Handler) SetStaleAfter(d time.Duration) {
+	h.staleAfter = d
+}
+
+// SetTimeZone sets the zone arrival and update timestamps are written in, reported as "timezone" in metadata
+// Feed times otherwise carry the host's local zone, so the default is UTC; nil restores it
+func (h *Handler) SetTimeZone(loc *time.Location) {
+	if loc == nil {
+		loc = time.UTC
+	}
+	h.timeZone = loc
+}
+
+// SetMaxStations sets the per-response station cap applied to every station-returning endpoint
+// Values <= 0 disable the cap
+func (h *Handler) SetMaxStations(n int) {
+	h.maxStations = n
+}
+
+// SetMaxBatchLocations sets how many points one /by-locations request may post; more are rejected with a 400
+// Values <= 0 disable the cap, though the body size limit still applies
+func (h *Handler) SetMaxBatchLocations(n int) {
+	h.maxPoints = n
+}
+
+// capStations truncates stations to the configured cap, reporting whether anything was dropped
+func (h *Handler) capStations(stations []models.Station) ([]models.Station, bool) {
+	if h.maxStations > 0 && len(stations) > h.maxStations {
+		return stations[:h.maxStations], true
+	}
+	return stations, false
+}
+
+// SetClock replaces the time source used for time-relative response fields
+func (h *Handler) SetClock(c clock.Clock) {
+	h.clock = c
 }
 
 func (h *Handler) RegisterRoutes(r *mux.Router) {
 	r.HandleFunc("/", h.handleIndex).Methods("GET")
 	r.HandleFunc("/by-location", h.handleByLocation).Methods("GET")
+	r.HandleFunc("/by-locations", h.handleByLocations).Methods("POST")
+	r.HandleFunc("/reachable", h.handleReachable).Methods("GET")
 	r.HandleFunc("/by-route/{route}", h.handleByRoute).Methods("GET")
+	r.HandleFunc("/by-trunk/{trunk}", h.handleByTrunk).Methods("GET")
 	r.HandleFunc("/by-id/{ids}", h.handleByID).Methods("GET")
+	r.HandleFunc("/by-id-prefix/{prefix}", h.handleByIDPrefix).Methods("GET")
+	r.HandleFunc("/station/{id}/by-route", h.handleStationByRoute).Methods("GET")
+	r.HandleFunc("/search", h.handleSearch).Methods("GET")
+	r.HandleFunc("/stations", h.handleStations).Methods("GET")
+	r.HandleFunc("/stations.geojson", h.handleStationsGeoJSON).Methods("GET")
+	r.HandleFunc("/bounds", h.handleBounds).Methods("GET")
 	r.HandleFunc("/routes", h.handleRoutes).Methods("GET")
+	// Registered before /routes/{route} so "nearby" and "status" aren't taken as route names
+	r.HandleFunc("/routes/nearby", h.handleRoutesNearby).Methods("GET")
+	r.HandleFunc("/routes/status", h.handleRouteStatus).Methods("GET")
+	r.HandleFunc("/routes/{route}", h.handleRouteInfo).Methods("GET")
+	r.HandleFunc("/routes/{route}/shape.geojson", h.handleRouteShape).Methods("GET")
+	r.HandleFunc("/routes/{route}/directions", h.handleRouteDirections).Methods("GET")
+	r.HandleFunc("/fares", h.handleFares).Methods("GET")
+	r.HandleFunc("/route/{route}/arrivals", h.handleRouteArrivals).Methods("GET")
+	r.HandleFunc("/trip/{tripID}/progress", h.handleTripProgress).Methods("GET")
 	r.HandleFunc("/alerts", h.handleAlerts).Methods("GET")
+	r.HandleFunc("/alerts.ics", h.handleAlertsICS).Methods("GET")
+	r.HandleFunc("/feed-info", h.handleFeedInfo).Methods("GET")
+	r.HandleFunc("/coverage", h.handleCoverage).Methods("GET")
+	r.HandleFunc("/stats", h.handleStats).Methods("GET")
 }
 
 // Base response metadata for all API responses
 type ResponseMetadata struct {
-	Updated           string `json:"updated,omitempty"`             // Real-time data update
-	StaticDataUpdated string `json:"static_data_updated,omitempty"` // Static GTFS data update
+	Timezone          string   `json:"timezone"`                      // Zone every timestamp in the response is written in
+	Updated           string   `json:"updated,omitempty"`             // Real-time data update
+	StaticDataUpdated string   `json:"static_data_updated,omitempty"` // Static GTFS data update
+	Truncated         bool     `json:"truncated,omitempty"`           // Result exceeded the station cap
+	UnknownIDs        []string `json:"unknown_ids,omitempty"`         // Requested station IDs that matched nothing
 }
 
 // Specific response types for each endpoint
 type StationsResponse struct {
-	Data []models.StationResponse `json:"data"`
+	Data  []models.StationResponse `json:"data"`
+	Links *ResponseLinks           `json:"links,omitempty"`
+	ResponseMetadata
+}
+
+// BatchLocationResponse holds the nearest stations for each posted point, in request order
+type BatchLocationResponse struct {
+	Data []LocationMatch `json:"data"`
+	ResponseMetadata
+}
+
+// LocationMatch is one point from a /by-locations request and the stations nearest it
+type LocationMatch struct {
+	Location models.Location          `json:"location"`
+	Stations []models.StationResponse `json:"stations"`
+}
+
+// ResponseLinks are hypermedia links for navigating from a response to related resources
+// Only included with ?links=true to keep default responses lean
+type ResponseLinks struct {
+	Self     string                  `json:"self"`
+	Stations map[string]StationLinks `json:"stations,omitempty"` // Keyed by station ID
+}
+
+// StationLinks point from a station to itself, the routes it serves and its alerts
+type StationLinks struct {
+	Self   string            `json:"self"`
+	Routes map[string]string `json:"routes"`
+	Alerts string            `json:"alerts"`
+}
+
+type StationByRouteResponse struct {
+	Data models.StationRouteArrivalsResponse `json:"data"`
+	ResponseMetadata
+}
+
+// StationSummariesResponse is /by-route/{route}?summary=true: stations without stops or arrivals
+type StationSummariesResponse struct {
+	Data []models.StationSummary `json:"data"`
 	ResponseMetadata
 }
 
@@ -49,11 +193,69 @@ type RoutesResponse struct {
 	ResponseMetadata
 }
 
+type FaresResponse struct {
+	Data []models.Fare `json:"data"`
+	ResponseMetadata
+}
+
+type RouteInfoResponse struct {
+	Data models.RouteInfo `json:"data"`
+	ResponseMetadata
+}
+
+type RouteDirectionsResponse struct {
+	Data models.RouteDirections `json:"data"`
+	ResponseMetadata
+}
+
+type RouteArrivalsResponse struct {
+	Data map[string]models.TrainsByDirection `json:"data"`
+	ResponseMetadata
+}
+
 type AlertsResponse struct {
 	Data []models.Alert `json:"data"`
 	ResponseMetadata
 }
 
+type RouteStatusResponse struct {
+	Data []models.RouteStatus `json:"data"`
+	ResponseMetadata
+}
+
+type FeedInfoResponse struct {
+	Data   []models.FeedLatency `json:"data"`
+	Counts StoreCounts          `json:"counts"`
+	ResponseMetadata
+}
+
+// StoreCounts are how many stations, routes and alerts are being served
+type StoreCounts struct {
+	Stations int `json:"stations"`
+	Routes   int `json:"routes"`
+	Alerts   int `json:"alerts"`
+}
+
+type TripProgressResponse struct {
+	Data models.TripProgress `json:"data"`
+	ResponseMetadata
+}
+
+type BoundsResponse struct {
+	Data models.Bounds `json:"data"`
+	ResponseMetadata
+}
+
+type StatsResponse struct {
+	Data models.Stats `json:"data"`
+	ResponseMetadata
+}
+
+type CoverageResponse struct {
+	Data models.CoverageReport `json:"data"`
+	ResponseMetadata
+}
+
 type InfoResponse struct {
 	Data map[string]string `json:"data"`
 	ResponseMetadata
@@ -65,21 +267,26 @@ type ErrorResponse struct {
 
 // getResponseMetadata creates metadata with update timestamps
 func (h *Handler) getResponseMetadata() ResponseMetadata {
-	meta := ResponseMetadata{}
+	meta := ResponseMetadata{Timezone: h.timeZone.String()}
 
 	// Add real-time data update time
 	if lastUpdate := h.client.GetLastUpdate(); !lastUpdate.IsZero() {
-		meta.Updated = lastUpdate.Format(time.RFC3339)
+		meta.Updated = h.formatTime(lastUpdate)
 	}
 
 	// Add static data update time if available
 	if staticUpdate := h.client.GetLastStaticUpdate(); !staticUpdate.IsZero() {
-		meta.StaticDataUpdated = staticUpdate.Format(time.RFC3339)
+		meta.StaticDataUpdated = h.formatTime(staticUpdate)
 	}
 
 	return meta
 }
 
+// formatTime writes t as RFC 3339 in the configured zone
+func (h *Handler) formatTime(t time.Time) string {
+	return t.In(h.timeZone).Format(time.RFC3339)
+}
+
 func (h *Handler) handleIndex(w http.ResponseWriter, r *http.Request) {
 	response := InfoResponse{
 		Data: map[string]string{
@@ -88,43 +295,264 @@ func (h *Handler) handleIndex(w http.ResponseWriter, r *http.Request) {
 		},
 		ResponseMetadata: h.getResponseMetadata(),
 	}
-	h.writeJSON(w, response)
+	h.writeJSON(w, r, response)
 }
 
 func (h *Handler) handleByLocation(w http.ResponseWriter, r *http.Request) {
 	// Extract and validate coordinate parameters
-	latStr := r.URL.Query().Get("lat")
-	lonStr := r.URL.Query().Get("lon")
+	lat, lon, err := parseLatLon(r)
+	if err != nil {
+		h.writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
-	if latStr == "" || lonStr == "" {
-		h.writeError(w, "Missing lat/lon parameter", http.StatusBadRequest)
+	// Opt-in collapsing of transfer complexes that GTFS splits into several stations
+	merge := false
+	if mergeStr := r.URL.Query().Get("merge"); mergeStr != "" {
+		merge, err = strconv.ParseBool(mergeStr)
+		if err != nil {
+			h.writeError(w, "Invalid merge parameter", http.StatusBadRequest)
+			return
+		}
+	}
+
+	imperial, ok := parseUnits(r)
+	if !ok {
+		h.writeError(w, "Invalid units parameter (use metric or imperial)", http.StatusBadRequest)
 		return
 	}
 
-	lat, err := strconv.ParseFloat(latStr, 64)
+	view, err := h.parseArrivalView(r)
 	if err != nil {
-		h.writeError(w, "Invalid lat parameter", http.StatusBadRequest)
+		h.writeError(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	lon, err := strconv.ParseFloat(lonStr, 64)
+	// Hardcoded limit of 5 stations for reasonable response size
+	var stations []models.Station
+	if merge {
+		stations, err = h.client.GetStationsByLocationMerged(lat, lon, 5, mta.DefaultMergeRadiusKm)
+	} else {
+		stations, err = h.client.GetStationsByLocation(lat, lon, 5)
+	}
 	if err != nil {
-		h.writeError(w, "Invalid lon parameter", http.StatusBadRequest)
+		h.writeError(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	// Hardcoded limit of 5 stations for reasonable response size
-	stations, err := h.client.GetStationsByLocation(lat, lon, 5)
+	response := h.stationsResponse(r, view, stations)
+	origin := models.Location{Lat: lat, Lon: lon}
+	for i, station := range response.Data {
+		// ?active_only= may have dropped stations, so go by each response entry's own location
+		km := origin.DistanceKm(models.Location{Lat: station.Location[0], Lon: station.Location[1]})
+		response.Data[i].Distance = formatDistance(km, imperial)
+	}
+	h.writeJSON(w, r, response)
+}
+
+// defaultReachableMinutes is the walk /reachable assumes without ?minutes=
+const defaultReachableMinutes = 10
+
+// maxReachableMinutes caps ?minutes= on /reachable; at the default speed an hour already spans most of Manhattan
+const maxReachableMinutes = 60
+
+// handleReachable returns the stations within a walk of ?minutes= (default 10) from lat/lon, nearest first
+// The walk is a straight line at the handler's walking speed, so it is an upper bound on what streets allow
+func (h *Handler) handleReachable(w http.ResponseWriter, r *http.Request) {
+	lat, lon, err := parseLatLon(r)
+	if err != nil {
+		h.writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	minutes := float64(defaultReachableMinutes)
+	if minutesStr := r.URL.Query().Get("minutes"); minutesStr != "" {
+		minutes, err = strconv.ParseFloat(minutesStr, 64)
+		// NaN fails every comparison, so it has to be ruled out explicitly
+		if err != nil || math.IsNaN(minutes) || minutes <= 0 || minutes > maxReachableMinutes {
+			h.writeError(w, "Invalid minutes parameter (use more than 0 and at most "+strconv.Itoa(maxReachableMinutes)+")", http.StatusBadRequest)
+			return
+		}
+	}
+
+	imperial, ok := parseUnits(r)
+	if !ok {
+		h.writeError(w, "Invalid units parameter (use metric or imperial)", http.StatusBadRequest)
+		return
+	}
+
+	view, err := h.parseArrivalView(r)
+	if err != nil {
+		h.writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	stations, err := h.client.GetStationsWithinRadius(lat, lon, minutes*h.walkSpeed/1000)
 	if err != nil {
 		h.writeError(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	h.writeStationsResponse(w, stations)
+	response := h.stationsResponse(r, view, stations)
+	origin := models.Location{Lat: lat, Lon: lon}
+	for i, station := range response.Data {
+		km := origin.DistanceKm(models.Location{Lat: station.Location[0], Lon: station.Location[1]})
+		response.Data[i].Distance = formatDistance(km, imperial)
+		// Rounded up so a station listed as a 10-minute walk is never further than 10 minutes
+		response.Data[i].WalkMinutes = max(1, int(math.Ceil(km*1000/h.walkSpeed)))
+	}
+	h.writeJSON(w, r, response)
+}
+
+// maxBatchLimit caps ?limit= on /by-locations, matching the stations /by-location returns per point
+const maxBatchLimit = 5
+
+// maxBatchBodyBytes is generous for DefaultMaxBatchLocations points; larger bodies are rejected before decoding
+const maxBatchBodyBytes = 1 << 20
+
+// handleByLocations finds the nearest stations for a JSON array of {lat, lon} points in one request
+// Returns the single nearest station per point unless ?limit= asks for up to maxBatchLimit
+func (h *Handler) handleByLocations(w http.ResponseWriter, r *http.Request) {
+	limit, err := parsePositiveIntParam(r, "limit", 1, maxBatchLimit)
+	if err != nil {
+		h.writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	imperial, ok := parseUnits(r)
+	if !ok {
+		h.writeError(w, "Invalid units parameter (use metric or imperial)", http.StatusBadRequest)
+		return
+	}
+
+	view, err := h.parseArrivalView(r)
+	if err != nil {
+		h.writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var points []models.Location
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBatchBodyBytes)).Decode(&points); err != nil {
+		h.writeError(w, "Invalid request body (expected a JSON array of {\"lat\", \"lon\"})", http.StatusBadRequest)
+		return
+	}
+	if len(points) == 0 {
+		h.writeError(w, "No locations given", http.StatusBadRequest)
+		return
+	}
+	if h.maxPoints > 0 && len(points) > h.maxPoints {
+		h.writeError(w, "Too many locations (max "+strconv.Itoa(h.maxPoints)+")", http.StatusBadRequest)
+		return
+	}
+
+	response := BatchLocationResponse{
+		Data:             make([]LocationMatch, len(points)),
+		ResponseMetadata: h.getResponseMetadata(),
+	}
+	for i, point := range points {
+		stations, err := h.client.GetStationsByLocation(point.Lat, point.Lon, limit)
+		if err != nil {
+			h.writeError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		match := LocationMatch{Location: point, Stations: make([]models.StationResponse, 0, len(stations))}
+		for _, station := range view.activeStations(stations) {
+			resp := stationResponse(station, view)
+			resp.Distance = formatDistance(point.DistanceKm(station.Location), imperial)
+			match.Stations = append(match.Stations, resp)
+		}
+		response.Data[i] = match
+	}
+	h.writeJSON(w, r, response)
+}
+
+const (
+	kmPerMile   = 1.609344
+	feetPerMile = 5280
+	// Below these, switch to the smaller unit so a one-block walk doesn't read as "0.1 mi"
+	metersCutoffKm  = 1.0
+	feetCutoffMiles = 0.1
+)
+
+// parseLatLon reads the ?lat= and ?lon= every location endpoint takes
+// The error is the message to send back with a 400, worded the same for every endpoint
+func parseLatLon(r *http.Request) (lat, lon float64, err error) {
+	latStr := r.URL.Query().Get("lat")
+	lonStr := r.URL.Query().Get("lon")
+	if latStr == "" || lonStr == "" {
+		return 0, 0, errors.New("Missing lat/lon parameter")
+	}
+
+	lat, err = strconv.ParseFloat(latStr, 64)
+	if err != nil || math.IsNaN(lat) || math.IsInf(lat, 0) {
+		return 0, 0, errors.New("Invalid lat parameter")
+	}
+	lon, err = strconv.ParseFloat(lonStr, 64)
+	if err != nil || math.IsNaN(lon) || math.IsInf(lon, 0) {
+		return 0, 0, errors.New("Invalid lon parameter")
+	}
+	return lat, lon, nil
+}
+
+// parsePositiveIntParam reads an integer query parameter from 1 to max, returning def when it is absent
+// The error is the message to send back with a 400, worded the same for every endpoint
+func parsePositiveIntParam(r *http.Request, name string, def, max int) (int, error) {
+	s := r.URL.Query().Get(name)
+	if s == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 1 || n > max {
+		return 0, errors.New("Invalid " + name + " parameter (use 1-" + strconv.Itoa(max) + ")")
+	}
+	return n, nil
+}
+
+// parseUnits reads ?units=, reporting whether imperial units were requested and whether the value was valid
+func parseUnits(r *http.Request) (imperial bool, ok bool) {
+	switch r.URL.Query().Get("units") {
+	case "", "metric":
+		return false, true
+	case "imperial":
+		return true, true
+	default:
+		return false, false
+	}
+}
+
+// formatDistance converts a distance in km to the response units
+// Distances are always computed in km; conversion only happens here at the response boundary
+func formatDistance(km float64, imperial bool) *models.Distance {
+	if imperial {
+		miles := km / kmPerMile
+		if miles < feetCutoffMiles {
+			return &models.Distance{Value: math.Round(miles * feetPerMile), Unit: "ft"}
+		}
+		return &models.Distance{Value: math.Round(miles*100) / 100, Unit: "mi"}
+	}
+	if km < metersCutoffKm {
+		return &models.Distance{Value: math.Round(km * 1000), Unit: "m"}
+	}
+	return &models.Distance{Value: math.Round(km*100) / 100, Unit: "km"}
 }
 
 func (h *Handler) handleByRoute(w http.ResponseWriter, r *http.Request) {
-	route := mux.Vars(r)["route"]
+	route, ok := h.routeParam(w, r)
+	if !ok {
+		return
+	}
+
+	// ?summary=true is for list views that only name the stations, and skips copying their stops and arrivals
+	if summary, _ := strconv.ParseBool(r.URL.Query().Get("summary")); summary {
+		summaries, err := h.client.GetStationSummariesByRoute(route)
+		if err != nil {
+			h.writeError(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		h.writeJSON(w, r, StationSummariesResponse{Data: summaries, ResponseMetadata: h.getResponseMetadata()})
+		return
+	}
 
 	stations, err := h.client.GetStationsByRoute(route)
 	if err != nil {
@@ -132,21 +560,299 @@ func (h *Handler) handleByRoute(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	h.writeStationsResponse(w, stations)
+	h.writeStationsResponse(w, r, stations)
+}
+
+// routeParam returns the {route} path variable without surrounding whitespace
+// A blank route is answered with a 400 here, since looking it up would only produce a baffling "route   not found"
+func (h *Handler) routeParam(w http.ResponseWriter, r *http.Request) (string, bool) {
+	route := strings.TrimSpace(mux.Vars(r)["route"])
+	if route == "" {
+		h.writeError(w, "route required", http.StatusBadRequest)
+		return "", false
+	}
+	return route, true
+}
+
+// splitIDs splits a comma-separated ID list, trimming each ID and dropping empty ones
+func splitIDs(s string) []string {
+	var ids []string
+	for _, id := range strings.Split(s, ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// handleByTrunk returns stations on any route of a trunk line, e.g. /by-trunk/yellow for N/Q/R/W
+func (h *Handler) handleByTrunk(w http.ResponseWriter, r *http.Request) {
+	trunk := strings.ToLower(mux.Vars(r)["trunk"])
+	if _, ok := models.TrunkRoutes[trunk]; !ok {
+		h.writeError(w, "Invalid trunk (use one of: "+strings.Join(models.TrunkNames(), ", ")+")", http.StatusBadRequest)
+		return
+	}
+
+	stations, err := h.client.GetStationsByTrunk(trunk)
+	if err != nil {
+		h.writeError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.writeStationsResponse(w, r, stations)
 }
 
 func (h *Handler) handleByID(w http.ResponseWriter, r *http.Request) {
 	// Parse comma-separated station IDs from URL path
-	idsStr := mux.Vars(r)["ids"]
-	ids := strings.Split(idsStr, ",")
+	ids := splitIDs(mux.Vars(r)["ids"])
+	if len(ids) == 0 {
+		h.writeError(w, "station ID required", http.StatusBadRequest)
+		return
+	}
+
+	view, err := h.parseArrivalView(r)
+	if err != nil {
+		h.writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	stations, missing, err := h.client.FindStationsByIDs(ids)
+	if err != nil {
+		h.writeError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if len(stations) == 0 {
+		h.writeError(w, "no stations found for given IDs", http.StatusNotFound)
+		return
+	}
+
+	// Lenient by default; ?strict=true treats any unknown ID as a failed request
+	strict, _ := strconv.ParseBool(r.URL.Query().Get("strict"))
+	if strict && len(missing) > 0 {
+		h.writeError(w, "unknown station IDs: "+strings.Join(missing, ","), http.StatusNotFound)
+		return
+	}
+
+	response := h.stationsResponse(r, view, stations)
+	response.UnknownIDs = missing
+	h.writeJSON(w, r, response)
+}
+
+// handleByIDPrefix lists stations whose ID starts with the given prefix, for exploring the data
+// No match returns an empty list rather than 404, since a prefix is a query rather than a lookup
+func (h *Handler) handleByIDPrefix(w http.ResponseWriter, r *http.Request) {
+	stations, err := h.client.GetStationsByIDPrefix(mux.Vars(r)["prefix"])
+	if err != nil {
+		h.writeError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.writeStationsResponse(w, r, stations)
+}
+
+func (h *Handler) handleStationByRoute(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	view, err := h.parseArrivalView(r)
+	if err != nil {
+		h.writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
-	stations, err := h.client.GetStationsByIDs(ids)
+	stations, err := h.client.GetStationsByIDs([]string{id})
 	if err != nil {
 		h.writeError(w, err.Error(), http.StatusNotFound)
 		return
 	}
+	if len(stations) == 0 {
+		h.writeError(w, "station "+id+" not found", http.StatusNotFound)
+		return
+	}
+
+	station := stations[0]
+	station.Trains.North = view.trains(station.Trains.North)
+	station.Trains.South = view.trains(station.Trains.South)
+	station.LastUpdate = station.LastUpdate.In(h.timeZone)
+	response := StationByRouteResponse{
+		Data:             station.ConvertToRouteArrivals(),
+		ResponseMetadata: h.getResponseMetadata(),
+	}
+	if !station.LastUpdate.IsZero() {
+		response.Updated = h.formatTime(station.LastUpdate)
+	}
+
+	h.writeJSON(w, r, response)
+}
+
+func (h *Handler) handleSearch(w http.ResponseWriter, r *http.Request) {
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" {
+		h.writeError(w, "Missing q parameter", http.StatusBadRequest)
+		return
+	}
+
+	// Substring matching is the cheap default; fuzzy scoring is opt-in
+	fuzzy := false
+	if fuzzyStr := r.URL.Query().Get("fuzzy"); fuzzyStr != "" {
+		var err error
+		fuzzy, err = strconv.ParseBool(fuzzyStr)
+		if err != nil {
+			h.writeError(w, "Invalid fuzzy parameter", http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Hardcoded limit of 10 matches for reasonable response size
+	stations, err := h.client.SearchStations(query, fuzzy, 10)
+	if err != nil {
+		h.writeError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.writeStationsResponse(w, r, stations)
+}
+
+// handleStations streams every station as a StationsResponse
+// Stations are encoded one at a time straight from the store so memory stays flat however many
+// there are. Metadata goes after the array since truncation and the newest update are only known
+// once every station has been seen. With ?links=true the links object is streamed too, from a
+// second pass over the same snapshot rather than a map of every station. Note http.TimeoutHandler buffers the encoded body, so with
+// -request-timeout the saving is the intermediate slices rather than the output bytes
+func (h *Handler) handleStations(w http.ResponseWriter, r *http.Request) {
+	view, err := h.parseArrivalView(r)
+	if err != nil {
+		h.writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	style, ok := parseStyle(r)
+	if !ok {
+		h.writeError(w, "Invalid style parameter (use default or camel)", http.StatusBadRequest)
+		return
+	}
+
+	withLinks, _ := strconv.ParseBool(r.URL.Query().Get("links"))
+
+	w.Header().Set("Content-Type", "application/json")
+	h.setCacheControl(w, r)
+	enc := json.NewEncoder(w)
+
+	// The sequence holds one snapshot, so the links pass sees exactly the stations written to data
+	stations := h.client.AllStations()
+	io.WriteString(w, `{"data":[`)
+	count := 0
+	truncated := false
+	var lastUpdate time.Time
+	for station := range stations {
+		if !view.active(station) {
+			continue
+		}
+		if h.maxStations > 0 && count >= h.maxStations {
+			truncated = true
+			break
+		}
+		if count > 0 {
+			io.WriteString(w, ",")
+		}
+		// Headers are already sent, so a failure can only cut the response short
+		if err := enc.Encode(styled(stationResponse(station, view), style)); err != nil {
+			log.Printf("Error streaming stations: %v", err)
+			return
+		}
+		if station.LastUpdate.After(lastUpdate) {
+			lastUpdate = station.LastUpdate
+		}
+		count++
+	}
+	io.WriteString(w, "]")
+
+	if withLinks && !h.streamLinks(w, r, stations, view, count) {
+		return
+	}
 
-	h.writeStationsResponse(w, stations)
+	meta := h.getResponseMetadata()
+	meta.Truncated = truncated
+	if !lastUpdate.IsZero() {
+		meta.Updated = h.formatTime(lastUpdate)
+	}
+	// Splice the metadata fields into the enclosing object after "data"
+	if fields, err := json.Marshal(styled(meta, style)); err == nil && len(fields) > 2 {
+		io.WriteString(w, ",")
+		w.Write(fields[1 : len(fields)-1])
+	}
+	io.WriteString(w, "}\n")
+}
+
+// handleBounds returns the box around every station so map UIs can fit the whole system on first load
+func (h *Handler) handleBounds(w http.ResponseWriter, r *http.Request) {
+	bounds, ok := h.client.GetBounds()
+	if !ok {
+		h.writeError(w, "no station data loaded", http.StatusServiceUnavailable)
+		return
+	}
+
+	response := BoundsResponse{
+		Data:             bounds,
+		ResponseMetadata: h.getResponseMetadata(),
+	}
+
+	h.writeJSON(w, r, response)
+}
+
+// handleStationsGeoJSON returns stations as a bare FeatureCollection so map libraries can load the URL directly
+// Bounding box params are optional but must be given all together, with each min no greater than its max
+func (h *Handler) handleStationsGeoJSON(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	boundsParams := []string{"min_lat", "min_lon", "max_lat", "max_lon"}
+
+	var bounds []float64
+	for _, param := range boundsParams {
+		value := query.Get(param)
+		if value == "" {
+			continue
+		}
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			h.writeError(w, "Invalid "+param+" parameter", http.StatusBadRequest)
+			return
+		}
+		bounds = append(bounds, v)
+	}
+
+	var stations []models.Station
+	var err error
+	switch len(bounds) {
+	case 0:
+		stations, err = h.client.GetAllStations()
+	case len(boundsParams):
+		if bounds[0] > bounds[2] || bounds[1] > bounds[3] {
+			h.writeError(w, "Bounding box min_lat and min_lon must not exceed max_lat and max_lon", http.StatusBadRequest)
+			return
+		}
+		stations, err = h.client.GetStationsInBounds(bounds[0], bounds[1], bounds[2], bounds[3])
+	default:
+		h.writeError(w, "Bounding box requires min_lat, min_lon, max_lat and max_lon", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		h.writeError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	stations, truncated := h.capStations(stations)
+	fc := models.GeoJSONFeatureCollection{
+		Type:      "FeatureCollection",
+		Features:  make([]models.GeoJSONFeature, len(stations)),
+		Truncated: truncated,
+	}
+	for i, station := range stations {
+		fc.Features[i] = station.ConvertToGeoJSONFeature()
+	}
+
+	w.Header().Set("Content-Type", "application/geo+json")
+	h.setCacheControl(w, r)
+	if err := json.NewEncoder(w).Encode(fc); err != nil {
+		h.writeError(w, "Failed to encode response", http.StatusInternalServerError)
+	}
 }
 
 func (h *Handler) handleRoutes(w http.ResponseWriter, r *http.Request) {
@@ -161,32 +867,355 @@ func (h *Handler) handleRoutes(w http.ResponseWriter, r *http.Request) {
 		ResponseMetadata: h.getResponseMetadata(),
 	}
 
-	h.writeJSON(w, response)
+	h.writeJSON(w, r, response)
+}
+
+// handleFares lists static GTFS fares, narrowed by ?route= and ?zone= when given
+// Feeds without fare files give an empty list
+func (h *Handler) handleFares(w http.ResponseWriter, r *http.Request) {
+	fares, err := h.client.GetFares(r.URL.Query().Get("route"), r.URL.Query().Get("zone"))
+	if err != nil {
+		h.writeError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	response := FaresResponse{
+		Data:             fares,
+		ResponseMetadata: h.getResponseMetadata(),
+	}
+
+	h.writeJSON(w, r, response)
+}
+
+// handleRoutesNearby lists the routes a rider can catch from stations within ?radius= km
+// Nothing nearby is an empty list rather than an error
+func (h *Handler) handleRoutesNearby(w http.ResponseWriter, r *http.Request) {
+	lat, lon, err := parseLatLon(r)
+	if err != nil {
+		h.writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	radius := mta.DefaultNearbyRadiusKm
+	if radiusStr := r.URL.Query().Get("radius"); radiusStr != "" {
+		radius, err = strconv.ParseFloat(radiusStr, 64)
+		if err != nil || radius <= 0 {
+			h.writeError(w, "Invalid radius parameter", http.StatusBadRequest)
+			return
+		}
+	}
+
+	routes, err := h.client.GetRoutesNearby(lat, lon, radius)
+	if err != nil {
+		h.writeError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	response := RoutesResponse{
+		Data:             routes,
+		ResponseMetadata: h.getResponseMetadata(),
+	}
+
+	h.writeJSON(w, r, response)
+}
+
+func (h *Handler) handleRouteInfo(w http.ResponseWriter, r *http.Request) {
+	route, ok := h.routeParam(w, r)
+	if !ok {
+		return
+	}
+
+	info, err := h.client.GetRouteInfo(route)
+	if err != nil {
+		h.writeError(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	response := RouteInfoResponse{
+		Data:             info,
+		ResponseMetadata: h.getResponseMetadata(),
+	}
+
+	h.writeJSON(w, r, response)
+}
+
+// handleRouteDirections returns the directions a route runs in, labelled by headsign for a direction picker
+func (h *Handler) handleRouteDirections(w http.ResponseWriter, r *http.Request) {
+	route, ok := h.routeParam(w, r)
+	if !ok {
+		return
+	}
+
+	info, err := h.client.GetRouteInfo(route)
+	if err != nil {
+		h.writeError(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	response := RouteDirectionsResponse{
+		Data:             info.Directions(),
+		ResponseMetadata: h.getResponseMetadata(),
+	}
+	h.writeJSON(w, r, response)
+}
+
+// handleRouteShape returns a route's track geometry as a GeoJSON Feature for map clients
+func (h *Handler) handleRouteShape(w http.ResponseWriter, r *http.Request) {
+	route, ok := h.routeParam(w, r)
+	if !ok {
+		return
+	}
+
+	lines, err := h.client.GetRouteShape(route)
+	if err != nil {
+		h.writeError(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if len(lines) == 0 {
+		h.writeError(w, "no shape data for route "+route, http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/geo+json")
+	h.setCacheControl(w, r)
+	if err := json.NewEncoder(w).Encode(models.NewRouteShapeFeature(route, lines)); err != nil {
+		h.writeError(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}
+
+// handleRouteArrivals returns a route's arrivals at every station it serves, keyed by station ID
+func (h *Handler) handleRouteArrivals(w http.ResponseWriter, r *http.Request) {
+	route, ok := h.routeParam(w, r)
+	if !ok {
+		return
+	}
+
+	view, err := h.parseArrivalView(r)
+	if err != nil {
+		h.writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	arrivals, err := h.client.GetArrivalsByRoute(route)
+	if err != nil {
+		h.writeError(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	for id, trains := range arrivals {
+		trains.North = view.trains(trains.North)
+		trains.South = view.trains(trains.South)
+		arrivals[id] = trains
+	}
+
+	response := RouteArrivalsResponse{
+		Data:             arrivals,
+		ResponseMetadata: h.getResponseMetadata(),
+	}
+
+	h.writeJSON(w, r, response)
+}
+
+// handleTripProgress shows where a train is and its remaining stops, e.g. "at 34 St, next 28 St, 23 St"
+func (h *Handler) handleTripProgress(w http.ResponseWriter, r *http.Request) {
+	tripID := mux.Vars(r)["tripID"]
+
+	progress, err := h.client.GetTripProgress(tripID)
+	if err != nil {
+		h.writeError(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	// Copied before moving times into the response zone, since the stops may be shared with the store
+	upcoming := make([]models.TripStop, len(progress.Upcoming))
+	for i, stop := range progress.Upcoming {
+		stop.Time = stop.Time.In(h.timeZone)
+		upcoming[i] = stop
+	}
+	progress.Upcoming = upcoming
+	if progress.Current != nil {
+		current := *progress.Current
+		current.Updated = current.Updated.In(h.timeZone)
+		progress.Current = &current
+	}
+
+	response := TripProgressResponse{
+		Data:             progress,
+		ResponseMetadata: h.getResponseMetadata(),
+	}
+
+	h.writeJSON(w, r, response)
 }
 
 func (h *Handler) handleAlerts(w http.ResponseWriter, r *http.Request) {
-	alerts, err := h.client.GetServiceAlerts()
+	from, to, ok := parseTimeRange(r)
+	if !ok {
+		h.writeError(w, "Invalid from/to parameter (use RFC3339 timestamps with from before to)", http.StatusBadRequest)
+		return
+	}
+
+	// ?from=&to= selects alerts active at any point in the range, e.g. for planning weekend comms
+	var alerts []models.Alert
+	var err error
+	if from.IsZero() && to.IsZero() {
+		alerts, err = h.client.GetServiceAlerts()
+	} else {
+		alerts, err = h.client.GetAlertsInRange(from, to)
+	}
 	if err != nil {
 		h.writeError(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	// ?station= narrows to alerts naming that station, e.g. for per-station links
+	if station := r.URL.Query().Get("station"); station != "" {
+		alerts = alertsForStation(alerts, station)
+	}
+
 	response := AlertsResponse{
-		Data:             alerts,
+		Data:             h.alertsInZone(alerts),
+		ResponseMetadata: h.getResponseMetadata(),
+	}
+
+	h.writeJSON(w, r, response)
+}
+
+// parseTimeRange reads optional RFC3339 ?from= and ?to= bounds; a missing bound is left zero
+// Reports false when a bound doesn't parse or the range is empty
+func parseTimeRange(r *http.Request) (from, to time.Time, ok bool) {
+	var err error
+	if s := r.URL.Query().Get("from"); s != "" {
+		if from, err = time.Parse(time.RFC3339, s); err != nil {
+			return time.Time{}, time.Time{}, false
+		}
+	}
+	if s := r.URL.Query().Get("to"); s != "" {
+		if to, err = time.Parse(time.RFC3339, s); err != nil {
+			return time.Time{}, time.Time{}, false
+		}
+	}
+	if !from.IsZero() && !to.IsZero() && !from.Before(to) {
+		return time.Time{}, time.Time{}, false
+	}
+	return from, to, true
+}
+
+// handleFeedInfo reports per-feed fetch latency for tuning the update interval, and how many
+// stations, routes and alerts are being served
+func (h *Handler) handleFeedInfo(w http.ResponseWriter, r *http.Request) {
+	stations, routes, alerts := h.client.Counts()
+	response := FeedInfoResponse{
+		Data:             h.client.GetFeedLatencies(),
+		Counts:           StoreCounts{Stations: stations, Routes: routes, Alerts: alerts},
+		ResponseMetadata: h.getResponseMetadata(),
+	}
+
+	h.writeJSON(w, r, response)
+}
+
+// handleStats reports fetch counts, errors and store sizes for debugging without a metrics stack
+func (h *Handler) handleStats(w http.ResponseWriter, r *http.Request) {
+	response := StatsResponse{
+		Data:             h.client.GetStats(),
+		ResponseMetadata: h.getResponseMetadata(),
+	}
+
+	h.writeJSON(w, r, response)
+}
+
+// handleRouteStatus reports the median and p90 wait for the next train on every route, for reliability dashboards
+func (h *Handler) handleRouteStatus(w http.ResponseWriter, r *http.Request) {
+	statuses, err := h.client.GetRouteStatus(h.clock.Now())
+	if err != nil {
+		h.writeError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	response := RouteStatusResponse{
+		Data:             statuses,
+		ResponseMetadata: h.getResponseMetadata(),
+	}
+	h.writeJSON(w, r, response)
+}
+
+// handleCoverage lists stations that got no real-time arrivals in the latest update cycle
+func (h *Handler) handleCoverage(w http.ResponseWriter, r *http.Request) {
+	report, err := h.client.GetCoverage()
+	if err != nil {
+		h.writeError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	response := CoverageResponse{
+		Data:             report,
 		ResponseMetadata: h.getResponseMetadata(),
 	}
 
-	h.writeJSON(w, response)
+	h.writeJSON(w, r, response)
+}
+
+// alertsInZone copies alerts with their active periods moved into the response zone
+// The periods point at the store's times, so each alert gets new ones rather than being modified
+func (h *Handler) alertsInZone(alerts []models.Alert) []models.Alert {
+	inZone := func(t *time.Time) *time.Time {
+		if t == nil {
+			return nil
+		}
+		local := t.In(h.timeZone)
+		return &local
+	}
+
+	result := make([]models.Alert, len(alerts))
+	for i, alert := range alerts {
+		if alert.ActivePeriods != nil {
+			periods := make([]models.TimePeriod, len(alert.ActivePeriods))
+			for j, period := range alert.ActivePeriods {
+				periods[j] = models.TimePeriod{Start: inZone(period.Start), End: inZone(period.End)}
+			}
+			alert.ActivePeriods = periods
+		}
+		result[i] = alert
+	}
+	return result
+}
+
+// alertsForStation returns the alerts whose informed stations include id
+func alertsForStation(alerts []models.Alert, id string) []models.Alert {
+	result := []models.Alert{}
+	for _, alert := range alerts {
+		for _, station := range alert.Stations {
+			if station == id {
+				result = append(result, alert)
+				break
+			}
+		}
+	}
+	return result
+}
+
+// writeStationsResponse renders stations for any station-returning endpoint
+// Per-train feed provenance is only included when the request sets ?debug=true
+func (h *Handler) writeStationsResponse(w http.ResponseWriter, r *http.Request, stations []models.Station) {
+	view, err := h.parseArrivalView(r)
+	if err != nil {
+		h.writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	h.writeJSON(w, r, h.stationsResponse(r, view, stations))
 }
 
-func (h *Handler) writeStationsResponse(w http.ResponseWriter, stations []models.Station) {
+// stationsResponse builds the response body for writeStationsResponse
+// Separate so endpoints can add their own metadata before writing
+func (h *Handler) stationsResponse(r *http.Request, view arrivalView, stations []models.Station) StationsResponse {
+	stations, truncated := h.capStations(view.activeStations(stations))
+
 	// Convert internal Station structs to API response format
 	data := make([]models.StationResponse, len(stations))
 	var lastUpdate time.Time
 
 	// Track the most recent update time across all stations
 	for i, station := range stations {
-		data[i] = station.ConvertToResponse()
+		data[i] = stationResponse(station, view)
 		if station.LastUpdate.After(lastUpdate) {
 			lastUpdate = station.LastUpdate
 		}
@@ -197,18 +1226,252 @@ func (h *Handler) writeStationsResponse(w http.ResponseWriter, stations []models
 		Data:             data,
 		ResponseMetadata: h.getResponseMetadata(),
 	}
+	response.Truncated = truncated
 
 	// Override with station-specific update time if more recent
 	if !lastUpdate.IsZero() {
-		response.Updated = lastUpdate.Format(time.RFC3339)
+		response.Updated = h.formatTime(lastUpdate)
+	}
+
+	if withLinks, _ := strconv.ParseBool(r.URL.Query().Get("links")); withLinks {
+		response.Links = buildLinks(r, stations)
 	}
 
-	h.writeJSON(w, response)
+	return response
 }
 
-func (h *Handler) writeJSON(w http.ResponseWriter, data interface{}) {
+// stationResponse converts a station to its API form, filtered and trimmed for display
+// A timeline is trimmed as a whole, so a busy direction can fill the board like it would on a platform display
+func stationResponse(station models.Station, view arrivalView) models.StationResponse {
+	var resp models.StationResponse
+	if view.timeline {
+		resp = station.ConvertToTimelineResponse()
+		resp.Timeline = view.trains(resp.Timeline)
+	} else {
+		resp = station.ConvertToResponse()
+		resp.N = view.trains(resp.N)
+		resp.S = view.trains(resp.S)
+	}
+	resp.Stale = view.stale(station)
+	if view.loc != nil {
+		resp.LastUpdate = resp.LastUpdate.In(view.loc)
+	}
+	return resp
+}
+
+// arrivalView is how one request wants arrivals rendered
+type arrivalView struct {
+	debug       bool      // Keep per-train feed provenance
+	verbose     bool      // Keep stop sequence, tracks and scheduled time
+	earliest    time.Time // Hide arrivals before this; zero keeps all
+	activeOnly  bool      // Drop stations with no arrivals left to show
+	timeline    bool      // Merge both directions into one chronological list
+	loc         *time.Location
+	staleBefore time.Time // Stations last updated before this are marked stale; zero marks none
+}
+
+// parseArrivalView reads ?debug=, ?verbose=, ?min=, ?active_only= and ?layout= for endpoints that return arrivals
+// ?min= is a duration like 2m; trains arriving sooner are hidden, for boards too far from the platform to make them.
+// The error is the message to send back with a 400
+func (h *Handler) parseArrivalView(r *http.Request) (arrivalView, error) {
+	view := arrivalView{loc: h.timeZone}
+	if h.staleAfter > 0 {
+		view.staleBefore = h.clock.Now().Add(-h.staleAfter)
+	}
+	view.debug, _ = strconv.ParseBool(r.URL.Query().Get("debug"))
+	view.verbose, _ = strconv.ParseBool(r.URL.Query().Get("verbose"))
+	view.activeOnly, _ = strconv.ParseBool(r.URL.Query().Get("active_only"))
+
+	if s := r.URL.Query().Get("min"); s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil || d < 0 {
+			return arrivalView{}, errors.New("Invalid min parameter (use a duration like 2m)")
+		}
+		view.earliest = h.clock.Now().Add(d)
+	}
+
+	switch r.URL.Query().Get("layout") {
+	case "", "grouped":
+	case "timeline":
+		view.timeline = true
+	default:
+		return arrivalView{}, errors.New("Invalid layout parameter (use grouped or timeline)")
+	}
+	return view, nil
+}
+
+// active reports whether station belongs in the response under ?active_only=
+// Judged after ?min= so a station whose only trains are hidden counts as silent rather than showing empty
+func (v arrivalView) active(station models.Station) bool {
+	if !v.activeOnly {
+		return true
+	}
+	return len(arrivingFrom(station.Trains.North, v.earliest)) > 0 || len(arrivingFrom(station.Trains.South, v.earliest)) > 0
+}
+
+// stale reports whether station's data is older than the handler's stale-after TTL
+// A station that has never had real-time data has a zero LastUpdate and so counts as stale
+func (v arrivalView) stale(station models.Station) bool {
+	return !v.staleBefore.IsZero() && station.LastUpdate.Before(v.staleBefore)
+}
+
+// activeStations drops the stations active rejects, leaving stations untouched when nothing is filtered
+func (v arrivalView) activeStations(stations []models.Station) []models.Station {
+	if !v.activeOnly {
+		return stations
+	}
+	kept := make([]models.Station, 0, len(stations))
+	for _, station := range stations {
+		if v.active(station) {
+			kept = append(kept, station)
+		}
+	}
+	return kept
+}
+
+// trains filters one direction's arrivals, trims them to the display limit and renders them in one copying pass:
+// debug and verbose fields are cleared unless asked for and times are put in the response zone.
+// Filtering comes first so hidden trains don't use up display slots. The result is always a copy rather than
+// the input mutated, since the slices are shared with the store
+func (v arrivalView) trains(trains []models.Train) []models.Train {
+	trains = displayArrivals(arrivingFrom(trains, v.earliest))
+	if trains == nil {
+		return nil
+	}
+	result := make([]models.Train, len(trains))
+	for i, train := range trains {
+		if !v.debug {
+			train.Source = ""
+		}
+		if !v.verbose {
+			train.StopSequence = 0
+			train.ScheduledTrack, train.ActualTrack = "", ""
+			train.ScheduledTime = nil
+		}
+		if v.loc != nil {
+			train.Time = train.Time.In(v.loc)
+			if train.ScheduledTime != nil {
+				scheduled := train.ScheduledTime.In(v.loc)
+				train.ScheduledTime = &scheduled
+			}
+		}
+		result[i] = train
+	}
+	return result
+}
+
+// arrivingFrom returns the trains arriving at or after earliest, in a new slice when any are filtered out
+func arrivingFrom(trains []models.Train, earliest time.Time) []models.Train {
+	if earliest.IsZero() || trains == nil {
+		return trains
+	}
+	result := make([]models.Train, 0, len(trains))
+	for _, train := range trains {
+		if !train.Time.Before(earliest) {
+			result = append(result, train)
+		}
+	}
+	return result
+}
+
+// streamLinks writes a ,"links":{...} member for the first count active stations in stations
+// It reports false if encoding failed and the response was cut short
+func (h *Handler) streamLinks(w io.Writer, r *http.Request, stations iter.Seq[models.Station], view arrivalView, count int) bool {
+	base := baseURL(r)
+	enc := json.NewEncoder(w)
+
+	io.WriteString(w, `,"links":{"self":`)
+	if err := enc.Encode(base + r.URL.RequestURI()); err != nil {
+		log.Printf("Error streaming station links: %v", err)
+		return false
+	}
+	io.WriteString(w, `,"stations":{`)
+	written := 0
+	for station := range stations {
+		if written >= count {
+			break
+		}
+		if !view.active(station) {
+			continue
+		}
+		if written > 0 {
+			io.WriteString(w, ",")
+		}
+		if err := enc.Encode(station.ID); err != nil {
+			log.Printf("Error streaming station links: %v", err)
+			return false
+		}
+		io.WriteString(w, ":")
+		if err := enc.Encode(stationLinks(base, station)); err != nil {
+			log.Printf("Error streaming station links: %v", err)
+			return false
+		}
+		written++
+	}
+	io.WriteString(w, "}}")
+	return true
+}
+
+// buildLinks constructs absolute links from the request's own scheme and host
+// so they resolve correctly behind whatever hostname the client used
+func buildLinks(r *http.Request, stations []models.Station) *ResponseLinks {
+	base := baseURL(r)
+	links := &ResponseLinks{
+		Self:     base + r.URL.RequestURI(),
+		Stations: make(map[string]StationLinks, len(stations)),
+	}
+
+	for _, station := range stations {
+		links.Stations[station.ID] = stationLinks(base, station)
+	}
+
+	return links
+}
+
+// stationLinks builds the links for one station against base
+func stationLinks(base string, station models.Station) StationLinks {
+	routes := make(map[string]string, len(station.Routes))
+	for _, route := range station.Routes {
+		routes[route] = base + "/by-route/" + url.PathEscape(route)
+	}
+	return StationLinks{
+		Self:   base + "/by-id/" + url.PathEscape(station.ID),
+		Routes: routes,
+		Alerts: base + "/alerts?station=" + url.QueryEscape(station.ID),
+	}
+}
+
+// baseURL returns scheme://host for the request, honouring X-Forwarded-Proto from a TLS-terminating proxy
+func baseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if proto := r.Header.Get("X-Forwarded-Proto"); proto == "http" || proto == "https" {
+		scheme = proto
+	}
+	return scheme + "://" + r.Host
+}
+
+// displayArrivals trims sorted arrivals to ArrivalDisplayLimit
+func displayArrivals(trains []models.Train) []models.Train {
+	if len(trains) > ArrivalDisplayLimit {
+		return trains[:ArrivalDisplayLimit]
+	}
+	return trains
+}
+
+// writeJSON encodes a response in the key style chosen by ?style=
+func (h *Handler) writeJSON(w http.ResponseWriter, r *http.Request, data interface{}) {
+	style, ok := parseStyle(r)
+	if !ok {
+		h.writeError(w, "Invalid style parameter (use default or camel)", http.StatusBadRequest)
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(data); err != nil {
+	h.setCacheControl(w, r)
+	if err := json.NewEncoder(w).Encode(styled(data, style)); err != nil {
 		h.writeError(w, "Failed to encode response", http.StatusInternalServerError)
 	}
 }
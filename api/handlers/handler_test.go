@@ -1,24 +1,180 @@
 package handlers
 
 import (
+	"encoding/json"
+	"fmt"
+	"iter"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
 	"testing"
 	"time"
+	"unicode/utf8"
 
+	"github.com/gorilla/mux"
+	"github.com/jusunglee/mta-go/internal/clock"
 	"github.com/jusunglee/mta-go/internal/models"
 )
 
 // MockClient implements mta.Client for testing
-type MockClient struct{}
+// stations, when set, backs the ID, route, bounds and all-stations lookups
+// alerts, when set, replaces the default alerts a1 and a2
+type MockClient struct {
+	stations []models.Station
+	alerts   []models.Alert
+}
 
 func (m *MockClient) GetStationsByLocation(lat, lon float64, limit int) ([]models.Station, error) {
+	origin := models.Location{Lat: lat, Lon: lon}
+	result := append([]models.Station{}, m.stations...)
+	sort.SliceStable(result, func(i, j int) bool {
+		return origin.DistanceKm(result[i].Location) < origin.DistanceKm(result[j].Location)
+	})
+	if len(result) > limit {
+		result = result[:limit]
+	}
+	return result, nil
+}
+
+func (m *MockClient) GetStationsByLocationMerged(lat, lon float64, limit int, radiusKm float64) ([]models.Station, error) {
 	return []models.Station{}, nil
 }
 
+func (m *MockClient) GetStationsWithinRadius(lat, lon, radiusKm float64) ([]models.Station, error) {
+	origin := models.Location{Lat: lat, Lon: lon}
+	result := []models.Station{}
+	for _, station := range m.stations {
+		if origin.DistanceKm(station.Location) <= radiusKm {
+			result = append(result, station)
+		}
+	}
+	return result, nil
+}
+
+func (m *MockClient) GetRoutesNearby(lat, lon, radiusKm float64) ([]string, error) {
+	stations, _ := m.GetStationsWithinRadius(lat, lon, radiusKm)
+	seen := make(map[string]bool)
+	routes := []string{}
+	for _, station := range stations {
+		for _, route := range station.Routes {
+			if !seen[route] {
+				seen[route] = true
+				routes = append(routes, route)
+			}
+		}
+	}
+	sort.Strings(routes)
+	return routes, nil
+}
+
+func (m *MockClient) AllStations() iter.Seq[models.Station] {
+	return slices.Values(m.stations)
+}
+
+func (m *MockClient) GetStationsByTrunk(trunk string) ([]models.Station, error) {
+	result := []models.Station{}
+	for _, station := range m.stations {
+		for _, route := range station.Routes {
+			if t, _ := models.TrunkOf(route); t == trunk {
+				result = append(result, station)
+				break
+			}
+		}
+	}
+	return result, nil
+}
+
 func (m *MockClient) GetStationsByRoute(route string) ([]models.Station, error) {
-	return []models.Station{}, nil
+	return append([]models.Station{}, m.stations...), nil
+}
+
+func (m *MockClient) GetStationSummariesByRoute(route string) ([]models.StationSummary, error) {
+	result := make([]models.StationSummary, len(m.stations))
+	for i, station := range m.stations {
+		result[i] = models.StationSummary{
+			ID:       station.ID,
+			Name:     station.Name,
+			Location: [2]float64{station.Location.Lat, station.Location.Lon},
+			Routes:   station.Routes,
+		}
+	}
+	return result, nil
 }
 
 func (m *MockClient) GetStationsByIDs(ids []string) ([]models.Station, error) {
+	result := []models.Station{}
+	for _, station := range m.stations {
+		for _, id := range ids {
+			if station.ID == id {
+				result = append(result, station)
+			}
+		}
+	}
+	return result, nil
+}
+
+func (m *MockClient) GetStationsByIDPrefix(prefix string) ([]models.Station, error) {
+	result := []models.Station{}
+	for _, station := range m.stations {
+		if strings.HasPrefix(station.ID, prefix) {
+			result = append(result, station)
+		}
+	}
+	return result, nil
+}
+
+func (m *MockClient) FindStationsByIDs(ids []string) ([]models.Station, []string, error) {
+	found, _ := m.GetStationsByIDs(ids)
+	var missing []string
+	for _, id := range ids {
+		matched := false
+		for _, station := range found {
+			if station.ID == id {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			missing = append(missing, id)
+		}
+	}
+	return found, missing, nil
+}
+
+func (m *MockClient) GetAllStations() ([]models.Station, error) {
+	return append([]models.Station{}, m.stations...), nil
+}
+
+func (m *MockClient) GetStationsInBounds(minLat, minLon, maxLat, maxLon float64) ([]models.Station, error) {
+	result := []models.Station{}
+	for _, station := range m.stations {
+		loc := station.Location
+		if loc.Lat >= minLat && loc.Lat <= maxLat && loc.Lon >= minLon && loc.Lon <= maxLon {
+			result = append(result, station)
+		}
+	}
+	return result, nil
+}
+
+func (m *MockClient) GetBounds() (models.Bounds, bool) {
+	if len(m.stations) == 0 {
+		return models.Bounds{}, false
+	}
+	b := models.Bounds{MinLat: 90, MinLon: 180, MaxLat: -90, MaxLon: -180}
+	for _, station := range m.stations {
+		b.MinLat = math.Min(b.MinLat, station.Location.Lat)
+		b.MinLon = math.Min(b.MinLon, station.Location.Lon)
+		b.MaxLat = math.Max(b.MaxLat, station.Location.Lat)
+		b.MaxLon = math.Max(b.MaxLon, station.Location.Lon)
+	}
+	return b, true
+}
+
+func (m *MockClient) SearchStations(query string, fuzzy bool, limit int) ([]models.Station, error) {
 	return []models.Station{}, nil
 }
 
@@ -26,8 +182,131 @@ func (m *MockClient) GetRoutes() ([]string, error) {
 	return []string{"A", "B", "C"}, nil
 }
 
+func (m *MockClient) GetRouteInfo(route string) (models.RouteInfo, error) {
+	info := models.RouteInfo{ShortName: route}
+	if route == "N" {
+		info.Headsigns = map[models.Direction]string{
+			models.DirectionNorth: "Astoria-Ditmars Blvd",
+			models.DirectionSouth: "Coney Island-Stillwell Av",
+		}
+	}
+	return info, nil
+}
+
+func (m *MockClient) GetFares(route, zone string) ([]models.Fare, error) {
+	fares := []models.Fare{
+		{ID: "base", Price: 2.9, Currency: "USD"},
+		{ID: "express", Price: 7, Currency: "USD", Rules: []models.FareRule{{Route: "X27"}}},
+	}
+	result := []models.Fare{}
+	for _, fare := range fares {
+		if fare.AppliesTo(route, zone) {
+			result = append(result, fare)
+		}
+	}
+	return result, nil
+}
+
+func (m *MockClient) GetRouteShape(route string) ([][]models.Location, error) {
+	switch route {
+	case "L":
+		return [][]models.Location{{{Lat: 40.74, Lon: -74.00}, {Lat: 40.73, Lon: -73.99}}}, nil
+	case "A":
+		return [][]models.Location{
+			{{Lat: 40.68, Lon: -73.90}, {Lat: 40.67, Lon: -73.83}},
+			{{Lat: 40.68, Lon: -73.90}, {Lat: 40.58, Lon: -73.82}},
+		}, nil
+	case "G":
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("route %s not found", route)
+	}
+}
+
+func (m *MockClient) GetArrivalsByRoute(route string) (map[string]models.TrainsByDirection, error) {
+	if route != "N" {
+		return nil, fmt.Errorf("route %s not found", route)
+	}
+	return map[string]models.TrainsByDirection{
+		"R16": {North: []models.Train{{Route: "N", Time: time.Now(), Source: "nqrw"}}, South: []models.Train{}},
+	}, nil
+}
+
+func (m *MockClient) GetRouteStatus(now time.Time) ([]models.RouteStatus, error) {
+	median, p90 := 4.5, 9.0
+	return []models.RouteStatus{
+		{Route: "N", Stations: 10, StationsWithTrains: 8, MedianMinutes: &median, P90Minutes: &p90},
+		{Route: "W", Stations: 5},
+	}, nil
+}
+
+func (m *MockClient) GetTripProgress(tripID string) (models.TripProgress, error) {
+	if tripID != "046400_N..N" {
+		return models.TripProgress{}, fmt.Errorf("trip %s not found", tripID)
+	}
+	return models.TripProgress{
+		TripID:    tripID,
+		Route:     "N",
+		Direction: "N",
+		Current:   &models.TripPosition{StationID: "R17", Name: "34 St-Herald Sq", Status: "STOPPED_AT"},
+		Upcoming:  []models.TripStop{{StationID: "R16", Name: "Times Sq-42 St", Time: time.Now()}},
+	}, nil
+}
+
+// mockOutageStart and mockOutageEnd bound alert a2's only active period
+var (
+	mockOutageStart = time.Date(2024, 12, 7, 6, 0, 0, 0, time.UTC)
+	mockOutageEnd   = time.Date(2024, 12, 7, 12, 0, 0, 0, time.UTC)
+)
+
 func (m *MockClient) GetServiceAlerts() ([]models.Alert, error) {
-	return []models.Alert{}, nil
+	if m.alerts != nil {
+		return m.alerts, nil
+	}
+	return []models.Alert{
+		{ID: "a1", Header: "Delays", Stations: []string{"127"}},
+		{ID: "a2", Header: "Elevator outage", Stations: []string{"631"},
+			ActivePeriods: []models.TimePeriod{{Start: &mockOutageStart, End: &mockOutageEnd}}},
+	}, nil
+}
+
+func (m *MockClient) GetAlertsInRange(start, end time.Time) ([]models.Alert, error) {
+	alerts, _ := m.GetServiceAlerts()
+	result := []models.Alert{}
+	for _, alert := range alerts {
+		if alert.OverlapsRange(start, end) {
+			result = append(result, alert)
+		}
+	}
+	return result, nil
+}
+
+func (m *MockClient) GetFeedLatencies() []models.FeedLatency {
+	return []models.FeedLatency{{Feed: "ace", LastMs: 120, AverageMs: 95.5, Samples: 4, Breaker: "closed", Status: "ok"}}
+}
+
+func (m *MockClient) GetStats() models.Stats {
+	return models.Stats{
+		Stations:     3,
+		Routes:       2,
+		UpdateCycles: 5,
+		Feeds:        []models.FeedStats{{Feed: "ace", Fetches: 5, Failures: 1, LastError: "HTTP 503"}},
+	}
+}
+
+func (m *MockClient) Counts() (stations, routes, alerts int) {
+	routeNames, _ := m.GetRoutes()
+	alertList, _ := m.GetServiceAlerts()
+	return len(m.stations), len(routeNames), len(alertList)
+}
+
+func (m *MockClient) GetCoverage() (models.CoverageReport, error) {
+	return models.CoverageReport{
+		TotalStations:  3,
+		MissingCount:   1,
+		Missing:        []string{"R20"},
+		MissingByRoute: map[string][]string{"N": {"R20"}},
+	}, nil
 }
 
 func (m *MockClient) GetLastUpdate() time.Time {
@@ -76,3 +355,2074 @@ func TestResponseTypes(t *testing.T) {
 		t.Errorf("Expected 0 stations, got %d", len(stationsResponse.Data))
 	}
 }
+
+func TestArrivalViewTrains(t *testing.T) {
+	scheduled := time.Date(2024, 12, 1, 13, 0, 0, 0, time.UTC)
+	trains := []models.Train{
+		{Route: "N", Time: scheduled.Add(time.Minute), Source: "nqrw", StopSequence: 4, ActualTrack: "2", ScheduledTime: &scheduled},
+	}
+	loc := time.FixedZone("EST", -5*60*60)
+
+	result := arrivalView{loc: loc}.trains(trains)
+	if result[0].Source != "" {
+		t.Errorf("Expected source to be cleared, got %q", result[0].Source)
+	}
+	if result[0].StopSequence != 0 || result[0].ActualTrack != "" || result[0].ScheduledTime != nil {
+		t.Errorf("Expected verbose fields to be cleared, got %+v", result[0])
+	}
+	if result[0].Time.Location() != loc {
+		t.Errorf("Expected time in %v, got %v", loc, result[0].Time.Location())
+	}
+	if trains[0].Source != "nqrw" || trains[0].StopSequence != 4 || trains[0].ScheduledTime != &scheduled || trains[0].Time.Location() != time.UTC {
+		t.Errorf("trains must not mutate the shared input slice, got %+v", trains[0])
+	}
+
+	result = arrivalView{debug: true, verbose: true, loc: loc}.trains(trains)
+	if result[0].Source != "nqrw" || result[0].StopSequence != 4 || result[0].ScheduledTime.Location() != loc {
+		t.Errorf("Expected debug and verbose fields kept with times in %v, got %+v", loc, result[0])
+	}
+	if scheduled.Location() != time.UTC {
+		t.Error("trains must not mutate the shared scheduled time")
+	}
+}
+
+func TestHandleStationByRoute(t *testing.T) {
+	now := time.Date(2024, 12, 1, 8, 0, 0, 0, time.UTC)
+	client := &MockClient{
+		stations: []models.Station{
+			{
+				ID:     "R16",
+				Name:   "Times Sq-42 St",
+				Routes: []string{"N", "Q", "R", "W"},
+				Trains: models.TrainsByDirection{
+					North: []models.Train{
+						{Route: "N", Time: now.Add(2 * time.Minute)},
+						{Route: "Q", Time: now.Add(4 * time.Minute)},
+						{Route: "N", Time: now.Add(7 * time.Minute)},
+					},
+					South: []models.Train{
+						{Route: "R", Time: now.Add(3 * time.Minute)},
+						{Route: "W", Time: now.Add(5 * time.Minute)},
+						{Route: "R", Time: now.Add(9 * time.Minute)},
+					},
+				},
+				LastUpdate: now,
+			},
+		},
+	}
+
+	r := mux.NewRouter()
+	NewHandler(client).RegisterRoutes(r)
+
+	t.Run("groups arrivals per direction and route", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest("GET", "/station/R16/by-route", nil))
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+
+		var response StationByRouteResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+
+		north := response.Data.N
+		if len(north) != 2 || len(north["N"]) != 2 || len(north["Q"]) != 1 {
+			t.Errorf("Unexpected northbound grouping: %v", north)
+		}
+		if !north["N"][0].Equal(now.Add(2*time.Minute)) || !north["N"][1].Equal(now.Add(7*time.Minute)) {
+			t.Errorf("Expected N arrivals sorted, got %v", north["N"])
+		}
+
+		south := response.Data.S
+		if len(south) != 2 || len(south["R"]) != 2 || len(south["W"]) != 1 {
+			t.Errorf("Unexpected southbound grouping: %v", south)
+		}
+	})
+
+	t.Run("unknown station", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest("GET", "/station/XYZ/by-route", nil))
+
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("Expected status 404, got %d", rec.Code)
+		}
+	})
+}
+
+func TestHandleStationsGeoJSON(t *testing.T) {
+	client := &MockClient{
+		stations: []models.Station{
+			{ID: "127", Name: "Times Sq-42 St", Location: models.Location{Lat: 40.75529, Lon: -73.987495}, Routes: []string{"1", "2", "3"}},
+			{ID: "631", Name: "Grand Central-42 St", Location: models.Location{Lat: 40.751776, Lon: -73.976848}, Routes: []string{"4", "5", "6"}},
+			{ID: "635", Name: "14 St-Union Sq", Location: models.Location{Lat: 40.734673, Lon: -73.989951}, Routes: []string{"4", "5", "6"}},
+		},
+	}
+
+	r := mux.NewRouter()
+	NewHandler(client).RegisterRoutes(r)
+
+	t.Run("all stations", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest("GET", "/stations.geojson", nil))
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		if ct := rec.Header().Get("Content-Type"); ct != "application/geo+json" {
+			t.Errorf("Expected application/geo+json, got %q", ct)
+		}
+
+		var fc struct {
+			Type     string `json:"type"`
+			Features []struct {
+				Type     string `json:"type"`
+				Geometry struct {
+					Type        string    `json:"type"`
+					Coordinates []float64 `json:"coordinates"`
+				} `json:"geometry"`
+				Properties map[string]interface{} `json:"properties"`
+			} `json:"features"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &fc); err != nil {
+			t.Fatalf("Failed to decode GeoJSON: %v", err)
+		}
+
+		if fc.Type != "FeatureCollection" {
+			t.Errorf("Expected FeatureCollection, got %q", fc.Type)
+		}
+		if len(fc.Features) != 3 {
+			t.Fatalf("Expected 3 features, got %d", len(fc.Features))
+		}
+
+		f := fc.Features[0]
+		if f.Type != "Feature" || f.Geometry.Type != "Point" {
+			t.Errorf("Expected Point Feature, got %s/%s", f.Type, f.Geometry.Type)
+		}
+		// GeoJSON is [lon, lat]
+		if f.Geometry.Coordinates[0] != -73.987495 || f.Geometry.Coordinates[1] != 40.75529 {
+			t.Errorf("Expected [lon, lat] coordinates, got %v", f.Geometry.Coordinates)
+		}
+		if f.Properties["id"] != "127" || f.Properties["name"] != "Times Sq-42 St" {
+			t.Errorf("Unexpected properties: %v", f.Properties)
+		}
+		if routes, ok := f.Properties["routes"].([]interface{}); !ok || len(routes) != 3 {
+			t.Errorf("Expected 3 routes in properties, got %v", f.Properties["routes"])
+		}
+	})
+
+	t.Run("bounding box", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest("GET", "/stations.geojson?min_lat=40.75&min_lon=-73.99&max_lat=40.76&max_lon=-73.97", nil))
+
+		var fc models.GeoJSONFeatureCollection
+		if err := json.Unmarshal(rec.Body.Bytes(), &fc); err != nil {
+			t.Fatalf("Failed to decode GeoJSON: %v", err)
+		}
+		if len(fc.Features) != 2 {
+			t.Errorf("Expected 2 features in bounds, got %d", len(fc.Features))
+		}
+	})
+
+	t.Run("partial bounding box", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest("GET", "/stations.geojson?min_lat=40.75", nil))
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400, got %d", rec.Code)
+		}
+	})
+
+	for _, query := range []string{
+		"min_lat=40.76&min_lon=-73.99&max_lat=40.75&max_lon=-73.97",
+		"min_lat=40.75&min_lon=-73.97&max_lat=40.76&max_lon=-73.99",
+	} {
+		t.Run("inverted bounding box "+query, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, httptest.NewRequest("GET", "/stations.geojson?"+query, nil))
+
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("Expected status 400, got %d", rec.Code)
+			}
+		})
+	}
+}
+
+func TestHandleByLocationUnits(t *testing.T) {
+	// Times Sq-42 St to Grand Central-42 St is about 978 m / 0.61 mi
+	client := &MockClient{
+		stations: []models.Station{
+			{ID: "631", Name: "Grand Central-42 St", Location: models.Location{Lat: 40.751776, Lon: -73.976848}},
+			{ID: "635", Name: "14 St-Union Sq", Location: models.Location{Lat: 40.734673, Lon: -73.989951}},
+		},
+	}
+
+	r := mux.NewRouter()
+	NewHandler(client).RegisterRoutes(r)
+
+	tests := []struct {
+		name  string
+		units string
+		want  []models.Distance
+	}{
+		{"default is metric", "", []models.Distance{{Value: 978, Unit: "m"}, {Value: 2.3, Unit: "km"}}},
+		{"metric", "&units=metric", []models.Distance{{Value: 978, Unit: "m"}, {Value: 2.3, Unit: "km"}}},
+		{"imperial", "&units=imperial", []models.Distance{{Value: 0.61, Unit: "mi"}, {Value: 1.43, Unit: "mi"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, httptest.NewRequest("GET", "/by-location?lat=40.75529&lon=-73.987495"+tt.units, nil))
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+			}
+			var resp StationsResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if len(resp.Data) != len(tt.want) {
+				t.Fatalf("Expected %d stations, got %d", len(tt.want), len(resp.Data))
+			}
+			for i, want := range tt.want {
+				if got := resp.Data[i].Distance; got == nil || *got != want {
+					t.Errorf("Station %s: expected distance %+v, got %+v", resp.Data[i].ID, want, got)
+				}
+			}
+		})
+	}
+
+	t.Run("short distances in feet", func(t *testing.T) {
+		got := formatDistance(0.1, true)
+		if got.Unit != "ft" || got.Value != 328 {
+			t.Errorf("Expected 328 ft, got %+v", got)
+		}
+	})
+
+	t.Run("invalid units", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest("GET", "/by-location?lat=40.75529&lon=-73.987495&units=furlongs", nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400, got %d", rec.Code)
+		}
+	})
+
+	t.Run("other endpoints omit distance", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest("GET", "/by-route/4", nil))
+		if strings.Contains(rec.Body.String(), `"distance"`) {
+			t.Errorf("Expected no distance outside location queries, got %s", rec.Body.String())
+		}
+	})
+}
+
+func TestByRouteSummary(t *testing.T) {
+	now := time.Date(2024, 12, 1, 8, 0, 0, 0, time.UTC)
+	client := &MockClient{stations: []models.Station{{
+		ID: "635", Name: "14 St-Union Sq", Location: models.Location{Lat: 40.734673, Lon: -73.989951}, Routes: []string{"4", "5", "6"},
+		Trains: models.TrainsByDirection{North: []models.Train{{Route: "4", Time: now.Add(time.Minute)}}},
+		Stops:  map[string]models.Location{"635N": {Lat: 40.734673, Lon: -73.989951}},
+	}}}
+	r := mux.NewRouter()
+	NewHandler(client).RegisterRoutes(r)
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest("GET", "/by-route/4?summary=true", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rec.Code)
+	}
+
+	var response StationSummariesResponse
+	dec := json.NewDecoder(rec.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&response); err != nil {
+		t.Fatalf("Expected only summary fields: %v", err)
+	}
+	expected := []models.StationSummary{{ID: "635", Name: "14 St-Union Sq", Location: [2]float64{40.734673, -73.989951}, Routes: []string{"4", "5", "6"}}}
+	if !reflect.DeepEqual(response.Data, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, response.Data)
+	}
+}
+
+func TestHandleReachable(t *testing.T) {
+	// Times Sq is the origin; Grand Central is ~0.98km away and Union Sq ~2.3km
+	client := &MockClient{stations: []models.Station{
+		{ID: "127", Name: "Times Sq-42 St", Location: models.Location{Lat: 40.75529, Lon: -73.987495}},
+		{ID: "631", Name: "Grand Central-42 St", Location: models.Location{Lat: 40.751776, Lon: -73.976848}},
+		{ID: "635", Name: "14 St-Union Sq", Location: models.Location{Lat: 40.734673, Lon: -73.989951}},
+	}}
+	h := NewHandler(client)
+	r := mux.NewRouter()
+	h.RegisterRoutes(r)
+
+	get := func(t *testing.T, query string) (int, StationsResponse) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest("GET", "/reachable?lat=40.75529&lon=-73.987495"+query, nil))
+		var response StationsResponse
+		if rec.Code == http.StatusOK {
+			if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+		}
+		return rec.Code, response
+	}
+
+	tests := []struct {
+		name     string
+		query    string
+		speed    float64
+		expected map[string]int // Station ID -> walk minutes
+	}{
+		{"default 10 minutes", "", 0, map[string]int{"127": 1}},
+		{"15 minutes", "&minutes=15", 0, map[string]int{"127": 1, "631": 13}},
+		{"30 minutes", "&minutes=30", 0, map[string]int{"127": 1, "631": 13, "635": 29}},
+		{"faster walker", "&minutes=10", 100, map[string]int{"127": 1, "631": 10}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h.SetWalkingSpeed(tt.speed)
+			defer h.SetWalkingSpeed(0)
+
+			code, response := get(t, tt.query)
+			if code != http.StatusOK {
+				t.Fatalf("Expected status 200, got %d", code)
+			}
+			got := make(map[string]int)
+			for _, station := range response.Data {
+				got[station.ID] = station.WalkMinutes
+				if station.Distance == nil {
+					t.Errorf("Expected a distance for %s", station.ID)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Expected walk minutes %v, got %v", tt.expected, got)
+			}
+		})
+	}
+
+	for _, query := range []string{"&minutes=0", "&minutes=-5", "&minutes=61", "&minutes=soon", "&minutes=NaN", "&minutes=Inf", "&minutes=-Inf"} {
+		if code, _ := get(t, query); code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", query, code)
+		}
+	}
+}
+
+func TestParsePositiveIntParam(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		expected int
+		wantErr  bool
+	}{
+		{"missing uses default", "", 3, false},
+		{"empty uses default", "?limit=", 3, false},
+		{"valid", "?limit=7", 7, false},
+		{"max allowed", "?limit=10", 10, false},
+		{"zero", "?limit=0", 0, true},
+		{"negative", "?limit=-2", 0, true},
+		{"over max", "?limit=11", 0, true},
+		{"not a number", "?limit=lots", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parsePositiveIntParam(httptest.NewRequest("GET", "/"+tt.query, nil), "limit", 3, 10)
+			if tt.wantErr {
+				if err == nil || err.Error() != "Invalid limit parameter (use 1-10)" {
+					t.Errorf("Expected the shared limit error, got %v", err)
+				}
+				return
+			}
+			if err != nil || got != tt.expected {
+				t.Errorf("Expected %d, got %d (err %v)", tt.expected, got, err)
+			}
+		})
+	}
+}
+
+func TestParseLatLon(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		wantErr string
+	}{
+		{"valid", "?lat=40.75529&lon=-73.987495", ""},
+		{"missing lon", "?lat=40.75529", "Missing lat/lon parameter"},
+		{"bad lat", "?lat=north&lon=-73.987495", "Invalid lat parameter"},
+		{"NaN lat", "?lat=NaN&lon=-73.987495", "Invalid lat parameter"},
+		{"infinite lon", "?lat=40.75529&lon=-Inf", "Invalid lon parameter"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lat, lon, err := parseLatLon(httptest.NewRequest("GET", "/"+tt.query, nil))
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("Expected error %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil || lat != 40.75529 || lon != -73.987495 {
+				t.Errorf("Expected 40.75529,-73.987495, got %v,%v (err %v)", lat, lon, err)
+			}
+		})
+	}
+}
+
+func TestHandleByLocations(t *testing.T) {
+	client := &MockClient{
+		stations: []models.Station{
+			{ID: "127", Name: "Times Sq-42 St", Location: models.Location{Lat: 40.75529, Lon: -73.987495}},
+			{ID: "631", Name: "Grand Central-42 St", Location: models.Location{Lat: 40.751776, Lon: -73.976848}},
+			{ID: "635", Name: "14 St-Union Sq", Location: models.Location{Lat: 40.734673, Lon: -73.989951}},
+		},
+	}
+
+	r := mux.NewRouter()
+	NewHandler(client).RegisterRoutes(r)
+
+	post := func(query, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest("POST", "/by-locations"+query, strings.NewReader(body)))
+		return rec
+	}
+
+	t.Run("nearest per point", func(t *testing.T) {
+		// Near Grand Central, near Union Sq, then exactly at Times Sq
+		rec := post("", `[{"lat": 40.7520, "lon": -73.9770}, {"lat": 40.7340, "lon": -73.9900}, {"lat": 40.75529, "lon": -73.987495}]`)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+
+		var resp BatchLocationResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		expected := []string{"631", "635", "127"}
+		if len(resp.Data) != len(expected) {
+			t.Fatalf("Expected %d matches, got %d", len(expected), len(resp.Data))
+		}
+		for i, id := range expected {
+			match := resp.Data[i]
+			if len(match.Stations) != 1 || match.Stations[0].ID != id {
+				t.Errorf("Point %d: expected nearest station %s, got %+v", i, id, match.Stations)
+				continue
+			}
+			if match.Stations[0].Distance == nil {
+				t.Errorf("Point %d: expected a distance", i)
+			}
+		}
+		if resp.Data[2].Location != (models.Location{Lat: 40.75529, Lon: -73.987495}) {
+			t.Errorf("Expected the posted location echoed back, got %+v", resp.Data[2].Location)
+		}
+	})
+
+	t.Run("limit returns top-K", func(t *testing.T) {
+		rec := post("?limit=2", `[{"lat": 40.75529, "lon": -73.987495}]`)
+		var resp BatchLocationResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		if len(resp.Data) != 1 || len(resp.Data[0].Stations) != 2 ||
+			resp.Data[0].Stations[0].ID != "127" || resp.Data[0].Stations[1].ID != "631" {
+			t.Errorf("Expected 127 then 631, got %+v", resp.Data)
+		}
+	})
+
+	tooMany := "[" + strings.Repeat(`{"lat": 40.75, "lon": -73.98},`, DefaultMaxBatchLocations) + `{"lat": 40.75, "lon": -73.98}]`
+	errorTests := []struct {
+		name     string
+		query    string
+		body     string
+		expected int
+	}{
+		{"malformed body", "", `{"lat": 40.75}`, http.StatusBadRequest},
+		{"empty batch", "", `[]`, http.StatusBadRequest},
+		{"bad limit", "?limit=6", `[{"lat": 40.75, "lon": -73.98}]`, http.StatusBadRequest},
+		{"too many points", "", tooMany, http.StatusBadRequest},
+	}
+	for _, tt := range errorTests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec := post(tt.query, tt.body); rec.Code != tt.expected {
+				t.Errorf("Expected status %d, got %d", tt.expected, rec.Code)
+			}
+		})
+	}
+
+	t.Run("configured cap", func(t *testing.T) {
+		capped := NewHandler(client)
+		capped.SetMaxBatchLocations(2)
+		cr := mux.NewRouter()
+		capped.RegisterRoutes(cr)
+
+		body := `[{"lat": 40.75, "lon": -73.98}, {"lat": 40.75, "lon": -73.98}, {"lat": 40.75, "lon": -73.98}]`
+		rec := httptest.NewRecorder()
+		cr.ServeHTTP(rec, httptest.NewRequest("POST", "/by-locations", strings.NewReader(body)))
+		if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "max 2") {
+			t.Errorf("Expected a 400 naming the cap of 2, got %d: %s", rec.Code, rec.Body.String())
+		}
+	})
+
+	t.Run("GET not allowed", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest("GET", "/by-locations", nil))
+		if rec.Code != http.StatusMethodNotAllowed {
+			t.Errorf("Expected status 405, got %d", rec.Code)
+		}
+	})
+}
+
+func TestHandleRoutesNearby(t *testing.T) {
+	client := &MockClient{
+		stations: []models.Station{
+			{ID: "127", Name: "Times Sq-42 St", Location: models.Location{Lat: 40.75529, Lon: -73.987495}, Routes: []string{"1", "2", "3"}},
+			{ID: "725", Name: "Times Sq-42 St", Location: models.Location{Lat: 40.755477, Lon: -73.987691}, Routes: []string{"7", "1"}},
+			{ID: "631", Name: "Grand Central-42 St", Location: models.Location{Lat: 40.751776, Lon: -73.976848}, Routes: []string{"4", "5", "6"}},
+		},
+	}
+
+	r := mux.NewRouter()
+	NewHandler(client).RegisterRoutes(r)
+
+	tests := []struct {
+		name     string
+		query    string
+		status   int
+		expected []string
+	}{
+		{"default radius", "lat=40.7553&lon=-73.9875", http.StatusOK, []string{"1", "2", "3", "7"}},
+		{"wider radius", "lat=40.7553&lon=-73.9875&radius=1.5", http.StatusOK, []string{"1", "2", "3", "4", "5", "6", "7"}},
+		{"nothing nearby", "lat=40.6&lon=-73.8", http.StatusOK, []string{}},
+		{"missing lon", "lat=40.7553", http.StatusBadRequest, nil},
+		{"invalid radius", "lat=40.7553&lon=-73.9875&radius=-1", http.StatusBadRequest, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, httptest.NewRequest("GET", "/routes/nearby?"+tt.query, nil))
+
+			if rec.Code != tt.status {
+				t.Fatalf("Expected status %d, got %d: %s", tt.status, rec.Code, rec.Body.String())
+			}
+			if tt.expected == nil {
+				return
+			}
+
+			var resp RoutesResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			// An empty result must be [] rather than null
+			if resp.Data == nil {
+				t.Fatalf("Expected a list, got null")
+			}
+			if !reflect.DeepEqual(resp.Data, tt.expected) {
+				t.Errorf("Expected routes %v, got %v", tt.expected, resp.Data)
+			}
+		})
+	}
+}
+
+func TestArrivalDisplayLimit(t *testing.T) {
+	now := time.Date(2024, 12, 1, 8, 0, 0, 0, time.UTC)
+	var trains []models.Train
+	for i := 0; i < 25; i++ {
+		trains = append(trains, models.Train{Route: "N", Time: now.Add(time.Duration(i) * time.Minute)})
+	}
+	client := &MockClient{stations: []models.Station{
+		{ID: "R16", Name: "Times Sq-42 St", Routes: []string{"N"}, Trains: models.TrainsByDirection{North: trains}},
+	}}
+
+	r := mux.NewRouter()
+	NewHandler(client).RegisterRoutes(r)
+
+	t.Run("station response", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest("GET", "/by-id/R16", nil))
+
+		var response StationsResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		if len(response.Data) != 1 || len(response.Data[0].N) != ArrivalDisplayLimit {
+			t.Fatalf("Expected %d northbound arrivals, got %+v", ArrivalDisplayLimit, response.Data)
+		}
+		if !response.Data[0].N[0].Time.Equal(now) {
+			t.Errorf("Expected the soonest arrivals to be shown, got first %v", response.Data[0].N[0].Time)
+		}
+	})
+
+	t.Run("grouped by route", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest("GET", "/station/R16/by-route", nil))
+
+		var response StationByRouteResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		if got := len(response.Data.N["N"]); got != ArrivalDisplayLimit {
+			t.Errorf("Expected %d northbound N arrivals, got %d", ArrivalDisplayLimit, got)
+		}
+	})
+
+	// The store's copy must be untouched so retained arrivals stay available
+	if len(client.stations[0].Trains.North) != 25 {
+		t.Errorf("Expected stored arrivals to be left intact, got %d", len(client.stations[0].Trains.North))
+	}
+}
+
+func TestMinArrivalFilter(t *testing.T) {
+	now := time.Date(2024, 12, 1, 8, 0, 0, 0, time.UTC)
+	trains := []models.Train{
+		{Route: "N", Time: now.Add(2*time.Minute - time.Second)},
+		{Route: "N", Time: now.Add(2 * time.Minute)},
+		{Route: "N", Time: now.Add(2*time.Minute + time.Second)},
+		{Route: "N", Time: now.Add(5 * time.Minute)},
+	}
+	client := &MockClient{stations: []models.Station{
+		{ID: "R16", Name: "Times Sq-42 St", Routes: []string{"N"}, Trains: models.TrainsByDirection{North: trains}},
+	}}
+
+	h := NewHandler(client)
+	h.SetClock(clock.NewFake(now))
+	r := mux.NewRouter()
+	h.RegisterRoutes(r)
+
+	tests := []struct {
+		name     string
+		query    string
+		status   int
+		expected []time.Time
+	}{
+		{"absent keeps all", "", http.StatusOK, []time.Time{trains[0].Time, trains[1].Time, trains[2].Time, trains[3].Time}},
+		{"hides trains just under the threshold", "?min=2m", http.StatusOK, []time.Time{trains[1].Time, trains[2].Time, trains[3].Time}},
+		{"zero keeps all", "?min=0s", http.StatusOK, []time.Time{trains[0].Time, trains[1].Time, trains[2].Time, trains[3].Time}},
+		{"invalid duration", "?min=two", http.StatusBadRequest, nil},
+		{"negative duration", "?min=-1m", http.StatusBadRequest, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, httptest.NewRequest("GET", "/by-id/R16"+tt.query, nil))
+			if rec.Code != tt.status {
+				t.Fatalf("Expected status %d, got %d", tt.status, rec.Code)
+			}
+			if tt.status != http.StatusOK {
+				return
+			}
+
+			var response StationsResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			var got []time.Time
+			for _, train := range response.Data[0].N {
+				got = append(got, train.Time)
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Expected arrivals %v, got %v", tt.expected, got)
+			}
+		})
+	}
+
+	t.Run("grouped by route", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest("GET", "/station/R16/by-route?min=2m", nil))
+
+		var response StationByRouteResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		if got := response.Data.N["N"]; len(got) != 3 || !got[0].Equal(trains[1].Time) {
+			t.Errorf("Expected 3 northbound N arrivals from the threshold on, got %v", got)
+		}
+	})
+
+	if len(client.stations[0].Trains.North) != len(trains) {
+		t.Errorf("Expected stored arrivals to be left intact, got %d", len(client.stations[0].Trains.North))
+	}
+}
+
+func TestTimelineLayout(t *testing.T) {
+	now := time.Date(2024, 12, 1, 8, 0, 0, 0, time.UTC)
+	client := &MockClient{stations: []models.Station{
+		{ID: "R16", Name: "Times Sq-42 St", Routes: []string{"N", "Q"}, Trains: models.TrainsByDirection{
+			North: []models.Train{
+				{Route: "N", Time: now.Add(1 * time.Minute)},
+				{Route: "Q", Time: now.Add(4 * time.Minute)},
+			},
+			South: []models.Train{
+				{Route: "Q", Time: now.Add(2 * time.Minute)},
+				{Route: "N", Time: now.Add(3 * time.Minute)},
+				{Route: "N", Time: now.Add(5 * time.Minute)},
+			},
+		}},
+	}}
+	h := NewHandler(client)
+	h.SetClock(clock.NewFake(now))
+	r := mux.NewRouter()
+	h.RegisterRoutes(r)
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest("GET", "/by-id/R16?layout=timeline", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rec.Code)
+	}
+	var response StationsResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	type arrival struct {
+		route     string
+		direction models.Direction
+		minutes   int
+	}
+	expected := []arrival{{"N", "N", 1}, {"Q", "S", 2}, {"N", "S", 3}, {"Q", "N", 4}, {"N", "S", 5}}
+	var got []arrival
+	for _, train := range response.Data[0].Timeline {
+		got = append(got, arrival{train.Route, train.Direction, int(train.Time.Sub(now).Minutes())})
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected timeline %v, got %v", expected, got)
+	}
+	if response.Data[0].N != nil || response.Data[0].S != nil {
+		t.Errorf("Expected N and S left out of the timeline layout, got %v and %v", response.Data[0].N, response.Data[0].S)
+	}
+
+	// The grouped default is unchanged and doesn't label directions
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest("GET", "/by-id/R16", nil))
+	if strings.Contains(rec.Body.String(), "timeline") || strings.Contains(rec.Body.String(), `"direction"`) {
+		t.Errorf("Expected the grouped layout without timeline fields, got %s", rec.Body.String())
+	}
+
+	// Filters apply to the merged list
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest("GET", "/by-id/R16?layout=timeline&min=3m", nil))
+	response = StationsResponse{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if timeline := response.Data[0].Timeline; len(timeline) != 3 || !timeline[0].Time.Equal(now.Add(3*time.Minute)) {
+		t.Errorf("Expected 3 arrivals from +3m, got %v", timeline)
+	}
+
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest("GET", "/by-id/R16?layout=columns", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an unknown layout, got %d", rec.Code)
+	}
+}
+
+func TestResponseTimeZone(t *testing.T) {
+	// Pretend the host runs on Pacific time; nothing in the response should pick that up
+	hostZone := time.Local
+	time.Local = time.FixedZone("PST", -8*60*60)
+	t.Cleanup(func() { time.Local = hostZone })
+
+	arrival := time.Date(2024, 12, 1, 8, 5, 0, 0, time.UTC).In(time.Local)
+	client := &MockClient{
+		stations: []models.Station{{
+			ID: "127", Name: "Times Sq-42 St", Routes: []string{"1"},
+			Trains:     models.TrainsByDirection{North: []models.Train{{Route: "1", Time: arrival}}},
+			LastUpdate: arrival.Add(-time.Minute),
+		}},
+		alerts: []models.Alert{{ID: "a1", Header: "Delays", ActivePeriods: []models.TimePeriod{{Start: &arrival}}}},
+	}
+
+	tests := []struct {
+		name     string
+		zone     *time.Location
+		timezone string
+		time     string
+	}{
+		{"default UTC", nil, "UTC", "2024-12-01T08:05:00Z"},
+		{"configured zone", mustLoadLocation(t, "America/New_York"), "America/New_York", "2024-12-01T03:05:00-05:00"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHandler(client)
+			h.SetTimeZone(tt.zone)
+			r := mux.NewRouter()
+			h.RegisterRoutes(r)
+
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, httptest.NewRequest("GET", "/by-id/127", nil))
+			var body struct {
+				Data []struct {
+					N []struct {
+						Time string `json:"time"`
+					} `json:"N"`
+				} `json:"data"`
+				Timezone string `json:"timezone"`
+				Updated  string `json:"updated"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if body.Timezone != tt.timezone {
+				t.Errorf("Expected timezone %q, got %q", tt.timezone, body.Timezone)
+			}
+			if len(body.Data) != 1 || len(body.Data[0].N) != 1 || body.Data[0].N[0].Time != tt.time {
+				t.Fatalf("Expected arrival at %s, got %+v", tt.time, body.Data)
+			}
+			// The mock's update times are the real now, so compare offsets rather than strings
+			updated, err := time.Parse(time.RFC3339, body.Updated)
+			if err != nil {
+				t.Fatalf("Failed to parse updated: %v", err)
+			}
+			_, got := updated.Zone()
+			_, want := updated.In(h.timeZone).Zone()
+			if got != want {
+				t.Errorf("Expected updated in the response zone, got %s", body.Updated)
+			}
+
+			for _, path := range []string{"/trip/046400_N..N/progress", "/station/127/by-route", "/alerts"} {
+				rec = httptest.NewRecorder()
+				r.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+				if strings.Contains(rec.Body.String(), "-08:00") {
+					t.Errorf("Expected no host-local times from %s, got %s", path, rec.Body.String())
+				}
+			}
+			if start := client.alerts[0].ActivePeriods[0].Start; !start.Equal(arrival) || start.Location() != time.Local {
+				t.Errorf("Expected the client's alert left unchanged, got %v", start)
+			}
+		})
+	}
+}
+
+func mustLoadLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Skipf("Time zone database unavailable: %v", err)
+	}
+	return loc
+}
+
+func TestStaleStations(t *testing.T) {
+	now := time.Date(2024, 12, 1, 8, 0, 0, 0, time.UTC)
+	client := &MockClient{stations: []models.Station{
+		{ID: "127", Name: "Times Sq-42 St", Routes: []string{"1"}, LastUpdate: now.Add(-time.Minute)},
+		{ID: "631", Name: "Grand Central-42 St", Routes: []string{"6"}, LastUpdate: now.Add(-10 * time.Minute)},
+	}}
+
+	tests := []struct {
+		name       string
+		staleAfter time.Duration
+		expected   map[string]bool
+	}{
+		{"default TTL", DefaultStaleAfter, map[string]bool{"127": false, "631": true}},
+		{"longer TTL", 15 * time.Minute, map[string]bool{"127": false, "631": false}},
+		{"disabled", 0, map[string]bool{"127": false, "631": false}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHandler(client)
+			h.SetClock(clock.NewFake(now))
+			h.SetStaleAfter(tt.staleAfter)
+			r := mux.NewRouter()
+			h.RegisterRoutes(r)
+
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, httptest.NewRequest("GET", "/by-id/127,631", nil))
+			var response StationsResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			got := make(map[string]bool)
+			staleCount := 0
+			for _, station := range response.Data {
+				got[station.ID] = station.Stale
+				if station.Stale {
+					staleCount++
+				}
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Expected stale flags %v, got %v", tt.expected, got)
+			}
+			// Fresh stations leave the flag out entirely
+			if n := strings.Count(rec.Body.String(), `"stale"`); n != staleCount {
+				t.Errorf("Expected %d stale keys, got %d", staleCount, n)
+			}
+		})
+	}
+}
+
+func TestVerboseArrivals(t *testing.T) {
+	now := time.Date(2024, 12, 1, 8, 0, 0, 0, time.UTC)
+	scheduled := now.Add(2 * time.Minute)
+	client := &MockClient{stations: []models.Station{{
+		ID: "127", Name: "Times Sq-42 St", Routes: []string{"1"}, LastUpdate: now,
+		Trains: models.TrainsByDirection{North: []models.Train{{
+			Route: "1", Time: now.Add(3 * time.Minute), Realtime: true,
+			StopSequence: 12, ScheduledTrack: "1", ActualTrack: "2", ScheduledTime: &scheduled,
+		}}},
+	}}}
+
+	h := NewHandler(client)
+	h.SetClock(clock.NewFake(now))
+	r := mux.NewRouter()
+	h.RegisterRoutes(r)
+
+	get := func(t *testing.T, path string) (string, models.Train) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+		var response StationsResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		if len(response.Data) != 1 || len(response.Data[0].N) != 1 {
+			t.Fatalf("Expected one northbound train, got %+v", response.Data)
+		}
+		return rec.Body.String(), response.Data[0].N[0]
+	}
+
+	body, _ := get(t, "/by-id/127")
+	for _, key := range []string{"stop_sequence", "scheduled_track", "actual_track", "scheduled_time"} {
+		if strings.Contains(body, key) {
+			t.Errorf("Expected %s left out without ?verbose=true", key)
+		}
+	}
+
+	_, train := get(t, "/by-id/127?verbose=true")
+	if train.StopSequence != 12 || train.ScheduledTrack != "1" || train.ActualTrack != "2" {
+		t.Errorf("Expected stop sequence 12 on track 1 diverted to 2, got %+v", train)
+	}
+	if train.ScheduledTime == nil || !train.ScheduledTime.Equal(scheduled) {
+		t.Errorf("Expected scheduled time %v, got %v", scheduled, train.ScheduledTime)
+	}
+}
+
+func TestActiveOnlyFilter(t *testing.T) {
+	now := time.Date(2024, 12, 1, 8, 0, 0, 0, time.UTC)
+	soon := []models.Train{{Route: "N", Time: now.Add(time.Minute)}}
+	later := []models.Train{{Route: "N", Time: now.Add(5 * time.Minute)}}
+	client := &MockClient{stations: []models.Station{
+		{ID: "R16", Name: "Times Sq-42 St", Location: models.Location{Lat: 40.7547, Lon: -73.9864}, Trains: models.TrainsByDirection{North: later}},
+		{ID: "R17", Name: "34 St-Herald Sq", Location: models.Location{Lat: 40.7496, Lon: -73.9880}, Trains: models.TrainsByDirection{South: soon}},
+		{ID: "R18", Name: "28 St", Location: models.Location{Lat: 40.7455, Lon: -73.9888}},
+		{ID: "R19", Name: "23 St", Location: models.Location{Lat: 40.7413, Lon: -73.9893}, Trains: models.TrainsByDirection{North: []models.Train{}, South: []models.Train{}}},
+	}}
+
+	h := NewHandler(client)
+	h.SetClock(clock.NewFake(now))
+	r := mux.NewRouter()
+	h.RegisterRoutes(r)
+
+	ids := func(t *testing.T, path string) []string {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d", rec.Code)
+		}
+		var response StationsResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		got := []string{}
+		for _, station := range response.Data {
+			got = append(got, station.ID)
+		}
+		return got
+	}
+
+	tests := []struct {
+		name     string
+		path     string
+		expected []string
+	}{
+		{"absent keeps silent stations", "/by-id/R16,R17,R18,R19", []string{"R16", "R17", "R18", "R19"}},
+		{"false keeps silent stations", "/by-id/R16,R17,R18,R19?active_only=false", []string{"R16", "R17", "R18", "R19"}},
+		{"either direction counts", "/by-id/R16,R17,R18,R19?active_only=true", []string{"R16", "R17"}},
+		{"judged after min", "/by-id/R16,R17,R18,R19?active_only=true&min=2m", []string{"R16"}},
+		{"streamed stations", "/stations?active_only=true", []string{"R16", "R17"}},
+		{"nearest stations", "/by-location?lat=40.7413&lon=-73.9893&active_only=true", []string{"R17", "R16"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ids(t, tt.path); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Expected stations %v, got %v", tt.expected, got)
+			}
+		})
+	}
+
+	// Distances must follow the stations that are left, not their positions before filtering
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest("GET", "/by-location?lat=40.7496&lon=-73.9880&active_only=true", nil))
+	var response StationsResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(response.Data) == 0 || response.Data[0].ID != "R17" || response.Data[0].Distance.Value != 0 {
+		t.Errorf("Expected R17 first at distance 0, got %+v", response.Data)
+	}
+}
+
+func TestResponseStyleCamel(t *testing.T) {
+	now := time.Date(2024, 12, 1, 8, 0, 0, 0, time.UTC)
+	client := &MockClient{stations: []models.Station{{
+		ID:     "R16",
+		Name:   "Times Sq-42 St",
+		Routes: []string{"N", "S"},
+		Trains: models.TrainsByDirection{
+			North: []models.Train{{Route: "N", Time: now.Add(time.Minute)}},
+			South: []models.Train{{Route: "S", Time: now.Add(2 * time.Minute), UnlistedRoute: true}},
+		},
+		Stops:      map[string]models.Location{"R16N": {Lat: 40.7547, Lon: -73.9868}},
+		LastUpdate: now,
+	}}}
+	h := NewHandler(client)
+	h.SetClock(clock.NewFake(now)) // Keeps the station fresh, so no stale key
+	r := mux.NewRouter()
+	h.RegisterRoutes(r)
+
+	get := func(t *testing.T, path string) map[string]interface{} {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var body map[string]interface{}
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		return body
+	}
+	keys := func(v interface{}) []string {
+		var result []string
+		for k := range v.(map[string]interface{}) {
+			result = append(result, k)
+		}
+		sort.Strings(result)
+		return result
+	}
+
+	t.Run("station keys", func(t *testing.T) {
+		for _, path := range []string{"/by-id/R16?style=camel", "/stations?style=camel"} {
+			body := get(t, path)
+			station := body["data"].([]interface{})[0].(map[string]interface{})
+			expected := []string{"activeRoutes", "id", "lastUpdate", "location", "name", "north", "routes", "south", "stops"}
+			if got := keys(station); !reflect.DeepEqual(got, expected) {
+				t.Errorf("%s: expected station keys %v, got %v", path, expected, got)
+			}
+			train := station["south"].([]interface{})[0].(map[string]interface{})
+			if _, ok := train["unlistedRoute"]; !ok {
+				t.Errorf("%s: expected unlistedRoute in %v", path, train)
+			}
+			// Map keys are data, not field names
+			if _, ok := station["stops"].(map[string]interface{})["R16N"]; !ok {
+				t.Errorf("%s: expected stop ID keys to be kept, got %v", path, station["stops"])
+			}
+			if _, ok := body["staticDataUpdated"]; !ok {
+				t.Errorf("%s: expected embedded metadata keys to be renamed, got %v", path, keys(body))
+			}
+			if _, ok := body["truncated"]; ok {
+				t.Errorf("%s: expected omitempty fields to stay omitted, got %v", path, keys(body))
+			}
+		}
+	})
+
+	t.Run("route keys under directions", func(t *testing.T) {
+		body := get(t, "/station/R16/by-route?style=camel")
+		data := body["data"].(map[string]interface{})
+		if got := keys(data["north"]); !reflect.DeepEqual(got, []string{"N"}) {
+			t.Errorf("Expected route N kept as a key under north, got %v", got)
+		}
+		if got := keys(data["south"]); !reflect.DeepEqual(got, []string{"S"}) {
+			t.Errorf("Expected route S kept as a key under south, got %v", got)
+		}
+		if _, ok := data["lastUpdate"]; !ok {
+			t.Errorf("Expected lastUpdate, got keys %v", keys(data))
+		}
+	})
+
+	t.Run("default unchanged", func(t *testing.T) {
+		body := get(t, "/by-id/R16")
+		station := body["data"].([]interface{})[0].(map[string]interface{})
+		for _, key := range []string{"N", "S", "last_update"} {
+			if _, ok := station[key]; !ok {
+				t.Errorf("Expected default key %s, got keys %v", key, keys(station))
+			}
+		}
+	})
+
+	t.Run("invalid style", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest("GET", "/by-id/R16?style=kebab", nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400, got %d", rec.Code)
+		}
+	})
+}
+
+func TestHandleStationsStreaming(t *testing.T) {
+	lastUpdate := time.Date(2024, 12, 1, 8, 0, 0, 0, time.UTC)
+	client := &MockClient{}
+	for i := 0; i < 1200; i++ {
+		client.stations = append(client.stations, models.Station{
+			ID:   fmt.Sprintf("S%04d", i),
+			Name: fmt.Sprintf("Station %d, \"quoted\"", i),
+			Trains: models.TrainsByDirection{
+				North: []models.Train{{Route: "N", Time: lastUpdate, Source: "nqrw"}},
+			},
+			LastUpdate: lastUpdate,
+		})
+	}
+
+	h := NewHandler(client)
+	h.SetMaxStations(0)
+	r := mux.NewRouter()
+	h.RegisterRoutes(r)
+
+	decode := func(t *testing.T, path string) StationsResponse {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d", rec.Code)
+		}
+		if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+			t.Errorf("Expected application/json, got %q", ct)
+		}
+
+		var response StationsResponse
+		dec := json.NewDecoder(rec.Body)
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&response); err != nil {
+			t.Fatalf("Streamed output is not valid JSON: %v", err)
+		}
+		if dec.More() {
+			t.Error("Unexpected data after the response object")
+		}
+		return response
+	}
+
+	t.Run("all stations", func(t *testing.T) {
+		response := decode(t, "/stations")
+		if len(response.Data) != 1200 {
+			t.Fatalf("Expected 1200 stations, got %d", len(response.Data))
+		}
+		if response.Data[1].Name != `Station 1, "quoted"` {
+			t.Errorf("Unexpected station name %q", response.Data[1].Name)
+		}
+		if response.Data[0].N[0].Source != "" {
+			t.Error("Expected source hidden without ?debug=true")
+		}
+		if response.Updated != lastUpdate.Format(time.RFC3339) || response.Truncated {
+			t.Errorf("Unexpected metadata: %+v", response.ResponseMetadata)
+		}
+	})
+
+	t.Run("capped", func(t *testing.T) {
+		h.SetMaxStations(100)
+		defer h.SetMaxStations(0)
+
+		response := decode(t, "/stations")
+		if len(response.Data) != 100 || !response.Truncated {
+			t.Errorf("Expected 100 truncated stations, got %d (truncated=%v)", len(response.Data), response.Truncated)
+		}
+	})
+
+	t.Run("links", func(t *testing.T) {
+		h.SetMaxStations(100)
+		defer h.SetMaxStations(0)
+
+		response := decode(t, "/stations?links=true")
+		if response.Links == nil {
+			t.Fatal("Expected links with ?links=true")
+		}
+		if response.Links.Self != "http://example.com/stations?links=true" {
+			t.Errorf("Unexpected self link %q", response.Links.Self)
+		}
+		if len(response.Links.Stations) != len(response.Data) {
+			t.Errorf("Expected links for the %d streamed stations, got %d", len(response.Data), len(response.Links.Stations))
+		}
+		for _, station := range response.Data {
+			if got := response.Links.Stations[station.ID].Self; got != "http://example.com/by-id/"+station.ID {
+				t.Errorf("Unexpected link for station %s: %q", station.ID, got)
+			}
+		}
+		if decode(t, "/stations").Links != nil {
+			t.Error("Expected no links without ?links=true")
+		}
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		empty := NewHandler(&MockClient{})
+		er := mux.NewRouter()
+		empty.RegisterRoutes(er)
+
+		rec := httptest.NewRecorder()
+		er.ServeHTTP(rec, httptest.NewRequest("GET", "/stations", nil))
+
+		var response StationsResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Streamed output is not valid JSON: %v", err)
+		}
+		if response.Data == nil || len(response.Data) != 0 {
+			t.Errorf("Expected an empty data array, got %s", rec.Body.String())
+		}
+	})
+}
+
+func TestHandleRouteShape(t *testing.T) {
+	r := mux.NewRouter()
+	NewHandler(&MockClient{}).RegisterRoutes(r)
+
+	t.Run("single shape is a LineString", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest("GET", "/routes/L/shape.geojson", nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		if ct := rec.Header().Get("Content-Type"); ct != "application/geo+json" {
+			t.Errorf("Expected application/geo+json, got %q", ct)
+		}
+
+		var feature struct {
+			Type     string `json:"type"`
+			Geometry struct {
+				Type        string       `json:"type"`
+				Coordinates [][2]float64 `json:"coordinates"`
+			} `json:"geometry"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &feature); err != nil {
+			t.Fatalf("Failed to decode GeoJSON: %v", err)
+		}
+		if feature.Type != "Feature" || feature.Geometry.Type != "LineString" {
+			t.Errorf("Expected LineString Feature, got %s/%s", feature.Type, feature.Geometry.Type)
+		}
+		// GeoJSON is [lon, lat]
+		if len(feature.Geometry.Coordinates) != 2 || feature.Geometry.Coordinates[0] != [2]float64{-74.00, 40.74} {
+			t.Errorf("Unexpected coordinates %v", feature.Geometry.Coordinates)
+		}
+	})
+
+	t.Run("branches are a MultiLineString", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest("GET", "/routes/A/shape.geojson", nil))
+
+		var feature struct {
+			Geometry struct {
+				Type        string         `json:"type"`
+				Coordinates [][][2]float64 `json:"coordinates"`
+			} `json:"geometry"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &feature); err != nil {
+			t.Fatalf("Failed to decode GeoJSON: %v", err)
+		}
+		if feature.Geometry.Type != "MultiLineString" || len(feature.Geometry.Coordinates) != 2 {
+			t.Errorf("Expected 2-line MultiLineString, got %s with %d lines", feature.Geometry.Type, len(feature.Geometry.Coordinates))
+		}
+	})
+
+	for _, route := range []string{"G", "XYZ"} {
+		t.Run("no shape for "+route, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, httptest.NewRequest("GET", "/routes/"+route+"/shape.geojson", nil))
+			if rec.Code != http.StatusNotFound {
+				t.Errorf("Expected status 404, got %d", rec.Code)
+			}
+		})
+	}
+}
+
+func TestHandleRouteDirections(t *testing.T) {
+	r := mux.NewRouter()
+	NewHandler(&MockClient{}).RegisterRoutes(r)
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest("GET", "/routes/N/directions", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var response RouteDirectionsResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	expected := models.RouteDirections{
+		Route: "N",
+		Directions: []models.RouteDirection{
+			{Direction: models.DirectionNorth, Headsign: "Astoria-Ditmars Blvd", Label: "Astoria-Ditmars Blvd"},
+			{Direction: models.DirectionSouth, Headsign: "Coney Island-Stillwell Av", Label: "Coney Island-Stillwell Av"},
+		},
+	}
+	if !reflect.DeepEqual(response.Data, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, response.Data)
+	}
+	if got := rec.Header().Get("Cache-Control"); got != "public, max-age=3600" {
+		t.Errorf("Expected static Cache-Control, got %q", got)
+	}
+}
+
+func TestHandleByTrunk(t *testing.T) {
+	client := &MockClient{
+		stations: []models.Station{
+			{ID: "127", Name: "Times Sq-42 St", Routes: []string{"1", "2", "3"}},
+			{ID: "R16", Name: "Times Sq-42 St", Routes: []string{"N", "Q", "R", "W"}},
+			{ID: "631", Name: "Grand Central-42 St", Routes: []string{"4", "5", "6"}},
+			{ID: "635", Name: "14 St-Union Sq", Routes: []string{"4", "5", "6", "L", "N", "Q", "R", "W"}},
+		},
+	}
+
+	r := mux.NewRouter()
+	NewHandler(client).RegisterRoutes(r)
+
+	tests := []struct {
+		trunk    string
+		status   int
+		expected []string
+	}{
+		{"red", http.StatusOK, []string{"127"}},
+		{"yellow", http.StatusOK, []string{"R16", "635"}},
+		{"Green", http.StatusOK, []string{"631", "635"}},
+		{"lime", http.StatusOK, []string{}},
+		{"magenta", http.StatusBadRequest, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.trunk, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, httptest.NewRequest("GET", "/by-trunk/"+tt.trunk, nil))
+			if rec.Code != tt.status {
+				t.Fatalf("Expected status %d, got %d: %s", tt.status, rec.Code, rec.Body.String())
+			}
+			if tt.expected == nil {
+				if !strings.Contains(rec.Body.String(), "yellow") {
+					t.Errorf("Expected error to list valid trunks, got %s", rec.Body.String())
+				}
+				return
+			}
+
+			var response StationsResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			ids := []string{}
+			for _, station := range response.Data {
+				ids = append(ids, station.ID)
+			}
+			if !reflect.DeepEqual(ids, tt.expected) {
+				t.Errorf("Expected stations %v, got %v", tt.expected, ids)
+			}
+		})
+	}
+}
+
+func TestStationCapTruncates(t *testing.T) {
+	client := &MockClient{}
+	for i := 0; i < 5; i++ {
+		client.stations = append(client.stations, models.Station{
+			ID:       fmt.Sprintf("S%d", i),
+			Name:     fmt.Sprintf("Station %d", i),
+			Location: models.Location{Lat: 40.7, Lon: -73.9},
+		})
+	}
+
+	h := NewHandler(client)
+	r := mux.NewRouter()
+	h.RegisterRoutes(r)
+
+	t.Run("under cap", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest("GET", "/by-route/N", nil))
+
+		var response StationsResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		if len(response.Data) != 5 || response.Truncated {
+			t.Errorf("Expected 5 untruncated stations, got %d (truncated=%v)", len(response.Data), response.Truncated)
+		}
+	})
+
+	h.SetMaxStations(3)
+
+	t.Run("station endpoints", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest("GET", "/by-route/N", nil))
+
+		var response StationsResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		if len(response.Data) != 3 {
+			t.Errorf("Expected 3 stations after truncation, got %d", len(response.Data))
+		}
+		if !response.Truncated {
+			t.Error("Expected truncated flag to be set")
+		}
+	})
+
+	t.Run("geojson", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest("GET", "/stations.geojson", nil))
+
+		var fc models.GeoJSONFeatureCollection
+		if err := json.Unmarshal(rec.Body.Bytes(), &fc); err != nil {
+			t.Fatalf("Failed to decode GeoJSON: %v", err)
+		}
+		if len(fc.Features) != 3 || !fc.Truncated {
+			t.Errorf("Expected 3 truncated features, got %d (truncated=%v)", len(fc.Features), fc.Truncated)
+		}
+	})
+}
+
+func TestHandleFeedInfo(t *testing.T) {
+	h := NewHandler(&MockClient{stations: []models.Station{{ID: "127"}}})
+	router := mux.NewRouter()
+	h.RegisterRoutes(router)
+
+	req := httptest.NewRequest("GET", "/feed-info", nil)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rr.Code)
+	}
+
+	var response FeedInfoResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(response.Data) != 1 || response.Data[0].Feed != "ace" || response.Data[0].AverageMs != 95.5 {
+		t.Errorf("Unexpected feed info: %+v", response.Data)
+	}
+	if expected := (StoreCounts{Stations: 1, Routes: 3, Alerts: 2}); response.Counts != expected {
+		t.Errorf("Expected counts %+v, got %+v", expected, response.Counts)
+	}
+}
+
+func TestHandleByIDPrefix(t *testing.T) {
+	client := &MockClient{stations: []models.Station{
+		{ID: "R16", Name: "Times Sq-42 St"},
+		{ID: "R17", Name: "34 St-Herald Sq"},
+		{ID: "127", Name: "Times Sq-42 St"},
+	}}
+	router := mux.NewRouter()
+	NewHandler(client).RegisterRoutes(router)
+
+	tests := []struct {
+		prefix   string
+		expected []string
+	}{
+		{"R1", []string{"R16", "R17"}},
+		{"X", []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.prefix, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, httptest.NewRequest("GET", "/by-id-prefix/"+tt.prefix, nil))
+			if rr.Code != http.StatusOK {
+				t.Fatalf("Expected status 200, got %d", rr.Code)
+			}
+
+			var response StationsResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			ids := []string{}
+			for _, station := range response.Data {
+				ids = append(ids, station.ID)
+			}
+			if !slices.Equal(ids, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, ids)
+			}
+		})
+	}
+}
+
+func TestHandleRouteStatus(t *testing.T) {
+	router := mux.NewRouter()
+	NewHandler(&MockClient{}).RegisterRoutes(router)
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest("GET", "/routes/status", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rr.Code)
+	}
+
+	var response RouteStatusResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(response.Data) != 2 || response.Data[0].Route != "N" || *response.Data[0].MedianMinutes != 4.5 {
+		t.Errorf("Unexpected route status: %+v", response.Data)
+	}
+	// A route without arrivals omits the wait fields rather than reporting zero
+	if strings.Contains(rr.Body.String(), `"median_minutes":0`) {
+		t.Errorf("Expected no zero median for a route without arrivals, got %s", rr.Body.String())
+	}
+}
+
+func TestHandleTripProgress(t *testing.T) {
+	h := NewHandler(&MockClient{})
+	router := mux.NewRouter()
+	h.RegisterRoutes(router)
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest("GET", "/trip/046400_N..N/progress", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rr.Code)
+	}
+
+	var response TripProgressResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if response.Data.Current == nil || response.Data.Current.StationID != "R17" {
+		t.Errorf("Expected current position at R17, got %+v", response.Data.Current)
+	}
+	if len(response.Data.Upcoming) != 1 || response.Data.Upcoming[0].StationID != "R16" {
+		t.Errorf("Expected R16 as the next stop, got %+v", response.Data.Upcoming)
+	}
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest("GET", "/trip/unknown/progress", nil))
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for unknown trip, got %d", rr.Code)
+	}
+}
+
+func TestHandleBounds(t *testing.T) {
+	client := &MockClient{stations: []models.Station{
+		{ID: "127", Location: models.Location{Lat: 40.75529, Lon: -73.987495}},
+		{ID: "635", Location: models.Location{Lat: 40.734673, Lon: -73.989951}},
+		{ID: "631", Location: models.Location{Lat: 40.751776, Lon: -73.976848}},
+	}}
+	router := mux.NewRouter()
+	NewHandler(client).RegisterRoutes(router)
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest("GET", "/bounds", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rr.Code)
+	}
+
+	var response BoundsResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	expected := models.Bounds{MinLat: 40.734673, MinLon: -73.989951, MaxLat: 40.75529, MaxLon: -73.976848}
+	if response.Data != expected {
+		t.Errorf("Expected bounds %+v, got %+v", expected, response.Data)
+	}
+
+	rr = httptest.NewRecorder()
+	NewHandler(&MockClient{}).handleBounds(rr, httptest.NewRequest("GET", "/bounds", nil))
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status 503 without stations, got %d", rr.Code)
+	}
+}
+
+func TestHandleStats(t *testing.T) {
+	h := NewHandler(&MockClient{})
+	router := mux.NewRouter()
+	h.RegisterRoutes(router)
+
+	req := httptest.NewRequest("GET", "/stats", nil)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rr.Code)
+	}
+
+	var response StatsResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if response.Data.Stations != 3 || response.Data.UpdateCycles != 5 {
+		t.Errorf("Unexpected stats: %+v", response.Data)
+	}
+	if len(response.Data.Feeds) != 1 || response.Data.Feeds[0].LastError != "HTTP 503" {
+		t.Errorf("Unexpected feed stats: %+v", response.Data.Feeds)
+	}
+	if strings.Contains(rr.Body.String(), "last_static_update") {
+		t.Errorf("Expected unset times to be omitted, got %s", rr.Body.String())
+	}
+}
+
+func TestHandleByIDUnknownIDs(t *testing.T) {
+	client := &MockClient{
+		stations: []models.Station{{ID: "127", Name: "Times Sq-42 St"}},
+	}
+	h := NewHandler(client)
+	r := mux.NewRouter()
+	h.RegisterRoutes(r)
+
+	tests := []struct {
+		name            string
+		path            string
+		expectedStatus  int
+		expectedUnknown []string
+	}{
+		{"all found", "/by-id/127", http.StatusOK, nil},
+		{"partial lenient", "/by-id/127,999", http.StatusOK, []string{"999"}},
+		{"partial strict", "/by-id/127,999?strict=true", http.StatusNotFound, nil},
+		{"none found", "/by-id/998,999", http.StatusNotFound, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, httptest.NewRequest("GET", tt.path, nil))
+
+			if rec.Code != tt.expectedStatus {
+				t.Fatalf("Expected status %d, got %d", tt.expectedStatus, rec.Code)
+			}
+			if rec.Code != http.StatusOK {
+				return
+			}
+
+			var response StationsResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if fmt.Sprint(response.UnknownIDs) != fmt.Sprint(tt.expectedUnknown) {
+				t.Errorf("Expected unknown IDs %v, got %v", tt.expectedUnknown, response.UnknownIDs)
+			}
+		})
+	}
+}
+
+func TestMalformedPathParams(t *testing.T) {
+	client := &MockClient{
+		stations: []models.Station{{ID: "127", Name: "Times Sq-42 St", Routes: []string{"1"}}},
+	}
+	h := NewHandler(client)
+	r := mux.NewRouter()
+	h.RegisterRoutes(r)
+
+	tests := []struct {
+		path           string
+		expectedStatus int
+		expectedError  string
+	}{
+		{"/by-route/%20", http.StatusBadRequest, "route required"},
+		{"/by-route/%20%09", http.StatusBadRequest, "route required"},
+		{"/routes/%20", http.StatusBadRequest, "route required"},
+		{"/routes/%20/shape.geojson", http.StatusBadRequest, "route required"},
+		{"/route/%20/arrivals", http.StatusBadRequest, "route required"},
+		{"/by-route/%201%20", http.StatusOK, ""},
+		{"/by-id/%20", http.StatusBadRequest, "station ID required"},
+		{"/by-id/,,", http.StatusBadRequest, "station ID required"},
+		{"/by-id/%20,%20", http.StatusBadRequest, "station ID required"},
+		{"/by-id/%20127,,", http.StatusOK, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, httptest.NewRequest("GET", tt.path, nil))
+			if rec.Code != tt.expectedStatus {
+				t.Fatalf("Expected status %d, got %d: %s", tt.expectedStatus, rec.Code, rec.Body.String())
+			}
+			if tt.expectedError == "" {
+				return
+			}
+
+			var response ErrorResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if response.Error != tt.expectedError {
+				t.Errorf("Expected error %q, got %q", tt.expectedError, response.Error)
+			}
+		})
+	}
+}
+
+func TestHandleRouteArrivals(t *testing.T) {
+	h := NewHandler(&MockClient{})
+	r := mux.NewRouter()
+	h.RegisterRoutes(r)
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest("GET", "/route/N/arrivals", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rec.Code)
+	}
+
+	var response RouteArrivalsResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	north := response.Data["R16"].North
+	if len(north) != 1 || north[0].Route != "N" {
+		t.Errorf("Expected one northbound N at R16, got %v", north)
+	}
+	if north[0].Source != "" {
+		t.Errorf("Expected source hidden without debug, got %q", north[0].Source)
+	}
+
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest("GET", "/route/X/arrivals", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for unknown route, got %d", rec.Code)
+	}
+}
+
+func TestStationsResponseLinks(t *testing.T) {
+	client := &MockClient{
+		stations: []models.Station{{ID: "127", Name: "Times Sq-42 St", Routes: []string{"1", "2"}}},
+	}
+	h := NewHandler(client)
+	r := mux.NewRouter()
+	h.RegisterRoutes(r)
+
+	t.Run("omitted by default", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest("GET", "http://mta.example.com/by-id/127", nil))
+
+		var response StationsResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		if response.Links != nil {
+			t.Errorf("Expected no links without ?links=true, got %+v", response.Links)
+		}
+	})
+
+	t.Run("generated on request", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "http://mta.example.com/by-id/127?links=true", nil)
+		req.Header.Set("X-Forwarded-Proto", "https")
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+
+		var response StationsResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		if response.Links == nil {
+			t.Fatal("Expected links with ?links=true")
+		}
+		if response.Links.Self != "https://mta.example.com/by-id/127?links=true" {
+			t.Errorf("Unexpected self link: %s", response.Links.Self)
+		}
+
+		station := response.Links.Stations["127"]
+		expected := map[string]string{
+			"self":    "https://mta.example.com/by-id/127",
+			"alerts":  "https://mta.example.com/alerts?station=127",
+			"route 1": "https://mta.example.com/by-route/1",
+			"route 2": "https://mta.example.com/by-route/2",
+		}
+		got := map[string]string{
+			"self":    station.Self,
+			"alerts":  station.Alerts,
+			"route 1": station.Routes["1"],
+			"route 2": station.Routes["2"],
+		}
+		for name, want := range expected {
+			if got[name] != want {
+				t.Errorf("Expected %s link %s, got %s", name, want, got[name])
+			}
+		}
+	})
+}
+
+func TestHandleAlertsStationFilter(t *testing.T) {
+	h := NewHandler(&MockClient{})
+	r := mux.NewRouter()
+	h.RegisterRoutes(r)
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest("GET", "/alerts?station=127", nil))
+
+	var response AlertsResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(response.Data) != 1 || response.Data[0].ID != "a1" {
+		t.Errorf("Expected only alert a1 for station 127, got %+v", response.Data)
+	}
+}
+
+func TestHandleAlertsTimeRange(t *testing.T) {
+	h := NewHandler(&MockClient{})
+	r := mux.NewRouter()
+	h.RegisterRoutes(r)
+
+	tests := []struct {
+		name     string
+		query    string
+		status   int
+		expected []string
+	}{
+		{"no range", "", http.StatusOK, []string{"a1", "a2"}},
+		{"overlapping range", "?from=2024-12-07T11:00:00Z&to=2024-12-08T00:00:00Z", http.StatusOK, []string{"a1", "a2"}},
+		{"range before outage", "?from=2024-12-06T00:00:00Z&to=2024-12-07T06:00:00Z", http.StatusOK, []string{"a1"}},
+		{"open-ended from", "?from=2024-12-07T12:00:00Z", http.StatusOK, []string{"a1"}},
+		{"open-ended to", "?to=2024-12-07T06:00:01Z", http.StatusOK, []string{"a1", "a2"}},
+		{"invalid timestamp", "?from=saturday", http.StatusBadRequest, nil},
+		{"reversed range", "?from=2024-12-08T00:00:00Z&to=2024-12-07T00:00:00Z", http.StatusBadRequest, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, httptest.NewRequest("GET", "/alerts"+tt.query, nil))
+			if rec.Code != tt.status {
+				t.Fatalf("Expected status %d, got %d: %s", tt.status, rec.Code, rec.Body.String())
+			}
+			if tt.expected == nil {
+				return
+			}
+
+			var response AlertsResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			var ids []string
+			for _, alert := range response.Data {
+				ids = append(ids, alert.ID)
+			}
+			if !reflect.DeepEqual(ids, tt.expected) {
+				t.Errorf("Expected alerts %v, got %v", tt.expected, ids)
+			}
+		})
+	}
+}
+
+// parseICalEvents unfolds an iCalendar body and returns each VEVENT's properties
+func parseICalEvents(t *testing.T, body string) []map[string]string {
+	t.Helper()
+	if !strings.HasPrefix(body, "BEGIN:VCALENDAR\r\n") || !strings.HasSuffix(body, "END:VCALENDAR\r\n") {
+		t.Fatalf("Expected a CRLF-delimited VCALENDAR, got %q", body)
+	}
+
+	var events []map[string]string
+	var event map[string]string
+	for _, line := range strings.Split(strings.ReplaceAll(body, "\r\n ", ""), "\r\n") {
+		name, value, _ := strings.Cut(line, ":")
+		switch {
+		case line == "BEGIN:VEVENT":
+			event = map[string]string{}
+		case line == "END:VEVENT":
+			events = append(events, event)
+			event = nil
+		case event != nil:
+			event[name] = value
+		}
+	}
+	return events
+}
+
+func TestHandleAlertsICS(t *testing.T) {
+	now := time.Date(2024, 12, 7, 8, 0, 0, 0, time.UTC)
+	at := func(hour int) *time.Time {
+		ts := now.Add(time.Duration(hour) * time.Hour)
+		return &ts
+	}
+	client := &MockClient{alerts: []models.Alert{
+		{ID: "ongoing", Header: "Delays; signal problems", Description: "Expect delays, plan ahead", Routes: []string{"A", "C"},
+			ActivePeriods: []models.TimePeriod{{Start: at(-2), End: at(2)}}},
+		{ID: "planned", Header: "Weekend work",
+			ActivePeriods: []models.TimePeriod{{Start: at(-30), End: at(-20)}, {Start: at(24), End: at(48)}}},
+		{ID: "no-periods", Header: "Reduced service"},
+		{ID: "open-ended", Header: "Station closed", ActivePeriods: []models.TimePeriod{{Start: at(-1)}}},
+	}}
+	h := NewHandler(client)
+	h.SetClock(clock.NewFake(now))
+	r := mux.NewRouter()
+	h.RegisterRoutes(r)
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest("GET", "/alerts.ics", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rec.Code)
+	}
+	if got := rec.Header().Get("Content-Type"); !strings.HasPrefix(got, "text/calendar") {
+		t.Errorf("Expected a text/calendar Content-Type, got %q", got)
+	}
+
+	events := parseICalEvents(t, rec.Body.String())
+	if len(events) != 2 {
+		t.Fatalf("Expected 2 VEVENTs (ongoing and the upcoming planned period), got %d: %+v", len(events), events)
+	}
+
+	expected := []map[string]string{
+		{
+			"UID":         "ongoing-0@mta-go",
+			"DTSTAMP":     "20241207T080000Z",
+			"DTSTART":     "20241207T060000Z",
+			"DTEND":       "20241207T100000Z",
+			"SUMMARY":     `Delays\; signal problems`,
+			"DESCRIPTION": `Expect delays\, plan ahead`,
+			"CATEGORIES":  "A,C",
+		},
+		{
+			"UID":     "planned-1@mta-go",
+			"DTSTAMP": "20241207T080000Z",
+			"DTSTART": "20241208T080000Z",
+			"DTEND":   "20241209T080000Z",
+			"SUMMARY": "Weekend work",
+		},
+	}
+	if !reflect.DeepEqual(events, expected) {
+		t.Errorf("Expected events %+v, got %+v", expected, events)
+	}
+}
+
+func TestAlertsCalendarUIDStable(t *testing.T) {
+	now := time.Date(2024, 12, 7, 8, 0, 0, 0, time.UTC)
+	start, end := now.Add(-time.Hour), now.Add(3*time.Hour)
+	alert := models.Alert{ID: "lmm:alert:1", Header: "Delays", ActivePeriods: []models.TimePeriod{{Start: &start, End: &end}}}
+
+	// The feed reports the alert again a cycle later under the same entity ID
+	first := parseICalEvents(t, string(alertsCalendar([]models.Alert{alert}, now)))
+	second := parseICalEvents(t, string(alertsCalendar([]models.Alert{alert}, now.Add(time.Minute))))
+	if len(first) != 1 || len(second) != 1 {
+		t.Fatalf("Expected one VEVENT per cycle, got %+v and %+v", first, second)
+	}
+	for _, name := range []string{"UID", "DTSTART", "DTEND"} {
+		if first[0][name] != second[0][name] {
+			t.Errorf("Expected %s unchanged across update cycles, got %q then %q", name, first[0][name], second[0][name])
+		}
+	}
+}
+
+func TestFoldICalLine(t *testing.T) {
+	line := "DESCRIPTION:" + strings.Repeat("é", 100)
+	folded := foldICalLine(line)
+	for _, part := range strings.Split(folded, "\r\n") {
+		if len(part) > 75 {
+			t.Errorf("Folded line is %d octets: %q", len(part), part)
+		}
+		if !utf8.ValidString(part) {
+			t.Errorf("Fold split a UTF-8 character: %q", part)
+		}
+	}
+	if got := strings.ReplaceAll(folded, "\r\n ", ""); got != line {
+		t.Errorf("Unfolding did not restore the line: %q", got)
+	}
+}
+
+func TestHandleFares(t *testing.T) {
+	h := NewHandler(&MockClient{})
+	r := mux.NewRouter()
+	h.RegisterRoutes(r)
+
+	tests := []struct {
+		query    string
+		expected []string
+	}{
+		{"", []string{"base", "express"}},
+		{"?route=x27", []string{"base", "express"}},
+		{"?route=1", []string{"base"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, httptest.NewRequest("GET", "/fares"+tt.query, nil))
+			if rec.Code != http.StatusOK {
+				t.Fatalf("Expected status 200, got %d", rec.Code)
+			}
+
+			var response FaresResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			var ids []string
+			for _, fare := range response.Data {
+				ids = append(ids, fare.ID)
+			}
+			if !reflect.DeepEqual(ids, tt.expected) {
+				t.Errorf("Expected fares %v, got %v", tt.expected, ids)
+			}
+		})
+	}
+}
+
+func TestHandleCoverage(t *testing.T) {
+	h := NewHandler(&MockClient{})
+	r := mux.NewRouter()
+	h.RegisterRoutes(r)
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest("GET", "/coverage", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rec.Code)
+	}
+
+	var response CoverageResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if response.Data.MissingCount != 1 || response.Data.MissingByRoute["N"][0] != "R20" {
+		t.Errorf("Unexpected coverage report: %+v", response.Data)
+	}
+}
+
+func TestCacheControl(t *testing.T) {
+	client := &MockClient{stations: []models.Station{
+		{ID: "127", Name: "Times Sq-42 St", Location: models.Location{Lat: 40.75529, Lon: -73.987495}, Routes: []string{"1"}},
+	}}
+	router := mux.NewRouter()
+	NewHandler(client).RegisterRoutes(router)
+
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{"/by-route/1", "public, max-age=30"},
+		{"/stations", "public, max-age=30"},
+		{"/trip/046400_N..N/progress", "public, max-age=30"},
+		{"/alerts", "public, max-age=60"},
+		{"/alerts.ics", "public, max-age=60"},
+		{"/routes/1", "public, max-age=60"},
+		{"/routes", "public, max-age=3600"},
+		{"/routes/L/shape.geojson", "public, max-age=3600"},
+		{"/fares", "public, max-age=3600"},
+		{"/stations.geojson", "public, max-age=3600"},
+		{"/bounds", "public, max-age=3600"},
+		{"/stats", "no-cache"},
+		// Errors are never cacheable
+		{"/routes/X/shape.geojson", ""},
+		{"/by-location", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, httptest.NewRequest("GET", tt.path, nil))
+			if got := rr.Header().Get("Cache-Control"); got != tt.expected {
+				t.Errorf("Expected Cache-Control %q, got %q (status %d)", tt.expected, got, rr.Code)
+			}
+		})
+	}
+
+	t.Run("custom policy", func(t *testing.T) {
+		h := NewHandler(client)
+		h.SetCachePolicy(CachePolicy{"/routes": 5 * time.Minute})
+		router := mux.NewRouter()
+		h.RegisterRoutes(router)
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest("GET", "/routes", nil))
+		if got := rr.Header().Get("Cache-Control"); got != "public, max-age=300" {
+			t.Errorf("Expected the custom max-age, got %q", got)
+		}
+
+		rr = httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest("GET", "/alerts", nil))
+		if got := rr.Header().Get("Cache-Control"); got != "" {
+			t.Errorf("Expected no Cache-Control for routes outside the policy, got %q", got)
+		}
+	})
+}
@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/jusunglee/mta-go/internal/models"
+)
+
+// icalTimeFormat is an RFC 5545 UTC date-time
+const icalTimeFormat = "20060102T150405Z"
+
+// icalLineOctets is the longest content line RFC 5545 allows before folding
+const icalLineOctets = 75
+
+// handleAlertsICS returns alerts as an iCalendar feed with one VEVENT per active period
+// Only periods with an end that hasn't passed are included; a period without a start starts now.
+// Open-ended periods and alerts without periods are skipped, since they have nothing to put on a calendar
+func (h *Handler) handleAlertsICS(w http.ResponseWriter, r *http.Request) {
+	alerts, err := h.client.GetServiceAlerts()
+	if err != nil {
+		h.writeError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="mta-alerts.ics"`)
+	h.setCacheControl(w, r)
+	w.Write(alertsCalendar(alerts, h.clock.Now()))
+}
+
+// alertsCalendar renders alerts as a VCALENDAR with CRLF line endings, as RFC 5545 requires
+func alertsCalendar(alerts []models.Alert, now time.Time) []byte {
+	var buf bytes.Buffer
+	line := func(name, value string) {
+		buf.WriteString(foldICalLine(name + ":" + value))
+		buf.WriteString("\r\n")
+	}
+
+	line("BEGIN", "VCALENDAR")
+	line("VERSION", "2.0")
+	line("PRODID", "-//mta-go//Service Alerts//EN")
+	line("CALSCALE", "GREGORIAN")
+	line("X-WR-CALNAME", "MTA Subway Service Alerts")
+	for _, alert := range alerts {
+		for i, period := range alert.ActivePeriods {
+			if period.End == nil || !period.End.After(now) {
+				continue
+			}
+			start := now
+			if period.Start != nil {
+				start = *period.Start
+			}
+
+			line("BEGIN", "VEVENT")
+			// Alert IDs are the feed's entity IDs, kept across update cycles, so calendar apps update
+			// events rather than duplicating them
+			line("UID", fmt.Sprintf("%s-%d@mta-go", alert.ID, i))
+			line("DTSTAMP", now.UTC().Format(icalTimeFormat))
+			line("DTSTART", start.UTC().Format(icalTimeFormat))
+			line("DTEND", period.End.UTC().Format(icalTimeFormat))
+			line("SUMMARY", escapeICalText(alert.Header))
+			if alert.Description != "" {
+				line("DESCRIPTION", escapeICalText(alert.Description))
+			}
+			if len(alert.Routes) > 0 {
+				routes := make([]string, len(alert.Routes))
+				for j, route := range alert.Routes {
+					routes[j] = escapeICalText(route)
+				}
+				line("CATEGORIES", strings.Join(routes, ","))
+			}
+			line("END", "VEVENT")
+		}
+	}
+	line("END", "VCALENDAR")
+	return buf.Bytes()
+}
+
+// icalTextEscaper escapes the characters RFC 5545 reserves in TEXT values
+var icalTextEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+
+func escapeICalText(s string) string {
+	return icalTextEscaper.Replace(s)
+}
+
+// foldICalLine splits a content line longer than icalLineOctets, continuing with a leading space
+// Splits fall between UTF-8 characters so multi-byte text in alert descriptions survives
+func foldICalLine(s string) string {
+	if len(s) <= icalLineOctets {
+		return s
+	}
+
+	var b strings.Builder
+	limit := icalLineOctets
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		b.WriteString(s[:cut])
+		b.WriteString("\r\n ")
+		s = s[cut:]
+		// Continuation lines lose one octet to the leading space
+		limit = icalLineOctets - 1
+	}
+	b.WriteString(s)
+	return b.String()
+}
@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+// ResponseStyle selects the key naming used in JSON responses
+type ResponseStyle int
+
+const (
+	// StyleDefault keeps the original keys: snake_case fields and N/S for directions
+	StyleDefault ResponseStyle = iota
+	// StyleCamel renames struct keys to camelCase and N/S to north/south for typed clients
+	StyleCamel
+)
+
+// parseStyle reads ?style=, reporting false for an unknown value
+func parseStyle(r *http.Request) (ResponseStyle, bool) {
+	switch r.URL.Query().Get("style") {
+	case "", "default":
+		return StyleDefault, true
+	case "camel":
+		return StyleCamel, true
+	default:
+		return StyleDefault, false
+	}
+}
+
+// styled returns data ready to encode in the requested style
+// The camel form is derived from the default struct tags, so response types only declare their keys once
+func styled(data interface{}, style ResponseStyle) interface{} {
+	if style != StyleCamel {
+		return data
+	}
+	return camelValue(reflect.ValueOf(data))
+}
+
+// camelKey converts a default-style JSON key to camelCase
+func camelKey(key string) string {
+	switch key {
+	case "N":
+		return "north"
+	case "S":
+		return "south"
+	}
+	parts := strings.Split(key, "_")
+	for i := 1; i < len(parts); i++ {
+		if parts[i] != "" {
+			parts[i] = strings.ToUpper(parts[i][:1]) + parts[i][1:]
+		}
+	}
+	return strings.Join(parts, "")
+}
+
+var jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+
+// camelValue rebuilds v with struct keys renamed by camelKey
+// Only struct field names change: map keys are data (station IDs, route names like "N") and are kept as is.
+// Follows encoding/json for tags, omitempty and embedded structs; types with their own MarshalJSON, like time.Time, pass through
+func camelValue(v reflect.Value) interface{} {
+	if !v.IsValid() {
+		return nil
+	}
+	if v.Type().Implements(jsonMarshalerType) {
+		return v.Interface()
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return camelValue(v.Elem())
+	case reflect.Struct:
+		obj := orderedObject{}
+		appendCamelFields(&obj, v)
+		return obj
+	case reflect.Map:
+		if v.IsNil() {
+			return nil
+		}
+		result := make(map[string]interface{}, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			result[fmt.Sprint(iter.Key().Interface())] = camelValue(iter.Value())
+		}
+		return result
+	case reflect.Slice:
+		if v.IsNil() {
+			return nil
+		}
+		fallthrough
+	case reflect.Array:
+		result := make([]interface{}, v.Len())
+		for i := range result {
+			result[i] = camelValue(v.Index(i))
+		}
+		return result
+	default:
+		return v.Interface()
+	}
+}
+
+// appendCamelFields adds v's exported fields to obj, flattening untagged embedded structs like ResponseMetadata
+func appendCamelFields(obj *orderedObject, v reflect.Value) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		value := v.Field(i)
+
+		if field.Anonymous && name == "" && value.Kind() == reflect.Struct {
+			appendCamelFields(obj, value)
+			continue
+		}
+		if strings.Contains(opts, "omitempty") && isEmptyJSONValue(value) {
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+		*obj = append(*obj, objectField{key: camelKey(name), value: camelValue(value)})
+	}
+}
+
+// isEmptyJSONValue mirrors encoding/json's omitempty rule; notably structs, including time.Time, are never empty
+func isEmptyJSONValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64, reflect.Interface, reflect.Pointer:
+		return v.IsZero()
+	}
+	return false
+}
+
+// orderedObject is a JSON object that keeps struct field order, unlike map[string]interface{}
+type orderedObject []objectField
+
+type objectField struct {
+	key   string
+	value interface{}
+}
+
+func (o orderedObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, field := range o {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(field.key)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(field.value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
@@ -1,16 +1,27 @@
 package main
 
 import (
+	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
 	"log/slog"
 	"os"
-	"time"
+	"strings"
 
 	"github.com/jusunglee/mta-go/pkg/mta"
 )
 
 func main() {
+	// Validate mode checks a static GTFS directory without fetching anything
+	if len(os.Args) > 1 && os.Args[1] == "validate" {
+		os.Exit(runValidate(os.Args[2:]))
+	}
+	// Graph mode exports station adjacency from a static GTFS directory, also without fetching
+	if len(os.Args) > 1 && os.Args[1] == "graph" {
+		os.Exit(runGraph(os.Args[2:], os.Stdout))
+	}
+
 	var (
 		apiKey = flag.String("api-key", "", "MTA API key")
 		lat    = flag.Float64("lat", 40.7527, "Latitude")
@@ -36,59 +47,185 @@ func main() {
 		slog.Error("Failed to create MTA client", "error", err)
 		os.Exit(1)
 	}
-	defer client.Close()
 
-	// Allow feed manager time to populate initial data
 	fmt.Println("Waiting for initial data...")
-	time.Sleep(2 * time.Second)
+	<-client.Ready()
+
+	opts := queryOptions{lat: *lat, lon: *lon, route: *route}
+	code := runQuery(os.Stdout, os.Stderr, client, client.LoadStatus(), opts)
+	// Closed explicitly since os.Exit skips deferred calls
+	client.Close()
+	os.Exit(code)
+}
+
+// queryOptions selects between the route and nearest-station queries
+type queryOptions struct {
+	lat, lon float64
+	route    string
+}
+
+// noDataMessage is shown instead of an empty list, which users read as a bug
+const noDataMessage = "No station data yet: data is still loading, try again in a moment"
+
+// runQuery prints the requested stations and returns the process exit code
+// An empty store exits nonzero with noDataMessage rather than printing a bare heading
+func runQuery(out, errOut io.Writer, client mta.Client, status mta.LoadStatus, opts queryOptions) int {
+	switch status {
+	case mta.LoadStatusFailed:
+		fmt.Fprintln(errOut, "Station data failed to load; check your API key and network, then try again")
+		return 1
+	case mta.LoadStatusDegraded:
+		slog.Warn("Some real-time feeds failed; arrivals may be incomplete")
+	}
 
 	// Route-specific query mode
-	if *route != "" {
-		stations, err := client.GetStationsByRoute(*route)
+	if opts.route != "" {
+		stations, err := client.GetStationsByRoute(opts.route)
 		if err != nil {
-			slog.Error("Failed to get stations for route", "route", *route, "error", err)
-			os.Exit(1)
+			slog.Error("Failed to get stations for route", "route", opts.route, "error", err)
+			return 1
+		}
+		if len(stations) == 0 {
+			fmt.Fprintln(errOut, noDataMessage)
+			return 1
 		}
 
-		fmt.Printf("\nStations on route %s:\n", *route)
+		fmt.Fprintf(out, "\nStations on route %s:\n", opts.route)
 		for _, station := range stations {
-			fmt.Printf("- %s (%s)\n", station.Name, station.ID)
+			fmt.Fprintf(out, "- %s (%s)\n", station.Name, station.ID)
 		}
-		return
+		return 0
 	}
 
 	// Default location-based query mode
-	stations, err := client.GetStationsByLocation(*lat, *lon, 5)
+	stations, err := client.GetStationsByLocation(opts.lat, opts.lon, 5)
 	if err != nil {
 		slog.Error("Failed to get stations", "error", err)
-		os.Exit(1)
+		return 1
+	}
+	// Any loaded station is "nearest" to something, so nothing at all means nothing has loaded
+	if len(stations) == 0 {
+		fmt.Fprintln(errOut, noDataMessage)
+		return 1
 	}
 
-	fmt.Printf("\nNearest stations to (%.4f, %.4f):\n", *lat, *lon)
+	fmt.Fprintf(out, "\nNearest stations to (%.4f, %.4f):\n", opts.lat, opts.lon)
 	for _, station := range stations {
-		fmt.Printf("\n%s (%s)\n", station.Name, station.ID)
-		fmt.Printf("  Routes: %v\n", station.Routes)
+		fmt.Fprintf(out, "\n%s (%s)\n", station.Name, station.ID)
+		fmt.Fprintf(out, "  Routes: %v\n", station.Routes)
 
 		if len(station.Trains.North) > 0 {
-			fmt.Println("  Northbound:")
+			fmt.Fprintln(out, "  Northbound:")
 			for _, train := range station.Trains.North[:min(3, len(station.Trains.North))] {
-				fmt.Printf("    %s - %s\n", train.Route, train.Time.Format("3:04 PM"))
+				fmt.Fprintf(out, "    %s - %s\n", train.Route, train.Time.Format("3:04 PM"))
 			}
 		}
 
 		if len(station.Trains.South) > 0 {
-			fmt.Println("  Southbound:")
+			fmt.Fprintln(out, "  Southbound:")
 			for _, train := range station.Trains.South[:min(3, len(station.Trains.South))] {
-				fmt.Printf("    %s - %s\n", train.Route, train.Time.Format("3:04 PM"))
+				fmt.Fprintf(out, "    %s - %s\n", train.Route, train.Time.Format("3:04 PM"))
 			}
 		}
 	}
 
 	// Show update times
-	fmt.Printf("\nLast real-time update: %s\n", client.GetLastUpdate().Format("3:04 PM"))
+	fmt.Fprintf(out, "\nLast real-time update: %s\n", client.GetLastUpdate().Format("3:04 PM"))
 	if staticUpdate := client.GetLastStaticUpdate(); !staticUpdate.IsZero() {
-		fmt.Printf("Last static data update: %s\n", staticUpdate.Format("3:04 PM"))
+		fmt.Fprintf(out, "Last static data update: %s\n", staticUpdate.Format("3:04 PM"))
+	}
+	return 0
+}
+
+// runValidate handles "mta-local validate <gtfs-dir>" and returns the process exit code
+func runValidate(args []string) int {
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, "usage: mta-local validate <gtfs-dir>")
+		return 2
+	}
+
+	report, err := mta.ValidateStaticGTFS(args[0])
+	if err != nil {
+		slog.Error("Static GTFS validation failed", "dir", args[0], "error", err)
+		return 1
+	}
+
+	fmt.Printf("Stations:   %d\n", report.Stations)
+	fmt.Printf("Routes:     %d\n", report.Routes)
+	fmt.Printf("Trips:      %d\n", report.Trips)
+	fmt.Printf("Stop times: %d\n", report.StopTimes)
+	if len(report.Warnings) > 0 {
+		fmt.Printf("\nWarnings (%d):\n", len(report.Warnings))
+		for _, warning := range report.Warnings {
+			fmt.Printf("- %s\n", warning)
+		}
 	}
+	return 0
+}
+
+// runGraph handles "mta-local graph [-format json|dot] [-o file] <gtfs-dir>" and returns the process exit code
+func runGraph(args []string, stdout io.Writer) int {
+	fs := flag.NewFlagSet("graph", flag.ContinueOnError)
+	format := fs.String("format", "json", "Output format: json or dot (Graphviz)")
+	output := fs.String("o", "", "Write to this file instead of stdout")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 || (*format != "json" && *format != "dot") {
+		fmt.Fprintln(os.Stderr, "usage: mta-local graph [-format json|dot] [-o file] <gtfs-dir>")
+		return 2
+	}
+
+	graph, err := mta.BuildStationGraph(fs.Arg(0))
+	if err != nil {
+		slog.Error("Failed to build station graph", "dir", fs.Arg(0), "error", err)
+		return 1
+	}
+
+	out := stdout
+	if *output != "" {
+		file, err := os.Create(*output)
+		if err != nil {
+			slog.Error("Failed to create output file", "error", err)
+			return 1
+		}
+		defer file.Close()
+		out = file
+	}
+
+	if *format == "dot" {
+		err = writeGraphDOT(out, graph)
+	} else {
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(graph)
+	}
+	if err != nil {
+		slog.Error("Failed to write station graph", "error", err)
+		return 1
+	}
+	return 0
+}
+
+// writeGraphDOT writes graph as an undirected Graphviz graph labeled with station names and routes
+// Positions use longitude and latitude so "neato -n" lays it out roughly as a map
+func writeGraphDOT(w io.Writer, graph *mta.StationGraph) error {
+	var b strings.Builder
+	b.WriteString("graph subway {\n")
+	for _, station := range graph.Stations {
+		fmt.Fprintf(&b, "  %s [label=%s, pos=\"%f,%f\"];\n", dotQuote(station.ID), dotQuote(station.Name), station.Lon, station.Lat)
+	}
+	for _, edge := range graph.Edges {
+		fmt.Fprintf(&b, "  %s -- %s [label=%s];\n", dotQuote(edge.From), dotQuote(edge.To), dotQuote(strings.Join(edge.Routes, ",")))
+	}
+	b.WriteString("}\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// dotQuote quotes s as a DOT string; unlike strconv.Quote it leaves non-ASCII text alone, which DOT reads as UTF-8
+func dotQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
 }
 
 func min(a, b int) int {
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jusunglee/mta-go/internal/models"
+	"github.com/jusunglee/mta-go/pkg/mta"
+)
+
+// fakeClient serves a fixed station list; unused Client methods panic via the nil embedded interface
+type fakeClient struct {
+	mta.Client
+	stations []models.Station
+}
+
+func (c *fakeClient) GetStationsByLocation(lat, lon float64, limit int) ([]models.Station, error) {
+	return c.stations, nil
+}
+
+func (c *fakeClient) GetStationsByRoute(route string) ([]models.Station, error) {
+	if len(c.stations) == 0 {
+		return []models.Station{}, nil
+	}
+	if route != "L" {
+		return nil, fmt.Errorf("route %s not found", route)
+	}
+	return c.stations, nil
+}
+
+func (c *fakeClient) GetLastUpdate() time.Time       { return time.Time{} }
+func (c *fakeClient) GetLastStaticUpdate() time.Time { return time.Time{} }
+
+func TestRunQuery(t *testing.T) {
+	loaded := &fakeClient{stations: []models.Station{{ID: "L06", Name: "1 Av", Routes: []string{"L"}}}}
+	empty := &fakeClient{}
+
+	tests := []struct {
+		name       string
+		client     *fakeClient
+		status     mta.LoadStatus
+		opts       queryOptions
+		expectCode int
+		expectOut  string
+		expectErr  string
+	}{
+		{"nearest stations", loaded, mta.LoadStatusReady, queryOptions{lat: 40.73, lon: -73.98}, 0, "1 Av (L06)", ""},
+		{"route stations", loaded, mta.LoadStatusReady, queryOptions{route: "L"}, 0, "- 1 Av (L06)", ""},
+		{"empty store by location", empty, mta.LoadStatusReady, queryOptions{lat: 40.73, lon: -73.98}, 1, "", noDataMessage},
+		{"empty store by route", empty, mta.LoadStatusReady, queryOptions{route: "L"}, 1, "", noDataMessage},
+		{"static load failed", empty, mta.LoadStatusFailed, queryOptions{lat: 40.73, lon: -73.98}, 1, "", "failed to load"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out, errOut bytes.Buffer
+			code := runQuery(&out, &errOut, tt.client, tt.status, tt.opts)
+
+			if code != tt.expectCode {
+				t.Errorf("Expected exit code %d, got %d", tt.expectCode, code)
+			}
+			if !strings.Contains(out.String(), tt.expectOut) {
+				t.Errorf("Expected output to contain %q, got %q", tt.expectOut, out.String())
+			}
+			if !strings.Contains(errOut.String(), tt.expectErr) {
+				t.Errorf("Expected error output to contain %q, got %q", tt.expectErr, errOut.String())
+			}
+			// An empty result must not print a heading that looks like a successful empty list
+			if tt.expectCode != 0 && out.Len() != 0 {
+				t.Errorf("Expected no stdout on failure, got %q", out.String())
+			}
+		})
+	}
+}
+
+func TestWriteGraphDOT(t *testing.T) {
+	graph := &mta.StationGraph{
+		Stations: []mta.GraphStation{
+			{ID: "120", Name: "96 St", Lat: 40.79, Lon: -73.97},
+			{ID: "127", Name: `Times Sq "42" St`, Lat: 40.75, Lon: -73.98},
+		},
+		Edges: []mta.GraphEdge{{From: "120", To: "127", Routes: []string{"2", "3"}}},
+	}
+
+	var out bytes.Buffer
+	if err := writeGraphDOT(&out, graph); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, expected := range []string{
+		"graph subway {\n",
+		`"127" [label="Times Sq \"42\" St", pos="-73.980000,40.750000"];`,
+		`"120" -- "127" [label="2,3"];`,
+	} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("Expected DOT output to contain %q, got:\n%s", expected, out.String())
+		}
+	}
+}
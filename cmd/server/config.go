@@ -0,0 +1,345 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jusunglee/mta-go/api/handlers"
+	"github.com/jusunglee/mta-go/pkg/mta"
+	"gopkg.in/yaml.v3"
+)
+
+// ServerConfig is every server setting, loaded in increasing precedence from defaults,
+// the -config file, command-line flags and finally environment variables.
+// File keys match the flag names, e.g. update-interval: 30s
+type ServerConfig struct {
+	Port             string   `json:"port" yaml:"port"`
+	APIKey           string   `json:"api-key" yaml:"api-key"`
+	APIKeyHeader     string   `json:"api-key-header" yaml:"api-key-header"`
+	APIKeyQuery      string   `json:"api-key-query" yaml:"api-key-query"`
+	UpdateInterval   Duration `json:"update-interval" yaml:"update-interval"`
+	StaticInterval   Duration `json:"static-update-interval" yaml:"static-update-interval"`
+	PastCutoff       Duration `json:"past-arrival-cutoff" yaml:"past-arrival-cutoff"`
+	MaxAlertAge      Duration `json:"max-alert-age" yaml:"max-alert-age"`
+	Retention        int      `json:"arrival-retention" yaml:"arrival-retention"`
+	Dedup            string   `json:"dedup" yaml:"dedup"`
+	ScheduleFallback bool     `json:"schedule-fallback" yaml:"schedule-fallback"`
+	ServiceCutoff    Duration `json:"service-day-cutoff" yaml:"service-day-cutoff"`
+	MaxStations      int      `json:"max-stations" yaml:"max-stations"`
+	MaxLocations     int      `json:"max-batch-locations" yaml:"max-batch-locations"`
+	TimeZone         string   `json:"timezone" yaml:"timezone"`
+	StaleAfter       Duration `json:"stale-after" yaml:"stale-after"`
+	WalkingSpeed     float64  `json:"walking-speed" yaml:"walking-speed"`
+	CORSMaxAge       Duration `json:"cors-max-age" yaml:"cors-max-age"`
+	RequestTimeout   Duration `json:"request-timeout" yaml:"request-timeout"`
+	RequireUserAgent bool     `json:"require-user-agent" yaml:"require-user-agent"`
+	GTFSDir          string   `json:"gtfs-dir" yaml:"gtfs-dir"`
+	StaticGTFS       string   `json:"static-gtfs" yaml:"static-gtfs"`
+	MergeGTFS        bool     `json:"merge-gtfs" yaml:"merge-gtfs"`
+	StationsFile     string   `json:"stations-file" yaml:"stations-file"`
+	ServiceArea      string   `json:"service-area" yaml:"service-area"`
+	OverridesFile    string   `json:"station-overrides" yaml:"station-overrides"`
+	AuditLog         bool     `json:"audit-log" yaml:"audit-log"`
+	RealtimeVersion  string   `json:"gtfs-rt-version" yaml:"gtfs-rt-version"`
+	BreakerThreshold int      `json:"breaker-threshold" yaml:"breaker-threshold"`
+	BreakerCooldown  Duration `json:"breaker-cooldown" yaml:"breaker-cooldown"`
+	Feeds            List     `json:"feeds" yaml:"feeds"`
+	TLSCert          string   `json:"tls-cert" yaml:"tls-cert"`
+	TLSKey           string   `json:"tls-key" yaml:"tls-key"`
+	TLSAuto          bool     `json:"tls-auto" yaml:"tls-auto"`
+	TLSDomain        string   `json:"tls-domain" yaml:"tls-domain"`
+	TLSCacheDir      string   `json:"tls-cache-dir" yaml:"tls-cache-dir"`
+}
+
+// defaultServerConfig returns the settings used when neither file, flag nor environment sets them
+func defaultServerConfig() ServerConfig {
+	return ServerConfig{
+		Port:             "8080",
+		UpdateInterval:   Duration(60 * time.Second),
+		StaticInterval:   Duration(mta.DefaultStaticUpdateInterval),
+		PastCutoff:       Duration(mta.DefaultPastArrivalCutoff),
+		MaxAlertAge:      Duration(mta.DefaultMaxAlertAge),
+		Retention:        mta.DefaultArrivalRetention,
+		Dedup:            "trip",
+		MaxStations:      handlers.DefaultMaxStations,
+		MaxLocations:     handlers.DefaultMaxBatchLocations,
+		TimeZone:         "UTC",
+		StaleAfter:       Duration(handlers.DefaultStaleAfter),
+		WalkingSpeed:     handlers.DefaultWalkingSpeed,
+		CORSMaxAge:       Duration(defaultCORSMaxAge),
+		RequestTimeout:   Duration(10 * time.Second),
+		GTFSDir:          mta.DefaultGTFSDataDir,
+		StationsFile:     "data/stations.json",
+		RealtimeVersion:  mta.DefaultGTFSRealtimeVersion,
+		BreakerThreshold: mta.DefaultBreakerThreshold,
+		BreakerCooldown:  Duration(mta.DefaultBreakerCooldown),
+		TLSCacheDir:      "data/autocert",
+	}
+}
+
+// bindFlags registers a flag for every setting, writing straight into c
+func (c *ServerConfig) bindFlags(fs *flag.FlagSet) {
+	fs.StringVar(&c.Port, "port", c.Port, "Server port")
+	fs.StringVar(&c.APIKey, "api-key", c.APIKey, "MTA API key")
+	fs.StringVar(&c.APIKeyHeader, "api-key-header", c.APIKeyHeader, "Header carrying the API key (default x-api-key)")
+	fs.StringVar(&c.APIKeyQuery, "api-key-query", c.APIKeyQuery, "Send the API key as this query parameter instead of a header")
+	fs.Var(&c.UpdateInterval, "update-interval", "Feed update interval")
+	fs.Var(&c.StaticInterval, "static-update-interval", "Static GTFS refresh interval")
+	fs.Var(&c.PastCutoff, "past-arrival-cutoff", "How long after arriving a train is still listed")
+	fs.Var(&c.MaxAlertAge, "max-alert-age", "Drop alerts this long after first seen, even without an end time")
+	fs.IntVar(&c.Retention, "arrival-retention", c.Retention, "Arrivals kept per station direction in memory")
+	fs.StringVar(&c.Dedup, "dedup", c.Dedup, "Duplicate arrival matching: trip (by trip ID when present) or route-time")
+	fs.BoolVar(&c.ScheduleFallback, "schedule-fallback", c.ScheduleFallback, "Show scheduled arrivals when a station has no real-time data")
+	fs.Var(&c.ServiceCutoff, "service-day-cutoff", "Treat schedule times before this (e.g. 4h) as the previous service day (0 keeps strict GTFS)")
+	fs.IntVar(&c.MaxStations, "max-stations", c.MaxStations, "Maximum stations in a single response (0 disables)")
+	fs.IntVar(&c.MaxLocations, "max-batch-locations", c.MaxLocations, "Maximum points in one POST /by-locations request (0 disables)")
+	fs.Var(&c.StaleAfter, "stale-after", "Mark a station stale in responses once its arrivals are this old (0 disables)")
+	fs.Float64Var(&c.WalkingSpeed, "walking-speed", c.WalkingSpeed, "Walking speed in metres per minute used by /reachable")
+	fs.StringVar(&c.TimeZone, "timezone", c.TimeZone, "IANA time zone for timestamps in responses, e.g. America/New_York")
+	fs.Var(&c.CORSMaxAge, "cors-max-age", "How long browsers may cache CORS preflight results (0 omits Access-Control-Max-Age)")
+	fs.Var(&c.RequestTimeout, "request-timeout", "Per-request handler timeout (0 disables)")
+	fs.BoolVar(&c.RequireUserAgent, "require-user-agent", c.RequireUserAgent, "Reject requests without a User-Agent header with a 400")
+	fs.StringVar(&c.GTFSDir, "gtfs-dir", c.GTFSDir, "Directory for downloaded static GTFS data (must be writable)")
+	fs.StringVar(&c.StaticGTFS, "static-gtfs", c.StaticGTFS, "Local GTFS zip or extracted directory (path or file:// URL) to load instead of downloading static GTFS")
+	fs.BoolVar(&c.MergeGTFS, "merge-gtfs", c.MergeGTFS, "Merge the regular and supplemented static GTFS feeds instead of preferring supplemented")
+	fs.StringVar(&c.StationsFile, "stations-file", c.StationsFile, "Stations JSON file")
+	fs.StringVar(&c.ServiceArea, "service-area", c.ServiceArea, "minLat,minLon,maxLat,maxLon that static stops must fall in (empty for NYC, off to load every stop)")
+	fs.StringVar(&c.OverridesFile, "station-overrides", c.OverridesFile, "JSON file of station name, location or route overrides keyed by station ID")
+	fs.BoolVar(&c.AuditLog, "audit-log", c.AuditLog, "Log a summary of every store update: stations added or removed, route changes, alert changes")
+	fs.StringVar(&c.RealtimeVersion, "gtfs-rt-version", c.RealtimeVersion, "GTFS-RT version feeds are expected to declare; others are logged and flagged in /feed-info")
+	fs.IntVar(&c.BreakerThreshold, "breaker-threshold", c.BreakerThreshold, "Consecutive fetch failures from a feed host before its fetches are skipped (-1 disables)")
+	fs.Var(&c.BreakerCooldown, "breaker-cooldown", "How long to skip fetches to a failing feed host before probing it again")
+	fs.Var(&c.Feeds, "feeds", "Comma-separated feed groups to poll (1234567,l,nqrw,bdfm,ace,jz,g); empty polls all")
+	fs.StringVar(&c.TLSCert, "tls-cert", c.TLSCert, "TLS certificate file (enables HTTPS and HTTP/2)")
+	fs.StringVar(&c.TLSKey, "tls-key", c.TLSKey, "TLS private key file")
+	fs.BoolVar(&c.TLSAuto, "tls-auto", c.TLSAuto, "Obtain TLS certificates from Let's Encrypt")
+	fs.StringVar(&c.TLSDomain, "tls-domain", c.TLSDomain, "Comma-separated domains for -tls-auto")
+	fs.StringVar(&c.TLSCacheDir, "tls-cache-dir", c.TLSCacheDir, "Certificate cache directory for -tls-auto")
+}
+
+// loadServerConfig builds the server settings from args and the environment, then validates them
+// Flags are parsed first only to find -config; the file is then applied under any flags that were set,
+// so a flag always beats the file regardless of where -config appears on the command line
+func loadServerConfig(args []string, getenv func(string) string) (ServerConfig, error) {
+	cfg := defaultServerConfig()
+	fs := flag.NewFlagSet("server", flag.ContinueOnError)
+	configPath := fs.String("config", "", "YAML (.yaml/.yml) or JSON (.json) file of settings keyed by flag name; flags and env override it")
+	cfg.bindFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return ServerConfig{}, err
+	}
+
+	if *configPath != "" {
+		set := make(map[string]string)
+		fs.Visit(func(f *flag.Flag) { set[f.Name] = f.Value.String() })
+
+		cfg = defaultServerConfig()
+		if err := cfg.loadFile(*configPath); err != nil {
+			return ServerConfig{}, err
+		}
+		for name, value := range set {
+			// Values came from this flag's own String, so they always parse again
+			fs.Set(name, value)
+		}
+	}
+
+	if err := cfg.applyEnv(getenv); err != nil {
+		return ServerConfig{}, err
+	}
+	if err := cfg.validate(); err != nil {
+		return ServerConfig{}, err
+	}
+	return cfg, nil
+}
+
+// loadFile decodes a YAML or JSON settings file over c, chosen by extension
+// Unknown keys are errors so a misspelled setting doesn't silently keep its default
+func (c *ServerConfig) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		// An empty file decodes to io.EOF; it just leaves every setting alone
+		if err := dec.Decode(c); err != nil && len(bytes.TrimSpace(data)) > 0 {
+			return fmt.Errorf("invalid config file %s: %w", path, err)
+		}
+	case ".json":
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(c); err != nil {
+			return fmt.Errorf("invalid config file %s: %w", path, err)
+		}
+	default:
+		return fmt.Errorf("config file %s must end in .yaml, .yml or .json, not %q", path, ext)
+	}
+	return nil
+}
+
+// applyEnv overrides c with the environment variables deployments already use
+func (c *ServerConfig) applyEnv(getenv func(string) string) error {
+	if v := getenv("MTA_API_KEY"); v != "" {
+		c.APIKey = v
+	}
+	if v := getenv("PORT"); v != "" {
+		c.Port = v
+	}
+	if v := getenv("UPDATE_INTERVAL"); v != "" {
+		if err := c.UpdateInterval.Set(v); err != nil {
+			return fmt.Errorf("invalid UPDATE_INTERVAL %q: %w", v, err)
+		}
+	}
+	return nil
+}
+
+// validate reports every invalid setting at once, naming each by its flag
+func (c ServerConfig) validate() error {
+	var errs []error
+	if c.APIKey == "" {
+		errs = append(errs, errors.New("MTA API key required (use -api-key, api-key in the config file, or MTA_API_KEY)"))
+	}
+	if port, err := strconv.Atoi(c.Port); err != nil || port < 1 || port > 65535 {
+		errs = append(errs, fmt.Errorf("port must be a number from 1 to 65535, got %q", c.Port))
+	}
+	if c.UpdateInterval <= 0 {
+		errs = append(errs, fmt.Errorf("update-interval must be positive, got %v", c.UpdateInterval))
+	}
+	if c.StaticInterval <= 0 {
+		errs = append(errs, fmt.Errorf("static-update-interval must be positive, got %v", c.StaticInterval))
+	}
+	for name, d := range map[string]Duration{
+		"past-arrival-cutoff": c.PastCutoff,
+		"max-alert-age":       c.MaxAlertAge,
+		"service-day-cutoff":  c.ServiceCutoff,
+		"cors-max-age":        c.CORSMaxAge,
+		"request-timeout":     c.RequestTimeout,
+		"stale-after":         c.StaleAfter,
+		"breaker-cooldown":    c.BreakerCooldown,
+	} {
+		if d < 0 {
+			errs = append(errs, fmt.Errorf("%s must not be negative, got %v", name, d))
+		}
+	}
+	if c.Retention < 0 {
+		errs = append(errs, fmt.Errorf("arrival-retention must not be negative, got %d", c.Retention))
+	}
+	if c.MaxStations < 0 {
+		errs = append(errs, fmt.Errorf("max-stations must not be negative, got %d", c.MaxStations))
+	}
+	if c.MaxLocations < 0 {
+		errs = append(errs, fmt.Errorf("max-batch-locations must not be negative, got %d", c.MaxLocations))
+	}
+	if c.WalkingSpeed <= 0 {
+		errs = append(errs, fmt.Errorf("walking-speed must be positive, got %v", c.WalkingSpeed))
+	}
+	if _, err := c.location(); err != nil {
+		errs = append(errs, fmt.Errorf("timezone must be an IANA time zone name, got %q", c.TimeZone))
+	}
+	if c.Dedup != "trip" && c.Dedup != "route-time" {
+		errs = append(errs, fmt.Errorf("dedup must be trip or route-time, got %q", c.Dedup))
+	}
+	if err := mta.ValidateServiceArea(c.ServiceArea); err != nil {
+		errs = append(errs, fmt.Errorf("service-area must be minLat,minLon,maxLat,maxLon with each minimum below its maximum, or off; got %q", c.ServiceArea))
+	}
+	if err := c.tlsOptions().validate(); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// location returns the zone responses are written in
+// An empty name is rejected rather than read as UTC, since a blank timezone: in a config file is more likely a mistake
+func (c ServerConfig) location() (*time.Location, error) {
+	if c.TimeZone == "" {
+		return nil, errors.New("empty time zone")
+	}
+	return time.LoadLocation(c.TimeZone)
+}
+
+func (c ServerConfig) tlsOptions() tlsOptions {
+	return tlsOptions{
+		certFile: c.TLSCert,
+		keyFile:  c.TLSKey,
+		auto:     c.TLSAuto,
+		domain:   c.TLSDomain,
+		cacheDir: c.TLSCacheDir,
+	}
+}
+
+// mtaConfig returns the client settings
+func (c ServerConfig) mtaConfig() mta.Config {
+	return mta.Config{
+		APIKey:                  c.APIKey,
+		UpdateInterval:          time.Duration(c.UpdateInterval),
+		StaticUpdateInterval:    time.Duration(c.StaticInterval),
+		StationsFile:            c.StationsFile,
+		GTFSDataDir:             c.GTFSDir,
+		StaticGTFSSource:        c.StaticGTFS,
+		StationOverridesFile:    c.OverridesFile,
+		FeedGroups:              c.Feeds,
+		DedupStrategy:           c.Dedup,
+		ArrivalRetention:        c.Retention,
+		PastArrivalCutoff:       time.Duration(c.PastCutoff),
+		MaxAlertAge:             time.Duration(c.MaxAlertAge),
+		ScheduleFallback:        c.ScheduleFallback,
+		MergeStaticGTFS:         c.MergeGTFS,
+		ExpectedRealtimeVersion: c.RealtimeVersion,
+		ServiceDayCutoff:        time.Duration(c.ServiceCutoff),
+		BreakerThreshold:        c.BreakerThreshold,
+		BreakerCooldown:         time.Duration(c.BreakerCooldown),
+		APIKeyHeader:            c.APIKeyHeader,
+		APIKeyQueryParam:        c.APIKeyQuery,
+		ServiceArea:             c.ServiceArea,
+		AuditLog:                c.AuditLog,
+	}
+}
+
+// Duration is a time.Duration written like "30s" in flags and config files alike
+// encoding/json would otherwise want integer nanoseconds
+type Duration time.Duration
+
+func (d Duration) String() string {
+	return time.Duration(d).String()
+}
+
+func (d *Duration) Set(s string) error {
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+func (d *Duration) UnmarshalText(text []byte) error {
+	return d.Set(string(text))
+}
+
+// List is a comma-separated flag or a list in config files
+type List []string
+
+func (l List) String() string {
+	return strings.Join(l, ",")
+}
+
+func (l *List) Set(s string) error {
+	*l = nil
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			*l = append(*l, item)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeConfigFile writes a config file named name into a temp directory and returns its path
+func writeConfigFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	return path
+}
+
+// envMap stands in for os.Getenv
+func envMap(env map[string]string) func(string) string {
+	return func(key string) string { return env[key] }
+}
+
+func TestLoadServerConfigPrecedence(t *testing.T) {
+	yamlPath := writeConfigFile(t, "mta.yaml", `
+api-key: from-file
+port: "9000"
+update-interval: 30s
+max-stations: 100
+feeds: [ace, l]
+schedule-fallback: true
+`)
+	jsonPath := writeConfigFile(t, "mta.json", `{"api-key": "from-file", "port": "9000", "update-interval": "30s", "max-stations": 100, "feeds": ["ace", "l"], "schedule-fallback": true}`)
+
+	tests := []struct {
+		name        string
+		args        []string
+		env         map[string]string
+		port        string
+		interval    time.Duration
+		maxStations int
+		apiKey      string
+		feeds       List
+	}{
+		{"defaults", []string{"-api-key", "k"}, nil, "8080", time.Minute, 500, "k", nil},
+		{"yaml file over defaults", []string{"-config", yamlPath}, nil, "9000", 30 * time.Second, 100, "from-file", List{"ace", "l"}},
+		{"json file over defaults", []string{"-config", jsonPath}, nil, "9000", 30 * time.Second, 100, "from-file", List{"ace", "l"}},
+		{"flags over file", []string{"-port", "9100", "-config", yamlPath, "-feeds", "g"}, nil, "9100", 30 * time.Second, 100, "from-file", List{"g"}},
+		{"env over flags and file", []string{"-config", yamlPath, "-port", "9100", "-api-key", "from-flag"},
+			map[string]string{"PORT": "9200", "MTA_API_KEY": "from-env", "UPDATE_INTERVAL": "15s"}, "9200", 15 * time.Second, 100, "from-env", List{"ace", "l"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := loadServerConfig(tt.args, envMap(tt.env))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if cfg.Port != tt.port || time.Duration(cfg.UpdateInterval) != tt.interval || cfg.MaxStations != tt.maxStations || cfg.APIKey != tt.apiKey {
+				t.Errorf("Expected port %s, interval %v, max stations %d, key %s; got %s, %v, %d, %s",
+					tt.port, tt.interval, tt.maxStations, tt.apiKey, cfg.Port, cfg.UpdateInterval, cfg.MaxStations, cfg.APIKey)
+			}
+			if !reflect.DeepEqual(cfg.Feeds, tt.feeds) {
+				t.Errorf("Expected feeds %v, got %v", tt.feeds, cfg.Feeds)
+			}
+		})
+	}
+
+	// Settings the file doesn't mention keep their defaults
+	cfg, err := loadServerConfig([]string{"-config", yamlPath}, envMap(nil))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if cfg.Dedup != "trip" || cfg.StationsFile != "data/stations.json" || !cfg.ScheduleFallback {
+		t.Errorf("Expected unset settings to keep defaults, got %+v", cfg)
+	}
+}
+
+func TestLoadServerConfigValidation(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		file     string // Written as config.yaml and passed with -config when set
+		expected string
+	}{
+		{"missing API key", nil, "", "MTA API key required"},
+		{"non-numeric port", []string{"-api-key", "k", "-port", "http"}, "", `port must be a number from 1 to 65535, got "http"`},
+		{"zero update interval", []string{"-api-key", "k", "-update-interval", "0s"}, "", "update-interval must be positive"},
+		{"negative duration", []string{"-api-key", "k", "-request-timeout", "-1s"}, "", "request-timeout must not be negative"},
+		{"negative retention", []string{"-api-key", "k", "-arrival-retention", "-5"}, "", "arrival-retention must not be negative"},
+		{"zero walking speed", []string{"-api-key", "k", "-walking-speed", "0"}, "", "walking-speed must be positive"},
+		{"unknown time zone", []string{"-api-key", "k", "-timezone", "Mars/Olympus"}, "", `timezone must be an IANA time zone name, got "Mars/Olympus"`},
+		{"bad service area", []string{"-api-key", "k", "-service-area", "40.5,-74.3"}, "", `service-area must be minLat,minLon,maxLat,maxLon with each minimum below its maximum, or off; got "40.5,-74.3"`},
+		{"unknown dedup", []string{"-api-key", "k", "-dedup", "fuzzy"}, "", `dedup must be trip or route-time, got "fuzzy"`},
+		{"incomplete TLS", []string{"-api-key", "k", "-tls-cert", "cert.pem"}, "", "-tls-cert and -tls-key must be provided together"},
+		{"unknown file key", []string{"-api-key", "k"}, "update-intervl: 30s\n", "field update-intervl not found"},
+		{"bad file duration", []string{"-api-key", "k"}, "update-interval: soon\n", "invalid config file"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := tt.args
+			if tt.file != "" {
+				args = append(args, "-config", writeConfigFile(t, "config.yaml", tt.file))
+			}
+			_, err := loadServerConfig(args, envMap(nil))
+			if err == nil || !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("Expected error containing %q, got %v", tt.expected, err)
+			}
+		})
+	}
+
+	t.Run("all problems reported together", func(t *testing.T) {
+		_, err := loadServerConfig([]string{"-port", "0", "-max-stations", "-1", "-max-batch-locations", "-1"}, envMap(nil))
+		for _, expected := range []string{"MTA API key required", "port must be", "max-stations must not be negative", "max-batch-locations must not be negative"} {
+			if err == nil || !strings.Contains(err.Error(), expected) {
+				t.Errorf("Expected error containing %q, got %v", expected, err)
+			}
+		}
+	})
+
+	t.Run("unsupported extension", func(t *testing.T) {
+		path := writeConfigFile(t, "config.toml", "port = 9000\n")
+		if _, err := loadServerConfig([]string{"-config", path}, envMap(nil)); err == nil || !strings.Contains(err.Error(), "must end in .yaml, .yml or .json") {
+			t.Errorf("Expected extension error, got %v", err)
+		}
+	})
+
+	t.Run("bad UPDATE_INTERVAL", func(t *testing.T) {
+		_, err := loadServerConfig([]string{"-api-key", "k"}, envMap(map[string]string{"UPDATE_INTERVAL": "often"}))
+		if err == nil || !strings.Contains(err.Error(), "invalid UPDATE_INTERVAL") {
+			t.Errorf("Expected UPDATE_INTERVAL error, got %v", err)
+		}
+	})
+}
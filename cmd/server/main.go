@@ -2,73 +2,137 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"flag"
 	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/jusunglee/mta-go/api/handlers"
 	"github.com/jusunglee/mta-go/pkg/mta"
+	"golang.org/x/crypto/acme/autocert"
 )
 
-func main() {
-	var (
-		port           = flag.String("port", "8080", "Server port")
-		apiKey         = flag.String("api-key", "", "MTA API key")
-		updateInterval = flag.Duration("update-interval", 60*time.Second, "Feed update interval")
-		stationsFile   = flag.String("stations-file", "data/stations.json", "Stations JSON file")
-	)
-	flag.Parse()
-
-	// Fallback to environment variable if API key not provided via flag
-	if *apiKey == "" {
-		*apiKey = os.Getenv("MTA_API_KEY")
+// tlsOptions selects how the server terminates TLS
+// Static cert/key files and autocert are mutually exclusive; neither means plain HTTP
+type tlsOptions struct {
+	certFile string
+	keyFile  string
+	auto     bool
+	domain   string
+	cacheDir string
+}
+
+func (o tlsOptions) validate() error {
+	if (o.certFile == "") != (o.keyFile == "") {
+		return errors.New("-tls-cert and -tls-key must be provided together")
 	}
-	if *apiKey == "" {
-		slog.Error("MTA API key required (use -api-key flag or MTA_API_KEY env var)")
-		os.Exit(1)
+	if o.auto && o.certFile != "" {
+		return errors.New("-tls-auto cannot be combined with -tls-cert/-tls-key")
+	}
+	if o.auto && o.domain == "" {
+		return errors.New("-tls-auto requires -tls-domain")
+	}
+	return nil
+}
+
+func (o tlsOptions) enabled() bool {
+	return o.auto || o.certFile != ""
+}
+
+// serve runs srv on ln until shutdown, terminating TLS when configured
+// ServeTLS negotiates HTTP/2 via ALPN automatically, so no explicit h2 setup is needed
+func serve(srv *http.Server, ln net.Listener, opts tlsOptions) error {
+	switch {
+	case opts.auto:
+		m := &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			HostPolicy: autocert.HostWhitelist(strings.Split(opts.domain, ",")...),
+			Cache:      autocert.DirCache(opts.cacheDir),
+		}
+		// TLSConfig answers TLS-ALPN-01 challenges on this listener, so no port 80 handler is required
+		srv.TLSConfig = m.TLSConfig()
+		return srv.ServeTLS(ln, "", "")
+	case opts.certFile != "":
+		return srv.ServeTLS(ln, opts.certFile, opts.keyFile)
+	default:
+		return srv.Serve(ln)
 	}
+}
 
-	config := mta.Config{
-		APIKey:         *apiKey,
-		UpdateInterval: *updateInterval,
-		StationsFile:   *stationsFile,
+func main() {
+	cfg, err := loadServerConfig(os.Args[1:], os.Getenv)
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
+	if err != nil {
+		slog.Error("Invalid configuration", "error", err)
+		os.Exit(1)
 	}
+	tlsOpts := cfg.tlsOptions()
 
-	client, err := mta.NewLocal(config)
+	client, err := mta.NewLocal(cfg.mtaConfig())
 	if err != nil {
 		slog.Error("Failed to create MTA client", "error", err)
 		os.Exit(1)
 	}
 	defer client.Close()
 
-	// Allow time for feed manager to fetch initial station data
-	slog.Info("Waiting for initial data...")
-	time.Sleep(2 * time.Second)
+	// The port opens straight away rather than after the initial load, which covers the whole static
+	// GTFS download; until it finishes responses are empty and /stats reports load_status "loading"
+	go func() {
+		<-client.Ready()
+		if status := client.LoadStatus(); status != mta.LoadStatusReady {
+			slog.Warn("Initial data load incomplete", "status", status)
+			return
+		}
+		slog.Info("Initial data loaded")
+	}()
 
 	r := mux.NewRouter()
 	h := handlers.NewHandler(client)
+	h.SetMaxStations(cfg.MaxStations)
+	h.SetMaxBatchLocations(cfg.MaxLocations)
+	loc, _ := cfg.location() // Checked by validate
+	h.SetTimeZone(loc)
+	h.SetStaleAfter(time.Duration(cfg.StaleAfter))
+	h.SetWalkingSpeed(cfg.WalkingSpeed)
 	h.RegisterRoutes(r)
 
 	r.Use(loggingMiddleware)
-	r.Use(corsMiddleware)
+	r.Use(userAgentMiddleware(cfg.RequireUserAgent))
+	if cfg.RequestTimeout > 0 {
+		r.Use(timeoutMiddleware(time.Duration(cfg.RequestTimeout)))
+	}
 
 	srv := &http.Server{
-		Addr:         ":" + *port,
-		Handler:      r,
+		Addr: ":" + cfg.Port,
+		// CORS wraps the router instead of using r.Use: mux only runs middleware on matched
+		// routes, and every route is GET-only, so preflight OPTIONS requests would get a bare 405
+		Handler:      corsMiddleware(time.Duration(cfg.CORSMaxAge))(r),
 		ReadTimeout:  15 * time.Second,
 		WriteTimeout: 15 * time.Second,
 		IdleTimeout:  60 * time.Second,
 	}
 
+	ln, err := net.Listen("tcp", srv.Addr)
+	if err != nil {
+		slog.Error("Failed to listen", "addr", srv.Addr, "error", err)
+		os.Exit(1)
+	}
+
 	// Start HTTP server in goroutine for graceful shutdown
 	go func() {
-		slog.Info("Server starting", "port", *port)
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		slog.Info("Server starting", "port", cfg.Port, "tls", tlsOpts.enabled())
+		if err := serve(srv, ln, tlsOpts); err != nil && err != http.ErrServerClosed {
 			slog.Error("Server failed to start", "error", err)
 			os.Exit(1)
 		}
@@ -102,20 +166,68 @@ func loggingMiddleware(next http.Handler) http.Handler {
 	})
 }
 
-// corsMiddleware enables CORS for web browser access
-// Allows all origins since this is a public transit API
-func corsMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
-
-		// Handle preflight requests
-		if r.Method == "OPTIONS" {
-			w.WriteHeader(http.StatusOK)
-			return
+// userAgentMiddleware rejects requests without a User-Agent with a 400 when required is set
+// A cheap filter for the most naive scrapers; anything that sets a header gets through, so it
+// isn't real protection. Rejections are logged with the remote address to spot repeat offenders
+func userAgentMiddleware(required bool) mux.MiddlewareFunc {
+	body, _ := json.Marshal(handlers.ErrorResponse{Error: "User-Agent header required"})
+	return func(next http.Handler) http.Handler {
+		if !required {
+			return next
 		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if strings.TrimSpace(r.UserAgent()) == "" {
+				slog.Warn("Rejected request without User-Agent", "remote_addr", r.RemoteAddr, "uri", r.RequestURI)
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write(body)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
 
-		next.ServeHTTP(w, r)
-	})
+// defaultCORSMaxAge is how long browsers may cache a preflight result
+const defaultCORSMaxAge = 10 * time.Minute
+
+// corsMiddleware enables CORS for web browser access
+// Allows all origins since this is a public transit API. Preflight responses carry
+// Access-Control-Max-Age so browsers don't re-preflight every request; zero omits it
+func corsMiddleware(maxAge time.Duration) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Access-Control-Allow-Origin", "*")
+			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+			w.Header().Add("Vary", "Origin")
+
+			// Handle preflight requests
+			if r.Method == "OPTIONS" {
+				if maxAge > 0 {
+					w.Header().Set("Access-Control-Max-Age", strconv.Itoa(int(maxAge.Seconds())))
+				}
+				w.WriteHeader(http.StatusOK)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// timeoutMiddleware bounds how long a single handler may run
+// Registered innermost so logging still records the 503 and CORS headers survive the timeout response.
+// The request context is cancelled on timeout so handlers can abandon work early
+func timeoutMiddleware(timeout time.Duration) mux.MiddlewareFunc {
+	body, _ := json.Marshal(handlers.ErrorResponse{Error: "Request timed out"})
+	return func(next http.Handler) http.Handler {
+		th := http.TimeoutHandler(next, timeout, string(body))
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// TimeoutHandler writes its body without a Content-Type; handlers that complete
+			// in time overwrite this with their own headers
+			w.Header().Set("Content-Type", "application/json")
+			th.ServeHTTP(w, r)
+		})
+	}
 }
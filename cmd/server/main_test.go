@@ -0,0 +1,331 @@
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/jusunglee/mta-go/api/handlers"
+)
+
+// writeSelfSignedCert generates a localhost certificate and returns the cert and key paths
+func writeSelfSignedCert(t *testing.T) (string, string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("Failed to marshal key: %v", err)
+	}
+
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatalf("Failed to write cert: %v", err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		t.Fatalf("Failed to write key: %v", err)
+	}
+	return certFile, keyFile
+}
+
+func TestServeTLS(t *testing.T) {
+	certFile, keyFile := writeSelfSignedCert(t)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+
+	srv := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}),
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- serve(srv, ln, tlsOptions{certFile: certFile, keyFile: keyFile})
+	}()
+
+	client := &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig:   &tls.Config{InsecureSkipVerify: true},
+			ForceAttemptHTTP2: true,
+		},
+		Timeout: 5 * time.Second,
+	}
+
+	resp, err := client.Get("https://" + ln.Addr().String() + "/")
+	if err != nil {
+		t.Fatalf("HTTPS request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.TLS == nil {
+		t.Error("Expected response over TLS")
+	}
+	if resp.ProtoMajor != 2 {
+		t.Errorf("Expected HTTP/2, got %s", resp.Proto)
+	}
+
+	// Graceful shutdown must still work over TLS
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown failed: %v", err)
+	}
+	if err := <-done; err != http.ErrServerClosed {
+		t.Errorf("Expected http.ErrServerClosed after shutdown, got %v", err)
+	}
+}
+
+func TestServePlainHTTP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+
+	srv := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}),
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- serve(srv, ln, tlsOptions{})
+	}()
+
+	resp, err := http.Get("http://" + ln.Addr().String() + "/")
+	if err != nil {
+		t.Fatalf("HTTP request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.TLS != nil {
+		t.Error("Expected plain HTTP response")
+	}
+
+	if err := srv.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown failed: %v", err)
+	}
+	<-done
+}
+
+func TestTLSOptionsValidate(t *testing.T) {
+	tests := []struct {
+		name        string
+		opts        tlsOptions
+		expectError bool
+	}{
+		{"plain HTTP", tlsOptions{}, false},
+		{"cert and key", tlsOptions{certFile: "cert.pem", keyFile: "key.pem"}, false},
+		{"cert without key", tlsOptions{certFile: "cert.pem"}, true},
+		{"key without cert", tlsOptions{keyFile: "key.pem"}, true},
+		{"autocert with domain", tlsOptions{auto: true, domain: "mta.example.com"}, false},
+		{"autocert without domain", tlsOptions{auto: true}, true},
+		{"autocert with files", tlsOptions{auto: true, domain: "mta.example.com", certFile: "cert.pem", keyFile: "key.pem"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.opts.validate()
+			if tt.expectError && err == nil {
+				t.Error("Expected error but got none")
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestTimeoutMiddleware(t *testing.T) {
+	cancelled := make(chan struct{})
+
+	r := mux.NewRouter()
+	r.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+			close(cancelled)
+		case <-time.After(5 * time.Second):
+		}
+	}).Methods("GET")
+	r.HandleFunc("/fast", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"data":"ok"}`))
+	}).Methods("GET")
+
+	r.Use(loggingMiddleware)
+	r.Use(corsMiddleware(defaultCORSMaxAge))
+	r.Use(timeoutMiddleware(50 * time.Millisecond))
+
+	t.Run("slow handler times out", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest("GET", "/slow", nil))
+
+		if rec.Code != http.StatusServiceUnavailable {
+			t.Errorf("Expected status 503, got %d", rec.Code)
+		}
+		if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+			t.Errorf("Expected JSON content type, got %q", ct)
+		}
+		if origin := rec.Header().Get("Access-Control-Allow-Origin"); origin != "*" {
+			t.Errorf("Expected CORS header on timeout response, got %q", origin)
+		}
+
+		var body handlers.ErrorResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatalf("Timeout body is not JSON: %v", err)
+		}
+		if body.Error == "" {
+			t.Error("Expected error message in timeout body")
+		}
+
+		select {
+		case <-cancelled:
+		case <-time.After(time.Second):
+			t.Error("Expected handler context to be cancelled on timeout")
+		}
+	})
+
+	t.Run("fast handler unaffected", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest("GET", "/fast", nil))
+
+		if rec.Code != http.StatusOK {
+			t.Errorf("Expected status 200, got %d", rec.Code)
+		}
+		if rec.Body.String() != `{"data":"ok"}` {
+			t.Errorf("Unexpected body: %s", rec.Body.String())
+		}
+	})
+}
+
+func TestUserAgentMiddleware(t *testing.T) {
+	newRouter := func(required bool) *mux.Router {
+		r := mux.NewRouter()
+		r.HandleFunc("/stations", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"data":[]}`))
+		}).Methods("GET")
+		r.Use(userAgentMiddleware(required))
+		return r
+	}
+
+	tests := []struct {
+		name      string
+		required  bool
+		userAgent string
+		expected  int
+	}{
+		{"missing rejected when required", true, "", http.StatusBadRequest},
+		{"blank rejected when required", true, "  ", http.StatusBadRequest},
+		{"present allowed when required", true, "transit-app/1.0", http.StatusOK},
+		{"missing allowed when not required", false, "", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/stations", nil)
+			req.Header.Set("User-Agent", tt.userAgent)
+			rec := httptest.NewRecorder()
+			newRouter(tt.required).ServeHTTP(rec, req)
+
+			if rec.Code != tt.expected {
+				t.Fatalf("Expected status %d, got %d", tt.expected, rec.Code)
+			}
+			if rec.Code == http.StatusBadRequest {
+				var body handlers.ErrorResponse
+				if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || body.Error != "User-Agent header required" {
+					t.Errorf("Expected JSON error body, got %q", rec.Body.String())
+				}
+			}
+		})
+	}
+}
+
+func TestCORSPreflight(t *testing.T) {
+	r := mux.NewRouter()
+	r.HandleFunc("/routes", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"data":[]}`))
+	}).Methods("GET")
+
+	preflight := func(h http.Handler) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("OPTIONS", "/routes", nil)
+		req.Header.Set("Origin", "https://example.com")
+		req.Header.Set("Access-Control-Request-Method", "GET")
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("preflight is cacheable", func(t *testing.T) {
+		rec := preflight(corsMiddleware(defaultCORSMaxAge)(r))
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d", rec.Code)
+		}
+		if maxAge := rec.Header().Get("Access-Control-Max-Age"); maxAge != "600" {
+			t.Errorf("Expected Access-Control-Max-Age 600, got %q", maxAge)
+		}
+		if vary := rec.Header().Get("Vary"); vary != "Origin" {
+			t.Errorf("Expected Vary: Origin, got %q", vary)
+		}
+	})
+
+	t.Run("zero max age omits header", func(t *testing.T) {
+		rec := preflight(corsMiddleware(0)(r))
+		if maxAge := rec.Header().Get("Access-Control-Max-Age"); maxAge != "" {
+			t.Errorf("Expected no Access-Control-Max-Age, got %q", maxAge)
+		}
+	})
+
+	t.Run("simple requests skip max age", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		corsMiddleware(defaultCORSMaxAge)(r).ServeHTTP(rec, httptest.NewRequest("GET", "/routes", nil))
+
+		if rec.Code != http.StatusOK || rec.Body.String() != `{"data":[]}` {
+			t.Errorf("Expected route response, got %d %s", rec.Code, rec.Body.String())
+		}
+		if rec.Header().Get("Access-Control-Max-Age") != "" {
+			t.Error("Access-Control-Max-Age only belongs on preflight responses")
+		}
+		if rec.Header().Get("Vary") != "Origin" {
+			t.Errorf("Expected Vary: Origin, got %q", rec.Header().Get("Vary"))
+		}
+	})
+}
@@ -9,3 +9,12 @@ require github.com/gorilla/mux v1.8.0
 require google.golang.org/protobuf v1.36.6
 
 require golang.org/x/sync v0.15.0
+
+require golang.org/x/crypto v0.39.0
+
+require gopkg.in/yaml.v3 v3.0.1
+
+require (
+	golang.org/x/net v0.21.0 // indirect
+	golang.org/x/text v0.26.0 // indirect
+)
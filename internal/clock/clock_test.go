@@ -0,0 +1,34 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFakeClock(t *testing.T) {
+	start := time.Date(2024, 12, 1, 8, 0, 0, 0, time.UTC)
+	c := NewFake(start)
+
+	if !c.Now().Equal(start) {
+		t.Errorf("Expected %v, got %v", start, c.Now())
+	}
+
+	c.Advance(90 * time.Second)
+	if want := start.Add(90 * time.Second); !c.Now().Equal(want) {
+		t.Errorf("Expected %v after Advance, got %v", want, c.Now())
+	}
+
+	later := start.Add(time.Hour)
+	c.Set(later)
+	if !c.Now().Equal(later) {
+		t.Errorf("Expected %v after Set, got %v", later, c.Now())
+	}
+}
+
+func TestRealClock(t *testing.T) {
+	before := time.Now()
+	now := Real{}.Now()
+	if now.Before(before) {
+		t.Errorf("Real clock returned %v, before %v", now, before)
+	}
+}
@@ -0,0 +1,51 @@
+package feed
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+)
+
+// Feed access states as reported in /feed-info
+// Auth failures are kept apart from other errors because retrying won't fix them: the key lacks access to the feed
+const (
+	feedStatusOK         = "ok"
+	feedStatusAuthFailed = "auth_failed"
+	feedStatusError      = "error"
+)
+
+// isAuthError reports whether err is the feed rejecting the API key
+func isAuthError(err error) bool {
+	var statusErr *HTTPStatusError
+	if !errors.As(err, &statusErr) {
+		return false
+	}
+	return statusErr.StatusCode == http.StatusUnauthorized || statusErr.StatusCode == http.StatusForbidden
+}
+
+// recordFeedAccess stores the outcome of a fetch from url
+// The warning is logged only when a feed starts rejecting the key, rather than every cycle it stays rejected
+func (m *Manager) recordFeedAccess(url string, err error) {
+	status := feedStatusOK
+	switch {
+	case isAuthError(err):
+		status = feedStatusAuthFailed
+	case err != nil:
+		status = feedStatusError
+	}
+
+	m.latencyMu.Lock()
+	defer m.latencyMu.Unlock()
+
+	if m.access == nil {
+		m.access = make(map[string]string)
+	}
+	previous := m.access[url]
+	m.access[url] = status
+
+	if status == feedStatusAuthFailed && previous != feedStatusAuthFailed {
+		slog.Warn("Feed rejected the API key; check the key has access to it", "feed", feedGroupName(url), "error", err)
+	} else if previous == feedStatusAuthFailed && status == feedStatusOK {
+		slog.Info("Feed accepted the API key again", "feed", feedGroupName(url))
+	}
+}
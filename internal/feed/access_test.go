@@ -0,0 +1,126 @@
+package feed
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jusunglee/mta-go/internal/clock"
+	"github.com/jusunglee/mta-go/internal/models"
+	"github.com/jusunglee/mta-go/internal/store"
+)
+
+// statusFetcher answers the URLs in status with that HTTP status and defers the rest to mapFetcher
+type statusFetcher struct {
+	mapFetcher
+	status map[string]int
+}
+
+func (f *statusFetcher) Fetch(ctx context.Context, url string) ([]byte, error) {
+	if code, ok := f.status[url]; ok {
+		return nil, &HTTPStatusError{StatusCode: code}
+	}
+	return f.mapFetcher.Fetch(ctx, url)
+}
+
+func TestFeedAccessStatus(t *testing.T) {
+	fetcher := &statusFetcher{
+		mapFetcher: mapFetcher{data: map[string][]byte{FeedGroups["ace"]: {}}},
+		status: map[string]int{
+			FeedGroups["g"]: http.StatusUnauthorized,
+			FeedGroups["l"]: http.StatusServiceUnavailable,
+		},
+	}
+	m := NewManager("test-key", store.NewStore(), time.Minute)
+	m.SetClock(clock.NewFake(testNow))
+	m.SetFetcher(fetcher)
+	if err := m.SetFeedGroups([]string{"ace", "g", "l"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	for _, group := range []string{"ace", "g", "l"} {
+		m.fetchFeed(FeedGroups[group])
+	}
+
+	byFeed := make(map[string]models.FeedLatency)
+	for _, info := range m.GetFeedLatencies() {
+		byFeed[info.Feed] = info
+	}
+	if info := byFeed["ace"]; info.Status != feedStatusOK || info.Samples != 1 {
+		t.Errorf("Expected ace fetched ok, got %+v", info)
+	}
+	// Listed despite never succeeding, so the operator can see the key doesn't cover it
+	if info, ok := byFeed["g"]; !ok || info.Status != feedStatusAuthFailed {
+		t.Errorf("Expected g reported as auth_failed, got %+v", info)
+	}
+	// A transient error alone isn't worth listing a feed with no timing to show
+	if info, ok := byFeed["l"]; ok {
+		t.Errorf("Expected l left out after a transient error, got %+v", info)
+	}
+
+	// 403 counts as an auth failure too, and recovery is reported once the key is accepted
+	fetcher.status[FeedGroups["ace"]] = http.StatusForbidden
+	m.fetchFeed(FeedGroups["ace"])
+	if got := m.GetFeedLatencies()[0]; got.Feed != "ace" || got.Status != feedStatusAuthFailed {
+		t.Errorf("Expected ace auth_failed after a 403, got %+v", got)
+	}
+	delete(fetcher.status, FeedGroups["ace"])
+	m.fetchFeed(FeedGroups["ace"])
+	if got := m.GetFeedLatencies()[0]; got.Status != feedStatusOK {
+		t.Errorf("Expected ace ok again, got %+v", got)
+	}
+}
+
+func TestFeedAuthFailureWarnsOnce(t *testing.T) {
+	var buf bytes.Buffer
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(slog.New(slog.NewTextHandler(&buf, nil)))
+
+	fetcher := &statusFetcher{
+		status: map[string]int{
+			FeedGroups["g"]: http.StatusUnauthorized,
+			FeedGroups["l"]: http.StatusServiceUnavailable,
+		},
+	}
+	m := NewManager("test-key", store.NewStore(), time.Minute)
+	m.SetClock(clock.NewFake(testNow))
+	m.SetFetcher(fetcher)
+	if err := m.SetFeedGroups([]string{"g", "l"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	for range 3 {
+		if err := m.updateRealTimeData(); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	logged := buf.String()
+	if n := strings.Count(logged, "Feed rejected the API key"); n != 1 {
+		t.Errorf("Expected the auth failure warned once, got %d times:\n%s", n, logged)
+	}
+	// Only l's transient failure is warned about every cycle; g's repeats are at debug level
+	if n := strings.Count(logged, "Failed to process feed"); n != 3 || strings.Contains(logged, "failed to fetch feed: HTTP 401") {
+		t.Errorf("Expected only the transient failure warned each cycle, got %d warnings:\n%s", n, logged)
+	}
+}
+
+func TestHTTPFetcherStatusError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	_, err := NewHTTPFetcher("test-key").Fetch(context.Background(), server.URL)
+	if !isAuthError(err) {
+		t.Errorf("Expected a 401 to be an auth error, got %v", err)
+	}
+	if err == nil || err.Error() != "HTTP 401" {
+		t.Errorf("Expected error text HTTP 401, got %v", err)
+	}
+}
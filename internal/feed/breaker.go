@@ -0,0 +1,143 @@
+package feed
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"time"
+)
+
+// DefaultBreakerThreshold is how many consecutive fetch failures from one host open its breaker
+// Every real-time feed shares the MTA API host, so this is a bit more than one full cycle of all seven failing:
+// a single bad cycle still fetches every feed, a second one confirms the outage
+const DefaultBreakerThreshold = 10
+
+// DefaultBreakerCooldown is how long an open breaker skips fetches before letting a probe through
+const DefaultBreakerCooldown = 2 * time.Minute
+
+// Breaker states as reported in /feed-info
+const (
+	breakerClosed   = "closed"
+	breakerOpen     = "open"
+	breakerHalfOpen = "half-open"
+)
+
+// errBreakerOpen marks a fetch skipped because its host's breaker is open
+var errBreakerOpen = errors.New("circuit breaker open")
+
+// hostBreaker tracks consecutive fetch failures for a single feed host
+type hostBreaker struct {
+	state    string
+	failures int
+	openedAt time.Time
+	probing  bool // A half-open probe is in flight; other fetches wait for its result
+}
+
+// SetCircuitBreaker configures when fetches to a failing host are skipped
+// threshold consecutive failures open the breaker for cooldown, after which one fetch probes the host.
+// Zero values restore the defaults; a negative threshold disables the breaker
+func (m *Manager) SetCircuitBreaker(threshold int, cooldown time.Duration) {
+	m.breakerThreshold = threshold
+	m.breakerCooldown = cooldown
+}
+
+// breakerSettings returns the effective threshold and cooldown
+func (m *Manager) breakerSettings() (int, time.Duration) {
+	threshold, cooldown := m.breakerThreshold, m.breakerCooldown
+	if threshold == 0 {
+		threshold = DefaultBreakerThreshold
+	}
+	if cooldown == 0 {
+		cooldown = DefaultBreakerCooldown
+	}
+	return threshold, cooldown
+}
+
+// feedHost returns the host a feed URL is fetched from, the unit the breaker tracks
+func feedHost(feedURL string) string {
+	u, err := url.Parse(feedURL)
+	if err != nil || u.Host == "" {
+		return feedURL
+	}
+	return u.Host
+}
+
+// breakerAllow reports whether a fetch from host may go ahead
+// An open breaker turns half-open once its cooldown passes and lets a single probe through
+func (m *Manager) breakerAllow(host string) error {
+	threshold, cooldown := m.breakerSettings()
+	if threshold < 0 {
+		return nil
+	}
+
+	m.breakerMu.Lock()
+	defer m.breakerMu.Unlock()
+
+	b, ok := m.breakers[host]
+	if !ok {
+		return nil
+	}
+	switch b.state {
+	case breakerOpen:
+		if m.now().Sub(b.openedAt) < cooldown {
+			return fmt.Errorf("%w for %s", errBreakerOpen, host)
+		}
+		b.state = breakerHalfOpen
+		b.probing = true
+		return nil
+	case breakerHalfOpen:
+		if b.probing {
+			return fmt.Errorf("%w for %s", errBreakerOpen, host)
+		}
+		b.probing = true
+	}
+	return nil
+}
+
+// breakerRecord folds a fetch outcome into host's breaker
+// A failed half-open probe reopens the breaker straight away rather than waiting for the threshold again
+func (m *Manager) breakerRecord(host string, err error) {
+	threshold, _ := m.breakerSettings()
+	if threshold < 0 {
+		return
+	}
+
+	m.breakerMu.Lock()
+	defer m.breakerMu.Unlock()
+
+	if m.breakers == nil {
+		m.breakers = make(map[string]*hostBreaker)
+	}
+	b, ok := m.breakers[host]
+	if !ok {
+		b = &hostBreaker{state: breakerClosed}
+		m.breakers[host] = b
+	}
+	b.probing = false
+
+	if err == nil {
+		if b.state != breakerClosed {
+			slog.Info("Feed host recovered, closing circuit breaker", "host", host)
+		}
+		b.state, b.failures = breakerClosed, 0
+		return
+	}
+
+	b.failures++
+	if b.state == breakerHalfOpen || (b.state == breakerClosed && b.failures >= threshold) {
+		slog.Warn("Opening circuit breaker for feed host", "host", host, "consecutive_failures", b.failures)
+		b.state, b.openedAt = breakerOpen, m.now()
+	}
+}
+
+// breakerState returns host's breaker state for reporting
+func (m *Manager) breakerState(host string) string {
+	m.breakerMu.Lock()
+	defer m.breakerMu.Unlock()
+
+	if b, ok := m.breakers[host]; ok {
+		return b.state
+	}
+	return breakerClosed
+}
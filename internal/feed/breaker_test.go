@@ -0,0 +1,101 @@
+package feed
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jusunglee/mta-go/internal/clock"
+	"github.com/jusunglee/mta-go/internal/store"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	url := FeedGroups["ace"]
+	fetcher := &mapFetcher{data: map[string][]byte{}}
+	fake := clock.NewFake(testNow)
+
+	m := NewManager("test-key", store.NewStore(), time.Minute)
+	m.SetClock(fake)
+	m.SetFetcher(fetcher)
+	m.SetCircuitBreaker(3, time.Minute)
+	if err := m.SetFeedGroups([]string{"ace"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	breakerFor := func() string {
+		for _, feed := range m.GetFeedLatencies() {
+			if feed.Feed == "ace" {
+				return feed.Breaker
+			}
+		}
+		return ""
+	}
+
+	// Failures below the threshold are still fetched; the feed isn't listed until its breaker trips
+	for i := 0; i < 3; i++ {
+		if _, err := m.fetchFeed(url); err == nil || errors.Is(err, errBreakerOpen) {
+			t.Fatalf("Fetch %d: expected a fetch error, got %v", i+1, err)
+		}
+	}
+	if got := len(fetcher.urls()); got != 3 {
+		t.Fatalf("Expected 3 fetches, got %d", got)
+	}
+	if got := breakerFor(); got != breakerOpen {
+		t.Fatalf("Expected breaker open after 3 failures, got %q", got)
+	}
+
+	// Open: fetches are skipped without reaching the fetcher
+	for i := 0; i < 5; i++ {
+		fake.Advance(10 * time.Second)
+		if _, err := m.fetchFeed(url); !errors.Is(err, errBreakerOpen) {
+			t.Fatalf("Expected a skipped fetch while open, got %v", err)
+		}
+	}
+	if got := len(fetcher.urls()); got != 3 {
+		t.Errorf("Expected no fetches while open, got %d", got-3)
+	}
+
+	// After the cooldown a single probe goes through; failing it reopens the breaker at once
+	fake.Advance(10 * time.Second)
+	if _, err := m.fetchFeed(url); err == nil || errors.Is(err, errBreakerOpen) {
+		t.Fatalf("Expected the probe to reach the fetcher, got %v", err)
+	}
+	if got := len(fetcher.urls()); got != 4 {
+		t.Errorf("Expected one probe fetch, got %d", got-3)
+	}
+	if _, err := m.fetchFeed(url); !errors.Is(err, errBreakerOpen) {
+		t.Errorf("Expected the breaker to reopen after a failed probe, got %v", err)
+	}
+
+	// A successful probe closes it again
+	fetcher.mu.Lock()
+	fetcher.data[url] = []byte{}
+	fetcher.mu.Unlock()
+	fake.Advance(time.Minute)
+	if _, err := m.fetchFeed(url); err != nil {
+		t.Fatalf("Expected the probe to succeed, got %v", err)
+	}
+	if got := breakerFor(); got != breakerClosed {
+		t.Errorf("Expected breaker closed after a successful probe, got %q", got)
+	}
+	if _, err := m.fetchFeed(url); err != nil {
+		t.Errorf("Expected fetches to resume, got %v", err)
+	}
+}
+
+func TestCircuitBreakerDisabled(t *testing.T) {
+	fetcher := &mapFetcher{data: map[string][]byte{}}
+	m := NewManager("test-key", store.NewStore(), time.Minute)
+	m.SetClock(clock.NewFake(testNow))
+	m.SetFetcher(fetcher)
+	m.SetCircuitBreaker(-1, 0)
+
+	for i := 0; i < DefaultBreakerThreshold+5; i++ {
+		if _, err := m.fetchFeed(FeedGroups["ace"]); errors.Is(err, errBreakerOpen) {
+			t.Fatalf("Fetch %d skipped with the breaker disabled", i+1)
+		}
+	}
+	if got := len(fetcher.urls()); got != DefaultBreakerThreshold+5 {
+		t.Errorf("Expected every fetch to go through, got %d", got)
+	}
+}
@@ -0,0 +1,87 @@
+package feed
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jusunglee/mta-go/internal/models"
+)
+
+// DedupStrategy decides when two arrivals at the same platform are the same train
+type DedupStrategy int
+
+const (
+	// DedupTripAware matches on trip ID when both arrivals carry one and falls back to DedupRouteTime otherwise.
+	// Trip IDs keep two trains of a route arriving in the same second apart at merges
+	DedupTripAware DedupStrategy = iota
+	// DedupRouteTime treats arrivals of the same route within dedupTolerance of each other as one train
+	DedupRouteTime
+)
+
+// dedupTolerance absorbs the second or two by which feeds disagree on the same train's arrival
+const dedupTolerance = 2 * time.Second
+
+func (d DedupStrategy) String() string {
+	switch d {
+	case DedupRouteTime:
+		return "route-time"
+	default:
+		return "trip"
+	}
+}
+
+// ParseDedupStrategy parses "trip" or "route-time"
+func ParseDedupStrategy(s string) (DedupStrategy, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "trip":
+		return DedupTripAware, nil
+	case "route-time":
+		return DedupRouteTime, nil
+	default:
+		return DedupTripAware, fmt.Errorf("unknown dedup strategy %q", s)
+	}
+}
+
+// SetDedupStrategy configures how duplicate arrivals are collapsed
+func (m *Manager) SetDedupStrategy(strategy DedupStrategy) {
+	m.dedupStrategy = strategy
+}
+
+// sameTrain reports whether b duplicates the already-kept arrival a under the strategy
+func (d DedupStrategy) sameTrain(a, b models.Train) bool {
+	if d == DedupTripAware && a.TripID != "" && b.TripID != "" {
+		return a.TripID == b.TripID
+	}
+
+	diff := b.Time.Sub(a.Time)
+	if diff < 0 {
+		diff = -diff
+	}
+	return a.Route == b.Route && diff <= dedupTolerance
+}
+
+// dedupTrains drops duplicate arrivals from trains, which must already be sorted by time
+// Keeps the earliest report of each train
+func (d DedupStrategy) dedupTrains(trains []models.Train) []models.Train {
+	result := make([]models.Train, 0, len(trains))
+	for _, train := range trains {
+		duplicate := false
+		// Only arrivals within the tolerance window can match on route and time, but a trip ID
+		// can match anywhere, so trip-aware dedup scans everything kept so far
+		for i := len(result) - 1; i >= 0; i-- {
+			kept := result[i]
+			if d != DedupTripAware && train.Time.Sub(kept.Time) > dedupTolerance {
+				break
+			}
+			if d.sameTrain(kept, train) {
+				duplicate = true
+				break
+			}
+		}
+		if !duplicate {
+			result = append(result, train)
+		}
+	}
+	return result
+}
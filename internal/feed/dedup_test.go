@@ -0,0 +1,121 @@
+package feed
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jusunglee/mta-go/internal/models"
+)
+
+func TestDedupTrains(t *testing.T) {
+	now := testNow
+
+	tests := []struct {
+		name     string
+		strategy DedupStrategy
+		trains   []models.Train
+		expected int
+	}{
+		{
+			// Two N trains reach a merge in the same second; collapsing them hides a real train
+			name:     "distinct trips at the same time are kept",
+			strategy: DedupTripAware,
+			trains: []models.Train{
+				{Route: "N", Time: now, TripID: "trip-1"},
+				{Route: "N", Time: now, TripID: "trip-2"},
+			},
+			expected: 2,
+		},
+		{
+			// Two feeds report the same trip a second apart; keeping both double-counts it
+			name:     "same trip with jittered times collapses",
+			strategy: DedupTripAware,
+			trains: []models.Train{
+				{Route: "N", Time: now, TripID: "trip-1"},
+				{Route: "N", Time: now.Add(time.Second), TripID: "trip-1"},
+			},
+			expected: 1,
+		},
+		{
+			name:     "same trip far apart still collapses",
+			strategy: DedupTripAware,
+			trains: []models.Train{
+				{Route: "N", Time: now, TripID: "trip-1"},
+				{Route: "Q", Time: now.Add(time.Minute)},
+				{Route: "N", Time: now.Add(2 * time.Minute), TripID: "trip-1"},
+			},
+			expected: 2,
+		},
+		{
+			name:     "without trip IDs falls back to route and time",
+			strategy: DedupTripAware,
+			trains: []models.Train{
+				{Route: "N", Time: now},
+				{Route: "N", Time: now.Add(dedupTolerance)},
+				{Route: "N", Time: now.Add(dedupTolerance + time.Second)},
+			},
+			expected: 2,
+		},
+		{
+			name:     "one side missing a trip ID falls back to route and time",
+			strategy: DedupTripAware,
+			trains: []models.Train{
+				{Route: "N", Time: now, TripID: "trip-1"},
+				{Route: "N", Time: now.Add(time.Second)},
+			},
+			expected: 1,
+		},
+		{
+			name:     "different routes at the same time are kept",
+			strategy: DedupTripAware,
+			trains: []models.Train{
+				{Route: "N", Time: now},
+				{Route: "Q", Time: now},
+			},
+			expected: 2,
+		},
+		{
+			name:     "route-time ignores trip IDs",
+			strategy: DedupRouteTime,
+			trains: []models.Train{
+				{Route: "N", Time: now, TripID: "trip-1"},
+				{Route: "N", Time: now, TripID: "trip-2"},
+			},
+			expected: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := tt.strategy.dedupTrains(tt.trains)
+			if len(result) != tt.expected {
+				t.Errorf("Expected %d trains, got %d: %+v", tt.expected, len(result), result)
+			}
+		})
+	}
+}
+
+func TestParseDedupStrategy(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected DedupStrategy
+		wantErr  bool
+	}{
+		{"", DedupTripAware, false},
+		{"trip", DedupTripAware, false},
+		{"Route-Time", DedupRouteTime, false},
+		{"bogus", DedupTripAware, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseDedupStrategy(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseDedupStrategy(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if got != tt.expected {
+				t.Errorf("ParseDedupStrategy(%q) = %v, want %v", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package feed
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"path/filepath"
+	"sort"
+	"strconv"
+
+	"github.com/jusunglee/mta-go/internal/models"
+)
+
+// loadFares reads fare_attributes.txt and fare_rules.txt into fares sorted by ID
+// Both files are optional in GTFS: without fare_attributes.txt there are no fares, and without
+// fare_rules.txt every fare applies system-wide. routes is routes.txt as parsed by parseRoutesFile
+func (m *Manager) loadFares(gtfsDir string, routes map[string]models.RouteInfo) ([]models.Fare, error) {
+	fares, err := parseFareAttributes(filepath.Join(gtfsDir, "fare_attributes.txt"))
+	if errors.Is(err, fs.ErrNotExist) {
+		slog.Info("No fare_attributes.txt in static GTFS, fares unavailable")
+		return []models.Fare{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse fare attributes: %w", err)
+	}
+
+	rules, err := parseFareRules(filepath.Join(gtfsDir, "fare_rules.txt"))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("failed to parse fare rules: %w", err)
+	}
+	if len(rules) > 0 {
+		// Rules name GTFS route_ids; the API speaks in short names
+		for fareID, fareRules := range rules {
+			for i, rule := range fareRules {
+				if info, ok := routes[rule.Route]; ok && info.ShortName != "" {
+					fareRules[i].Route = info.ShortName
+				}
+			}
+			if fare, ok := fares[fareID]; ok {
+				fare.Rules = fareRules
+				fares[fareID] = fare
+			}
+		}
+	}
+
+	result := make([]models.Fare, 0, len(fares))
+	for _, fare := range fares {
+		result = append(result, fare)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ID < result[j].ID })
+	return result, nil
+}
+
+// parseFareAttributes reads fare_attributes.txt into fare_id -> fare
+// Rows with an unreadable price are skipped rather than shown as free
+func parseFareAttributes(path string) (map[string]models.Fare, error) {
+	file, read, columns, err := scheduleCSV(path, "fare_id", "price", "currency_type")
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	fares := make(map[string]models.Fare)
+	for {
+		record, err := read()
+		if err == io.EOF {
+			return fares, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		id, priceStr, currency := csvField(record, columns, "fare_id"), csvField(record, columns, "price"), csvField(record, columns, "currency_type")
+		price, err := strconv.ParseFloat(priceStr, 64)
+		if id == "" || err != nil || price < 0 {
+			continue
+		}
+		fares[id] = models.Fare{ID: id, Price: price, Currency: currency}
+	}
+}
+
+// parseFareRules reads fare_rules.txt into fare_id -> rules, with route_id left for the caller to resolve
+func parseFareRules(path string) (map[string][]models.FareRule, error) {
+	file, read, columns, err := scheduleCSV(path, "fare_id")
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	rules := make(map[string][]models.FareRule)
+	for {
+		record, err := read()
+		if err == io.EOF {
+			return rules, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		id := csvField(record, columns, "fare_id")
+		if id == "" {
+			continue
+		}
+		rules[id] = append(rules[id], models.FareRule{
+			Route:           csvField(record, columns, "route_id"),
+			OriginZone:      csvField(record, columns, "origin_id"),
+			DestinationZone: csvField(record, columns, "destination_id"),
+			ContainsZone:    csvField(record, columns, "contains_id"),
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package feed
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jusunglee/mta-go/internal/models"
+)
+
+func TestLoadFares(t *testing.T) {
+	files := goodGTFSFiles()
+	files["routes.txt"] = "route_id,route_short_name\n" +
+		"1,1\n" +
+		"SIR,SI\n"
+	// Zone-based fares alongside a flat one; the BAD row has no usable price
+	files["fare_attributes.txt"] = "fare_id,price,currency_type,payment_method,transfers\n" +
+		"subway,2.90,USD,1,\n" +
+		"zone_ab,4.50,USD,1,0\n" +
+		"BAD,free,USD,1,0\n"
+	files["fare_rules.txt"] = "fare_id,route_id,origin_id,destination_id,contains_id\n" +
+		"subway,1,,,\n" +
+		"zone_ab,SIR,A,B,\n" +
+		"zone_ab,SIR,B,A,\n"
+	dir := writeGTFSDir(t, files)
+
+	m := &Manager{}
+	fares, err := m.loadFares(dir, routesIn(t, dir))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []models.Fare{
+		{ID: "subway", Price: 2.9, Currency: "USD", Rules: []models.FareRule{{Route: "1"}}},
+		{ID: "zone_ab", Price: 4.5, Currency: "USD", Rules: []models.FareRule{
+			{Route: "SI", OriginZone: "A", DestinationZone: "B"},
+			{Route: "SI", OriginZone: "B", DestinationZone: "A"},
+		}},
+	}
+	if !reflect.DeepEqual(fares, expected) {
+		t.Errorf("Expected fares %+v, got %+v", expected, fares)
+	}
+
+	if !fares[1].AppliesTo("si", "B") || fares[1].AppliesTo("SI", "C") || fares[1].AppliesTo("1", "") {
+		t.Errorf("Unexpected zone fare matching for %+v", fares[1])
+	}
+
+	t.Run("no fare_rules.txt applies system-wide", func(t *testing.T) {
+		delete(files, "fare_rules.txt")
+		dir := writeGTFSDir(t, files)
+		fares, err := m.loadFares(dir, routesIn(t, dir))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(fares) != 2 || fares[0].Rules != nil || !fares[0].AppliesTo("6", "Z") {
+			t.Errorf("Expected rule-less fares applying everywhere, got %+v", fares)
+		}
+	})
+
+	t.Run("no fare files", func(t *testing.T) {
+		dir := writeGTFSDir(t, goodGTFSFiles())
+		fares, err := m.loadFares(dir, routesIn(t, dir))
+		if err != nil || fares == nil || len(fares) != 0 {
+			t.Errorf("Expected an empty fare list and no error, got %+v, %v", fares, err)
+		}
+	})
+}
@@ -2,17 +2,28 @@ package feed
 
 import (
 	"archive/zip"
+	"bufio"
+	"bytes"
+	"context"
 	"encoding/csv"
+	"errors"
 	"fmt"
+	"hash/fnv"
 	"io"
 	"log/slog"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
+	"runtime/debug"
+	"sort"
 	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/jusunglee/mta-go/internal/clock"
 	"github.com/jusunglee/mta-go/internal/gtfsrt"
 	"github.com/jusunglee/mta-go/internal/models"
 	"github.com/jusunglee/mta-go/internal/store"
@@ -40,41 +51,323 @@ var FeedURLs = []string{
 	"https://api-endpoint.mta.info/Dataservice/mtagtfsfeeds/nyct%2Fgtfs-g",    // G
 }
 
+// FeedGroups maps operator-facing feed group names to their GTFS-RT URL
+// Names follow the suffix of the MTA feed path so they're easy to cross-reference
+var FeedGroups = map[string]string{
+	"1234567": FeedURLs[0],
+	"l":       FeedURLs[1],
+	"nqrw":    FeedURLs[2],
+	"bdfm":    FeedURLs[3],
+	"ace":     FeedURLs[4],
+	"jz":      FeedURLs[5],
+	"g":       FeedURLs[6],
+}
+
+// DuplicateStopPolicy decides which record survives when stops.txt repeats a stop_id
+type DuplicateStopPolicy int
+
+const (
+	// DuplicateStopLastWins keeps the later record, matching the historical overwrite behavior
+	DuplicateStopLastWins DuplicateStopPolicy = iota
+	DuplicateStopFirstWins
+	// DuplicateStopError fails the static load so bad data is never served
+	DuplicateStopError
+)
+
+func (p DuplicateStopPolicy) String() string {
+	switch p {
+	case DuplicateStopFirstWins:
+		return "first-wins"
+	case DuplicateStopError:
+		return "error"
+	default:
+		return "last-wins"
+	}
+}
+
+// ParseDuplicateStopPolicy parses "last-wins", "first-wins" or "error"
+func ParseDuplicateStopPolicy(s string) (DuplicateStopPolicy, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "last-wins":
+		return DuplicateStopLastWins, nil
+	case "first-wins":
+		return DuplicateStopFirstWins, nil
+	case "error":
+		return DuplicateStopError, nil
+	default:
+		return DuplicateStopLastWins, fmt.Errorf("unknown duplicate stop policy %q", s)
+	}
+}
+
 // Manager handles feed fetching and processing
 // Runs background goroutine to periodically fetch and parse MTA GTFS-RT data
 type Manager struct {
-	apiKey               string
 	store                *store.Store
 	updateInterval       time.Duration
 	staticUpdateInterval time.Duration // How often to refresh static GTFS data
-	httpClient           *http.Client
+	fetcher              Fetcher
+	feedURLs             []string // GTFS-RT feeds to poll; defaults to all of FeedURLs
+	duplicateStopPolicy  DuplicateStopPolicy
+	dedupStrategy        DedupStrategy
+	arrivalRetention     int               // Arrivals kept per direction; zero means DefaultArrivalRetention
+	pastArrivalCutoff    time.Duration     // How long after arriving a train is still listed; zero means DefaultPastArrivalCutoff
+	maxAlertAge          time.Duration     // How long an alert is kept after first being seen; zero means DefaultMaxAlertAge
+	scheduleFallback     bool              // Fill directions without real-time arrivals from the static timetable
+	mergeStaticGTFS      bool              // Merge the regular static feed into the supplemented one rather than preferring supplemented
+	schedule             *schedule         // Loaded only when scheduleFallback is set
+	platformParents      map[string]string // Platform stop ID to parent station ID, from stops.txt parent_station
+	stopTimes            *staticStopTimes  // Static stop times by trip, for what real-time updates leave out
+	serviceDayCutoff     time.Duration     // Stop times before this are on the previous service day; zero is strict GTFS
+	stationOverrides     map[string]StationOverride
+	serviceArea          *models.Bounds // Stops outside are skipped while parsing; nil disables the check
+	clock                clock.Clock
 	stopCh               chan struct{}
 	wg                   sync.WaitGroup
+	updating             atomic.Bool  // Set while an update cycle is in flight
+	loadStatus           atomic.Int32 // LoadStatus after the most recent update
+	failedFeeds          int          // Real-time feeds that failed in the most recent update
+	ready                chan struct{}
+	readyOnce            sync.Once
 	gtfsDataDir          string    // Directory to store GTFS static data
+	staticSource         string    // Local GTFS zip or directory to load instead of downloading; empty downloads from the MTA
 	staticsLoaded        bool      // Track if static data has been loaded
 	lastStaticUpdate     time.Time // When static data was last successfully updated
+	unlistedRoutes       sync.Map  // Route and station pairs already logged by logUnlistedRoute
+	latencyMu            sync.Mutex
+	latencies            map[string]*feedLatency // Fetch timing and declared version by feed URL
+	access               map[string]string       // Outcome of the latest fetch by feed URL, one of the feedStatus values; guarded by latencyMu
+	expectedVersion      string                  // GTFS-RT version feeds are expected to declare; empty means DefaultGTFSRealtimeVersion
+	breakerMu            sync.Mutex
+	breakers             map[string]*hostBreaker // Circuit breaker by feed host
+	breakerThreshold     int                     // Consecutive failures that open a breaker; zero means DefaultBreakerThreshold, negative disables
+	breakerCooldown      time.Duration           // How long an open breaker skips fetches; zero means DefaultBreakerCooldown
+	statsMu              sync.Mutex
+	stats                managerStats
+	trainsMu             sync.Mutex // Guards station train lists while processFeed's entity goroutines append to them
+	alertMu              sync.Mutex
+	alertSightings       map[string]alertSighting // By alert ID; outlives the alert so pruning sticks, guarded by alertMu
 }
 
 func NewManager(apiKey string, store *store.Store, updateInterval time.Duration) *Manager {
+	area := DefaultServiceArea
+	// The key goes only to the real-time feeds' hosts; static GTFS lives on a public S3 bucket
+	fetcher := NewHTTPFetcher(apiKey)
+	fetcher.KeyHosts = urlHosts(FeedURLs)
 	return &Manager{
-		apiKey:               apiKey,
 		store:                store,
 		updateInterval:       updateInterval,
-		staticUpdateInterval: 6 * time.Hour, // Refresh static data every 6 hours
-		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
-		},
-		stopCh:      make(chan struct{}),
-		gtfsDataDir: "data/gtfs", // Default directory for GTFS data
+		staticUpdateInterval: DefaultStaticUpdateInterval,
+		fetcher:              fetcher,
+		feedURLs:             FeedURLs,
+		clock:                clock.Real{},
+		stopCh:               make(chan struct{}),
+		ready:                make(chan struct{}),
+		gtfsDataDir:          DefaultGTFSDataDir,
+		serviceArea:          &area,
 	}
 }
 
+// DefaultStaticUpdateInterval is how often static GTFS is refreshed unless SetStaticUpdateInterval changes it
+const DefaultStaticUpdateInterval = 6 * time.Hour
+
+// DefaultGTFSDataDir is where static GTFS downloads are stored, relative to the working directory
+const DefaultGTFSDataDir = "data/gtfs"
+
+// SetGTFSDataDir sets where static GTFS zips are downloaded and extracted
+// Needed in containers or read-only images where the working directory isn't writable
+func (m *Manager) SetGTFSDataDir(dir string) {
+	m.gtfsDataDir = dir
+}
+
+// SetStaticGTFSSource loads static GTFS from a local zip or extracted directory instead of the MTA's S3 URLs
+// Accepts a plain path or a file:// URL; empty restores downloading. For offline development and tests
+func (m *Manager) SetStaticGTFSSource(source string) error {
+	if source == "" {
+		m.staticSource = ""
+		return nil
+	}
+	path, err := localGTFSPath(source)
+	if err != nil {
+		return err
+	}
+	m.staticSource = path
+	return nil
+}
+
+// localGTFSPath turns a static GTFS source into a filesystem path
+func localGTFSPath(source string) (string, error) {
+	if !strings.HasPrefix(source, "file:") {
+		return source, nil
+	}
+	u, err := url.Parse(source)
+	if err != nil {
+		return "", fmt.Errorf("invalid static GTFS URL %q: %w", source, err)
+	}
+	if u.Host != "" && u.Host != "localhost" {
+		return "", fmt.Errorf("static GTFS URL %q names a remote host; only local files are supported", source)
+	}
+	if u.Path == "" {
+		// file:relative/path parses as opaque rather than as a path
+		return u.Opaque, nil
+	}
+	return u.Path, nil
+}
+
+// PrepareGTFSDataDir creates the GTFS data directory and checks it is writable
+// Called before Start so a bad path fails fast instead of on the first static download
+func (m *Manager) PrepareGTFSDataDir() error {
+	if err := os.MkdirAll(m.gtfsDataDir, 0755); err != nil {
+		return fmt.Errorf("GTFS data directory %s cannot be created: %w", m.gtfsDataDir, err)
+	}
+
+	probe, err := os.CreateTemp(m.gtfsDataDir, ".write-check-*")
+	if err != nil {
+		return fmt.Errorf("GTFS data directory %s is not writable: %w", m.gtfsDataDir, err)
+	}
+	probe.Close()
+	os.Remove(probe.Name())
+	return nil
+}
+
+// SetFetcher replaces how feed and static GTFS bytes are retrieved
+func (m *Manager) SetFetcher(f Fetcher) {
+	m.fetcher = f
+}
+
+// SetAPIKeyAuth changes where the API key is sent on feed requests
+// Only the built-in HTTPFetcher is configurable; custom fetchers handle their own auth
+func (m *Manager) SetAPIKeyAuth(auth APIKeyAuth) error {
+	f, ok := m.fetcher.(*HTTPFetcher)
+	if !ok {
+		return fmt.Errorf("fetcher %T does not support API key auth settings", m.fetcher)
+	}
+	f.Auth = auth
+	return nil
+}
+
+// SetClock replaces the time source used for arrival filtering and update timestamps
+// Intended for tests that need deterministic control over "now"
+func (m *Manager) SetClock(c clock.Clock) {
+	m.clock = c
+}
+
+// now returns the current time from the configured clock
+// Falls back to the wall clock so zero-value Managers used in tests keep working
+func (m *Manager) now() time.Time {
+	if m.clock == nil {
+		return time.Now()
+	}
+	return m.clock.Now()
+}
+
+// feedGroupName returns the FeedGroups name for a feed URL
+// Falls back to the URL itself for feeds not in FeedGroups
+func feedGroupName(feedURL string) string {
+	for name, url := range FeedGroups {
+		if url == feedURL {
+			return name
+		}
+	}
+	return feedURL
+}
+
+// SetFeedGroups restricts real-time polling to the named feed groups (see FeedGroups)
+// Names are case-insensitive. Static GTFS is still loaded in full so station lookups keep working
+func (m *Manager) SetFeedGroups(names []string) error {
+	urls := make([]string, 0, len(names))
+	seen := make(map[string]bool)
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		url, ok := FeedGroups[name]
+		if !ok {
+			return fmt.Errorf("unknown feed group %q", name)
+		}
+		if !seen[url] {
+			seen[url] = true
+			urls = append(urls, url)
+		}
+	}
+	if len(urls) == 0 {
+		return fmt.Errorf("no feed groups specified")
+	}
+
+	m.feedURLs = urls
+	if f, ok := m.fetcher.(*HTTPFetcher); ok {
+		f.KeyHosts = urlHosts(urls)
+	}
+	return nil
+}
+
+// SetDuplicateStopPolicy configures how repeated stop_ids in stops.txt are resolved
+func (m *Manager) SetDuplicateStopPolicy(policy DuplicateStopPolicy) {
+	m.duplicateStopPolicy = policy
+}
+
+// DefaultPastArrivalCutoff keeps a just-departed train on the board briefly
+// so riders running for it can see it has left rather than having it vanish
+const DefaultPastArrivalCutoff = time.Minute
+
+// SetPastArrivalCutoff configures how long past its arrival time a train is still reported
+// Zero restores DefaultPastArrivalCutoff
+func (m *Manager) SetPastArrivalCutoff(cutoff time.Duration) {
+	m.pastArrivalCutoff = cutoff
+}
+
+// DefaultMaxAlertAge bounds how long an alert is served after it was first seen
+// A day outlasts any ordinary service change while still clearing alerts that would otherwise never expire
+const DefaultMaxAlertAge = 24 * time.Hour
+
+// SetMaxAlertAge configures how long after first being seen an alert is dropped, even without an end time
+// Zero restores DefaultMaxAlertAge
+func (m *Manager) SetMaxAlertAge(age time.Duration) {
+	m.maxAlertAge = age
+}
+
+// alertMaxAge returns the effective maximum alert age
+func (m *Manager) alertMaxAge() time.Duration {
+	if m.maxAlertAge <= 0 {
+		return DefaultMaxAlertAge
+	}
+	return m.maxAlertAge
+}
+
+// DefaultArrivalRetention is how many arrivals per direction a station keeps in the store
+// Generous so headway and schedule features see more than the few trains a response shows
+const DefaultArrivalRetention = 30
+
+// SetArrivalRetention sets how many arrivals per direction are kept after each update
+// Zero or negative restores DefaultArrivalRetention
+func (m *Manager) SetArrivalRetention(n int) {
+	m.arrivalRetention = n
+}
+
+// retention returns the effective per-direction arrival retention
+func (m *Manager) retention() int {
+	if m.arrivalRetention <= 0 {
+		return DefaultArrivalRetention
+	}
+	return m.arrivalRetention
+}
+
+// arrivalCutoff returns the effective past-arrival cutoff
+// Falls back to the default so zero-value Managers used in tests keep working
+func (m *Manager) arrivalCutoff() time.Duration {
+	if m.pastArrivalCutoff <= 0 {
+		return DefaultPastArrivalCutoff
+	}
+	return m.pastArrivalCutoff
+}
+
 // SetStaticUpdateInterval configures how often static GTFS data is refreshed
 // Default is 6 hours. Set to 0 to disable automatic refresh (only load once).
 func (m *Manager) SetStaticUpdateInterval(interval time.Duration) {
 	m.staticUpdateInterval = interval
 }
 
+func (m *Manager) GetStaticUpdateInterval() time.Duration {
+	return m.staticUpdateInterval
+}
+
 // GetLastStaticUpdate returns when static GTFS data was last successfully updated
 // Returns zero time if static data hasn't been loaded yet
 func (m *Manager) GetLastStaticUpdate() time.Time {
@@ -97,9 +390,10 @@ func (m *Manager) updateLoop() {
 	defer m.wg.Done()
 
 	// Fetch initial data before starting periodic updates
-	if err := m.update(); err != nil {
+	if err := m.safeUpdate(); err != nil {
 		slog.Error("Initial update failed", "error", err)
 	}
+	m.readyOnce.Do(func() { close(m.ready) })
 
 	ticker := time.NewTicker(m.updateInterval)
 	defer ticker.Stop()
@@ -108,33 +402,72 @@ func (m *Manager) updateLoop() {
 	for {
 		select {
 		case <-ticker.C:
-			if err := m.update(); err != nil {
-				slog.Error("Update failed", "error", err)
-			}
+			m.tryUpdate()
 		case <-m.stopCh:
 			return
 		}
 	}
 }
 
+// tryUpdate starts an update in the background unless one is already running
+// A slow MTA API would otherwise have cycles run back-to-back from queued ticks;
+// skipping keeps updates spaced by at least one interval and never overlapping
+func (m *Manager) tryUpdate() {
+	if !m.updating.CompareAndSwap(false, true) {
+		slog.Warn("Skipping update, previous update still running", "interval", m.updateInterval)
+		return
+	}
+
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		defer m.updating.Store(false)
+
+		if err := m.safeUpdate(); err != nil {
+			slog.Error("Update failed", "error", err)
+		}
+	}()
+}
+
+// safeUpdate runs update, converting a panic into an error
+// A panic would otherwise kill the update goroutine and leave the server serving stale data indefinitely
+func (m *Manager) safeUpdate() (err error) {
+	defer m.recoverPanic(&err, "update")
+	return m.update()
+}
+
+// recoverPanic is deferred to turn a panic into an error in *errp, logging the stack and counting it in Stats
+// where names the work that panicked for the log and error message
+func (m *Manager) recoverPanic(errp *error, where string) {
+	if r := recover(); r != nil {
+		m.recordPanic()
+		slog.Error("Recovered from panic", "in", where, "panic", r, "stack", string(debug.Stack()))
+		*errp = fmt.Errorf("panic in %s: %v", where, r)
+	}
+}
+
 func (m *Manager) update() error {
 	// Load static GTFS data on first run OR if enough time has passed
 	needsStaticUpdate := !m.staticsLoaded ||
-		(m.staticUpdateInterval > 0 && !m.lastStaticUpdate.IsZero() && time.Since(m.lastStaticUpdate) > m.staticUpdateInterval)
+		(m.staticUpdateInterval > 0 && !m.lastStaticUpdate.IsZero() && m.now().Sub(m.lastStaticUpdate) > m.staticUpdateInterval)
 
+	degraded := false
 	if needsStaticUpdate {
 		if err := m.loadStaticGTFSData(); err != nil {
-			if !m.staticsLoaded {
-				// First load failed - this is critical
+			if stations, _, _ := m.store.Counts(); !m.staticsLoaded && stations == 0 {
+				// First load failed with no stations to serve - this is critical
+				m.setLoadStatus(LoadStatusFailed)
 				return fmt.Errorf("failed to load initial static GTFS data: %w", err)
 			}
-			// Refresh failed but we have existing data - log warning and continue
+			// Refresh failed, or the first load did with stations already in the store from a
+			// previous manager or a snapshot - log warning and continue with existing data
+			degraded = true
 			slog.Warn("Failed to refresh static GTFS data, continuing with existing data",
 				"error", err, "last_update", m.lastStaticUpdate)
 		} else {
 			// Success - update tracking variables
 			m.staticsLoaded = true
-			m.lastStaticUpdate = time.Now()
+			m.lastStaticUpdate = m.now()
 			slog.Info("Successfully refreshed static GTFS data", "update_time", m.lastStaticUpdate)
 		}
 	}
@@ -143,6 +476,13 @@ func (m *Manager) update() error {
 	if err := m.updateRealTimeData(); err != nil {
 		slog.Warn("Failed to update real-time data", "error", err)
 		// Don't return error - static data should still be available
+		degraded = true
+	}
+
+	if degraded || m.failedFeeds > 0 {
+		m.setLoadStatus(LoadStatusDegraded)
+	} else {
+		m.setLoadStatus(LoadStatusReady)
 	}
 
 	return nil
@@ -150,85 +490,261 @@ func (m *Manager) update() error {
 
 // updateRealTimeData fetches and processes GTFS-RT feeds for live train data
 func (m *Manager) updateRealTimeData() error {
-	// Get current stations from store to update with real-time data
-	stations := make(map[string]*models.Station)
-
-	// Get all routes and fetch stations for each to build a map
-	routes := m.store.GetRoutes()
-	for _, route := range routes {
-		routeStations, err := m.store.GetStationsByRoute(route)
-		if err != nil {
-			continue
+	// Start from the current stations with their arrivals cleared, to be refilled from the feeds
+	stations := m.store.StationsForUpdate()
+
+	// Process each enabled GTFS-RT feed, tracking the newest MTA generation time
+	var feedTime time.Time
+	feedTimes := make(map[string]time.Time) // Generation time by feed group, the Source arrivals carry
+	failed := 0
+	vehicles := newVehicleSet()
+	for _, feedURL := range m.feedURLs {
+		generated, err := m.processFeed(feedURL, stations, vehicles)
+		m.recordFeedResult(feedURL, err)
+		if generated.After(feedTime) {
+			feedTime = generated
 		}
-		for _, station := range routeStations {
-			if _, exists := stations[station.ID]; !exists {
-				// Create a copy to avoid modifying store data directly
-				stationCopy := station
-				stationCopy.Trains = models.TrainsByDirection{
-					North: []models.Train{},
-					South: []models.Train{},
-				}
-				stations[station.ID] = &stationCopy
-			}
+		if !generated.IsZero() {
+			feedTimes[feedGroupName(feedURL)] = generated
 		}
-	}
-
-	// Process each GTFS-RT feed
-	for _, feedURL := range FeedURLs {
-		if err := m.processFeed(feedURL, stations); err != nil {
-			slog.Warn("Failed to process feed", "url", feedURL, "error", err)
+		if err != nil {
+			// recordFeedAccess already warned once when the feed started rejecting the key
+			if isAuthError(err) {
+				slog.Debug("Failed to process feed", "url", feedURL, "error", err)
+			} else {
+				slog.Warn("Failed to process feed", "url", feedURL, "error", err)
+			}
+			failed++
 			// Continue with other feeds
 		}
 	}
+	m.failedFeeds = failed
+
+	trips := buildTripProgress(stations, vehicles)
 
 	// Sort and clean up train arrivals for each station
+	// LastUpdate only advances for stations that got arrivals this cycle so it reflects per-station freshness
+	now := m.now()
 	for _, station := range stations {
+		if len(station.Trains.North) > 0 || len(station.Trains.South) > 0 {
+			station.LastUpdate = stationFreshness(station.Trains, feedTimes, now)
+		}
+		if m.schedule != nil {
+			m.fillScheduledArrivals(station, now)
+		}
 		station.Trains.North = m.sortAndLimitTrains(station.Trains.North)
 		station.Trains.South = m.sortAndLimitTrains(station.Trains.South)
-		station.LastUpdate = time.Now()
+	}
+
+	// Report freshness from when the MTA generated the data rather than when we fetched it,
+	// so a feed that keeps serving the same stale message shows up as stale. When every feed
+	// failed nothing new arrived, so the previous update time stands
+	if feedTime.IsZero() {
+		if failed < len(m.feedURLs) {
+			feedTime = now
+		} else {
+			feedTime = m.store.GetLastUpdate()
+		}
 	}
 
 	// Update store with real-time data
-	m.store.UpdateStations(stations)
+	m.store.UpdateStationsAt(stations, feedTime)
+	m.store.UpdateTrips(trips)
+	m.pruneExpiredAlerts()
+	m.recordUpdateCycle()
 
 	return nil
 }
 
+// stationFreshness returns when the newest feed serving trains was generated
+// Stations are served by different feeds, and one feed can keep repeating an old message while the others move on,
+// so this rather than the fetch time is what says how current a station's arrivals are.
+// Feeds without a header timestamp count as fetched now, and a timestamp ahead of now is clamped to it
+func stationFreshness(trains models.TrainsByDirection, feedTimes map[string]time.Time, now time.Time) time.Time {
+	var latest time.Time
+	for _, direction := range [][]models.Train{trains.North, trains.South} {
+		for _, train := range direction {
+			generated, ok := feedTimes[train.Source]
+			if !ok {
+				generated = now
+			}
+			if generated.After(latest) {
+				latest = generated
+			}
+		}
+	}
+	if latest.After(now) {
+		return now
+	}
+	return latest
+}
+
+// alertExpiryGrace keeps an alert visible for a while after its last active period ends
+// so riders still see a service change that overran its published end time
+const alertExpiryGrace = time.Hour
+
+// pruneExpiredAlerts drops alerts whose latest active period ended more than alertExpiryGrace ago
+// Alerts without an end time are kept until the feed stops reporting them, or until they reach the
+// maximum alert age: a safety net so an open-ended alert can't outlive its removal from the feed.
+// It runs once per update cycle and always writes the alerts back, so the store audits the cycle's
+// upserts and removals together
+func (m *Manager) pruneExpiredAlerts() {
+	alerts := m.store.GetServiceAlerts()
+	now := m.now()
+	cutoff := now.Add(-alertExpiryGrace)
+	seenCutoff := now.Add(-m.alertMaxAge())
+
+	kept := make([]models.Alert, 0, len(alerts))
+	for _, alert := range alerts {
+		if end, ok := latestAlertEnd(alert); ok && end.Before(cutoff) {
+			slog.Debug("Pruning expired alert", "id", alert.ID, "end", end)
+			continue
+		}
+		if !alert.FirstSeen.IsZero() && alert.FirstSeen.Before(seenCutoff) {
+			slog.Debug("Pruning alert past maximum age", "id", alert.ID, "first_seen", alert.FirstSeen)
+			continue
+		}
+		kept = append(kept, alert)
+	}
+
+	m.store.UpdateAlerts(kept)
+	m.forgetAlertSightings(cutoff)
+}
+
+// alertSighting is when the feed first and last reported an alert
+type alertSighting struct {
+	first, last time.Time
+}
+
+// sightAlert records that the feed reported the alert with id at now and returns when it was first reported
+// Sightings outlive the alert in the store, so an alert pruned at the maximum age stays pruned while the
+// feed keeps reporting it instead of coming back with a fresh FirstSeen
+func (m *Manager) sightAlert(id string, now time.Time) time.Time {
+	m.alertMu.Lock()
+	defer m.alertMu.Unlock()
+
+	if m.alertSightings == nil {
+		m.alertSightings = make(map[string]alertSighting)
+	}
+	sighting, ok := m.alertSightings[id]
+	if !ok {
+		sighting.first = now
+	}
+	sighting.last = now
+	m.alertSightings[id] = sighting
+	return sighting.first
+}
+
+// forgetAlertSightings drops sightings last reported before cutoff
+// An alert the feed stopped reporting and later reissues under the same ID then counts as new
+func (m *Manager) forgetAlertSightings(cutoff time.Time) {
+	m.alertMu.Lock()
+	defer m.alertMu.Unlock()
+
+	for id, sighting := range m.alertSightings {
+		if sighting.last.Before(cutoff) {
+			delete(m.alertSightings, id)
+		}
+	}
+}
+
+// latestAlertEnd returns the end of an alert's last active period
+// Reports false when any period is open-ended, since the alert has no known expiry
+func latestAlertEnd(alert models.Alert) (time.Time, bool) {
+	if len(alert.ActivePeriods) == 0 {
+		return time.Time{}, false
+	}
+
+	var latest time.Time
+	for _, period := range alert.ActivePeriods {
+		if period.End == nil {
+			return time.Time{}, false
+		}
+		if period.End.After(latest) {
+			latest = *period.End
+		}
+	}
+	return latest, true
+}
+
+// errNonProtobufResponse marks a feed body that is clearly not GTFS-RT, such as an HTML error page
+var errNonProtobufResponse = errors.New("non-protobuf response")
+
+// checkProtobufBody rejects feed bodies that are markup rather than protobuf
+// When the MTA endpoint is down it can answer 200 with an HTML page, which proto.Unmarshal
+// either rejects with a confusing wire-format error or partially decodes into junk entities.
+// A FeedMessage starts with its header field (0x0A), never '<', so markup is unambiguous
+func checkProtobufBody(data []byte) error {
+	trimmed := bytes.TrimLeft(data, " \t\r\n")
+	if len(trimmed) > 0 && trimmed[0] == '<' {
+		return fmt.Errorf("%w (got %s)", errNonProtobufResponse, http.DetectContentType(data))
+	}
+	return nil
+}
+
 // processFeed fetches and parses a single GTFS-RT feed
-func (m *Manager) processFeed(feedURL string, stations map[string]*models.Station) error {
+// Returns the feed header timestamp (zero if absent or unreadable) even when some entities fail.
+// Vehicle positions are collected into vehicles when it is non-nil
+func (m *Manager) processFeed(feedURL string, stations map[string]*models.Station, vehicles *vehicleSet) (generated time.Time, err error) {
+	// Tag arrivals with their feed group so odd data can be traced back to its source
+	source := feedGroupName(feedURL)
+
+	// One malformed feed shouldn't take the others down with it
+	defer m.recoverPanic(&err, "feed "+source)
+
 	// Fetch the protobuf data
 	data, err := m.fetchFeed(feedURL)
 	if err != nil {
-		return fmt.Errorf("failed to fetch feed: %w", err)
+		return time.Time{}, fmt.Errorf("failed to fetch feed: %w", err)
+	}
+
+	if err := checkProtobufBody(data); err != nil {
+		return time.Time{}, err
 	}
 
+	// Parse timing starts after the fetch; network time is tracked separately by recordFetchLatency
+	parseStart := time.Now()
+
 	// Parse the protobuf message
 	var feedMessage gtfsrt.FeedMessage
 	if err := proto.Unmarshal(data, &feedMessage); err != nil {
-		return fmt.Errorf("failed to unmarshal protobuf: %w", err)
+		return time.Time{}, fmt.Errorf("failed to unmarshal protobuf: %w", err)
+	}
+
+	if ts := feedMessage.GetHeader().GetTimestamp(); ts > 0 {
+		generated = time.Unix(int64(ts), 0)
 	}
+	m.checkFeedVersion(feedURL, feedMessage.GetHeader().GetGtfsRealtimeVersion())
 
 	// Process each entity in the feed
+	// Each goroutine recovers on its own; a panic in an errgroup goroutine would crash the process
 	var eg errgroup.Group
 	for _, entity := range feedMessage.Entity {
-		eg.Go(func() error {
+		eg.Go(func() (err error) {
+			defer m.recoverPanic(&err, "feed "+source+" entity "+entity.GetId())
 			if entity.TripUpdate != nil {
-				err := m.processTripUpdate(entity.TripUpdate, stations)
+				err := m.processTripUpdate(entity.TripUpdate, stations, source)
 				if err != nil {
 					return fmt.Errorf("failed to process trip update for entity %v: %w", entity.Id, err)
 				}
 			}
+			if entity.Vehicle != nil {
+				m.processVehicle(entity.Vehicle, vehicles)
+			}
 			if entity.Alert != nil {
-				return m.processAlert(entity.Alert)
+				return m.processAlert(entity.GetId(), entity.Alert)
 			}
 			return nil
 		})
 	}
-	return eg.Wait()
+	err = eg.Wait()
+	m.recordParseDuration(feedURL, time.Since(parseStart))
+	return generated, err
 }
 
 // processTripUpdate processes a GTFS-RT trip update to extract arrival times
-func (m *Manager) processTripUpdate(tripUpdate *gtfsrt.TripUpdate, stations map[string]*models.Station) error {
+// source is the feed group name recorded on each resulting train
+func (m *Manager) processTripUpdate(tripUpdate *gtfsrt.TripUpdate, stations map[string]*models.Station, source string) error {
 	if tripUpdate.Trip == nil || tripUpdate.Trip.RouteId == nil {
 		return fmt.Errorf("trip update is missing required fields")
 	}
@@ -243,26 +759,18 @@ func (m *Manager) processTripUpdate(tripUpdate *gtfsrt.TripUpdate, stations map[
 
 	// Process each stop time update
 	for _, stopTimeUpdate := range tripUpdate.StopTimeUpdate {
+		// NO_DATA means there's no prediction for this stop, usually with no arrival time at all;
+		// skip it rather than failing the rest of the trip
+		if stopTimeUpdate.GetScheduleRelationship() == gtfsrt.StopTimeUpdate_NO_DATA {
+			continue
+		}
+
 		if stopTimeUpdate.StopId == nil || stopTimeUpdate.Arrival == nil {
 			return fmt.Errorf("stop time update is missing required fields")
 		}
 
 		stopID := *stopTimeUpdate.StopId
-
-		// Extract parent station ID (remove direction suffix)
-		parentStationID := stopID
-		direction := ""
-		if len(stopID) > 0 {
-			lastChar := stopID[len(stopID)-1]
-			if lastChar == 'N' || lastChar == 'S' {
-				parentStationID = stopID[:len(stopID)-1]
-				if lastChar == 'N' {
-					direction = "North"
-				} else {
-					direction = "South"
-				}
-			}
-		}
+		parentStationID, direction := m.resolveStop(stopID)
 
 		// Find the station
 		station, exists := stations[parentStationID]
@@ -270,45 +778,106 @@ func (m *Manager) processTripUpdate(tripUpdate *gtfsrt.TripUpdate, stations map[
 			return fmt.Errorf("station not found: %s", parentStationID)
 		}
 
+		// The static timetable fills in what the update leaves out; NYCT updates rarely carry a sequence or delay
+		static, hasStatic := m.stopTimes.lookup(tripUpdate.Trip.GetTripId(), stopID)
+
 		// Calculate arrival time
 		var arrivalTime time.Time
+		var scheduledTime *time.Time
 		if stopTimeUpdate.Arrival.Time != nil {
 			arrivalTime = time.Unix(*stopTimeUpdate.Arrival.Time, 0)
 		} else if stopTimeUpdate.Arrival.Delay != nil {
-			// If only delay is provided, add it to current time
-			// This is a simplification - ideally we'd use scheduled time + delay
-			arrivalTime = time.Now().Add(time.Duration(*stopTimeUpdate.Arrival.Delay) * time.Second)
+			delay := time.Duration(*stopTimeUpdate.Arrival.Delay) * time.Second
+			if scheduled, ok := m.stopTimes.scheduledAt(static, tripUpdate.Trip.GetStartDate(), m.now()); hasStatic && ok {
+				arrivalTime = scheduled.Add(delay)
+				scheduledTime = &scheduled
+			} else {
+				// Without a timetable entry the delay can only be applied to the current time
+				arrivalTime = m.now().Add(delay)
+			}
 		} else {
 			return fmt.Errorf("no usable time data")
 		}
 
-		// Skip past arrivals (more than 1 minute ago)
-		if time.Since(arrivalTime) > time.Minute {
-			return fmt.Errorf("arrival time is more than 1 minute ago")
+		// Skip arrivals further in the past than the cutoff
+		if cutoff := m.arrivalCutoff(); m.now().Sub(arrivalTime) > cutoff {
+			return fmt.Errorf("arrival time is more than %v ago", cutoff)
 		}
 
 		// Create train arrival
 		train := models.Train{
-			Route: routeName,
-			Time:  arrivalTime,
+			Route:    routeName,
+			Time:     arrivalTime,
+			Source:   source,
+			TripID:   tripUpdate.Trip.GetTripId(),
+			Realtime: true,
+			Assigned: tripAssigned(tripUpdate.Trip),
+		}
+		train.StopSequence = stopTimeUpdate.GetStopSequence()
+		if train.StopSequence == 0 && hasStatic {
+			train.StopSequence = static.sequence
+		}
+		train.ScheduledTrack, train.ActualTrack = stopTracks(stopTimeUpdate)
+		switch {
+		case scheduledTime != nil:
+			train.ScheduledTime = scheduledTime
+		case stopTimeUpdate.Arrival.Time != nil && stopTimeUpdate.Arrival.Delay != nil:
+			scheduled := arrivalTime.Add(-time.Duration(*stopTimeUpdate.Arrival.Delay) * time.Second)
+			train.ScheduledTime = &scheduled
+		case hasStatic:
+			if scheduled, ok := m.stopTimes.scheduledAt(static, tripUpdate.Trip.GetStartDate(), arrivalTime); ok {
+				train.ScheduledTime = &scheduled
+			}
+		}
+
+		// Keep arrivals for routes static data doesn't place here (diversions, temporary routes)
+		// but flag them, since the station's Routes and the route index won't include them
+		if !servesRoute(station, routeName) {
+			train.UnlistedRoute = true
+			m.logUnlistedRoute(routeName, parentStationID)
 		}
 
 		// Add to appropriate direction
+		// Entities are processed concurrently and trips share stations, so appends take trainsMu
 		switch direction {
-		case "North":
+		case models.DirectionNorth:
+			m.trainsMu.Lock()
 			station.Trains.North = append(station.Trains.North, train)
-		case "South":
+			m.trainsMu.Unlock()
+		case models.DirectionSouth:
+			m.trainsMu.Lock()
 			station.Trains.South = append(station.Trains.South, train)
-		default:
-			return fmt.Errorf("invalid direction: %s", direction)
+			m.trainsMu.Unlock()
+		case models.DirectionUnknown:
+			// A bare parent station ID doesn't say which platform the train stops at
+			return fmt.Errorf("stop %s has no direction suffix", stopID)
 		}
 	}
 
 	return nil
 }
 
+// servesRoute reports whether route is in the station's static route list
+func servesRoute(station *models.Station, route string) bool {
+	for _, r := range station.Routes {
+		if r == route {
+			return true
+		}
+	}
+	return false
+}
+
+// logUnlistedRoute warns the first time a route shows up at a station that doesn't list it
+// Once per route and station is enough to trace a diversion's path without flooding the log every cycle
+func (m *Manager) logUnlistedRoute(route, stationID string) {
+	key := struct{ route, stationID string }{route, stationID}
+	if _, seen := m.unlistedRoutes.LoadOrStore(key, true); !seen {
+		slog.Warn("Real-time arrival for route not listed in static data for station", "route", route, "station", stationID)
+	}
+}
+
 // processAlert processes a GTFS-RT alert and adds it to the store
-func (m *Manager) processAlert(alert *gtfsrt.Alert) error {
+func (m *Manager) processAlert(entityID string, alert *gtfsrt.Alert) error {
 	if alert.HeaderText == nil || len(alert.HeaderText.Translation) == 0 {
 		return fmt.Errorf("alert is missing header text")
 	}
@@ -336,23 +905,29 @@ func (m *Manager) processAlert(alert *gtfsrt.Alert) error {
 			}
 		}
 		if entity.StopId != nil {
-			stopID := *entity.StopId
-			// Extract parent station ID
-			if len(stopID) > 0 && (stopID[len(stopID)-1] == 'N' || stopID[len(stopID)-1] == 'S') {
-				stopID = stopID[:len(stopID)-1]
-			}
-			stationIDs = append(stationIDs, stopID)
+			stationID, _ := m.resolveStop(*entity.StopId)
+			stationIDs = append(stationIDs, stationID)
 		}
 	}
 
+	// Age from the first sighting, which survives the alert being pruned
+	id := alertID(entityID, *headerText)
+	now := m.now()
+	firstSeen := m.sightAlert(id, now)
+	if firstSeen.Before(now.Add(-m.alertMaxAge())) {
+		slog.Debug("Skipping alert past maximum age", "id", id, "first_seen", firstSeen)
+		return nil
+	}
+
 	// Create alert model
 	alertModel := models.Alert{
-		ID:            fmt.Sprintf("rt_%d", time.Now().Unix()), // Generate unique ID
+		ID:            id,
 		Header:        *headerText,
 		Description:   descriptionText,
 		Routes:        routes,
 		Stations:      stationIDs,
 		ActivePeriods: []models.TimePeriod{}, // TODO: Parse active periods from alert.ActivePeriod
+		FirstSeen:     firstSeen,
 	}
 
 	// Add active periods
@@ -369,14 +944,24 @@ func (m *Manager) processAlert(alert *gtfsrt.Alert) error {
 		alertModel.ActivePeriods = append(alertModel.ActivePeriods, timePeriod)
 	}
 
-	// Get current alerts and add this one
-	currentAlerts := m.store.GetServiceAlerts()
-	currentAlerts = append(currentAlerts, alertModel)
-	m.store.UpdateAlerts(currentAlerts)
+	// Replace the copy from an earlier cycle, keeping its FirstSeen
+	m.store.UpsertAlert(alertModel)
 
 	return nil
 }
 
+// alertID is the stable ID of a feed alert: its entity ID, which the feed keeps across cycles
+// Entity IDs are required by GTFS-RT, but an alert without one is keyed by its header so
+// re-seeing it still replaces rather than duplicates it
+func alertID(entityID, header string) string {
+	if entityID != "" {
+		return entityID
+	}
+	h := fnv.New64a()
+	h.Write([]byte(header))
+	return fmt.Sprintf("rt_%x", h.Sum64())
+}
+
 // extractRouteFromID extracts route name from GTFS route ID
 // E.g., "A20241201" -> "A", "N20241201" -> "N", "123_20241201" -> "123_"
 func (m *Manager) extractRouteFromID(routeID string) string {
@@ -418,49 +1003,78 @@ func (m *Manager) extractRouteFromID(routeID string) string {
 }
 
 // fetchFeed retrieves GTFS-RT protobuf data from MTA API
+// Skipped without a request while the host's circuit breaker is open
 func (m *Manager) fetchFeed(url string) ([]byte, error) {
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
+	host := feedHost(url)
+	if err := m.breakerAllow(host); err != nil {
 		return nil, err
 	}
-	// MTA requires API key in x-api-key header
-	req.Header.Set("x-api-key", m.apiKey)
 
-	resp, err := m.httpClient.Do(req)
+	ctx, cancel := m.fetchContext()
+	defer cancel()
+
+	start := time.Now()
+	data, err := m.fetcher.Fetch(ctx, url)
+	m.breakerRecord(host, err)
+	m.recordFeedAccess(url, err)
 	if err != nil {
+		// Failures are logged by the caller; fast 4xx responses would make the feed look healthier than it is
 		return nil, err
 	}
-	defer resp.Body.Close()
+	m.recordFetchLatency(url, time.Since(start))
+	return data, nil
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("HTTP %d", resp.StatusCode)
+// fetchContext returns a context cancelled when the manager stops
+// so an in-flight fetch doesn't hold up shutdown until the HTTP timeout
+func (m *Manager) fetchContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	if m.stopCh != nil {
+		go func() {
+			select {
+			case <-m.stopCh:
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
 	}
-
-	return io.ReadAll(resp.Body)
+	return ctx, cancel
 }
 
 // loadStaticGTFSData downloads and parses GTFS static data
 func (m *Manager) loadStaticGTFSData() error {
+	if m.staticSource != "" {
+		return m.loadLocalStaticGTFS(m.staticSource)
+	}
+
 	// Create data directory if it doesn't exist
 	if err := os.MkdirAll(m.gtfsDataDir, 0755); err != nil {
 		return fmt.Errorf("failed to create GTFS data directory: %w", err)
 	}
 
+	supplementedPath := filepath.Join(m.gtfsDataDir, "gtfs_supplemented.zip")
+	regularPath := filepath.Join(m.gtfsDataDir, "gtfs_subway.zip")
+	extractDir := filepath.Join(m.gtfsDataDir, "extracted")
+
 	// Download and extract GTFS data (prefer supplemented for current service changes)
-	gtfsPath := filepath.Join(m.gtfsDataDir, "gtfs_supplemented.zip")
-	if err := m.downloadFile(GTFSSupplementedURL, gtfsPath); err != nil {
+	if m.mergeStaticGTFS {
+		if err := m.downloadMergedGTFS(extractDir, supplementedPath, regularPath); err != nil {
+			return err
+		}
+	} else if err := m.downloadFile(GTFSSupplementedURL, supplementedPath); err != nil {
 		slog.Warn("Failed to download supplemented GTFS, trying regular", "error", err)
 		// Fallback to regular GTFS
-		gtfsPath = filepath.Join(m.gtfsDataDir, "gtfs_subway.zip")
-		if err := m.downloadFile(GTFSRegularURL, gtfsPath); err != nil {
+		if err := m.downloadFile(GTFSRegularURL, regularPath); err != nil {
 			return fmt.Errorf("failed to download GTFS data: %w", err)
 		}
-	}
-
-	// Extract ZIP file
-	extractDir := filepath.Join(m.gtfsDataDir, "extracted")
-	if err := m.extractZip(gtfsPath, extractDir); err != nil {
-		return fmt.Errorf("failed to extract GTFS data: %w", err)
+		if err := m.extractFresh(regularPath, extractDir); err != nil {
+			return fmt.Errorf("failed to extract GTFS data: %w", err)
+		}
+	} else {
+		if err := m.extractFresh(supplementedPath, extractDir); err != nil {
+			return fmt.Errorf("failed to extract GTFS data: %w", err)
+		}
+		m.fillMissingGTFSFiles(extractDir, regularPath)
 	}
 
 	// Parse GTFS data and populate store
@@ -471,29 +1085,140 @@ func (m *Manager) loadStaticGTFSData() error {
 	return nil
 }
 
-// downloadFile downloads a file from URL to local path
-func (m *Manager) downloadFile(url, filepath string) error {
-	resp, err := m.httpClient.Get(url)
+// loadLocalStaticGTFS parses static GTFS from a local path without any downloads
+// A directory is parsed in place; anything else is taken as a zip and extracted into the data directory as usual
+func (m *Manager) loadLocalStaticGTFS(path string) error {
+	info, err := os.Stat(path)
 	if err != nil {
-		return fmt.Errorf("failed to create directory %s: %w", m.gtfsDataDir, err)
+		return fmt.Errorf("failed to read local GTFS: %w", err)
+	}
+
+	dir := path
+	if !info.IsDir() {
+		dir = filepath.Join(m.gtfsDataDir, "extracted")
+		if err := m.extractFresh(path, dir); err != nil {
+			return fmt.Errorf("failed to extract GTFS data: %w", err)
+		}
+	}
+
+	if err := m.parseGTFSData(dir); err != nil {
+		return fmt.Errorf("failed to parse GTFS data: %w", err)
 	}
-	defer resp.Body.Close()
+	return nil
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("HTTP %d", resp.StatusCode)
+// extractFresh extracts a zip into an emptied dest
+// Leftovers from an earlier download would otherwise stand in for files the new zip doesn't have
+func (m *Manager) extractFresh(src, dest string) error {
+	if err := os.RemoveAll(dest); err != nil {
+		return fmt.Errorf("failed to clear %s: %w", dest, err)
 	}
+	return m.extractZip(src, dest)
+}
 
-	out, err := os.Create(filepath)
+// fillMissingGTFSFiles copies files the supplemented feed left out of dir from the regular feed
+// The supplemented zip occasionally omits files like shapes.txt or transfers.txt; taking only those
+// from the regular feed keeps the supplemented service changes for everything it does include.
+// Failures are only logged, since parsing reports any required file that is still missing
+func (m *Manager) fillMissingGTFSFiles(dir, regularPath string) {
+	var missing []string
+	for _, name := range append(append([]string(nil), requiredGTFSFiles...), optionalGTFSFiles...) {
+		if _, err := os.Stat(filepath.Join(dir, name)); os.IsNotExist(err) {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) == 0 {
+		return
+	}
+
+	slog.Info("Supplemented GTFS is missing files, taking them from regular GTFS", "files", missing)
+	if err := m.downloadFile(GTFSRegularURL, regularPath); err != nil {
+		slog.Warn("Failed to download regular GTFS for missing files", "error", err)
+		return
+	}
+	regularDir := filepath.Join(m.gtfsDataDir, "extracted-regular")
+	if err := m.extractFresh(regularPath, regularDir); err != nil {
+		slog.Warn("Failed to extract regular GTFS for missing files", "error", err)
+		return
+	}
+
+	for _, name := range missing {
+		err := os.Rename(filepath.Join(regularDir, name), filepath.Join(dir, name))
+		if os.IsNotExist(err) {
+			slog.Warn("GTFS file missing from both supplemented and regular feeds", "file", name)
+		} else if err != nil {
+			slog.Warn("Failed to copy GTFS file from regular feed", "file", name, "error", err)
+		}
+	}
+}
+
+// downloadFile downloads a file from URL to local path
+// The previous file at dest survives a failed download, so a retry never extracts a truncated zip.
+// A StreamFetcher's response goes straight to disk rather than being held in memory first
+func (m *Manager) downloadFile(url, dest string) error {
+	ctx, cancel := m.fetchContext()
+	defer cancel()
+
+	if f, ok := m.fetcher.(StreamFetcher); ok {
+		err := writeFileAtomicFrom(dest, func(w io.Writer) error { return f.FetchTo(ctx, url, w) })
+		if err != nil {
+			return fmt.Errorf("failed to download %s: %w", url, err)
+		}
+		return nil
+	}
+
+	data, err := m.fetcher.Fetch(ctx, url)
 	if err != nil {
-		return fmt.Errorf("failed to create file %s: %w", filepath, err)
+		return fmt.Errorf("failed to download %s: %w", url, err)
 	}
-	defer out.Close()
 
-	_, err = io.Copy(out, resp.Body)
+	if err := writeFileAtomic(dest, data); err != nil {
+		return fmt.Errorf("failed to write file %s: %w", dest, err)
+	}
+
+	return nil
+}
+
+// writeFileAtomic writes to a temp file in dest's directory and renames it into place
+// so dest is either the previous complete file or the new one, never a truncated zip
+func writeFileAtomic(dest string, data []byte) error {
+	return writeFileAtomicFrom(dest, func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	})
+}
+
+// writeFileAtomicFrom is writeFileAtomic for contents written by write, which can fail partway
+func writeFileAtomicFrom(dest string, write func(w io.Writer) error) error {
+	tmp, err := os.CreateTemp(filepath.Dir(dest), filepath.Base(dest)+".tmp-*")
 	if err != nil {
-		return fmt.Errorf("failed to copy file %s: %w", filepath, err)
+		return err
 	}
+	tmpName := tmp.Name()
 
+	if err := write(tmp); err != nil {
+		tmp.Close()
+		os.Remove(tmpName)
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		os.Remove(tmpName)
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+	if err := os.Chmod(tmpName, 0644); err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+
+	if err := os.Rename(tmpName, dest); err != nil {
+		os.Remove(tmpName)
+		return err
+	}
 	return nil
 }
 
@@ -542,22 +1267,100 @@ func (m *Manager) extractZip(src, dest string) error {
 	return nil
 }
 
+// utf8BOM is prepended to CSVs exported by some spreadsheet tools
+const utf8BOM = "\ufeff"
+
+// newCSVReader returns a csv.Reader that skips a leading UTF-8 BOM
+// encoding/csv already normalizes CRLF record terminators to LF
+// Field counts aren't enforced by the reader, since by default one short row fails the
+// whole file; parsers check each record against the header instead. LazyQuotes accepts
+// stray quotes inside unquoted names, while quoted fields with commas still parse normally
+func newCSVReader(r io.Reader) *csv.Reader {
+	br := bufio.NewReader(r)
+	if b, err := br.Peek(len(utf8BOM)); err == nil && string(b) == utf8BOM {
+		br.Discard(len(utf8BOM))
+	}
+	reader := csv.NewReader(br)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	return reader
+}
+
+// headerColumns maps GTFS column names to their indices
+// Names are trimmed so stray whitespace or carriage returns don't break required-column lookups
+func headerColumns(header []string) map[string]int {
+	columns := make(map[string]int, len(header))
+	for i, col := range header {
+		columns[strings.TrimSpace(strings.TrimPrefix(col, utf8BOM))] = i
+	}
+	return columns
+}
+
+// csvField returns the named column of record, or "" when the header lacks it or the row is too short
+// Meant for optional GTFS columns, which a feed may leave out entirely
+func csvField(record []string, columns map[string]int, col string) string {
+	if i, ok := columns[col]; ok && i < len(record) {
+		return record[i]
+	}
+	return ""
+}
+
 // parseGTFSData reads GTFS CSV files and populates the store
 func (m *Manager) parseGTFSData(gtfsDir string) error {
+	start := time.Now()
+
 	// Parse stops.txt for station information
 	stations, err := m.parseStops(filepath.Join(gtfsDir, "stops.txt"))
 	if err != nil {
 		return fmt.Errorf("failed to parse stops: %w", err)
 	}
 
-	// Parse routes.txt and associate with stations
-	if err := m.parseRoutes(filepath.Join(gtfsDir, "routes.txt"), stations); err != nil {
+	// Parse routes.txt once for everything below that resolves route_ids, and associate routes with stations
+	routes, err := m.parseRoutesFile(filepath.Join(gtfsDir, "routes.txt"))
+	if err != nil {
+		return fmt.Errorf("failed to parse routes: %w", err)
+	}
+	routeInfos, stopTimes, err := m.parseRoutes(gtfsDir, routes, stations)
+	if err != nil {
 		return fmt.Errorf("failed to parse routes: %w", err)
 	}
 
+	// Overrides go after route parsing so they can replace the derived route lists too
+	applyStationOverrides(stations, m.stationOverrides)
+
+	// Nothing reaches the store until the parsed data looks like a whole feed; returning an error
+	// keeps the previous data in place and marks the refresh as failed so it's retried
+	if err := m.checkStaticSanity(stations); err != nil {
+		return fmt.Errorf("static GTFS failed sanity check: %w", err)
+	}
+
+	m.loadScheduleIfEnabled(gtfsDir, routes)
+
+	// Shapes only feed map drawing, so a bad shapes file shouldn't block station data
+	routeShapes, err := m.loadRouteShapes(gtfsDir, routes)
+	if err != nil {
+		slog.Warn("Failed to load route shapes", "error", err)
+	}
+
+	// Likewise fares are informational; a bad fare file leaves the previous fares in place
+	fares, err := m.loadFares(gtfsDir, routes)
+	if err != nil {
+		slog.Warn("Failed to load fares", "error", err)
+	}
+
+	m.platformParents = platformParents(stations)
+	m.stopTimes = newStaticStopTimes(stopTimes)
+
 	// Update store with parsed data
 	m.store.UpdateStations(stations)
-	m.store.UpdateAlerts([]models.Alert{}) // No static alerts in GTFS
+	m.store.UpdateRouteInfo(routeInfos)
+	if routeShapes != nil {
+		m.store.UpdateRouteShapes(routeShapes)
+	}
+	if fares != nil {
+		m.store.UpdateFares(fares)
+	}
+	m.recordStaticParse(time.Since(start))
 
 	slog.Info("Loaded stations from GTFS data", "count", len(stations))
 	return nil
@@ -572,22 +1375,17 @@ func (m *Manager) parseStops(stopsFile string) (map[string]*models.Station, erro
 	}
 	defer file.Close()
 
-	reader := csv.NewReader(file)
-	records, err := reader.ReadAll()
+	reader := newCSVReader(file)
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("empty stops file")
+	}
 	if err != nil {
 		return nil, err
 	}
 
-	if len(records) == 0 {
-		return nil, fmt.Errorf("empty stops file")
-	}
-
 	// Parse header to find column indices
-	header := records[0]
-	columns := make(map[string]int)
-	for i, col := range header {
-		columns[col] = i
-	}
+	columns := headerColumns(header)
 
 	requiredCols := []string{"stop_id", "stop_name", "stop_lat", "stop_lon"}
 	for _, col := range requiredCols {
@@ -600,9 +1398,20 @@ func (m *Manager) parseStops(stopsFile string) (map[string]*models.Station, erro
 	platformStops := make([][]string, 0) // Store platform stops for second pass
 
 	// First pass: Process parent stations (location_type=1)
-	for _, record := range records[1:] {
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
 		if len(record) != len(header) {
-			continue // Skip incomplete records
+			// Quoted fields can span lines, so the reader's position is the only reliable line number
+			line, _ := reader.FieldPos(0)
+			slog.Warn("Skipping stops.txt row with wrong field count",
+				"line", line, "expected", len(header), "got", len(record))
+			continue
 		}
 
 		stopID := record[columns["stop_id"]]
@@ -616,10 +1425,7 @@ func (m *Manager) parseStops(stopsFile string) (map[string]*models.Station, erro
 		}
 
 		// Check if this is a parent station
-		locationType := ""
-		if locTypeCol, ok := columns["location_type"]; ok && locTypeCol < len(record) {
-			locationType = record[locTypeCol]
-		}
+		locationType := csvField(record, columns, "location_type")
 
 		lat, err := strconv.ParseFloat(latStr, 64)
 		if err != nil {
@@ -633,7 +1439,24 @@ func (m *Manager) parseStops(stopsFile string) (map[string]*models.Station, erro
 			continue
 		}
 
+		// Platforms are checked here too, so a bad one doesn't drag its station's stop map off the map
+		if !m.inServiceArea(lat, lon) {
+			slog.Warn("Skipping stop outside the service area", "stop_id", stopID, "lat", lat, "lon", lon)
+			continue
+		}
+
 		if locationType == "1" {
+			if existing, dup := stations[stopID]; dup {
+				replace, err := m.resolveDuplicateStop(stopID,
+					existing.Name, existing.Location, stopName, models.Location{Lat: lat, Lon: lon})
+				if err != nil {
+					return nil, err
+				}
+				if !replace {
+					continue
+				}
+			}
+
 			// This is a parent station
 			stations[stopID] = &models.Station{
 				ID:         stopID,
@@ -642,7 +1465,7 @@ func (m *Manager) parseStops(stopsFile string) (map[string]*models.Station, erro
 				Routes:     []string{},                 // Will be populated by parseRoutes
 				Trains:     models.TrainsByDirection{}, // No static train data
 				Stops:      make(map[string]models.Location),
-				LastUpdate: time.Now(),
+				LastUpdate: m.now(),
 			}
 		} else {
 			// This is a platform stop, save for second pass
@@ -656,7 +1479,31 @@ func (m *Manager) parseStops(stopsFile string) (map[string]*models.Station, erro
 		parentStationCol = col
 	}
 
+	// Platform stops are keyed by parent, so collisions are tracked separately
+	seenPlatforms := make(map[string]int) // stop_id -> index into platformStops
+	resolved := make([][]string, 0, len(platformStops))
 	for _, record := range platformStops {
+		stopID := record[columns["stop_id"]]
+		i, dup := seenPlatforms[stopID]
+		if !dup {
+			seenPlatforms[stopID] = len(resolved)
+			resolved = append(resolved, record)
+			continue
+		}
+
+		existing := resolved[i]
+		replace, err := m.resolveDuplicateStop(stopID,
+			existing[columns["stop_name"]], recordLocation(existing, columns),
+			record[columns["stop_name"]], recordLocation(record, columns))
+		if err != nil {
+			return nil, err
+		}
+		if replace {
+			resolved[i] = record
+		}
+	}
+
+	for _, record := range resolved {
 		stopID := record[columns["stop_id"]]
 		latStr := record[columns["stop_lat"]]
 		lonStr := record[columns["stop_lon"]]
@@ -692,33 +1539,64 @@ func (m *Manager) parseStops(stopsFile string) (map[string]*models.Station, erro
 	return stations, nil
 }
 
-// parseRoutes reads routes.txt and associates routes with stations
-// Joins routes.txt -> trips.txt -> stop_times.txt to map routes to stations
-func (m *Manager) parseRoutes(routesFile string, stations map[string]*models.Station) error {
-	gtfsDir := filepath.Dir(routesFile)
-
-	// Step 1: Parse routes.txt to get route_id -> route_short_name mapping
-	routes, err := m.parseRoutesFile(routesFile)
-	if err != nil {
-		return fmt.Errorf("failed to parse routes file: %w", err)
+// resolveDuplicateStop logs a stop_id collision and reports whether the incoming record should replace the existing one
+func (m *Manager) resolveDuplicateStop(stopID, existingName string, existingLoc models.Location, name string, loc models.Location) (bool, error) {
+	slog.Warn("Duplicate stop_id in stops.txt",
+		"stop_id", stopID,
+		"existing_name", existingName, "existing_lat", existingLoc.Lat, "existing_lon", existingLoc.Lon,
+		"name", name, "lat", loc.Lat, "lon", loc.Lon,
+		"policy", m.duplicateStopPolicy)
+
+	switch m.duplicateStopPolicy {
+	case DuplicateStopFirstWins:
+		return false, nil
+	case DuplicateStopError:
+		return false, fmt.Errorf("duplicate stop_id %s (%q and %q)", stopID, existingName, name)
+	default:
+		return true, nil
 	}
+}
 
-	// Step 2: Parse trips.txt to get route_id -> trip_ids mapping
-	routeTrips, err := m.parseTripsFile(filepath.Join(gtfsDir, "trips.txt"))
+// recordLocation parses a stops.txt record's coordinates for logging, ignoring malformed values
+func recordLocation(record []string, columns map[string]int) models.Location {
+	lat, _ := strconv.ParseFloat(record[columns["stop_lat"]], 64)
+	lon, _ := strconv.ParseFloat(record[columns["stop_lon"]], 64)
+	return models.Location{Lat: lat, Lon: lon}
+}
+
+// parseRoutes associates the routes from routes.txt, as parsed by parseRoutesFile, with stations
+// Joins routes.txt -> trips.txt -> stop_times.txt to map routes to stations
+// Returns route metadata keyed by short name for the store's route info index, and the parsed stop times
+func (m *Manager) parseRoutes(gtfsDir string, routes map[string]models.RouteInfo, stations map[string]*models.Station) (map[string]models.RouteInfo, tripStopTimes, error) {
+	// Step 1: Parse trips.txt to get route_id -> trip_ids mapping
+	routeTrips, tripHeadsigns, err := m.parseTripsFile(filepath.Join(gtfsDir, "trips.txt"))
 	if err != nil {
-		return fmt.Errorf("failed to parse trips file: %w", err)
+		return nil, nil, fmt.Errorf("failed to parse trips file: %w", err)
 	}
 
-	// Step 3: Parse stop_times.txt to get trip_id -> stop_ids mapping
+	// Step 2: Parse stop_times.txt to get trip_id -> stop_ids mapping
 	tripStops, err := m.parseStopTimesFile(filepath.Join(gtfsDir, "stop_times.txt"))
 	if err != nil {
-		return fmt.Errorf("failed to parse stop_times file: %w", err)
+		return nil, nil, fmt.Errorf("failed to parse stop_times file: %w", err)
 	}
 
-	// Step 4: Join the data to build route -> stations mapping
+	// Step 3: Join the data to build route -> stations mapping
 	stationRoutes := make(map[string]map[string]bool) // station_id -> set of routes
+	// station_id -> route -> the platform directions it stops at
+	stationRouteDirs := make(map[string]map[string]*platformDirections)
+	routeInfos := make(map[string]models.RouteInfo)
+	routeInfoIDs := make(map[string]string) // short name -> route_id backing routeInfos
+	headsigns := make(headsignCounts)
+
+	for routeID, info := range routes {
+		routeName := info.ShortName
+		// Several route_ids share a short name (e.g. the S shuttles); pick the lowest
+		// route_id so the exposed info doesn't depend on map iteration order
+		if chosen, ok := routeInfoIDs[routeName]; !ok || routeID < chosen {
+			routeInfos[routeName] = info
+			routeInfoIDs[routeName] = routeID
+		}
 
-	for routeID, routeName := range routes {
 		tripIDs, ok := routeTrips[routeID]
 		if !ok {
 			continue
@@ -730,22 +1608,33 @@ func (m *Manager) parseRoutes(routesFile string, stations map[string]*models.Sta
 				continue
 			}
 
+			// The trip's direction comes from its platforms, as for stations; trips without suffixed stops have none
+			var tripDirections platformDirections
 			for stopID := range stopIDs {
-				// Extract parent station ID (remove direction suffix)
-				parentID := stopID
-				if len(stopID) > 0 && (stopID[len(stopID)-1] == 'N' || stopID[len(stopID)-1] == 'S') {
-					parentID = stopID[:len(stopID)-1]
-				}
+				parentID, direction := splitStopID(stopID)
+				tripDirections.add(direction)
 
 				if stationRoutes[parentID] == nil {
 					stationRoutes[parentID] = make(map[string]bool)
+					stationRouteDirs[parentID] = make(map[string]*platformDirections)
 				}
 				stationRoutes[parentID][routeName] = true
+				if stationRouteDirs[parentID][routeName] == nil {
+					stationRouteDirs[parentID][routeName] = &platformDirections{}
+				}
+				stationRouteDirs[parentID][routeName].add(direction)
+			}
+			if direction, ok := tripDirections.only(); ok && tripHeadsigns[tripID] != "" {
+				headsigns.add(routeName, direction, tripHeadsigns[tripID])
 			}
 		}
 	}
+	for routeName, info := range routeInfos {
+		info.Headsigns = headsigns.common(routeName)
+		routeInfos[routeName] = info
+	}
 
-	// Step 5: Update stations with route information
+	// Step 4: Update stations with route information
 	for stationID, station := range stations {
 		if routeSet, ok := stationRoutes[stationID]; ok {
 			routes := make([]string, 0, len(routeSet))
@@ -754,21 +1643,63 @@ func (m *Manager) parseRoutes(routesFile string, stations map[string]*models.Sta
 			}
 			station.Routes = routes
 		}
+		station.OneWayRoutes = oneWayRoutes(stationRouteDirs[stationID])
 	}
 
 	slog.Info("Mapped routes to stations", "station_count", len(stationRoutes))
-	return nil
+	return routeInfos, tripStops, nil
+}
+
+// platformDirections tracks which platform directions a route or trip has been seen stopping at
+type platformDirections struct {
+	direction models.Direction // The first direction seen
+	both      bool             // Seen both ways, or at a stop without a platform suffix, which says nothing about direction
+}
+
+// add records a stop in direction
+func (p *platformDirections) add(direction models.Direction) {
+	switch {
+	case direction == models.DirectionUnknown:
+		p.both = true
+	case p.direction == models.DirectionUnknown:
+		p.direction = direction
+	case p.direction != direction:
+		p.both = true
+	}
 }
 
-// parseRoutesFile reads routes.txt and returns route_id -> route_short_name mapping
-func (m *Manager) parseRoutesFile(routesFile string) (map[string]string, error) {
+// only returns the one direction seen, false if none was or it was seen both ways
+func (p platformDirections) only() (models.Direction, bool) {
+	return p.direction, !p.both && p.direction != models.DirectionUnknown
+}
+
+// oneWayRoutes picks the routes only seen at one platform, nil when every route stops both ways
+// Terminals and skip-stop patterns are the usual cause, e.g. a route running express one way through a station
+func oneWayRoutes(directions map[string]*platformDirections) map[string]models.Direction {
+	var result map[string]models.Direction
+	for route, seen := range directions {
+		direction, ok := seen.only()
+		if !ok {
+			continue
+		}
+		if result == nil {
+			result = make(map[string]models.Direction)
+		}
+		result[route] = direction
+	}
+	return result
+}
+
+// parseRoutesFile reads routes.txt and returns route_id -> route info mapping
+// route_long_name, route_desc and route_color are optional in GTFS so missing columns are left empty
+func (m *Manager) parseRoutesFile(routesFile string) (map[string]models.RouteInfo, error) {
 	file, err := os.Open(routesFile)
 	if err != nil {
 		return nil, err
 	}
 	defer file.Close()
 
-	reader := csv.NewReader(file)
+	reader := newCSVReader(file)
 	records, err := reader.ReadAll()
 	if err != nil {
 		return nil, err
@@ -780,71 +1711,77 @@ func (m *Manager) parseRoutesFile(routesFile string) (map[string]string, error)
 
 	// Parse header
 	header := records[0]
-	columns := make(map[string]int)
-	for i, col := range header {
-		columns[col] = i
-	}
+	columns := headerColumns(header)
 
 	routeIDCol, ok := columns["route_id"]
 	if !ok {
 		return nil, fmt.Errorf("missing route_id column")
 	}
 
-	routeNameCol, ok := columns["route_short_name"]
-	if !ok {
-		return nil, fmt.Errorf("missing route_short_name column")
-	}
-
-	routes := make(map[string]string)
+	routes := make(map[string]models.RouteInfo)
 	for _, record := range records[1:] {
-		if len(record) > routeIDCol && len(record) > routeNameCol {
-			routeID := record[routeIDCol]
-			routeName := record[routeNameCol]
-			if routeID != "" && routeName != "" {
-				routes[routeID] = routeName
-			}
+		if len(record) <= routeIDCol || record[routeIDCol] == "" {
+			continue
+		}
+		routeID := record[routeIDCol]
+		longName := csvField(record, columns, "route_long_name")
+
+		// GTFS only requires one of the short and long names; shuttles in some feeds have just the long one
+		routeName := csvField(record, columns, "route_short_name")
+		if routeName == "" {
+			routeName = longName
+		}
+		if routeName == "" {
+			routeName = routeID
+		}
+
+		routes[routeID] = models.RouteInfo{
+			ShortName:   routeName,
+			LongName:    longName,
+			Description: csvField(record, columns, "route_desc"),
+			Color:       csvField(record, columns, "route_color"),
 		}
 	}
 
 	return routes, nil
 }
 
-// parseTripsFile reads trips.txt and returns route_id -> set of trip_ids mapping
-func (m *Manager) parseTripsFile(tripsFile string) (map[string]map[string]bool, error) {
+// parseTripsFile reads trips.txt and returns route_id -> set of trip_ids mapping, plus trip_id -> trip_headsign
+// trip_headsign is optional in GTFS, so a file without the column yields empty headsigns rather than an error
+func (m *Manager) parseTripsFile(tripsFile string) (map[string]map[string]bool, map[string]string, error) {
 	file, err := os.Open(tripsFile)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	defer file.Close()
 
-	reader := csv.NewReader(file)
+	reader := newCSVReader(file)
 	records, err := reader.ReadAll()
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	if len(records) == 0 {
-		return nil, fmt.Errorf("empty trips file")
+		return nil, nil, fmt.Errorf("empty trips file")
 	}
 
 	// Parse header
 	header := records[0]
-	columns := make(map[string]int)
-	for i, col := range header {
-		columns[col] = i
-	}
+	columns := headerColumns(header)
 
 	routeIDCol, ok := columns["route_id"]
 	if !ok {
-		return nil, fmt.Errorf("missing route_id column")
+		return nil, nil, fmt.Errorf("missing route_id column")
 	}
 
 	tripIDCol, ok := columns["trip_id"]
 	if !ok {
-		return nil, fmt.Errorf("missing trip_id column")
+		return nil, nil, fmt.Errorf("missing trip_id column")
 	}
+	headsignCol, hasHeadsigns := columns["trip_headsign"]
 
 	routeTrips := make(map[string]map[string]bool)
+	headsigns := make(map[string]string)
 	for _, record := range records[1:] {
 		if len(record) > routeIDCol && len(record) > tripIDCol {
 			routeID := record[routeIDCol]
@@ -855,21 +1792,25 @@ func (m *Manager) parseTripsFile(tripsFile string) (map[string]map[string]bool,
 				}
 				routeTrips[routeID][tripID] = true
 			}
+			if hasHeadsigns && tripID != "" && len(record) > headsignCol && record[headsignCol] != "" {
+				headsigns[tripID] = record[headsignCol]
+			}
 		}
 	}
 
-	return routeTrips, nil
+	return routeTrips, headsigns, nil
 }
 
-// parseStopTimesFile reads stop_times.txt and returns trip_id -> set of stop_ids mapping
-func (m *Manager) parseStopTimesFile(stopTimesFile string) (map[string]map[string]bool, error) {
+// parseStopTimesFile reads stop_times.txt and returns trip_id -> stop_id -> its sequence and scheduled arrival
+// Arrival times before the service day cutoff are moved to the previous day, as for the schedule fallback
+func (m *Manager) parseStopTimesFile(stopTimesFile string) (tripStopTimes, error) {
 	file, err := os.Open(stopTimesFile)
 	if err != nil {
 		return nil, err
 	}
 	defer file.Close()
 
-	reader := csv.NewReader(file)
+	reader := newCSVReader(file)
 
 	// Parse header first
 	header, err := reader.Read()
@@ -877,10 +1818,7 @@ func (m *Manager) parseStopTimesFile(stopTimesFile string) (map[string]map[strin
 		return nil, fmt.Errorf("failed to read header: %w", err)
 	}
 
-	columns := make(map[string]int)
-	for i, col := range header {
-		columns[col] = i
-	}
+	columns := headerColumns(header)
 
 	tripIDCol, ok := columns["trip_id"]
 	if !ok {
@@ -892,7 +1830,7 @@ func (m *Manager) parseStopTimesFile(stopTimesFile string) (map[string]map[strin
 		return nil, fmt.Errorf("missing stop_id column")
 	}
 
-	tripStops := make(map[string]map[string]bool)
+	tripStops := make(tripStopTimes)
 
 	// Process records one by one to handle large files efficiently
 	for {
@@ -909,9 +1847,19 @@ func (m *Manager) parseStopTimesFile(stopTimesFile string) (map[string]map[strin
 			stopID := record[stopIDCol]
 			if tripID != "" && stopID != "" {
 				if tripStops[tripID] == nil {
-					tripStops[tripID] = make(map[string]bool)
+					tripStops[tripID] = make(map[string]staticStopTime)
+				}
+				var stop staticStopTime
+				if n, err := strconv.ParseUint(csvField(record, columns, "stop_sequence"), 10, 32); err == nil {
+					stop.sequence = uint32(n)
 				}
-				tripStops[tripID][stopID] = true
+				if offset, err := parseGTFSTime(csvField(record, columns, "arrival_time")); err == nil {
+					if offset < m.serviceDayCutoff {
+						offset += 24 * time.Hour
+					}
+					stop.offset, stop.timed = offset, true
+				}
+				tripStops[tripID][stopID] = stop
 			}
 		}
 	}
@@ -919,37 +1867,20 @@ func (m *Manager) parseStopTimesFile(stopTimesFile string) (map[string]map[strin
 	return tripStops, nil
 }
 
-// sortAndLimitTrains sorts trains by arrival time and limits to next 10 arrivals
+// sortAndLimitTrains sorts trains by arrival time, drops duplicates and keeps the next retention() arrivals
 func (m *Manager) sortAndLimitTrains(trains []models.Train) []models.Train {
 	if len(trains) == 0 {
 		return trains
 	}
 
-	// Remove duplicates and sort by time
-	trainMap := make(map[string]models.Train)
-	for _, train := range trains {
-		key := fmt.Sprintf("%s_%d", train.Route, train.Time.Unix())
-		trainMap[key] = train
-	}
-
-	// Convert back to slice
-	uniqueTrains := make([]models.Train, 0, len(trainMap))
-	for _, train := range trainMap {
-		uniqueTrains = append(uniqueTrains, train)
-	}
-
-	// Sort by arrival time
-	for i := 0; i < len(uniqueTrains)-1; i++ {
-		for j := i + 1; j < len(uniqueTrains); j++ {
-			if uniqueTrains[i].Time.After(uniqueTrains[j].Time) {
-				uniqueTrains[i], uniqueTrains[j] = uniqueTrains[j], uniqueTrains[i]
-			}
-		}
-	}
+	sorted := append([]models.Train(nil), trains...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Time.Before(sorted[j].Time)
+	})
+	uniqueTrains := m.dedupStrategy.dedupTrains(sorted)
 
-	// Limit to next 10 arrivals
-	if len(uniqueTrains) > 10 {
-		uniqueTrains = uniqueTrains[:10]
+	if limit := m.retention(); len(uniqueTrains) > limit {
+		uniqueTrains = uniqueTrains[:limit]
 	}
 
 	return uniqueTrains
@@ -1,11 +1,24 @@
 package feed
 
 import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
 	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/jusunglee/mta-go/internal/clock"
+	"github.com/jusunglee/mta-go/internal/gtfsrt"
+	"github.com/jusunglee/mta-go/internal/models"
 	"github.com/jusunglee/mta-go/internal/store"
+	"google.golang.org/protobuf/proto"
 )
 
 func TestParseGTFSData(t *testing.T) {
@@ -175,6 +188,251 @@ func TestParseStops(t *testing.T) {
 	}
 }
 
+func TestParseStopsBOMAndCRLF(t *testing.T) {
+	stopsFile := filepath.Join(t.TempDir(), "stops.txt")
+	content := "\ufeffstop_id,stop_name,stop_lat,stop_lon,location_type,parent_station\r\n" +
+		"127,Times Sq-42 St,40.75529,-73.987495,1,\r\n" +
+		"127N,Times Sq-42 St,40.75529,-73.987495,,127\r\n" +
+		"127S,Times Sq-42 St,40.75529,-73.987495,,127\r\n"
+	if err := os.WriteFile(stopsFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write fixture: %v", err)
+	}
+
+	m := &Manager{}
+	stations, err := m.parseStops(stopsFile)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	station, ok := stations["127"]
+	if !ok {
+		t.Fatalf("Expected station 127, got %v", stations)
+	}
+	if len(station.Stops) != 2 {
+		t.Errorf("Expected 2 platform stops, got %d", len(station.Stops))
+	}
+	if _, ok := station.Stops["127N"]; !ok {
+		t.Errorf("Expected stop ID without trailing carriage return, got %v", station.Stops)
+	}
+}
+
+func TestParseStopsQuotedFields(t *testing.T) {
+	stopsFile := filepath.Join(t.TempDir(), "stops.txt")
+	content := "stop_id,stop_name,stop_lat,stop_lon,location_type,parent_station\n" +
+		"128,\"34 St-Penn Station, 7 Av\",40.750373,-73.991057,1,\n" +
+		"128N,\"34 St-Penn Station, 7 Av\",40.750373,-73.991057,,128\n" +
+		"A27,\"42 St-\"\"Port Authority\"\" Bus Terminal\",40.757308,-73.989735,1,\n" +
+		"R16,Times Sq \"Shuttle\" Annex,40.754672,-73.986754,1,\n" +
+		"R17,Short Row,40.75,1,\n" +
+		"127,Times Sq-42 St,40.75529,-73.987495,1,\n"
+	if err := os.WriteFile(stopsFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write fixture: %v", err)
+	}
+
+	m := &Manager{}
+	stations, err := m.parseStops(stopsFile)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := map[string]string{
+		"128": "34 St-Penn Station, 7 Av",
+		"A27": `42 St-"Port Authority" Bus Terminal`,
+		"R16": `Times Sq "Shuttle" Annex`,
+		"127": "Times Sq-42 St",
+	}
+	for id, name := range expected {
+		station, ok := stations[id]
+		if !ok {
+			t.Errorf("Expected station %s to be parsed", id)
+			continue
+		}
+		if station.Name != name {
+			t.Errorf("Station %s: expected name %q, got %q", id, name, station.Name)
+		}
+	}
+
+	// A malformed row is skipped on its own instead of failing the whole file
+	if _, ok := stations["R17"]; ok {
+		t.Error("Expected row with missing fields to be skipped")
+	}
+	if len(stations) != len(expected) {
+		t.Errorf("Expected %d stations, got %d", len(expected), len(stations))
+	}
+	if _, ok := stations["128"].Stops["128N"]; !ok {
+		t.Errorf("Expected platform 128N under quoted-name station, got %v", stations["128"].Stops)
+	}
+}
+
+func TestParseOtherFilesBOMAndCRLF(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write fixture: %v", err)
+		}
+		return path
+	}
+
+	m := &Manager{}
+
+	routes, err := m.parseRoutesFile(write("routes.txt", "\ufeffroute_id,route_short_name\r\n1,1\r\n"))
+	if err != nil {
+		t.Fatalf("parseRoutesFile: %v", err)
+	}
+	if routes["1"].ShortName != "1" {
+		t.Errorf("Expected route 1, got %v", routes)
+	}
+
+	trips, _, err := m.parseTripsFile(write("trips.txt", "\ufeffroute_id,trip_id\r\n1,T1\r\n"))
+	if err != nil {
+		t.Fatalf("parseTripsFile: %v", err)
+	}
+	if !trips["1"]["T1"] {
+		t.Errorf("Expected trip T1 on route 1, got %v", trips)
+	}
+
+	stopTimes, err := m.parseStopTimesFile(write("stop_times.txt", "\ufefftrip_id,stop_id\r\nT1,127N\r\n"))
+	if err != nil {
+		t.Fatalf("parseStopTimesFile: %v", err)
+	}
+	if _, ok := stopTimes["T1"]["127N"]; !ok {
+		t.Errorf("Expected stop 127N on trip T1, got %v", stopTimes)
+	}
+}
+
+func TestParseStopsDuplicateIDs(t *testing.T) {
+	stopsFile := filepath.Join(t.TempDir(), "stops.txt")
+	content := "stop_id,stop_name,stop_lat,stop_lon,location_type,parent_station\n" +
+		"127,Times Sq-42 St,40.75529,-73.987495,1,\n" +
+		"127N,Times Sq-42 St,40.75529,-73.987495,,127\n" +
+		"127,Times Square Annex,40.755983,-73.986229,1,\n" +
+		"127N,Times Square Annex,40.755983,-73.986229,,127\n"
+	if err := os.WriteFile(stopsFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write fixture: %v", err)
+	}
+
+	tests := []struct {
+		policy       DuplicateStopPolicy
+		expectError  bool
+		expectedName string
+		expectedLat  float64
+	}{
+		{DuplicateStopLastWins, false, "Times Square Annex", 40.755983},
+		{DuplicateStopFirstWins, false, "Times Sq-42 St", 40.75529},
+		{DuplicateStopError, true, "", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.policy.String(), func(t *testing.T) {
+			m := &Manager{}
+			m.SetDuplicateStopPolicy(tt.policy)
+
+			stations, err := m.parseStops(stopsFile)
+			if tt.expectError {
+				if err == nil {
+					t.Error("Expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			station := stations["127"]
+			if station.Name != tt.expectedName {
+				t.Errorf("Expected station name %q, got %q", tt.expectedName, station.Name)
+			}
+			if len(station.Stops) != 1 {
+				t.Fatalf("Expected 1 platform stop, got %d", len(station.Stops))
+			}
+			if station.Stops["127N"].Lat != tt.expectedLat {
+				t.Errorf("Expected platform latitude %f, got %f", tt.expectedLat, station.Stops["127N"].Lat)
+			}
+		})
+	}
+}
+
+func TestParseDuplicateStopPolicy(t *testing.T) {
+	for input, expected := range map[string]DuplicateStopPolicy{
+		"":           DuplicateStopLastWins,
+		"last-wins":  DuplicateStopLastWins,
+		"First-Wins": DuplicateStopFirstWins,
+		"error":      DuplicateStopError,
+	} {
+		policy, err := ParseDuplicateStopPolicy(input)
+		if err != nil {
+			t.Errorf("ParseDuplicateStopPolicy(%q): unexpected error %v", input, err)
+		}
+		if policy != expected {
+			t.Errorf("ParseDuplicateStopPolicy(%q) = %v, want %v", input, policy, expected)
+		}
+	}
+
+	if _, err := ParseDuplicateStopPolicy("newest"); err == nil {
+		t.Error("Expected error for unknown policy")
+	}
+}
+
+func TestParseStopsServiceArea(t *testing.T) {
+	stopsFile := filepath.Join(t.TempDir(), "stops.txt")
+	content := "stop_id,stop_name,stop_lat,stop_lon,location_type,parent_station\n" +
+		"127,Times Sq-42 St,40.75529,-73.987495,1,\n" +
+		"127N,Times Sq-42 St,40.75529,-73.987495,,127\n" +
+		"127S,Times Sq-42 St,0,0,,127\n" +
+		"631,Grand Central-42 St,0,0,1,\n" +
+		"635,14 St-Union Sq,-73.989951,40.734673,1,\n"
+	if err := os.WriteFile(stopsFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write fixture: %v", err)
+	}
+
+	m := NewManager("test-key", store.NewStore(), time.Minute)
+	stations, err := m.parseStops(stopsFile)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(stations) != 1 || stations["127"] == nil {
+		t.Fatalf("Expected only 127 inside the service area, got %v", stations)
+	}
+	if _, ok := stations["127"].Stops["127S"]; ok || len(stations["127"].Stops) != 1 {
+		t.Errorf("Expected the platform at (0,0) skipped, got %v", stations["127"].Stops)
+	}
+
+	m.SetServiceArea(nil)
+	stations, err = m.parseStops(stopsFile)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(stations) != 3 {
+		t.Errorf("Expected every station with the check off, got %d", len(stations))
+	}
+}
+
+func TestParseServiceArea(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected *models.Bounds
+		wantErr  bool
+	}{
+		{"", &DefaultServiceArea, false},
+		{"OFF", nil, false},
+		{"40.5, -74.3, 40.9, -73.7", &models.Bounds{MinLat: 40.5, MinLon: -74.3, MaxLat: 40.9, MaxLon: -73.7}, false},
+		{"40.5,-74.3,40.9", nil, true},
+		{"40.5,-74.3,north,-73.7", nil, true},
+		{"40.9,-74.3,40.5,-73.7", nil, true},
+	}
+	for _, tt := range tests {
+		area, err := ParseServiceArea(tt.input)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseServiceArea(%q): unexpected error %v", tt.input, err)
+			continue
+		}
+		if !reflect.DeepEqual(area, tt.expected) {
+			t.Errorf("ParseServiceArea(%q) = %v, want %v", tt.input, area, tt.expected)
+		}
+	}
+}
+
 func TestParseRoutesFile(t *testing.T) {
 	tests := []struct {
 		name           string
@@ -223,8 +481,8 @@ func TestParseRoutesFile(t *testing.T) {
 
 			// Check that we found expected routes
 			routeNames := make(map[string]bool)
-			for _, routeName := range routes {
-				routeNames[routeName] = true
+			for _, route := range routes {
+				routeNames[route.ShortName] = true
 			}
 
 			foundCount := 0
@@ -246,6 +504,86 @@ func TestParseRoutesFile(t *testing.T) {
 	}
 }
 
+func TestParseRoutesFileLongNames(t *testing.T) {
+	for _, routesFile := range []string{
+		"testdata/gtfs_subway/routes.txt",
+		"testdata/gtfs_supplemented/routes.txt",
+	} {
+		t.Run(routesFile, func(t *testing.T) {
+			m := &Manager{}
+			routes, err := m.parseRoutesFile(routesFile)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			byShortName := make(map[string]models.RouteInfo)
+			for _, route := range routes {
+				byShortName[route.ShortName] = route
+			}
+
+			route1, ok := byShortName["1"]
+			if !ok {
+				t.Fatal("Route 1 not found")
+			}
+			if route1.LongName != "Broadway - 7 Avenue Local" {
+				t.Errorf("Expected route 1 long name %q, got %q", "Broadway - 7 Avenue Local", route1.LongName)
+			}
+			if route1.Description == "" {
+				t.Error("Expected route 1 to have a description")
+			}
+			if route1.Color == "" {
+				t.Error("Expected route 1 to have a color")
+			}
+		})
+	}
+}
+
+func TestParseRoutesFileNameFallback(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		expected map[string]string // route_id -> ShortName
+	}{
+		{
+			name: "blank short names",
+			content: "route_id,route_short_name,route_long_name\n" +
+				"1,1,Broadway - 7 Avenue Local\n" +
+				"H,,Rockaway Park Shuttle\n" +
+				"X,,\n",
+			expected: map[string]string{"1": "1", "H": "Rockaway Park Shuttle", "X": "X"},
+		},
+		{
+			name: "no short name column",
+			content: "route_id,route_long_name\n" +
+				"FS,Franklin Avenue Shuttle\n",
+			expected: map[string]string{"FS": "Franklin Avenue Shuttle"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "routes.txt")
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("Failed to write fixture: %v", err)
+			}
+
+			m := &Manager{}
+			routes, err := m.parseRoutesFile(path)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(routes) != len(tt.expected) {
+				t.Errorf("Expected %d routes, got %v", len(tt.expected), routes)
+			}
+			for routeID, shortName := range tt.expected {
+				if got := routes[routeID].ShortName; got != shortName {
+					t.Errorf("Route %s: expected name %q, got %q", routeID, shortName, got)
+				}
+			}
+		})
+	}
+}
+
 func TestParseTripsFile(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -275,7 +613,7 @@ func TestParseTripsFile(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			m := &Manager{}
-			routeTrips, err := m.parseTripsFile(tt.tripsFile)
+			routeTrips, _, err := m.parseTripsFile(tt.tripsFile)
 
 			if tt.expectError {
 				if err == nil {
@@ -406,7 +744,11 @@ func TestParseRoutes(t *testing.T) {
 			}
 
 			// Now associate routes
-			err = m.parseRoutes(filepath.Join(tt.gtfsDir, "routes.txt"), stations)
+			routes, err := m.parseRoutesFile(filepath.Join(tt.gtfsDir, "routes.txt"))
+			if err != nil {
+				t.Fatalf("Failed to parse routes file: %v", err)
+			}
+			_, _, err = m.parseRoutes(tt.gtfsDir, routes, stations)
 
 			if tt.expectError {
 				if err == nil {
@@ -450,6 +792,155 @@ func TestParseRoutes(t *testing.T) {
 	}
 }
 
+func TestGTrainDirections(t *testing.T) {
+	// The G's own feed files Court Sq-bound trains under N platforms and Church Av-bound ones under S
+	stopUpdate := func(stopID string, minutes int) *gtfsrt.StopTimeUpdate {
+		return &gtfsrt.StopTimeUpdate{
+			StopId:  proto.String(stopID),
+			Arrival: &gtfsrt.StopTimeEvent{Time: proto.Int64(testNow.Add(time.Duration(minutes) * time.Minute).Unix())},
+		}
+	}
+	data, err := proto.Marshal(&gtfsrt.FeedMessage{
+		Header: &gtfsrt.FeedHeader{GtfsRealtimeVersion: proto.String("1.0")},
+		Entity: []*gtfsrt.FeedEntity{
+			{Id: proto.String("1"), TripUpdate: &gtfsrt.TripUpdate{
+				Trip:           &gtfsrt.TripDescriptor{TripId: proto.String("G_TO_COURT_SQ"), RouteId: proto.String("G")},
+				StopTimeUpdate: []*gtfsrt.StopTimeUpdate{stopUpdate("G26N", 2), stopUpdate("G22N", 9)},
+			}},
+			{Id: proto.String("2"), TripUpdate: &gtfsrt.TripUpdate{
+				Trip:           &gtfsrt.TripDescriptor{TripId: proto.String("G_TO_CHURCH_AV"), RouteId: proto.String("G")},
+				StopTimeUpdate: []*gtfsrt.StopTimeUpdate{stopUpdate("G26S", 4)},
+			}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to marshal feed: %v", err)
+	}
+
+	s := store.NewStore()
+	s.UpdateStations(map[string]*models.Station{
+		"G26": {ID: "G26", Name: "Greenpoint Av", Routes: []string{"G"}},
+		"G22": {ID: "G22", Name: "Court Sq", Routes: []string{"G"}},
+	})
+	m := NewManager("test-key", s, time.Minute)
+	m.SetClock(clock.NewFake(testNow))
+	m.SetFetcher(&mapFetcher{data: map[string][]byte{FeedGroups["g"]: data}})
+	if err := m.SetFeedGroups([]string{"g"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := m.updateRealTimeData(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	stations, err := s.GetStationsByIDs([]string{"G26", "G22"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	greenpoint, courtSq := stations[0], stations[1]
+	if len(greenpoint.Trains.North) != 1 || greenpoint.Trains.North[0].TripID != "G_TO_COURT_SQ" {
+		t.Errorf("Expected the Court Sq-bound train northbound at Greenpoint Av, got %+v", greenpoint.Trains.North)
+	}
+	if len(greenpoint.Trains.South) != 1 || greenpoint.Trains.South[0].TripID != "G_TO_CHURCH_AV" {
+		t.Errorf("Expected the Church Av-bound train southbound at Greenpoint Av, got %+v", greenpoint.Trains.South)
+	}
+	if len(courtSq.Trains.North) != 1 {
+		t.Errorf("Expected Court Sq populated from the G feed, got %+v", courtSq.Trains)
+	}
+
+	labels := greenpoint.ConvertToResponse().DirectionLabels
+	if labels[models.DirectionNorth] != "Court Sq" || labels[models.DirectionSouth] != "Church Av" {
+		t.Errorf("Expected Court Sq and Church Av labels, got %v", labels)
+	}
+}
+
+func TestParseRoutesOneWay(t *testing.T) {
+	// The 1 stops both ways at Times Sq; the 5 only southbound, as when it runs express northbound.
+	// The 7 stops at Times Sq under the unsuffixed station ID, which says nothing about direction
+	files := goodGTFSFiles()
+	files["routes.txt"] += "5,5,Lexington Avenue Express\n7,7,Flushing Local\n"
+	files["trips.txt"] += "5,T5N\n5,T5S\n7,T7\n"
+	files["stop_times.txt"] += "T5S,127S\nT5S,631S\nT5N,631N\nT7,127\nT7,631N\n"
+	dir := writeGTFSDir(t, files)
+
+	m := &Manager{}
+	stations, err := m.parseStops(filepath.Join(dir, "stops.txt"))
+	if err != nil {
+		t.Fatalf("Failed to parse stops: %v", err)
+	}
+	if _, _, err := m.parseRoutes(dir, routesIn(t, dir), stations); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := map[string]map[string]models.Direction{
+		"127": {"5": models.DirectionSouth},
+		// The 6 fixture trip only has the northbound platform; the 5 stops both ways
+		"631": {"6": models.DirectionNorth, "7": models.DirectionNorth},
+	}
+	for id, want := range expected {
+		if got := stations[id].OneWayRoutes; !reflect.DeepEqual(got, want) {
+			t.Errorf("Station %s: expected one-way routes %v, got %v", id, want, got)
+		}
+	}
+
+	response := stations["127"].ConvertToResponse()
+	if response.OneWayRoutes["5"] != models.DirectionSouth || len(response.OneWayRoutes) != 1 {
+		t.Errorf("Expected the response to carry the 5 as southbound only, got %v", response.OneWayRoutes)
+	}
+}
+
+func TestParseRoutesHeadsigns(t *testing.T) {
+	files := goodGTFSFiles()
+	files["stops.txt"] += "R01,Astoria-Ditmars Blvd,40.775036,-73.912034,1,\n" +
+		"R01N,Astoria-Ditmars Blvd,40.775036,-73.912034,,R01\n" +
+		"R01S,Astoria-Ditmars Blvd,40.775036,-73.912034,,R01\n" +
+		"D43,Coney Island-Stillwell Av,40.577422,-73.981233,1,\n" +
+		"D43N,Coney Island-Stillwell Av,40.577422,-73.981233,,D43\n" +
+		"D43S,Coney Island-Stillwell Av,40.577422,-73.981233,,D43\n"
+	files["routes.txt"] += "N,N,Broadway Express\n"
+	// One northbound N short-turns at Times Sq; the headsign most trips show wins
+	files["trips.txt"] = "route_id,trip_id,trip_headsign\n" +
+		"1,T1,\n" +
+		"6,T6,\n" +
+		"N,TN1,Astoria-Ditmars Blvd\n" +
+		"N,TN2,Astoria-Ditmars Blvd\n" +
+		"N,TN3,Times Sq-42 St\n" +
+		"N,TS1,Coney Island-Stillwell Av\n"
+	files["stop_times.txt"] += "TN1,D43N\nTN1,127N\nTN1,R01N\n" +
+		"TN2,D43N\nTN2,R01N\n" +
+		"TN3,D43N\nTN3,127N\n" +
+		"TS1,R01S\nTS1,127S\nTS1,D43S\n"
+	dir := writeGTFSDir(t, files)
+
+	m := &Manager{}
+	stations, err := m.parseStops(filepath.Join(dir, "stops.txt"))
+	if err != nil {
+		t.Fatalf("Failed to parse stops: %v", err)
+	}
+	routes, _, err := m.parseRoutes(dir, routesIn(t, dir), stations)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := map[models.Direction]string{
+		models.DirectionNorth: "Astoria-Ditmars Blvd",
+		models.DirectionSouth: "Coney Island-Stillwell Av",
+	}
+	if got := routes["N"].Headsigns; !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected N headsigns %v, got %v", expected, got)
+	}
+	// Trips without a headsign leave the route without any
+	if got := routes["1"].Headsigns; got != nil {
+		t.Errorf("Expected no headsigns for the 1, got %v", got)
+	}
+
+	t.Run("trips.txt without trip_headsign", func(t *testing.T) {
+		_, headsigns, err := m.parseTripsFile(filepath.Join(writeGTFSDir(t, goodGTFSFiles()), "trips.txt"))
+		if err != nil || len(headsigns) != 0 {
+			t.Errorf("Expected no headsigns and no error, got %v, %v", headsigns, err)
+		}
+	})
+}
+
 // Benchmark the most expensive operations
 func BenchmarkParseStopTimes(b *testing.B) {
 	m := &Manager{}
@@ -506,3 +997,474 @@ func TestStaticDataRefresh(t *testing.T) {
 		t.Error("GetLastStaticUpdate should return zero time before any updates")
 	}
 }
+
+func TestSetFeedGroups(t *testing.T) {
+	t.Run("only enabled feed is fetched", func(t *testing.T) {
+		m := NewManager("test-key", store.NewStore(), time.Minute)
+		if err := m.SetFeedGroups([]string{"L"}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		fetcher := &mapFetcher{}
+		m.SetFetcher(fetcher)
+
+		if err := m.updateRealTimeData(); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		if urls := fetcher.urls(); len(urls) != 1 || urls[0] != FeedGroups["l"] {
+			t.Errorf("Expected only %s to be fetched, got %v", FeedGroups["l"], urls)
+		}
+	})
+
+	t.Run("all feeds by default", func(t *testing.T) {
+		m := NewManager("test-key", store.NewStore(), time.Minute)
+		fetcher := &mapFetcher{}
+		m.SetFetcher(fetcher)
+
+		m.updateRealTimeData()
+
+		if urls := fetcher.urls(); len(urls) != len(FeedURLs) {
+			t.Errorf("Expected %d feeds fetched, got %d", len(FeedURLs), len(urls))
+		}
+	})
+
+	t.Run("unknown feed group is rejected", func(t *testing.T) {
+		m := NewManager("test-key", store.NewStore(), time.Minute)
+		if err := m.SetFeedGroups([]string{"ace", "xyz"}); err == nil {
+			t.Error("Expected error for unknown feed group")
+		}
+		if len(m.feedURLs) != len(FeedURLs) {
+			t.Error("Failed validation should leave enabled feeds unchanged")
+		}
+	})
+
+	t.Run("empty list is rejected", func(t *testing.T) {
+		m := NewManager("test-key", store.NewStore(), time.Minute)
+		if err := m.SetFeedGroups(nil); err == nil {
+			t.Error("Expected error for empty feed group list")
+		}
+	})
+}
+
+func TestTryUpdateSkipsWhileRunning(t *testing.T) {
+	fetcher := &blockingFetcher{
+		release: make(chan struct{}),
+		started: make(chan struct{}),
+	}
+
+	m := NewManager("test-key", store.NewStore(), time.Minute)
+	m.gtfsDataDir = t.TempDir()
+	m.SetFetcher(fetcher)
+
+	m.tryUpdate()
+	<-fetcher.started
+
+	// Ticks arriving while the first cycle is blocked must be skipped
+	for i := 0; i < 5; i++ {
+		m.tryUpdate()
+	}
+
+	close(fetcher.release)
+	m.wg.Wait()
+
+	if peak := fetcher.maxInFlight.Load(); peak != 1 {
+		t.Errorf("Expected update cycles to never overlap, saw %d concurrent requests", peak)
+	}
+	// The single cycle tries the supplemented then the regular static feed before giving up
+	if calls := fetcher.calls.Load(); calls != 2 {
+		t.Errorf("Expected only one update cycle (2 requests), got %d requests", calls)
+	}
+	if m.updating.Load() {
+		t.Error("Expected updating flag to be cleared after the cycle finished")
+	}
+
+	// Once idle, the next tick runs normally
+	m.tryUpdate()
+	m.wg.Wait()
+	if calls := fetcher.calls.Load(); calls != 4 {
+		t.Errorf("Expected a second update cycle after the first finished, got %d requests", calls)
+	}
+}
+
+// panickingFetcher panics on URLs in panicOn and otherwise serves from the wrapped mapFetcher
+type panickingFetcher struct {
+	*mapFetcher
+	panicOn map[string]bool
+}
+
+func (f *panickingFetcher) Fetch(ctx context.Context, url string) ([]byte, error) {
+	if f.panicOn[url] {
+		panic("nil pointer in malformed extension")
+	}
+	return f.mapFetcher.Fetch(ctx, url)
+}
+
+func TestUpdateRecoversFromFeedPanic(t *testing.T) {
+	emptyFeed, err := proto.Marshal(&gtfsrt.FeedMessage{
+		Header: &gtfsrt.FeedHeader{GtfsRealtimeVersion: proto.String("1.0")},
+	})
+	if err != nil {
+		t.Fatalf("Failed to marshal feed: %v", err)
+	}
+
+	m := NewManager("test-key", store.NewStore(), time.Minute)
+	m.SetClock(clock.NewFake(testNow))
+	m.SetGTFSDataDir(t.TempDir())
+	m.SetFetcher(&panickingFetcher{
+		mapFetcher: &mapFetcher{data: map[string][]byte{
+			GTFSSupplementedURL: zipGTFS(t, goodGTFSFiles()),
+			FeedGroups["ace"]:   emptyFeed,
+		}},
+		panicOn: map[string]bool{FeedGroups["l"]: true},
+	})
+	if err := m.SetFeedGroups([]string{"l", "ace"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if err := m.safeUpdate(); err != nil {
+		t.Fatalf("Expected the panic to be contained to its feed, got %v", err)
+	}
+
+	stats := m.Stats()
+	if stats.Panics != 1 || stats.UpdateCycles != 1 {
+		t.Errorf("Expected 1 panic in 1 completed cycle, got %d panics in %d cycles", stats.Panics, stats.UpdateCycles)
+	}
+	for _, feed := range stats.Feeds {
+		switch feed.Feed {
+		case "l":
+			if feed.Failures != 1 || !strings.Contains(feed.LastError, "panic") {
+				t.Errorf("Expected the l feed to record the panic as a failure, got %+v", feed)
+			}
+		case "ace":
+			if feed.Failures != 0 {
+				t.Errorf("Expected the ace feed to be processed normally, got %+v", feed)
+			}
+		}
+	}
+	if status := m.LoadStatus(); status != LoadStatusDegraded {
+		t.Errorf("Expected %v after a feed panic, got %v", LoadStatusDegraded, status)
+	}
+}
+
+func TestUpdateLoopSurvivesPanics(t *testing.T) {
+	// Every fetch panics, including the static download at the top of each cycle
+	fetcher := &panickingFetcher{
+		mapFetcher: &mapFetcher{},
+		panicOn: map[string]bool{
+			GTFSSupplementedURL: true,
+			GTFSRegularURL:      true,
+		},
+	}
+	m := NewManager("test-key", store.NewStore(), 10*time.Millisecond)
+	m.SetGTFSDataDir(t.TempDir())
+	m.SetFetcher(fetcher)
+
+	m.Start()
+	defer m.Stop()
+
+	deadline := time.After(5 * time.Second)
+	for m.Stats().Panics < 3 {
+		select {
+		case <-deadline:
+			t.Fatalf("Expected the loop to keep running after panics, saw only %d recovered", m.Stats().Panics)
+		case <-time.After(5 * time.Millisecond):
+		}
+	}
+
+	select {
+	case <-m.Ready():
+	default:
+		t.Error("Expected Ready to close even though the initial update panicked")
+	}
+}
+
+// zipGTFS packs GTFS files into an in-memory zip like the MTA's static downloads
+func zipGTFS(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatalf("Failed to add %s to zip: %v", name, err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatalf("Failed to write %s to zip: %v", name, err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("Failed to close zip: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestGTFSDataDir(t *testing.T) {
+	t.Run("downloads land in configured directory", func(t *testing.T) {
+		dir := filepath.Join(t.TempDir(), "gtfs")
+		s := store.NewStore()
+		m := NewManager("test-key", s, time.Minute)
+		m.SetFetcher(&mapFetcher{data: map[string][]byte{
+			GTFSSupplementedURL: zipGTFS(t, goodGTFSFiles()),
+		}})
+		m.SetGTFSDataDir(dir)
+
+		if err := m.PrepareGTFSDataDir(); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if err := m.loadStaticGTFSData(); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		for _, name := range []string{"gtfs_supplemented.zip", "extracted/stops.txt"} {
+			if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+				t.Errorf("Expected %s in data directory: %v", name, err)
+			}
+		}
+		if len(s.GetAllStations()) != 2 {
+			t.Errorf("Expected 2 stations loaded, got %d", len(s.GetAllStations()))
+		}
+	})
+
+	t.Run("unwritable directory rejected", func(t *testing.T) {
+		// A path beneath a regular file can never be created, even when running as root
+		file := filepath.Join(t.TempDir(), "not-a-dir")
+		if err := os.WriteFile(file, nil, 0644); err != nil {
+			t.Fatalf("Failed to create file: %v", err)
+		}
+
+		m := &Manager{}
+		m.SetGTFSDataDir(filepath.Join(file, "gtfs"))
+		if err := m.PrepareGTFSDataDir(); err == nil {
+			t.Error("Expected error for unwritable GTFS data directory")
+		}
+	})
+}
+
+func TestLoadStaticGTFSFillsMissingFiles(t *testing.T) {
+	supplemented := goodGTFSFiles()
+	delete(supplemented, "shapes.txt")
+	delete(supplemented, "transfers.txt")
+	supplemented["routes.txt"] = "route_id,route_short_name,route_long_name\n" +
+		"1,1,Broadway - 7 Avenue Local (supplemented)\n" +
+		"6,6,Lexington Avenue Local\n"
+
+	t.Run("missing files taken from regular", func(t *testing.T) {
+		dir := t.TempDir()
+		s := store.NewStore()
+		m := NewManager("test-key", s, time.Minute)
+		fetcher := &mapFetcher{data: map[string][]byte{
+			GTFSSupplementedURL: zipGTFS(t, supplemented),
+			GTFSRegularURL:      zipGTFS(t, goodGTFSFiles()),
+		}}
+		m.SetFetcher(fetcher)
+		m.SetGTFSDataDir(dir)
+
+		if err := m.loadStaticGTFSData(); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		for _, name := range []string{"shapes.txt", "transfers.txt"} {
+			if _, err := os.Stat(filepath.Join(dir, "extracted", name)); err != nil {
+				t.Errorf("Expected %s filled in from regular GTFS: %v", name, err)
+			}
+		}
+		// Files the supplemented feed does have must not be replaced by regular ones
+		info, err := s.GetRouteInfo("1")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if info.LongName != "Broadway - 7 Avenue Local (supplemented)" {
+			t.Errorf("Expected route info from supplemented GTFS, got %q", info.LongName)
+		}
+		if !slices.Contains(fetcher.urls(), GTFSRegularURL) {
+			t.Errorf("Expected regular GTFS to be downloaded, got requests %v", fetcher.urls())
+		}
+	})
+
+	t.Run("complete supplemented skips regular", func(t *testing.T) {
+		m := NewManager("test-key", store.NewStore(), time.Minute)
+		fetcher := &mapFetcher{data: map[string][]byte{
+			GTFSSupplementedURL: zipGTFS(t, goodGTFSFiles()),
+		}}
+		m.SetFetcher(fetcher)
+		m.SetGTFSDataDir(t.TempDir())
+
+		if err := m.loadStaticGTFSData(); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if slices.Contains(fetcher.urls(), GTFSRegularURL) {
+			t.Errorf("Expected no regular GTFS download, got requests %v", fetcher.urls())
+		}
+	})
+
+	t.Run("stale extraction does not stand in", func(t *testing.T) {
+		dir := t.TempDir()
+		if err := os.MkdirAll(filepath.Join(dir, "extracted"), 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "extracted", "shapes.txt"), []byte("stale"), 0644); err != nil {
+			t.Fatalf("Failed to write stale file: %v", err)
+		}
+
+		m := NewManager("test-key", store.NewStore(), time.Minute)
+		fetcher := &mapFetcher{data: map[string][]byte{
+			GTFSSupplementedURL: zipGTFS(t, supplemented),
+			GTFSRegularURL:      zipGTFS(t, goodGTFSFiles()),
+		}}
+		m.SetFetcher(fetcher)
+		m.SetGTFSDataDir(dir)
+
+		if err := m.loadStaticGTFSData(); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		content, err := os.ReadFile(filepath.Join(dir, "extracted", "shapes.txt"))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if string(content) != goodGTFSFiles()["shapes.txt"] {
+			t.Errorf("Expected shapes.txt from regular GTFS, got %q", content)
+		}
+	})
+}
+
+func TestDownloadFileInterrupted(t *testing.T) {
+	// Promise more bytes than are sent, then drop the connection mid-body
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "1000")
+		w.Write([]byte("PK partial zip"))
+		w.(http.Flusher).Flush()
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err == nil {
+			conn.Close()
+		}
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	dest := filepath.Join(dir, "gtfs_supplemented.zip")
+	m := &Manager{fetcher: NewHTTPFetcher("")}
+
+	t.Run("no partial file at destination", func(t *testing.T) {
+		if err := m.downloadFile(srv.URL, dest); err == nil {
+			t.Fatal("Expected error for interrupted download")
+		}
+		if _, err := os.Stat(dest); !os.IsNotExist(err) {
+			t.Errorf("Expected no file at destination, got err=%v", err)
+		}
+	})
+
+	t.Run("previous file kept intact", func(t *testing.T) {
+		if err := os.WriteFile(dest, []byte("previous complete zip"), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+		if err := m.downloadFile(srv.URL, dest); err == nil {
+			t.Fatal("Expected error for interrupted download")
+		}
+		data, err := os.ReadFile(dest)
+		if err != nil || string(data) != "previous complete zip" {
+			t.Errorf("Expected previous file untouched, got %q (err=%v)", data, err)
+		}
+	})
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("Failed to read dir: %v", err)
+	}
+	if len(entries) != 1 {
+		names := make([]string, len(entries))
+		for i, e := range entries {
+			names[i] = e.Name()
+		}
+		t.Errorf("Expected only the destination file left behind, got %v", names)
+	}
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	dest := filepath.Join(dir, "gtfs_subway.zip")
+
+	if err := writeFileAtomic(dest, []byte("complete")); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	data, err := os.ReadFile(dest)
+	if err != nil || string(data) != "complete" {
+		t.Errorf("Expected complete contents, got %q (err=%v)", data, err)
+	}
+
+	// Renaming onto a directory fails; the temp file must not be left behind
+	blocked := filepath.Join(dir, "blocked")
+	if err := os.Mkdir(blocked, 0755); err != nil {
+		t.Fatalf("Failed to create dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(blocked, "keep"), nil, 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if err := writeFileAtomic(blocked, []byte("new")); err == nil {
+		t.Error("Expected error renaming onto a directory")
+	}
+
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 2 {
+		t.Errorf("Expected temp file cleaned up, got %d entries", len(entries))
+	}
+}
+
+func TestLoadLocalStaticGTFS(t *testing.T) {
+	extracted := writeGTFSDir(t, goodGTFSFiles())
+	zipPath := filepath.Join(t.TempDir(), "gtfs_subway.zip")
+	if err := os.WriteFile(zipPath, zipGTFS(t, goodGTFSFiles()), 0644); err != nil {
+		t.Fatalf("Failed to write zip: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		source string
+	}{
+		{"extracted directory", extracted},
+		{"directory file URL", "file://" + extracted},
+		{"zip file", zipPath},
+		{"zip file URL", "file://" + zipPath},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := store.NewStore()
+			m := NewManager("test-key", s, time.Minute)
+			fetcher := &mapFetcher{}
+			m.SetFetcher(fetcher)
+			m.SetGTFSDataDir(t.TempDir())
+			if err := m.SetStaticGTFSSource(tt.source); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if err := m.loadStaticGTFSData(); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if stations, routes, _ := s.Counts(); stations != 2 || routes != 2 {
+				t.Errorf("Expected 2 stations and 2 routes, got %d and %d", stations, routes)
+			}
+			if urls := fetcher.urls(); len(urls) != 0 {
+				t.Errorf("Expected no downloads, got %v", urls)
+			}
+		})
+	}
+
+	t.Run("missing path", func(t *testing.T) {
+		m := NewManager("test-key", store.NewStore(), time.Minute)
+		m.SetGTFSDataDir(t.TempDir())
+		if err := m.SetStaticGTFSSource(filepath.Join(t.TempDir(), "missing.zip")); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if err := m.loadStaticGTFSData(); err == nil {
+			t.Error("Expected error for a missing local GTFS path")
+		}
+	})
+
+	t.Run("remote file URL", func(t *testing.T) {
+		m := NewManager("test-key", store.NewStore(), time.Minute)
+		if err := m.SetStaticGTFSSource("file://example.com/gtfs.zip"); err == nil {
+			t.Error("Expected error for a file URL naming a remote host")
+		}
+	})
+}
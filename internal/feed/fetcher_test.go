@@ -0,0 +1,407 @@
+package feed
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jusunglee/mta-go/internal/clock"
+	"github.com/jusunglee/mta-go/internal/gtfsrt"
+	"github.com/jusunglee/mta-go/internal/models"
+	"github.com/jusunglee/mta-go/internal/store"
+	"google.golang.org/protobuf/proto"
+)
+
+// mapFetcher serves canned bytes by URL and records every requested URL
+// Unknown URLs fail like a 404 so partially seeded feeds behave realistically
+type mapFetcher struct {
+	mu        sync.Mutex
+	data      map[string][]byte
+	requested []string
+}
+
+func (f *mapFetcher) Fetch(ctx context.Context, url string) ([]byte, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requested = append(f.requested, url)
+	if data, ok := f.data[url]; ok {
+		return data, nil
+	}
+	return nil, fmt.Errorf("HTTP %d", http.StatusNotFound)
+}
+
+func (f *mapFetcher) urls() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.requested...)
+}
+
+// blockingFetcher holds every fetch until release is closed and records peak concurrency
+type blockingFetcher struct {
+	release     chan struct{}
+	started     chan struct{}
+	calls       atomic.Int32
+	inFlight    atomic.Int32
+	maxInFlight atomic.Int32
+}
+
+func (f *blockingFetcher) Fetch(ctx context.Context, url string) ([]byte, error) {
+	if f.calls.Add(1) == 1 {
+		close(f.started)
+	}
+	n := f.inFlight.Add(1)
+	defer f.inFlight.Add(-1)
+	for {
+		peak := f.maxInFlight.Load()
+		if n <= peak || f.maxInFlight.CompareAndSwap(peak, n) {
+			break
+		}
+	}
+
+	<-f.release
+	return nil, fmt.Errorf("HTTP %d", http.StatusNotFound)
+}
+
+func TestHTTPFetcher(t *testing.T) {
+	var gotKey string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotKey = r.Header.Get("x-api-key")
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte("feed-bytes"))
+	}))
+	defer srv.Close()
+
+	f := NewHTTPFetcher("secret")
+
+	data, err := f.Fetch(context.Background(), srv.URL+"/feed")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if string(data) != "feed-bytes" {
+		t.Errorf("Expected body feed-bytes, got %q", data)
+	}
+	if gotKey != "secret" {
+		t.Errorf("Expected the API key sent with no KeyHosts set, got %q", gotKey)
+	}
+
+	f.KeyHosts = map[string]bool{"api-endpoint.mta.info": true}
+	if _, err := f.Fetch(context.Background(), srv.URL+"/feed"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if gotKey != "" {
+		t.Errorf("API key should only be sent to KeyHosts, got %q", gotKey)
+	}
+
+	if _, err := f.Fetch(context.Background(), srv.URL+"/missing"); err == nil {
+		t.Error("Expected error for non-200 response")
+	}
+}
+
+func TestProcessFeedRejectsHTML(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("\n<!DOCTYPE html>\n<html><body><h1>503 Service Temporarily Unavailable</h1></body></html>"))
+	}))
+	defer srv.Close()
+
+	m := NewManager("test-key", store.NewStore(), time.Minute)
+	m.SetClock(clock.NewFake(testNow))
+	stations := map[string]*models.Station{
+		"L06": {ID: "L06", Name: "1 Av", Routes: []string{"L"}},
+	}
+
+	_, err := m.processFeed(srv.URL, stations, nil)
+	if !errors.Is(err, errNonProtobufResponse) {
+		t.Fatalf("Expected non-protobuf response error, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "text/html") {
+		t.Errorf("Expected error to name the detected content type, got %v", err)
+	}
+	if trains := stations["L06"].Trains; len(trains.North) != 0 || len(trains.South) != 0 {
+		t.Errorf("Expected no arrivals from an HTML body, got %+v", trains)
+	}
+}
+
+func TestCheckProtobufBody(t *testing.T) {
+	feed, err := proto.Marshal(&gtfsrt.FeedMessage{
+		Header: &gtfsrt.FeedHeader{GtfsRealtimeVersion: proto.String("2.0")},
+	})
+	if err != nil {
+		t.Fatalf("Failed to marshal feed: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		body    []byte
+		wantErr bool
+	}{
+		{"protobuf", feed, false},
+		{"empty", nil, false},
+		{"html", []byte("<html><body>Error</body></html>"), true},
+		{"xml with leading whitespace", []byte("  \r\n<?xml version=\"1.0\"?><Error/>"), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkProtobufBody(tt.body)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("checkProtobufBody() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// roundTripFunc lets a test capture outgoing requests without a network listener
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestAPIKeyAuth(t *testing.T) {
+	tests := []struct {
+		name          string
+		auth          *APIKeyAuth
+		expectedHead  string
+		expectedQuery string
+	}{
+		{"default header", nil, "x-api-key", ""},
+		{"custom header", &APIKeyAuth{Header: "X-MTA-Key"}, "X-MTA-Key", ""},
+		{"query param", &APIKeyAuth{QueryParam: "key"}, "", "key"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var captured *http.Request
+			m := NewManager("secret", store.NewStore(), time.Minute)
+			m.fetcher.(*HTTPFetcher).Client.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+				captured = req
+				return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader("ok"))}, nil
+			})
+			if tt.auth != nil {
+				if err := m.SetAPIKeyAuth(*tt.auth); err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+			}
+
+			if _, err := m.fetchFeed(FeedGroups["ace"]); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if tt.expectedHead != "" && captured.Header.Get(tt.expectedHead) != "secret" {
+				t.Errorf("Expected key in header %s, got headers %v", tt.expectedHead, captured.Header)
+			}
+			if tt.expectedQuery != "" {
+				if got := captured.URL.Query().Get(tt.expectedQuery); got != "secret" {
+					t.Errorf("Expected key in query param %s, got %q", tt.expectedQuery, got)
+				}
+				if got := captured.Header.Get("x-api-key"); got != "" {
+					t.Errorf("Expected no x-api-key header in query mode, got %q", got)
+				}
+			}
+		})
+	}
+
+	t.Run("static downloads sent no key", func(t *testing.T) {
+		var captured *http.Request
+		m := NewManager("secret", store.NewStore(), time.Minute)
+		m.fetcher.(*HTTPFetcher).Client.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			captured = req
+			return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader("zip"))}, nil
+		})
+		dest := filepath.Join(t.TempDir(), "gtfs_supplemented.zip")
+		if err := m.downloadFile(GTFSSupplementedURL, dest); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if got := captured.Header.Get("x-api-key"); got != "" {
+			t.Errorf("Expected no API key on the static download, got %q", got)
+		}
+		if data, err := os.ReadFile(dest); err != nil || string(data) != "zip" {
+			t.Errorf("Expected the body streamed to %s, got %q (err %v)", dest, data, err)
+		}
+	})
+
+	t.Run("custom fetcher rejected", func(t *testing.T) {
+		m := &Manager{fetcher: &mapFetcher{}}
+		if err := m.SetAPIKeyAuth(APIKeyAuth{QueryParam: "key"}); err == nil {
+			t.Error("Expected error for non-HTTP fetcher")
+		}
+	})
+}
+
+func TestUpdateRealTimeDataWithMapFetcher(t *testing.T) {
+	routeID := "L"
+	stopID := "L06S"
+	entityID := "1"
+	arrivalTime := testNow.Add(5 * time.Minute).Unix()
+
+	data, err := proto.Marshal(&gtfsrt.FeedMessage{
+		Header: &gtfsrt.FeedHeader{GtfsRealtimeVersion: proto.String("1.0")},
+		Entity: []*gtfsrt.FeedEntity{
+			{
+				Id: &entityID,
+				TripUpdate: &gtfsrt.TripUpdate{
+					Trip: &gtfsrt.TripDescriptor{RouteId: &routeID},
+					StopTimeUpdate: []*gtfsrt.StopTimeUpdate{
+						{StopId: &stopID, Arrival: &gtfsrt.StopTimeEvent{Time: &arrivalTime}},
+					},
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to marshal feed: %v", err)
+	}
+
+	s := store.NewStore()
+	staticLoad := testNow.Add(-time.Hour)
+	s.UpdateStations(map[string]*models.Station{
+		"L06": {ID: "L06", Name: "1 Av", Routes: []string{"L"}, LastUpdate: staticLoad},
+		"L08": {ID: "L08", Name: "Bedford Av", Routes: []string{"L"}, LastUpdate: staticLoad},
+	})
+
+	m := NewManager("test-key", s, time.Minute)
+	m.SetClock(clock.NewFake(testNow))
+	m.SetFetcher(&mapFetcher{data: map[string][]byte{FeedGroups["l"]: data}})
+
+	if err := m.updateRealTimeData(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	stations, err := s.GetStationsByIDs([]string{"L06"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	trains := stations[0].Trains.South
+	if len(trains) != 1 || trains[0].Route != "L" || trains[0].Time.Unix() != arrivalTime {
+		t.Errorf("Expected one southbound L arrival from the canned feed, got %v", trains)
+	}
+	if !stations[0].LastUpdate.Equal(testNow) {
+		t.Errorf("Expected L06 last update %v, got %v", testNow, stations[0].LastUpdate)
+	}
+
+	// A station with no arrivals in the feed keeps its static-load timestamp
+	untouched, err := s.GetStationsByIDs([]string{"L08"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !untouched[0].LastUpdate.Equal(staticLoad) {
+		t.Errorf("Expected L08 last update to stay %v, got %v", staticLoad, untouched[0].LastUpdate)
+	}
+}
+
+func TestStationFreshness(t *testing.T) {
+	feedTimes := map[string]time.Time{
+		"ace": testNow.Add(-10 * time.Minute),
+		"g":   testNow.Add(-2 * time.Minute),
+		"l":   testNow.Add(time.Minute), // Feed clock running ahead of ours
+	}
+	trains := func(sources ...string) models.TrainsByDirection {
+		var t models.TrainsByDirection
+		for _, source := range sources {
+			t.South = append(t.South, models.Train{Route: "A", Source: source})
+		}
+		return t
+	}
+
+	tests := []struct {
+		name     string
+		trains   models.TrainsByDirection
+		expected time.Time
+	}{
+		{"single feed", trains("ace"), testNow.Add(-10 * time.Minute)},
+		{"newest of several feeds", trains("ace", "g"), testNow.Add(-2 * time.Minute)},
+		{"feed without a header timestamp", trains("ace", "jz"), testNow},
+		{"future timestamp clamped", trains("l"), testNow},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := stationFreshness(tt.trains, feedTimes, testNow); !got.Equal(tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestUpdateRealTimeDataUsesFeedTimestamp(t *testing.T) {
+	// The MTA generated this message ten minutes before our fetch
+	stale := testNow.Add(-10 * time.Minute)
+	older := testNow.Add(-30 * time.Minute)
+	feedWithTimestamp := func(ts time.Time) []byte {
+		data, err := proto.Marshal(&gtfsrt.FeedMessage{
+			Header: &gtfsrt.FeedHeader{
+				GtfsRealtimeVersion: proto.String("1.0"),
+				Timestamp:           proto.Uint64(uint64(ts.Unix())),
+			},
+		})
+		if err != nil {
+			t.Fatalf("Failed to marshal feed: %v", err)
+		}
+		return data
+	}
+	noTimestamp, err := proto.Marshal(&gtfsrt.FeedMessage{
+		Header: &gtfsrt.FeedHeader{GtfsRealtimeVersion: proto.String("1.0")},
+	})
+	if err != nil {
+		t.Fatalf("Failed to marshal feed: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		feeds    map[string][]byte
+		expected time.Time
+	}{
+		{
+			name: "newest header timestamp across feeds",
+			feeds: map[string][]byte{
+				FeedGroups["l"]:   feedWithTimestamp(stale),
+				FeedGroups["ace"]: feedWithTimestamp(older),
+			},
+			expected: stale,
+		},
+		{
+			name:     "falls back to clock without header timestamps",
+			feeds:    map[string][]byte{FeedGroups["l"]: noTimestamp},
+			expected: testNow,
+		},
+		{
+			name:     "keeps the previous update when every feed fails",
+			feeds:    map[string][]byte{},
+			expected: older,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := store.NewStore()
+			s.UpdateStationsAt(map[string]*models.Station{
+				"L06": {ID: "L06", Name: "1 Av", Routes: []string{"L"}},
+			}, older)
+
+			m := NewManager("test-key", s, time.Minute)
+			m.SetClock(clock.NewFake(testNow))
+			m.SetFetcher(&mapFetcher{data: tt.feeds})
+
+			if err := m.updateRealTimeData(); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got := s.GetLastUpdate(); !got.Equal(tt.expected) {
+				t.Errorf("Expected last update %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,168 @@
+package feed
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strconv"
+)
+
+// StationGraph is the subway network as stations joined by the routes running directly between them
+// Edges are undirected: a northbound and a southbound trip over the same track produce one edge
+type StationGraph struct {
+	Stations []GraphStation `json:"stations"`
+	Edges    []GraphEdge    `json:"edges"`
+}
+
+// GraphStation is a node of the station graph
+type GraphStation struct {
+	ID   string  `json:"id"`
+	Name string  `json:"name"`
+	Lat  float64 `json:"lat"`
+	Lon  float64 `json:"lon"`
+}
+
+// GraphEdge joins two consecutive stops of at least one trip, From sorting before To
+// Routes lists every route making that hop, so an express skipping local stops gets its own edges
+type GraphEdge struct {
+	From   string   `json:"from"`
+	To     string   `json:"to"`
+	Routes []string `json:"routes"`
+}
+
+// BuildStationGraph reads a static GTFS directory into the station graph without touching the store
+// Adjacency comes from each trip's stops in stop_sequence order, mapped from platforms to parent stations
+func (m *Manager) BuildStationGraph(dir string) (*StationGraph, error) {
+	stations, err := m.parseStops(filepath.Join(dir, "stops.txt"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse stops: %w", err)
+	}
+	parents := platformParents(stations)
+
+	routes, err := m.parseRoutesFile(filepath.Join(dir, "routes.txt"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse routes: %w", err)
+	}
+	routeTrips, _, err := m.parseTripsFile(filepath.Join(dir, "trips.txt"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse trips: %w", err)
+	}
+	tripRoute := make(map[string]string)
+	for routeID, trips := range routeTrips {
+		name := routeID
+		if info, ok := routes[routeID]; ok {
+			name = info.ShortName
+		}
+		for tripID := range trips {
+			tripRoute[tripID] = name
+		}
+	}
+
+	sequences, err := parseTripSequences(filepath.Join(dir, "stop_times.txt"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse stop_times: %w", err)
+	}
+
+	type edgeKey struct{ from, to string }
+	edgeRoutes := make(map[edgeKey]map[string]bool)
+	for tripID, stops := range sequences {
+		route, ok := tripRoute[tripID]
+		if !ok {
+			continue
+		}
+		previous := ""
+		for _, stop := range stops {
+			station, ok := parents[stop.stopID]
+			if !ok {
+				if _, isStation := stations[stop.stopID]; !isStation {
+					continue
+				}
+				station = stop.stopID
+			}
+			// Consecutive platforms of one station, e.g. at a terminal, aren't a hop
+			if previous != "" && previous != station {
+				key := edgeKey{previous, station}
+				if key.to < key.from {
+					key.from, key.to = key.to, key.from
+				}
+				if edgeRoutes[key] == nil {
+					edgeRoutes[key] = make(map[string]bool)
+				}
+				edgeRoutes[key][route] = true
+			}
+			previous = station
+		}
+	}
+
+	graph := &StationGraph{
+		Stations: make([]GraphStation, 0, len(stations)),
+		Edges:    make([]GraphEdge, 0, len(edgeRoutes)),
+	}
+	for _, station := range stations {
+		graph.Stations = append(graph.Stations, GraphStation{
+			ID:   station.ID,
+			Name: station.Name,
+			Lat:  station.Location.Lat,
+			Lon:  station.Location.Lon,
+		})
+	}
+	sort.Slice(graph.Stations, func(i, j int) bool { return graph.Stations[i].ID < graph.Stations[j].ID })
+
+	for key, routeSet := range edgeRoutes {
+		edge := GraphEdge{From: key.from, To: key.to, Routes: make([]string, 0, len(routeSet))}
+		for route := range routeSet {
+			edge.Routes = append(edge.Routes, route)
+		}
+		sort.Strings(edge.Routes)
+		graph.Edges = append(graph.Edges, edge)
+	}
+	sort.Slice(graph.Edges, func(i, j int) bool {
+		if graph.Edges[i].From != graph.Edges[j].From {
+			return graph.Edges[i].From < graph.Edges[j].From
+		}
+		return graph.Edges[i].To < graph.Edges[j].To
+	})
+	return graph, nil
+}
+
+// sequencedStop is one stop_times.txt row of a trip
+type sequencedStop struct {
+	seq    int
+	stopID string
+}
+
+// parseTripSequences reads stop_times.txt into trip_id -> stops ordered by stop_sequence
+// GTFS only requires sequences to increase along a trip, not rows to be written in order
+func parseTripSequences(path string) (map[string][]sequencedStop, error) {
+	file, read, columns, err := scheduleCSV(path, "trip_id", "stop_id", "stop_sequence")
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	trips := make(map[string][]sequencedStop)
+	for {
+		record, err := read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(record) <= columns["trip_id"] || len(record) <= columns["stop_id"] || len(record) <= columns["stop_sequence"] {
+			continue
+		}
+		seq, err := strconv.Atoi(record[columns["stop_sequence"]])
+		if err != nil {
+			continue
+		}
+		tripID := record[columns["trip_id"]]
+		trips[tripID] = append(trips[tripID], sequencedStop{seq: seq, stopID: record[columns["stop_id"]]})
+	}
+
+	for _, stops := range trips {
+		sort.SliceStable(stops, func(i, j int) bool { return stops[i].seq < stops[j].seq })
+	}
+	return trips, nil
+}
@@ -0,0 +1,67 @@
+package feed
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBuildStationGraph(t *testing.T) {
+	files := goodGTFSFiles()
+	// A local stopping everywhere and an express skipping 120 on the same track
+	files["stops.txt"] = "stop_id,stop_name,stop_lat,stop_lon,location_type,parent_station\n" +
+		"120,96 St,40.793919,-73.972323,1,\n" +
+		"120N,96 St,40.793919,-73.972323,,120\n" +
+		"120S,96 St,40.793919,-73.972323,,120\n" +
+		"123,72 St,40.778453,-73.98197,1,\n" +
+		"123N,72 St,40.778453,-73.98197,,123\n" +
+		"123S,72 St,40.778453,-73.98197,,123\n" +
+		"127,Times Sq-42 St,40.75529,-73.987495,1,\n" +
+		"127N,Times Sq-42 St,40.75529,-73.987495,,127\n" +
+		"127S,Times Sq-42 St,40.75529,-73.987495,,127\n" +
+		"110,Cathedral Pkwy,40.803967,-73.966847,1,\n" +
+		"110S,Cathedral Pkwy,40.803967,-73.966847,,110\n"
+	files["routes.txt"] = "route_id,route_short_name\n" +
+		"1,1\n" +
+		"2,2\n"
+	files["trips.txt"] = "route_id,trip_id\n" +
+		"1,L_S\n" +
+		"1,L_N\n" +
+		"2,X_S\n"
+	// Rows are out of stop_sequence order; the northbound local retraces the southbound one
+	files["stop_times.txt"] = "trip_id,stop_id,stop_sequence\n" +
+		"L_S,123S,3\n" +
+		"L_S,110S,1\n" +
+		"L_S,120S,2\n" +
+		"L_S,127S,4\n" +
+		"L_N,127N,1\n" +
+		"L_N,123N,2\n" +
+		"L_N,120N,3\n" +
+		"X_S,120S,1\n" +
+		"X_S,127S,2\n" +
+		"UNKNOWN,123S,1\n" +
+		"UNKNOWN,110S,2\n"
+	dir := writeGTFSDir(t, files)
+
+	graph, err := (&Manager{}).BuildStationGraph(dir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []GraphEdge{
+		{From: "110", To: "120", Routes: []string{"1"}},
+		{From: "120", To: "123", Routes: []string{"1"}},
+		{From: "120", To: "127", Routes: []string{"2"}},
+		{From: "123", To: "127", Routes: []string{"1"}},
+	}
+	if !reflect.DeepEqual(graph.Edges, expected) {
+		t.Errorf("Expected edges %+v, got %+v", expected, graph.Edges)
+	}
+
+	var ids []string
+	for _, station := range graph.Stations {
+		ids = append(ids, station.ID)
+	}
+	if !reflect.DeepEqual(ids, []string{"110", "120", "123", "127"}) {
+		t.Errorf("Expected every parent station as a node, got %v", ids)
+	}
+}
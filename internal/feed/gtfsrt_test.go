@@ -1,12 +1,20 @@
 package feed
 
 import (
+	"bytes"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/jusunglee/mta-go/internal/clock"
 	"github.com/jusunglee/mta-go/internal/gtfsrt"
 	"github.com/jusunglee/mta-go/internal/models"
 	"github.com/jusunglee/mta-go/internal/store"
+	"google.golang.org/protobuf/proto"
 )
 
 func TestExtractRouteFromID(t *testing.T) {
@@ -34,9 +42,12 @@ func TestExtractRouteFromID(t *testing.T) {
 	}
 }
 
+// testNow is a fixed reference time so time-dependent tests are deterministic
+var testNow = time.Date(2024, 12, 1, 8, 0, 0, 0, time.UTC)
+
 func TestSortAndLimitTrains(t *testing.T) {
-	now := time.Now()
-	m := &Manager{}
+	now := testNow
+	m := &Manager{clock: clock.NewFake(testNow)}
 
 	trains := []models.Train{
 		{Route: "N", Time: now.Add(5 * time.Minute)},
@@ -68,8 +79,41 @@ func TestSortAndLimitTrains(t *testing.T) {
 	}
 }
 
+func TestSortAndLimitTrainsRetention(t *testing.T) {
+	trains := make([]models.Train, 50)
+	for i := range trains {
+		trains[i] = models.Train{Route: "L", Time: testNow.Add(time.Duration(50-i) * time.Minute)}
+	}
+
+	tests := []struct {
+		name      string
+		retention int
+		expected  int
+	}{
+		{"default", 0, DefaultArrivalRetention},
+		{"configured", 12, 12},
+		{"above available", 80, 50},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &Manager{clock: clock.NewFake(testNow)}
+			m.SetArrivalRetention(tt.retention)
+
+			result := m.sortAndLimitTrains(trains)
+			if len(result) != tt.expected {
+				t.Fatalf("Expected %d trains, got %d", tt.expected, len(result))
+			}
+			// Retention keeps the soonest arrivals, not whichever came first in the feed
+			if !result[0].Time.Equal(testNow.Add(time.Minute)) {
+				t.Errorf("Expected earliest arrival first, got %v", result[0].Time)
+			}
+		})
+	}
+}
+
 func TestProcessTripUpdate(t *testing.T) {
-	m := &Manager{}
+	m := &Manager{clock: clock.NewFake(testNow)}
 
 	// Create test stations
 	stations := map[string]*models.Station{
@@ -86,7 +130,7 @@ func TestProcessTripUpdate(t *testing.T) {
 	// Create test trip update
 	routeID := "N20241201"
 	stopID := "R16N"
-	arrivalTime := time.Now().Add(3 * time.Minute).Unix()
+	arrivalTime := testNow.Add(3 * time.Minute).Unix()
 
 	tripUpdate := &gtfsrt.TripUpdate{
 		Trip: &gtfsrt.TripDescriptor{
@@ -103,7 +147,7 @@ func TestProcessTripUpdate(t *testing.T) {
 	}
 
 	// Process the trip update
-	m.processTripUpdate(tripUpdate, stations)
+	m.processTripUpdate(tripUpdate, stations, "")
 
 	// Verify the train was added
 	station := stations["R16"]
@@ -126,10 +170,415 @@ func TestProcessTripUpdate(t *testing.T) {
 	}
 }
 
+func TestProcessTripUpdateSkipsNoData(t *testing.T) {
+	m := &Manager{clock: clock.NewFake(testNow)}
+	stations := map[string]*models.Station{
+		"R16": {ID: "R16", Name: "Times Sq-42 St"},
+		"R17": {ID: "R17", Name: "34 St-Herald Sq"},
+	}
+
+	routeID := "N20241201"
+	noDataStop := "R16S"
+	stopID := "R17S"
+	arrivalTime := testNow.Add(4 * time.Minute).Unix()
+	tripUpdate := &gtfsrt.TripUpdate{
+		Trip: &gtfsrt.TripDescriptor{RouteId: &routeID},
+		StopTimeUpdate: []*gtfsrt.StopTimeUpdate{
+			// No Arrival at all, which would otherwise fail the whole trip
+			{StopId: &noDataStop, ScheduleRelationship: gtfsrt.StopTimeUpdate_NO_DATA.Enum()},
+			{StopId: &stopID, ScheduleRelationship: gtfsrt.StopTimeUpdate_SCHEDULED.Enum(), Arrival: &gtfsrt.StopTimeEvent{Time: &arrivalTime}},
+		},
+	}
+
+	if err := m.processTripUpdate(tripUpdate, stations, ""); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if trains := stations["R16"].Trains.South; len(trains) != 0 {
+		t.Errorf("Expected no arrival for the NO_DATA stop, got %v", trains)
+	}
+	trains := stations["R17"].Trains.South
+	if len(trains) != 1 || trains[0].Time.Unix() != arrivalTime {
+		t.Errorf("Expected the scheduled stop's arrival to be kept, got %v", trains)
+	}
+}
+
+func TestProcessTripUpdateDirections(t *testing.T) {
+	m := &Manager{clock: clock.NewFake(testNow)}
+	arrivalTime := testNow.Add(3 * time.Minute).Unix()
+
+	tests := []struct {
+		stopID       string
+		north, south int
+		wantErr      bool
+	}{
+		{"R16N", 1, 0, false},
+		{"R16S", 0, 1, false},
+		// A parent station ID names no platform, so it is rejected rather than guessed
+		{"R16", 0, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.stopID, func(t *testing.T) {
+			stations := map[string]*models.Station{
+				"R16": {ID: "R16", Name: "Times Sq-42 St", Routes: []string{"N"}},
+			}
+			routeID, stopID := "N20241201", tt.stopID
+			tripUpdate := &gtfsrt.TripUpdate{
+				Trip: &gtfsrt.TripDescriptor{RouteId: &routeID},
+				StopTimeUpdate: []*gtfsrt.StopTimeUpdate{
+					{StopId: &stopID, Arrival: &gtfsrt.StopTimeEvent{Time: &arrivalTime}},
+				},
+			}
+
+			err := m.processTripUpdate(tripUpdate, stations, "")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Expected error %v, got %v", tt.wantErr, err)
+			}
+			trains := stations["R16"].Trains
+			if len(trains.North) != tt.north || len(trains.South) != tt.south {
+				t.Errorf("Expected %d north and %d south, got %d and %d", tt.north, tt.south, len(trains.North), len(trains.South))
+			}
+		})
+	}
+}
+
+func TestProcessTripUpdateParentStation(t *testing.T) {
+	files := goodGTFSFiles()
+	// 902N belongs to Times Sq even though stripping its suffix gives 902, which isn't a station
+	files["stops.txt"] += "902N,Times Sq-42 St,40.75529,-73.987495,,127\n"
+
+	s := store.NewStore()
+	m := NewManager("test-key", s, time.Minute)
+	m.SetClock(clock.NewFake(testNow))
+	if err := m.parseGTFSData(writeGTFSDir(t, files)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	stations := map[string]*models.Station{}
+	all := s.GetAllStations()
+	for _, station := range all {
+		stations[station.ID] = &station
+	}
+
+	arrivalTime := testNow.Add(2 * time.Minute).Unix()
+	routeID := "1"
+	tripUpdate := &gtfsrt.TripUpdate{
+		Trip: &gtfsrt.TripDescriptor{RouteId: &routeID},
+		StopTimeUpdate: []*gtfsrt.StopTimeUpdate{
+			{StopId: proto.String("902N"), Arrival: &gtfsrt.StopTimeEvent{Time: &arrivalTime}},
+			// Conventional IDs still resolve by suffix
+			{StopId: proto.String("631N"), Arrival: &gtfsrt.StopTimeEvent{Time: &arrivalTime}},
+		},
+	}
+	if err := m.processTripUpdate(tripUpdate, stations, ""); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if trains := stations["127"].Trains.North; len(trains) != 1 || trains[0].Time.Unix() != arrivalTime {
+		t.Errorf("Expected the 902N arrival at station 127 northbound, got %v", trains)
+	}
+	if trains := stations["631"].Trains.North; len(trains) != 1 {
+		t.Errorf("Expected the 631N arrival at station 631, got %v", trains)
+	}
+}
+
+func TestProcessTripUpdatePastArrivalCutoff(t *testing.T) {
+	fake := clock.NewFake(testNow)
+	m := &Manager{clock: fake}
+
+	routeID := "N20241201"
+	stopID := "R16N"
+	arrivalTime := testNow.Unix()
+
+	newStations := func() map[string]*models.Station {
+		return map[string]*models.Station{
+			"R16": {ID: "R16", Name: "Times Sq-42 St"},
+		}
+	}
+	tripUpdate := &gtfsrt.TripUpdate{
+		Trip: &gtfsrt.TripDescriptor{RouteId: &routeID},
+		StopTimeUpdate: []*gtfsrt.StopTimeUpdate{
+			{StopId: &stopID, Arrival: &gtfsrt.StopTimeEvent{Time: &arrivalTime}},
+		},
+	}
+
+	// Exactly one minute past is still kept
+	fake.Set(testNow.Add(time.Minute))
+	stations := newStations()
+	if err := m.processTripUpdate(tripUpdate, stations, ""); err != nil {
+		t.Fatalf("Unexpected error at cutoff: %v", err)
+	}
+	if len(stations["R16"].Trains.North) != 1 {
+		t.Errorf("Expected arrival at the cutoff to be kept, got %d trains", len(stations["R16"].Trains.North))
+	}
+
+	// One second beyond the cutoff is dropped
+	fake.Advance(time.Second)
+	stations = newStations()
+	if err := m.processTripUpdate(tripUpdate, stations, ""); err == nil {
+		t.Error("Expected error for arrival older than one minute")
+	}
+	if len(stations["R16"].Trains.North) != 0 {
+		t.Errorf("Expected stale arrival to be dropped, got %d trains", len(stations["R16"].Trains.North))
+	}
+}
+
+func TestProcessTripUpdateCustomPastArrivalCutoff(t *testing.T) {
+	fake := clock.NewFake(testNow)
+	m := &Manager{clock: fake}
+	m.SetPastArrivalCutoff(30 * time.Second)
+
+	routeID := "N20241201"
+	stopID := "R16N"
+
+	tests := []struct {
+		name     string
+		age      time.Duration
+		expected int
+	}{
+		{"just inside", 29 * time.Second, 1},
+		{"at cutoff", 30 * time.Second, 1},
+		{"just outside", 31 * time.Second, 0},
+		{"inside default but outside custom", 45 * time.Second, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			arrivalTime := testNow.Add(-tt.age).Unix()
+			tripUpdate := &gtfsrt.TripUpdate{
+				Trip: &gtfsrt.TripDescriptor{RouteId: &routeID},
+				StopTimeUpdate: []*gtfsrt.StopTimeUpdate{
+					{StopId: &stopID, Arrival: &gtfsrt.StopTimeEvent{Time: &arrivalTime}},
+				},
+			}
+			stations := map[string]*models.Station{
+				"R16": {ID: "R16", Name: "Times Sq-42 St"},
+			}
+
+			m.processTripUpdate(tripUpdate, stations, "")
+
+			if got := len(stations["R16"].Trains.North); got != tt.expected {
+				t.Errorf("Expected %d trains, got %d", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestProcessTripUpdateUnlistedRoute(t *testing.T) {
+	m := &Manager{clock: clock.NewFake(testNow)}
+
+	stations := map[string]*models.Station{
+		"R16": {ID: "R16", Name: "Times Sq-42 St", Routes: []string{"N", "Q", "R", "W"}},
+	}
+	arrivalTime := testNow.Add(5 * time.Minute).Unix()
+	stopID := "R16N"
+
+	tests := []struct {
+		name     string
+		routeID  string
+		unlisted bool
+	}{
+		{"listed route", "N20241201", false},
+		{"route absent from static data", "5X20241201", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stations["R16"].Trains = models.TrainsByDirection{}
+			routeID := tt.routeID
+			tripUpdate := &gtfsrt.TripUpdate{
+				Trip: &gtfsrt.TripDescriptor{RouteId: &routeID},
+				StopTimeUpdate: []*gtfsrt.StopTimeUpdate{
+					{StopId: &stopID, Arrival: &gtfsrt.StopTimeEvent{Time: &arrivalTime}},
+				},
+			}
+
+			if err := m.processTripUpdate(tripUpdate, stations, ""); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			trains := stations["R16"].Trains.North
+			if len(trains) != 1 {
+				t.Fatalf("Expected arrival to be kept, got %d trains", len(trains))
+			}
+			if trains[0].UnlistedRoute != tt.unlisted {
+				t.Errorf("Expected unlisted_route %v, got %v", tt.unlisted, trains[0].UnlistedRoute)
+			}
+			// Static route lists are left alone so the route index stays static-derived
+			if len(stations["R16"].Routes) != 4 {
+				t.Errorf("Expected station routes unchanged, got %v", stations["R16"].Routes)
+			}
+		})
+	}
+}
+
+func TestLogUnlistedRoutePerStation(t *testing.T) {
+	var buf bytes.Buffer
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(slog.New(slog.NewTextHandler(&buf, nil)))
+
+	m := &Manager{}
+	m.logUnlistedRoute("5X", "R16")
+	m.logUnlistedRoute("5X", "R16")
+	m.logUnlistedRoute("5X", "631")
+
+	if got := strings.Count(buf.String(), "route=5X"); got != 2 {
+		t.Errorf("Expected one warning per station the route is unlisted at, got %d:\n%s", got, buf.String())
+	}
+}
+
+func TestProcessTripUpdateDelayUsesClock(t *testing.T) {
+	m := &Manager{clock: clock.NewFake(testNow)}
+
+	routeID := "A20241201"
+	stopID := "A27S"
+	delay := int32(120)
+	stations := map[string]*models.Station{
+		"A27": {ID: "A27", Name: "42 St-Port Authority Bus Terminal"},
+	}
+	tripUpdate := &gtfsrt.TripUpdate{
+		Trip: &gtfsrt.TripDescriptor{RouteId: &routeID},
+		StopTimeUpdate: []*gtfsrt.StopTimeUpdate{
+			{StopId: &stopID, Arrival: &gtfsrt.StopTimeEvent{Delay: &delay}},
+		},
+	}
+
+	if err := m.processTripUpdate(tripUpdate, stations, ""); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	trains := stations["A27"].Trains.South
+	if len(trains) != 1 {
+		t.Fatalf("Expected 1 southbound train, got %d", len(trains))
+	}
+	if want := testNow.Add(2 * time.Minute); !trains[0].Time.Equal(want) {
+		t.Errorf("Expected delay-based arrival %v, got %v", want, trains[0].Time)
+	}
+}
+
+func TestProcessTripUpdateStaticStopTimes(t *testing.T) {
+	m := &Manager{clock: clock.NewFake(testNow)}
+
+	stopTimesFile := filepath.Join(t.TempDir(), "stop_times.txt")
+	stopTimes := "trip_id,arrival_time,departure_time,stop_id,stop_sequence\n" +
+		"AFA23GEN-1038-Sunday-00_018000_1..N03R,03:01:00,03:01:00,128N,6\n" +
+		"AFA23GEN-1038-Sunday-00_018000_1..N03R,03:05:00,03:05:00,127N,7\n"
+	if err := os.WriteFile(stopTimesFile, []byte(stopTimes), 0644); err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := m.parseStopTimesFile(stopTimesFile)
+	if err != nil {
+		t.Fatalf("parseStopTimesFile: %v", err)
+	}
+	m.stopTimes = newStaticStopTimes(parsed)
+
+	// 03:05 on 2024-12-01 in New York
+	scheduled := testNow.Add(5 * time.Minute)
+	tripID := "018000_1..N03R"
+	routeID := "1"
+	stopID := "127N"
+
+	t.Run("time only", func(t *testing.T) {
+		arrival := scheduled.Add(2 * time.Minute).Unix()
+		stations := map[string]*models.Station{"127": {ID: "127", Name: "Times Sq-42 St"}}
+		tripUpdate := &gtfsrt.TripUpdate{
+			Trip: &gtfsrt.TripDescriptor{TripId: &tripID, RouteId: &routeID},
+			StopTimeUpdate: []*gtfsrt.StopTimeUpdate{
+				{StopId: &stopID, Arrival: &gtfsrt.StopTimeEvent{Time: &arrival}},
+			},
+		}
+		if err := m.processTripUpdate(tripUpdate, stations, ""); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		trains := stations["127"].Trains.North
+		if len(trains) != 1 {
+			t.Fatalf("Expected 1 northbound train, got %d", len(trains))
+		}
+		if trains[0].StopSequence != 7 {
+			t.Errorf("Expected static stop sequence 7, got %d", trains[0].StopSequence)
+		}
+		if trains[0].ScheduledTime == nil || !trains[0].ScheduledTime.Equal(scheduled) {
+			t.Errorf("Expected static scheduled time %v, got %v", scheduled, trains[0].ScheduledTime)
+		}
+	})
+
+	t.Run("delay only", func(t *testing.T) {
+		delay := int32(120)
+		startDate := "20241201"
+		stations := map[string]*models.Station{"127": {ID: "127", Name: "Times Sq-42 St"}}
+		tripUpdate := &gtfsrt.TripUpdate{
+			Trip: &gtfsrt.TripDescriptor{TripId: &tripID, RouteId: &routeID, StartDate: &startDate},
+			StopTimeUpdate: []*gtfsrt.StopTimeUpdate{
+				{StopId: &stopID, Arrival: &gtfsrt.StopTimeEvent{Delay: &delay}},
+			},
+		}
+		if err := m.processTripUpdate(tripUpdate, stations, ""); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		trains := stations["127"].Trains.North
+		if len(trains) != 1 {
+			t.Fatalf("Expected 1 northbound train, got %d", len(trains))
+		}
+		if want := scheduled.Add(2 * time.Minute); !trains[0].Time.Equal(want) {
+			t.Errorf("Expected scheduled time plus delay %v, got %v", want, trains[0].Time)
+		}
+		if trains[0].ScheduledTime == nil || !trains[0].ScheduledTime.Equal(scheduled) {
+			t.Errorf("Expected static scheduled time %v, got %v", scheduled, trains[0].ScheduledTime)
+		}
+	})
+}
+
+func TestProcessFeedSetsSource(t *testing.T) {
+	routeID := "N"
+	stopID := "R16N"
+	entityID := "1"
+	arrivalTime := testNow.Add(3 * time.Minute).Unix()
+
+	data, err := proto.Marshal(&gtfsrt.FeedMessage{
+		Header: &gtfsrt.FeedHeader{GtfsRealtimeVersion: proto.String("1.0")},
+		Entity: []*gtfsrt.FeedEntity{
+			{
+				Id: &entityID,
+				TripUpdate: &gtfsrt.TripUpdate{
+					Trip: &gtfsrt.TripDescriptor{RouteId: &routeID},
+					StopTimeUpdate: []*gtfsrt.StopTimeUpdate{
+						{StopId: &stopID, Arrival: &gtfsrt.StopTimeEvent{Time: &arrivalTime}},
+					},
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to marshal feed: %v", err)
+	}
+
+	m := &Manager{
+		clock:   clock.NewFake(testNow),
+		fetcher: &mapFetcher{data: map[string][]byte{FeedGroups["nqrw"]: data}},
+	}
+	stations := map[string]*models.Station{
+		"R16": {ID: "R16", Name: "Times Sq-42 St"},
+	}
+
+	if _, err := m.processFeed(FeedGroups["nqrw"], stations, nil); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	trains := stations["R16"].Trains.North
+	if len(trains) != 1 {
+		t.Fatalf("Expected 1 northbound train, got %d", len(trains))
+	}
+	if trains[0].Source != "nqrw" {
+		t.Errorf("Expected source nqrw, got %q", trains[0].Source)
+	}
+}
+
 func TestProcessAlert(t *testing.T) {
 	// Create a real store for the manager
 	s := store.NewStore()
-	m := &Manager{store: s}
+	m := &Manager{store: s, clock: clock.NewFake(testNow)}
 
 	headerText := "Service Alert"
 	descriptionText := "Delays on N line"
@@ -158,7 +607,7 @@ func TestProcessAlert(t *testing.T) {
 	}
 
 	// Process the alert
-	m.processAlert(alert)
+	m.processAlert("lmm:alert:1", alert)
 
 	// Verify the alert was processed
 	alerts := s.GetServiceAlerts()
@@ -179,3 +628,166 @@ func TestProcessAlert(t *testing.T) {
 		t.Errorf("Expected routes [N], got %v", processedAlert.Routes)
 	}
 }
+
+func TestPruneExpiredAlerts(t *testing.T) {
+	s := store.NewStore()
+	m := &Manager{store: s, clock: clock.NewFake(testNow)}
+
+	at := func(d time.Duration) *time.Time {
+		ts := testNow.Add(d)
+		return &ts
+	}
+
+	s.UpdateAlerts([]models.Alert{
+		{ID: "expired", ActivePeriods: []models.TimePeriod{{Start: at(-72 * time.Hour), End: at(-48 * time.Hour)}}},
+		{ID: "within-grace", ActivePeriods: []models.TimePeriod{{Start: at(-3 * time.Hour), End: at(-30 * time.Minute)}}},
+		{ID: "active", ActivePeriods: []models.TimePeriod{{Start: at(-time.Hour), End: at(time.Hour)}}},
+		{ID: "later-period", ActivePeriods: []models.TimePeriod{{End: at(-48 * time.Hour)}, {Start: at(24 * time.Hour), End: at(48 * time.Hour)}}},
+		{ID: "open-ended", ActivePeriods: []models.TimePeriod{{Start: at(-72 * time.Hour)}}},
+		{ID: "no-periods"},
+	})
+
+	m.pruneExpiredAlerts()
+
+	var ids []string
+	for _, alert := range s.GetServiceAlerts() {
+		ids = append(ids, alert.ID)
+	}
+
+	expected := []string{"within-grace", "active", "later-period", "open-ended", "no-periods"}
+	if len(ids) != len(expected) {
+		t.Fatalf("Expected alerts %v, got %v", expected, ids)
+	}
+	for i := range expected {
+		if ids[i] != expected[i] {
+			t.Errorf("Expected alert %s at %d, got %s", expected[i], i, ids[i])
+		}
+	}
+}
+
+func TestPruneAlertsPastMaxAge(t *testing.T) {
+	s := store.NewStore()
+	m := &Manager{store: s, clock: clock.NewFake(testNow)}
+	m.SetMaxAlertAge(6 * time.Hour)
+
+	s.UpdateAlerts([]models.Alert{
+		{ID: "stale", FirstSeen: testNow.Add(-7 * time.Hour)},
+		{ID: "stale-open-ended", FirstSeen: testNow.Add(-7 * time.Hour), ActivePeriods: []models.TimePeriod{{Start: &testNow}}},
+		{ID: "recent", FirstSeen: testNow.Add(-5 * time.Hour)},
+		// Alerts loaded without tracking are left to the active-period rules
+		{ID: "untracked"},
+	})
+
+	m.pruneExpiredAlerts()
+
+	var ids []string
+	for _, alert := range s.GetServiceAlerts() {
+		ids = append(ids, alert.ID)
+	}
+	if !slices.Equal(ids, []string{"recent", "untracked"}) {
+		t.Errorf("Expected [recent untracked], got %v", ids)
+	}
+
+	// processAlert stamps new alerts so the age limit applies to them
+	header := "Delays"
+	m.processAlert("lmm:alert:1", &gtfsrt.Alert{HeaderText: &gtfsrt.TranslatedString{
+		Translation: []*gtfsrt.TranslatedString_Translation{{Text: &header}},
+	}})
+	alerts := s.GetServiceAlerts()
+	if got := alerts[len(alerts)-1].FirstSeen; !got.Equal(testNow) {
+		t.Errorf("Expected FirstSeen %v, got %v", testNow, got)
+	}
+}
+
+func TestReseenAlertAgesOut(t *testing.T) {
+	s := store.NewStore()
+	fake := clock.NewFake(testNow)
+	m := &Manager{store: s, clock: fake}
+	m.SetMaxAlertAge(6 * time.Hour)
+
+	header := "Delays"
+	alert := &gtfsrt.Alert{HeaderText: &gtfsrt.TranslatedString{
+		Translation: []*gtfsrt.TranslatedString_Translation{{Text: &header}},
+	}}
+
+	// An open-ended alert the feed keeps reporting every cycle, hourly for 7 hours
+	for i := 0; i <= 7; i++ {
+		if err := m.processAlert("lmm:alert:1", alert); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if i < 7 {
+			m.pruneExpiredAlerts()
+			if alerts := s.GetServiceAlerts(); len(alerts) != 1 || !alerts[0].FirstSeen.Equal(testNow) {
+				t.Fatalf("Cycle %d: expected one alert first seen at %v, got %+v", i, testNow, alerts)
+			}
+			fake.Advance(time.Hour)
+		}
+	}
+
+	m.pruneExpiredAlerts()
+	if alerts := s.GetServiceAlerts(); len(alerts) != 0 {
+		t.Errorf("Expected the alert dropped after re-sightings past the maximum age, got %+v", alerts)
+	}
+
+	// The feed still reporting it doesn't bring it back with a fresh FirstSeen
+	fake.Advance(time.Hour)
+	if err := m.processAlert("lmm:alert:1", alert); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if alerts := s.GetServiceAlerts(); len(alerts) != 0 {
+		t.Errorf("Expected the pruned alert to stay pruned, got %+v", alerts)
+	}
+
+	// Once the feed stops reporting it long enough, a reissue counts as new
+	m.pruneExpiredAlerts()
+	fake.Advance(2 * alertExpiryGrace)
+	m.pruneExpiredAlerts()
+	if err := m.processAlert("lmm:alert:1", alert); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if alerts := s.GetServiceAlerts(); len(alerts) != 1 || !alerts[0].FirstSeen.Equal(fake.Now()) {
+		t.Errorf("Expected the reissued alert first seen now, got %+v", alerts)
+	}
+}
+
+func TestStaticReloadKeepsAlerts(t *testing.T) {
+	s := store.NewStore()
+	m := NewManager("test-key", s, time.Minute)
+	s.UpdateAlerts([]models.Alert{{ID: "lmm:alert:1", Header: "Delays"}})
+
+	if err := m.parseGTFSData(writeGTFSDir(t, goodGTFSFiles())); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if alerts := s.GetServiceAlerts(); len(alerts) != 1 || alerts[0].ID != "lmm:alert:1" {
+		t.Errorf("Expected real-time alerts kept across a static reload, got %+v", alerts)
+	}
+}
+
+func TestProcessAlertStopParents(t *testing.T) {
+	s := store.NewStore()
+	m := &Manager{store: s, clock: clock.NewFake(testNow)}
+	// A platform whose ID isn't its parent's plus N/S, as parent_station in stops.txt records it
+	m.platformParents = map[string]string{"H19": "H18"}
+
+	header := "Elevator outage"
+	platform, suffixed := "H19", "127N"
+	m.processAlert("lmm:alert:1", &gtfsrt.Alert{
+		HeaderText:     &gtfsrt.TranslatedString{Translation: []*gtfsrt.TranslatedString_Translation{{Text: &header}}},
+		InformedEntity: []*gtfsrt.EntitySelector{{StopId: &platform}, {StopId: &suffixed}},
+	})
+
+	alerts := s.GetServiceAlerts()
+	if len(alerts) != 1 || !slices.Equal(alerts[0].Stations, []string{"H18", "127"}) {
+		t.Errorf("Expected alert stations [H18 127], got %+v", alerts)
+	}
+}
+
+func TestAlertID(t *testing.T) {
+	if got := alertID("lmm:planned_work:1234", "Delays"); got != "lmm:planned_work:1234" {
+		t.Errorf("Expected the entity ID, got %q", got)
+	}
+	// Without an entity ID the header keys the alert, so it is still stable across cycles
+	if a, b := alertID("", "Delays"), alertID("", "Delays"); a != b || a == alertID("", "No service") {
+		t.Errorf("Expected a stable ID per header, got %q, %q", a, b)
+	}
+}
@@ -0,0 +1,37 @@
+package feed
+
+import "github.com/jusunglee/mta-go/internal/models"
+
+// headsignCounts tallies how many trips of each route show each headsign in each direction
+type headsignCounts map[string]map[models.Direction]map[string]int
+
+// add counts one trip of route heading in direction under headsign
+func (c headsignCounts) add(route string, direction models.Direction, headsign string) {
+	if c[route] == nil {
+		c[route] = make(map[models.Direction]map[string]int)
+	}
+	if c[route][direction] == nil {
+		c[route][direction] = make(map[string]int)
+	}
+	c[route][direction][headsign]++
+}
+
+// common picks route's most frequent headsign per direction, nil without any
+// Short turns and reroutes show other headsigns on a minority of trips; ties go to the alphabetically first
+// so the result doesn't depend on map order
+func (c headsignCounts) common(route string) map[models.Direction]string {
+	var result map[models.Direction]string
+	for direction, counts := range c[route] {
+		best, bestCount := "", 0
+		for headsign, count := range counts {
+			if count > bestCount || (count == bestCount && headsign < best) {
+				best, bestCount = headsign, count
+			}
+		}
+		if result == nil {
+			result = make(map[models.Direction]string)
+		}
+		result[direction] = best
+	}
+	return result
+}
@@ -0,0 +1,89 @@
+package feed
+
+import (
+	"sort"
+	"time"
+
+	"github.com/jusunglee/mta-go/internal/models"
+)
+
+// latencyAlpha weights the newest fetch in the moving average
+// 0.2 smooths over a single slow fetch while still tracking a sustained slowdown within a few cycles
+const latencyAlpha = 0.2
+
+// feedLatency holds fetch timing for a single feed, plus the GTFS-RT version it last declared
+type feedLatency struct {
+	last    time.Duration
+	average time.Duration
+	samples int
+	version string
+	parsed  bool // Whether version has been read yet; a feed may declare an empty one
+}
+
+// recordFetchLatency folds a successful fetch duration into the feed's moving average
+func (m *Manager) recordFetchLatency(url string, d time.Duration) {
+	m.latencyMu.Lock()
+	defer m.latencyMu.Unlock()
+
+	if m.latencies == nil {
+		m.latencies = make(map[string]*feedLatency)
+	}
+
+	stats, ok := m.latencies[url]
+	if !ok || stats.samples == 0 {
+		// Seed with the first sample so the average doesn't start from zero
+		if !ok {
+			stats = &feedLatency{}
+			m.latencies[url] = stats
+		}
+		stats.last, stats.average, stats.samples = d, d, 1
+		return
+	}
+
+	stats.last = d
+	stats.average = time.Duration(latencyAlpha*float64(d) + (1-latencyAlpha)*float64(stats.average))
+	stats.samples++
+}
+
+// GetFeedLatencies returns fetch timing for every feed fetched so far, sorted by feed name
+// Each feed carries its host's circuit breaker state and the outcome of its latest fetch. A feed that has never
+// been fetched successfully is still listed while its breaker isn't closed or it is rejecting the API key,
+// since that is exactly when an operator looks here
+func (m *Manager) GetFeedLatencies() []models.FeedLatency {
+	expected := m.realtimeVersion()
+
+	m.latencyMu.Lock()
+	defer m.latencyMu.Unlock()
+
+	result := make([]models.FeedLatency, 0, len(m.latencies))
+	for url, stats := range m.latencies {
+		result = append(result, models.FeedLatency{
+			Feed:              feedGroupName(url),
+			LastMs:            durationMs(stats.last),
+			AverageMs:         durationMs(stats.average),
+			Samples:           stats.samples,
+			Version:           stats.version,
+			UnexpectedVersion: stats.parsed && stats.version != expected,
+			Breaker:           m.breakerState(feedHost(url)),
+			Status:            m.access[url],
+		})
+	}
+	for _, url := range m.feedURLs {
+		if _, ok := m.latencies[url]; ok {
+			continue
+		}
+		state := m.breakerState(feedHost(url))
+		if state != breakerClosed || m.access[url] == feedStatusAuthFailed {
+			result = append(result, models.FeedLatency{Feed: feedGroupName(url), Breaker: state, Status: m.access[url]})
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Feed < result[j].Feed
+	})
+	return result
+}
+
+func durationMs(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -0,0 +1,137 @@
+package feed
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jusunglee/mta-go/internal/clock"
+	"github.com/jusunglee/mta-go/internal/gtfsrt"
+	"github.com/jusunglee/mta-go/internal/models"
+	"github.com/jusunglee/mta-go/internal/store"
+	"google.golang.org/protobuf/proto"
+)
+
+// sleepFetcher returns an empty payload after a fixed delay
+type sleepFetcher struct {
+	delay time.Duration
+}
+
+func (f *sleepFetcher) Fetch(ctx context.Context, url string) ([]byte, error) {
+	time.Sleep(f.delay)
+	return []byte{}, nil
+}
+
+func TestRecordFetchLatency(t *testing.T) {
+	m := &Manager{}
+	url := FeedGroups["ace"]
+
+	m.recordFetchLatency(url, 100*time.Millisecond)
+	m.recordFetchLatency(url, 200*time.Millisecond)
+
+	latencies := m.GetFeedLatencies()
+	if len(latencies) != 1 {
+		t.Fatalf("Expected 1 feed, got %d", len(latencies))
+	}
+
+	got := latencies[0]
+	if got.Feed != "ace" {
+		t.Errorf("Expected feed 'ace', got '%s'", got.Feed)
+	}
+	if got.LastMs != 200 {
+		t.Errorf("Expected last 200ms, got %v", got.LastMs)
+	}
+	// 0.2*200 + 0.8*100
+	if got.AverageMs != 120 {
+		t.Errorf("Expected average 120ms, got %v", got.AverageMs)
+	}
+	if got.Samples != 2 {
+		t.Errorf("Expected 2 samples, got %d", got.Samples)
+	}
+}
+
+func TestFetchFeedRecordsLatency(t *testing.T) {
+	const delay = 20 * time.Millisecond
+	m := &Manager{fetcher: &sleepFetcher{delay: delay}}
+	url := FeedGroups["l"]
+
+	for i := 0; i < 5; i++ {
+		if _, err := m.fetchFeed(url); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	latencies := m.GetFeedLatencies()
+	if len(latencies) != 1 {
+		t.Fatalf("Expected 1 feed, got %d", len(latencies))
+	}
+
+	got := latencies[0]
+	if got.Samples != 5 {
+		t.Errorf("Expected 5 samples, got %d", got.Samples)
+	}
+	// Sleeps never return early; the upper bound leaves room for a loaded CI machine
+	if got.AverageMs < 20 || got.AverageMs > 200 {
+		t.Errorf("Expected average between 20ms and 200ms, got %v", got.AverageMs)
+	}
+}
+
+func TestFetchFeedErrorNotRecorded(t *testing.T) {
+	m := &Manager{fetcher: &mapFetcher{}}
+
+	if _, err := m.fetchFeed(FeedGroups["g"]); err == nil {
+		t.Fatal("Expected error for unknown feed")
+	}
+	if latencies := m.GetFeedLatencies(); len(latencies) != 0 {
+		t.Errorf("Expected no latency recorded for failed fetch, got %+v", latencies)
+	}
+}
+
+func TestFeedVersionCheck(t *testing.T) {
+	feedWithVersion := func(version string) []byte {
+		data, err := proto.Marshal(&gtfsrt.FeedMessage{
+			Header: &gtfsrt.FeedHeader{GtfsRealtimeVersion: proto.String(version)},
+		})
+		if err != nil {
+			t.Fatalf("Failed to marshal feed: %v", err)
+		}
+		return data
+	}
+
+	m := NewManager("test-key", store.NewStore(), time.Minute)
+	m.SetClock(clock.NewFake(testNow))
+	m.SetFetcher(&mapFetcher{data: map[string][]byte{
+		FeedGroups["ace"]: feedWithVersion("1.0"),
+		FeedGroups["g"]:   feedWithVersion("3.0"),
+	}})
+	if err := m.SetFeedGroups([]string{"ace", "g"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// An unexpected version is flagged, not rejected
+	if err := m.updateRealTimeData(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if failures := m.Stats().Feeds; len(failures) != 2 || failures[0].Failures+failures[1].Failures != 0 {
+		t.Errorf("Expected both feeds to be processed, got %+v", failures)
+	}
+
+	byFeed := make(map[string]models.FeedLatency)
+	for _, info := range m.GetFeedLatencies() {
+		byFeed[info.Feed] = info
+	}
+	if info := byFeed["ace"]; info.Version != "1.0" || info.UnexpectedVersion {
+		t.Errorf("Expected ace at the expected version 1.0, got %+v", info)
+	}
+	if info := byFeed["g"]; info.Version != "3.0" || !info.UnexpectedVersion {
+		t.Errorf("Expected g flagged for version 3.0, got %+v", info)
+	}
+
+	// The expected version is configurable
+	m.SetExpectedRealtimeVersion("3.0")
+	for _, info := range m.GetFeedLatencies() {
+		if info.UnexpectedVersion != (info.Feed == "ace") {
+			t.Errorf("Expected only ace flagged once 3.0 is expected, got %+v", info)
+		}
+	}
+}
@@ -0,0 +1,397 @@
+package feed
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SetMergeStaticGTFS loads both the regular and supplemented static feeds and merges them
+// instead of preferring supplemented. The regular feed carries the base schedule and the
+// supplemented one the next seven days of changes, so merging keeps regular service the
+// supplemented feed doesn't mention. Takes effect on the next static GTFS download
+func (m *Manager) SetMergeStaticGTFS(enabled bool) {
+	m.mergeStaticGTFS = enabled
+}
+
+// mergeKeyColumns names the columns identifying a row of each file merged by key
+// A regular row is dropped when the supplemented feed has a row with the same key; shapes.txt
+// has many rows per shape_id, so a supplemented shape replaces the regular one whole
+var mergeKeyColumns = []struct {
+	name    string
+	columns []string
+}{
+	{"agency.txt", []string{"agency_id"}},
+	{"stops.txt", []string{"stop_id"}},
+	{"routes.txt", []string{"route_id"}},
+	{"shapes.txt", []string{"shape_id"}},
+	{"transfers.txt", []string{"from_stop_id", "to_stop_id"}},
+}
+
+// downloadMergedGTFS downloads both static feeds and merges the regular one into the supplemented one in dir
+// Either feed alone is used if the other fails to download, so merging never makes a load fail that the default would pass
+func (m *Manager) downloadMergedGTFS(dir, supplementedPath, regularPath string) error {
+	supplementedErr := m.downloadFile(GTFSSupplementedURL, supplementedPath)
+	regularErr := m.downloadFile(GTFSRegularURL, regularPath)
+	switch {
+	case supplementedErr != nil && regularErr != nil:
+		return fmt.Errorf("failed to download GTFS data: %w", supplementedErr)
+	case supplementedErr != nil:
+		slog.Warn("Failed to download supplemented GTFS, loading regular only", "error", supplementedErr)
+		if err := m.extractFresh(regularPath, dir); err != nil {
+			return fmt.Errorf("failed to extract GTFS data: %w", err)
+		}
+		return nil
+	case regularErr != nil:
+		slog.Warn("Failed to download regular GTFS, loading supplemented only", "error", regularErr)
+		if err := m.extractFresh(supplementedPath, dir); err != nil {
+			return fmt.Errorf("failed to extract GTFS data: %w", err)
+		}
+		return nil
+	}
+
+	if err := m.extractFresh(supplementedPath, dir); err != nil {
+		return fmt.Errorf("failed to extract GTFS data: %w", err)
+	}
+	regularDir := filepath.Join(m.gtfsDataDir, "extracted-regular")
+	if err := m.extractFresh(regularPath, regularDir); err != nil {
+		return fmt.Errorf("failed to extract GTFS data: %w", err)
+	}
+	if err := mergeGTFSDirs(dir, regularDir); err != nil {
+		return fmt.Errorf("failed to merge GTFS data: %w", err)
+	}
+	return nil
+}
+
+// mergeGTFSDirs merges the regular feed in regularDir into the supplemented feed in dir, in place
+// Supplemented service IDs override the regular feed's: regular calendar entries and trips on a
+// service ID the supplemented feed defines are dropped, along with those trips' stop times.
+// Regular trips on other services are kept with their stop times, and files only the regular feed
+// has, such as the fare files, are copied over whole
+func mergeGTFSDirs(dir, regularDir string) error {
+	calendar, err := readGTFSTable(filepath.Join(dir, "calendar.txt"))
+	if err != nil {
+		return err
+	}
+	calendarDates, err := readGTFSTable(filepath.Join(dir, "calendar_dates.txt"))
+	if err != nil {
+		return err
+	}
+	trips, err := readGTFSTable(filepath.Join(dir, "trips.txt"))
+	if err != nil {
+		return err
+	}
+	services := make(map[string]bool)
+	for _, table := range []*gtfsTable{calendar, calendarDates, trips} {
+		for key := range table.keys("service_id") {
+			services[key] = true
+		}
+	}
+	tripIDs := trips.keys("trip_id")
+
+	notSupplementedService := func(t *gtfsTable, row []string) bool {
+		return !services[t.value(row, "service_id")]
+	}
+	if err := mergeGTFSFile(dir, regularDir, "calendar.txt", notSupplementedService); err != nil {
+		return err
+	}
+	if err := mergeGTFSFile(dir, regularDir, "calendar_dates.txt", notSupplementedService); err != nil {
+		return err
+	}
+
+	regularTrips := make(map[string]bool)
+	err = mergeGTFSFile(dir, regularDir, "trips.txt", func(t *gtfsTable, row []string) bool {
+		tripID := t.value(row, "trip_id")
+		if services[t.value(row, "service_id")] || tripIDs[tripID] {
+			return false
+		}
+		regularTrips[tripID] = true
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	// stop_times.txt is by far the largest file, so it is streamed rather than loaded like the rest
+	err = streamGTFSFile(dir, regularDir, "stop_times.txt", func(t *gtfsTable, row []string) bool {
+		return regularTrips[t.value(row, "trip_id")]
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, file := range mergeKeyColumns {
+		supplemented, err := readGTFSTable(filepath.Join(dir, file.name))
+		if err != nil {
+			return err
+		}
+		keys := supplemented.keys(file.columns...)
+		err = mergeGTFSFile(dir, regularDir, file.name, func(t *gtfsTable, row []string) bool {
+			return !keys[t.key(row, file.columns...)]
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return copyMissingGTFSFiles(dir, regularDir)
+}
+
+// copyMissingGTFSFiles copies every file in regularDir that dir lacks
+func copyMissingGTFSFiles(dir, regularDir string) error {
+	entries, err := os.ReadDir(regularDir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+		dest := filepath.Join(dir, entry.Name())
+		if _, err := os.Stat(dest); !os.IsNotExist(err) {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(regularDir, entry.Name()))
+		if err != nil {
+			return err
+		}
+		if err := writeFileAtomic(dest, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mergeGTFSFile appends the regular rows of name that keep accepts to the supplemented file in dir
+// Columns are matched by name, since the two feeds don't always write the same columns or order
+func mergeGTFSFile(dir, regularDir, name string, keep func(t *gtfsTable, row []string) bool) error {
+	regular, err := readGTFSTable(filepath.Join(regularDir, name))
+	if err != nil {
+		return err
+	}
+	if regular.header == nil {
+		return nil
+	}
+	merged, err := readGTFSTable(filepath.Join(dir, name))
+	if err != nil {
+		return err
+	}
+	if merged.header == nil {
+		merged = &gtfsTable{header: regular.header, columns: regular.columns}
+	}
+	merged.addColumns(regular.header)
+
+	for _, row := range regular.rows {
+		if keep(regular, row) {
+			merged.rows = append(merged.rows, merged.remap(regular, row))
+		}
+	}
+	return merged.write(filepath.Join(dir, name))
+}
+
+// streamGTFSFile is mergeGTFSFile for files too large to hold in memory
+// The supplemented rows are copied a row at a time and the regular rows keep accepts streamed onto the end
+func streamGTFSFile(dir, regularDir, name string, keep func(t *gtfsTable, row []string) bool) error {
+	regular, err := openGTFSRows(filepath.Join(regularDir, name))
+	if err != nil {
+		return err
+	}
+	defer regular.Close()
+	if regular.header == nil {
+		return nil
+	}
+
+	path := filepath.Join(dir, name)
+	return writeFileAtomicFrom(path, func(out io.Writer) error {
+		// Closed before writeFileAtomicFrom renames over it
+		supplemented, err := openGTFSRows(path)
+		if err != nil {
+			return err
+		}
+		defer supplemented.Close()
+
+		merged := &supplemented.gtfsTable
+		if merged.header == nil {
+			merged = &gtfsTable{header: regular.header, columns: regular.columns}
+		}
+		merged.addColumns(regular.header)
+
+		w := csv.NewWriter(out)
+		if err := w.Write(merged.header); err != nil {
+			return err
+		}
+		err = supplemented.each(func(row []string) error {
+			return w.Write(row)
+		})
+		if err != nil {
+			return err
+		}
+		err = regular.each(func(row []string) error {
+			if !keep(&regular.gtfsTable, row) {
+				return nil
+			}
+			return w.Write(merged.remap(&regular.gtfsTable, row))
+		})
+		if err != nil {
+			return err
+		}
+		w.Flush()
+		return w.Error()
+	})
+}
+
+// gtfsTable is a whole GTFS CSV file held in memory for merging
+type gtfsTable struct {
+	header  []string
+	columns map[string]int
+	rows    [][]string
+}
+
+// readGTFSTable reads a GTFS CSV file; a missing file is an empty table with a nil header
+func readGTFSTable(path string) (*gtfsTable, error) {
+	rows, err := openGTFSRows(path)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	table := &rows.gtfsTable
+	err = rows.each(func(row []string) error {
+		table.rows = append(table.rows, row)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return table, nil
+}
+
+// gtfsRows is a GTFS CSV file read a row at a time; only the header is held in memory
+type gtfsRows struct {
+	gtfsTable
+	name   string
+	file   *os.File
+	reader *csv.Reader
+}
+
+// openGTFSRows opens a GTFS CSV file and reads its header; a missing or empty file has a nil header and no rows
+func openGTFSRows(path string) (*gtfsRows, error) {
+	rows := &gtfsRows{gtfsTable: gtfsTable{columns: map[string]int{}}, name: filepath.Base(path)}
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return rows, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	reader := newCSVReader(file)
+	header, err := reader.Read()
+	if err == io.EOF {
+		file.Close()
+		return rows, nil
+	}
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to read %s header: %w", rows.name, err)
+	}
+	rows.header, rows.columns = header, headerColumns(header)
+	rows.file, rows.reader = file, reader
+	return rows, nil
+}
+
+// each calls fn with every remaining row, stopping at the first error
+func (r *gtfsRows) each(fn func(row []string) error) error {
+	if r.reader == nil {
+		return nil
+	}
+	for {
+		row, err := r.reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", r.name, err)
+		}
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+}
+
+// Close closes the file, if there was one
+func (r *gtfsRows) Close() error {
+	if r.file == nil {
+		return nil
+	}
+	return r.file.Close()
+}
+
+// value returns row's value in column col, empty if the file or row lacks it
+func (t *gtfsTable) value(row []string, col string) string {
+	i, ok := t.columns[col]
+	if !ok || i >= len(row) {
+		return ""
+	}
+	return row[i]
+}
+
+// addColumns appends the columns of header that t lacks, so rows from another file fit
+func (t *gtfsTable) addColumns(header []string) {
+	for _, col := range header {
+		col = strings.TrimSpace(col)
+		if _, ok := t.columns[col]; !ok {
+			t.columns[col] = len(t.header)
+			t.header = append(t.header, col)
+		}
+	}
+}
+
+// remap rearranges row, a row of from, into t's columns
+func (t *gtfsTable) remap(from *gtfsTable, row []string) []string {
+	out := make([]string, len(t.header))
+	for col, i := range from.columns {
+		if i < len(row) {
+			out[t.columns[col]] = row[i]
+		}
+	}
+	return out
+}
+
+// key joins row's values in cols into one map key
+func (t *gtfsTable) key(row []string, cols ...string) string {
+	values := make([]string, len(cols))
+	for i, col := range cols {
+		values[i] = t.value(row, col)
+	}
+	return strings.Join(values, "\x00")
+}
+
+// keys returns the set of keys over cols of every row
+// Rows with none of cols set are left out, so a feed without service IDs doesn't claim the empty one
+func (t *gtfsTable) keys(cols ...string) map[string]bool {
+	keys := make(map[string]bool, len(t.rows))
+	empty := t.key(nil, cols...)
+	for _, row := range t.rows {
+		if key := t.key(row, cols...); key != empty {
+			keys[key] = true
+		}
+	}
+	return keys
+}
+
+// write replaces path with the table as CSV
+func (t *gtfsTable) write(path string) error {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if err := w.Write(t.header); err != nil {
+		return err
+	}
+	if err := w.WriteAll(t.rows); err != nil {
+		return err
+	}
+	return writeFileAtomic(path, buf.Bytes())
+}
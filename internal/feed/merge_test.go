@@ -0,0 +1,182 @@
+package feed
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/jusunglee/mta-go/internal/store"
+)
+
+// mergeFixtures returns a regular feed running the 1 and 6 on Weekday and Sunday service, and a
+// supplemented feed replacing Weekday service with a 1 that only runs northbound
+func mergeFixtures() (regular, supplemented map[string]string) {
+	regular = goodGTFSFiles()
+	regular["calendar.txt"] = "service_id,monday,sunday\nWeekday,1,0\nSunday,0,1\n"
+	regular["trips.txt"] = "route_id,service_id,trip_id\n" +
+		"1,Weekday,T1\n" +
+		"6,Sunday,T6\n"
+	regular["stop_times.txt"] = "trip_id,stop_id,stop_sequence\n" +
+		"T1,127N,1\n" +
+		"T1,127S,2\n" +
+		"T6,631N,1\n"
+	regular["fare_attributes.txt"] = "fare_id,price,currency_type\nbase,2.90,USD\n"
+
+	supplemented = map[string]string{
+		"calendar.txt": "service_id,monday,sunday\nWeekday,1,0\n",
+		// Columns reordered and 631 left out: the merge matches columns by name and keeps regular-only stops
+		"stops.txt": "stop_id,parent_station,location_type,stop_name,stop_lat,stop_lon\n" +
+			"127,,1,Times Sq-42 St,40.75529,-73.987495\n" +
+			"127N,127,,Times Sq-42 St,40.75529,-73.987495\n",
+		"routes.txt": "route_id,route_short_name,route_long_name\n" +
+			"1,1,Broadway - 7 Avenue Local (supplemented)\n",
+		"trips.txt": "route_id,service_id,trip_id\n" +
+			"1,Weekday,T1-GO\n",
+		"stop_times.txt": "trip_id,stop_id,stop_sequence\n" +
+			"T1-GO,127N,1\n",
+	}
+	return regular, supplemented
+}
+
+func TestMergeGTFSDirs(t *testing.T) {
+	regular, supplemented := mergeFixtures()
+	dir := writeGTFSDir(t, supplemented)
+	if err := mergeGTFSDirs(dir, writeGTFSDir(t, regular)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	read := func(name string) [][]string {
+		table, err := readGTFSTable(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatalf("Failed to read merged %s: %v", name, err)
+		}
+		return append([][]string{table.header}, table.rows...)
+	}
+
+	tests := []struct {
+		name     string
+		expected [][]string
+	}{
+		// Regular Weekday service is replaced; Sunday service the supplemented feed doesn't define is kept
+		{"calendar.txt", [][]string{{"service_id", "monday", "sunday"}, {"Weekday", "1", "0"}, {"Sunday", "0", "1"}}},
+		{"trips.txt", [][]string{{"route_id", "service_id", "trip_id"}, {"1", "Weekday", "T1-GO"}, {"6", "Sunday", "T6"}}},
+		{"stop_times.txt", [][]string{{"trip_id", "stop_id", "stop_sequence"}, {"T1-GO", "127N", "1"}, {"T6", "631N", "1"}}},
+		{"routes.txt", [][]string{
+			{"route_id", "route_short_name", "route_long_name"},
+			{"1", "1", "Broadway - 7 Avenue Local (supplemented)"},
+			{"6", "6", "Lexington Avenue Local"},
+		}},
+		{"stops.txt", [][]string{
+			{"stop_id", "parent_station", "location_type", "stop_name", "stop_lat", "stop_lon"},
+			{"127", "", "1", "Times Sq-42 St", "40.75529", "-73.987495"},
+			{"127N", "127", "", "Times Sq-42 St", "40.75529", "-73.987495"},
+			{"127S", "127", "", "Times Sq-42 St", "40.75529", "-73.987495"},
+			{"631", "", "1", "Grand Central-42 St", "40.751776", "-73.976848"},
+			{"631N", "631", "", "Grand Central-42 St", "40.751776", "-73.976848"},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := read(tt.name); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+
+	t.Run("files only in regular are taken whole", func(t *testing.T) {
+		for _, name := range []string{"shapes.txt", "fare_attributes.txt"} {
+			data, err := os.ReadFile(filepath.Join(dir, name))
+			if err != nil {
+				t.Errorf("Expected %s from regular GTFS: %v", name, err)
+			} else if string(data) != regular[name] {
+				t.Errorf("Expected %s copied unchanged, got %q", name, data)
+			}
+		}
+	})
+}
+
+func TestStreamGTFSFile(t *testing.T) {
+	regular := map[string]string{
+		"stop_times.txt": "stop_sequence,arrival_time,trip_id,stop_id\n" +
+			"1,08:00:00,T1,127N\n" +
+			"1,08:10:00,T6,631N\n",
+	}
+	keep := func(t *gtfsTable, row []string) bool { return t.value(row, "trip_id") == "T6" }
+
+	t.Run("appended in supplemented columns", func(t *testing.T) {
+		dir := writeGTFSDir(t, map[string]string{"stop_times.txt": "trip_id,stop_id,stop_sequence\nT1-GO,127N,1\n"})
+		if err := streamGTFSFile(dir, writeGTFSDir(t, regular), "stop_times.txt", keep); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		data, err := os.ReadFile(filepath.Join(dir, "stop_times.txt"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Columns only the regular feed has are added after the supplemented ones
+		expected := "trip_id,stop_id,stop_sequence,arrival_time\nT1-GO,127N,1\nT6,631N,1,08:10:00\n"
+		if string(data) != expected {
+			t.Errorf("Expected %q, got %q", expected, data)
+		}
+	})
+
+	t.Run("missing supplemented file takes regular columns", func(t *testing.T) {
+		dir := t.TempDir()
+		if err := streamGTFSFile(dir, writeGTFSDir(t, regular), "stop_times.txt", keep); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		data, err := os.ReadFile(filepath.Join(dir, "stop_times.txt"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected := "stop_sequence,arrival_time,trip_id,stop_id\n1,08:10:00,T6,631N\n"; string(data) != expected {
+			t.Errorf("Expected %q, got %q", expected, data)
+		}
+	})
+}
+
+func TestLoadMergedStaticGTFS(t *testing.T) {
+	regular, supplemented := mergeFixtures()
+
+	s := store.NewStore()
+	m := NewManager("test-key", s, time.Minute)
+	fetcher := &mapFetcher{data: map[string][]byte{
+		GTFSSupplementedURL: zipGTFS(t, supplemented),
+		GTFSRegularURL:      zipGTFS(t, regular),
+	}}
+	m.SetFetcher(fetcher)
+	m.SetGTFSDataDir(t.TempDir())
+	m.SetMergeStaticGTFS(true)
+
+	if err := m.loadStaticGTFSData(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !slices.Contains(fetcher.urls(), GTFSRegularURL) {
+		t.Errorf("Expected regular GTFS to be downloaded, got requests %v", fetcher.urls())
+	}
+
+	// The supplemented 1 only runs northbound, overriding the regular trip that served both platforms
+	stations, err := s.GetStationsByIDs([]string{"127", "631"})
+	if err != nil {
+		t.Fatalf("Expected stations from both feeds: %v", err)
+	}
+	if got := stations[0].OneWayRoutes["1"]; got != "N" {
+		t.Errorf("Expected the supplemented 1 trip to win at Times Sq, got one-way routes %v", stations[0].OneWayRoutes)
+	}
+	if !slices.Equal(stations[1].Routes, []string{"6"}) {
+		t.Errorf("Expected the regular-only 6 kept at Grand Central, got %v", stations[1].Routes)
+	}
+	info, err := s.GetRouteInfo("1")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if info.LongName != "Broadway - 7 Avenue Local (supplemented)" {
+		t.Errorf("Expected route info from supplemented GTFS, got %q", info.LongName)
+	}
+	if fares := s.GetFares("", ""); len(fares) != 1 || fares[0].ID != "base" {
+		t.Errorf("Expected fares from the regular-only fare_attributes.txt, got %+v", fares)
+	}
+}
@@ -0,0 +1,80 @@
+package feed
+
+import (
+	"github.com/jusunglee/mta-go/internal/gtfsrt"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Field numbers from proto/nyct_subway.proto
+// The generated gtfsrt package doesn't know the NYCT extensions, so protobuf keeps them as
+// unknown fields and they are decoded here by hand instead of generating code for one bool
+const (
+	nyctTripDescriptorField protowire.Number = 1001 // NyctTripDescriptor on TripDescriptor
+	nyctIsAssignedField     protowire.Number = 2    // NyctTripDescriptor.is_assigned
+	nyctStopTimeUpdateField protowire.Number = 1001 // NyctStopTimeUpdate on StopTimeUpdate
+	nyctScheduledTrackField protowire.Number = 1    // NyctStopTimeUpdate.scheduled_track
+	nyctActualTrackField    protowire.Number = 2    // NyctStopTimeUpdate.actual_track
+)
+
+// tripAssigned reports whether NYCT has put a physical train on the trip
+// Trips without the NYCT extension, as in other agencies' feeds, count as assigned:
+// there is nothing to say the prediction is any less reliable than usual. A descriptor
+// that leaves out is_assigned takes the proto2 default of false
+func tripAssigned(trip *gtfsrt.TripDescriptor) bool {
+	descriptor, ok := unknownField(trip.ProtoReflect().GetUnknown(), nyctTripDescriptorField, protowire.BytesType)
+	if !ok {
+		return true
+	}
+	descriptor, _ = protowire.ConsumeBytes(descriptor)
+
+	value, ok := unknownField(descriptor, nyctIsAssignedField, protowire.VarintType)
+	if !ok {
+		return false
+	}
+	assigned, _ := protowire.ConsumeVarint(value)
+	return protowire.DecodeBool(assigned)
+}
+
+// stopTracks returns the NYCT scheduled and actual track for a stop, empty when the feed doesn't say
+// actual_track is only filled in once the train's route through the interlocking is set
+func stopTracks(update *gtfsrt.StopTimeUpdate) (scheduled, actual string) {
+	ext, ok := unknownField(update.ProtoReflect().GetUnknown(), nyctStopTimeUpdateField, protowire.BytesType)
+	if !ok {
+		return "", ""
+	}
+	ext, _ = protowire.ConsumeBytes(ext)
+
+	if value, ok := unknownField(ext, nyctScheduledTrackField, protowire.BytesType); ok {
+		track, _ := protowire.ConsumeBytes(value)
+		scheduled = string(track)
+	}
+	if value, ok := unknownField(ext, nyctActualTrackField, protowire.BytesType); ok {
+		track, _ := protowire.ConsumeBytes(value)
+		actual = string(track)
+	}
+	return scheduled, actual
+}
+
+// unknownField returns the encoded value of the last occurrence of field num in raw wire data
+// The last occurrence wins, matching how protobuf merges a repeated non-repeated field
+func unknownField(raw []byte, num protowire.Number, typ protowire.Type) ([]byte, bool) {
+	var found []byte
+	ok := false
+	for len(raw) > 0 {
+		fieldNum, fieldType, n := protowire.ConsumeTag(raw)
+		if n < 0 {
+			return nil, false
+		}
+		raw = raw[n:]
+
+		n = protowire.ConsumeFieldValue(fieldNum, fieldType, raw)
+		if n < 0 {
+			return nil, false
+		}
+		if fieldNum == num && fieldType == typ {
+			found, ok = raw[:n], true
+		}
+		raw = raw[n:]
+	}
+	return found, ok
+}
@@ -0,0 +1,156 @@
+package feed
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jusunglee/mta-go/internal/clock"
+	"github.com/jusunglee/mta-go/internal/gtfsrt"
+	"github.com/jusunglee/mta-go/internal/models"
+	"github.com/jusunglee/mta-go/internal/store"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+)
+
+// withNyctAssigned adds a NyctTripDescriptor extension carrying is_assigned to trip
+func withNyctAssigned(trip *gtfsrt.TripDescriptor, assigned bool) *gtfsrt.TripDescriptor {
+	descriptor := nyctTrainID()
+	descriptor = protowire.AppendTag(descriptor, nyctIsAssignedField, protowire.VarintType)
+	descriptor = protowire.AppendVarint(descriptor, protowire.EncodeBool(assigned))
+	return withNyctDescriptor(trip, descriptor)
+}
+
+// nyctTrainID encodes a NyctTripDescriptor holding only a train_id
+func nyctTrainID() []byte {
+	descriptor := protowire.AppendTag(nil, 1, protowire.BytesType) // train_id
+	return protowire.AppendString(descriptor, "06 0123+ PEL/BBR")
+}
+
+// withNyctDescriptor sets trip's unknown fields to a NyctTripDescriptor extension holding descriptor
+func withNyctDescriptor(trip *gtfsrt.TripDescriptor, descriptor []byte) *gtfsrt.TripDescriptor {
+	var raw []byte
+	raw = protowire.AppendTag(raw, nyctTripDescriptorField, protowire.BytesType)
+	raw = protowire.AppendBytes(raw, descriptor)
+	trip.ProtoReflect().SetUnknown(raw)
+	return trip
+}
+
+func TestTripAssigned(t *testing.T) {
+	tripUpdate := func(id, stopID string, trip *gtfsrt.TripDescriptor) *gtfsrt.FeedEntity {
+		return &gtfsrt.FeedEntity{Id: proto.String(id), TripUpdate: &gtfsrt.TripUpdate{
+			Trip: trip,
+			StopTimeUpdate: []*gtfsrt.StopTimeUpdate{{
+				StopId:  proto.String(stopID),
+				Arrival: &gtfsrt.StopTimeEvent{Time: proto.Int64(testNow.Add(2 * time.Minute).Unix())},
+			}},
+		}}
+	}
+	descriptor := func(tripID string) *gtfsrt.TripDescriptor {
+		return &gtfsrt.TripDescriptor{TripId: proto.String(tripID), RouteId: proto.String("6")}
+	}
+
+	// Round-trip through the wire format so the extension arrives as unknown fields, as it does from the MTA
+	data, err := proto.Marshal(&gtfsrt.FeedMessage{
+		Header: &gtfsrt.FeedHeader{GtfsRealtimeVersion: proto.String("1.0")},
+		Entity: []*gtfsrt.FeedEntity{
+			tripUpdate("1", "631N", withNyctAssigned(descriptor("ASSIGNED"), true)),
+			tripUpdate("2", "631S", withNyctAssigned(descriptor("UNASSIGNED"), false)),
+			tripUpdate("3", "635N", descriptor("NO_EXTENSION")),
+			tripUpdate("4", "635S", withNyctDescriptor(descriptor("NO_IS_ASSIGNED"), nyctTrainID())),
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to marshal feed: %v", err)
+	}
+
+	s := store.NewStore()
+	s.UpdateStations(map[string]*models.Station{
+		"631": {ID: "631", Name: "Grand Central-42 St", Routes: []string{"6"}},
+		"635": {ID: "635", Name: "14 St-Union Sq", Routes: []string{"6"}},
+	})
+	m := NewManager("test-key", s, time.Minute)
+	m.SetClock(clock.NewFake(testNow))
+	m.SetFetcher(&mapFetcher{data: map[string][]byte{FeedGroups["1234567"]: data}})
+	if err := m.SetFeedGroups([]string{"1234567"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if err := m.updateRealTimeData(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	stations, err := s.GetStationsByIDs([]string{"631", "635"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		trains   []models.Train
+		expected bool
+	}{
+		{"extension assigned", stations[0].Trains.North, true},
+		{"extension unassigned", stations[0].Trains.South, false},
+		{"no extension defaults to assigned", stations[1].Trains.North, true},
+		{"extension without is_assigned is unassigned", stations[1].Trains.South, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if len(tt.trains) != 1 {
+				t.Fatalf("Expected 1 train, got %v", tt.trains)
+			}
+			if tt.trains[0].Assigned != tt.expected {
+				t.Errorf("Expected assigned %v, got %v", tt.expected, tt.trains[0].Assigned)
+			}
+		})
+	}
+}
+
+func TestStopTracks(t *testing.T) {
+	withTracks := func(scheduled, actual string) *gtfsrt.StopTimeUpdate {
+		var ext []byte
+		if scheduled != "" {
+			ext = protowire.AppendTag(ext, nyctScheduledTrackField, protowire.BytesType)
+			ext = protowire.AppendString(ext, scheduled)
+		}
+		if actual != "" {
+			ext = protowire.AppendTag(ext, nyctActualTrackField, protowire.BytesType)
+			ext = protowire.AppendString(ext, actual)
+		}
+		var raw []byte
+		raw = protowire.AppendTag(raw, nyctStopTimeUpdateField, protowire.BytesType)
+		raw = protowire.AppendBytes(raw, ext)
+
+		update := &gtfsrt.StopTimeUpdate{StopId: proto.String("631N")}
+		update.ProtoReflect().SetUnknown(raw)
+
+		// Round-trip so the extension is read back the way it arrives from the MTA
+		data, err := proto.Marshal(update)
+		if err != nil {
+			t.Fatalf("Failed to marshal stop time update: %v", err)
+		}
+		decoded := &gtfsrt.StopTimeUpdate{}
+		if err := proto.Unmarshal(data, decoded); err != nil {
+			t.Fatalf("Failed to unmarshal stop time update: %v", err)
+		}
+		return decoded
+	}
+
+	tests := []struct {
+		name              string
+		update            *gtfsrt.StopTimeUpdate
+		scheduled, actual string
+	}{
+		{"both tracks", withTracks("1", "2"), "1", "2"},
+		{"scheduled only", withTracks("3", ""), "3", ""},
+		{"no extension", &gtfsrt.StopTimeUpdate{StopId: proto.String("631N")}, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scheduled, actual := stopTracks(tt.update)
+			if scheduled != tt.scheduled || actual != tt.actual {
+				t.Errorf("Expected tracks %q/%q, got %q/%q", tt.scheduled, tt.actual, scheduled, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package feed
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+
+	"github.com/jusunglee/mta-go/internal/models"
+)
+
+// StationOverride patches static fields of one station after GTFS parsing
+// Nil fields are left as GTFS has them, so an entry only needs the fields it corrects
+type StationOverride struct {
+	Name     *string          `json:"name,omitempty"`
+	Location *models.Location `json:"location,omitempty"`
+	Routes   []string         `json:"routes,omitempty"`
+}
+
+// LoadStationOverrides reads a JSON object mapping station IDs to StationOverride entries
+// Read once at startup so a malformed file fails fast rather than on a background static refresh
+func LoadStationOverrides(path string) (map[string]StationOverride, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read station overrides: %w", err)
+	}
+
+	var overrides map[string]StationOverride
+	if err := json.Unmarshal(data, &overrides); err != nil {
+		return nil, fmt.Errorf("failed to parse station overrides %s: %w", path, err)
+	}
+	return overrides, nil
+}
+
+// SetStationOverrides sets the patches applied to stations on every static GTFS load
+func (m *Manager) SetStationOverrides(overrides map[string]StationOverride) {
+	m.stationOverrides = overrides
+}
+
+// applyStationOverrides patches parsed stations in place before they reach the store
+// IDs GTFS doesn't know are skipped with a warning; a renumbered station shouldn't block the load
+func applyStationOverrides(stations map[string]*models.Station, overrides map[string]StationOverride) {
+	for id, override := range overrides {
+		station, ok := stations[id]
+		if !ok {
+			slog.Warn("Station override for unknown station ignored", "station", id)
+			continue
+		}
+		if override.Name != nil {
+			station.Name = *override.Name
+		}
+		if override.Location != nil {
+			station.Location = *override.Location
+		}
+		if override.Routes != nil {
+			station.Routes = append([]string(nil), override.Routes...)
+			// Directions for routes the override removed would describe service that isn't listed
+			for route := range station.OneWayRoutes {
+				if !slices.Contains(station.Routes, route) {
+					delete(station.OneWayRoutes, route)
+				}
+			}
+		}
+	}
+}
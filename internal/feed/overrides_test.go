@@ -0,0 +1,78 @@
+package feed
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jusunglee/mta-go/internal/models"
+	"github.com/jusunglee/mta-go/internal/store"
+)
+
+func TestStationOverridesApplied(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "overrides.json")
+	overridesJSON := `{
+		"127": {"name": "Times Square"},
+		"631": {"location": {"lat": 40.7527, "lon": -73.9772}, "routes": ["4", "5", "6", "7", "S"]},
+		"999": {"name": "Nowhere"}
+	}`
+	if err := os.WriteFile(path, []byte(overridesJSON), 0644); err != nil {
+		t.Fatalf("Failed to write overrides: %v", err)
+	}
+
+	overrides, err := LoadStationOverrides(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	s := store.NewStore()
+	m := NewManager("test-key", s, time.Minute)
+	m.SetStationOverrides(overrides)
+
+	// The unknown ID 999 is only a warning, so the load still succeeds
+	if err := m.parseGTFSData(writeGTFSDir(t, goodGTFSFiles())); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	stations, err := s.GetStationsByIDs([]string{"127", "631"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	timesSq, grandCentral := stations[0], stations[1]
+
+	if timesSq.Name != "Times Square" {
+		t.Errorf("Expected overridden name Times Square, got %q", timesSq.Name)
+	}
+	if timesSq.Location != (models.Location{Lat: 40.75529, Lon: -73.987495}) || !reflect.DeepEqual(timesSq.Routes, []string{"1"}) {
+		t.Errorf("Expected fields without overrides to keep GTFS values, got %+v", timesSq)
+	}
+
+	if grandCentral.Name != "Grand Central-42 St" {
+		t.Errorf("Expected GTFS name to be kept, got %q", grandCentral.Name)
+	}
+	if grandCentral.Location != (models.Location{Lat: 40.7527, Lon: -73.9772}) {
+		t.Errorf("Expected overridden location, got %+v", grandCentral.Location)
+	}
+	// Overridden routes feed the store's route index like GTFS-derived ones
+	byRoute, err := s.GetStationsByRoute("7")
+	if err != nil || len(byRoute) != 1 || byRoute[0].ID != "631" {
+		t.Errorf("Expected 631 indexed under route 7, got %v (err %v)", byRoute, err)
+	}
+}
+
+func TestLoadStationOverridesErrors(t *testing.T) {
+	dir := t.TempDir()
+	bad := filepath.Join(dir, "bad.json")
+	if err := os.WriteFile(bad, []byte(`{"127": {"name": 42}}`), 0644); err != nil {
+		t.Fatalf("Failed to write overrides: %v", err)
+	}
+
+	if _, err := LoadStationOverrides(bad); err == nil {
+		t.Error("Expected error for malformed overrides")
+	}
+	if _, err := LoadStationOverrides(filepath.Join(dir, "missing.json")); err == nil {
+		t.Error("Expected error for missing overrides file")
+	}
+}
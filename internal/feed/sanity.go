@@ -0,0 +1,80 @@
+package feed
+
+import (
+	"fmt"
+
+	"github.com/jusunglee/mta-go/internal/models"
+)
+
+// minStaticRetainRatio is the share of the current stations and routes a static reload must keep
+// A real service change adds or drops a handful; losing half points at a truncated or corrupt download
+const minStaticRetainRatio = 0.5
+
+// minStaticStations and minStaticRoutes are absolute floors for reloading a full system
+// The subway has about 500 stations and 25 routes, so a reload at or under these is broken however the
+// ratio works out. They only apply once more than that is loaded, leaving small feeds and fixtures alone
+const (
+	minStaticStations = 100
+	minStaticRoutes   = 10
+)
+
+// staticBoundsMarginDeg widens the current bounds before comparing, so new stations at the edge
+// of the system (roughly 10km) don't count as a mismatch
+const staticBoundsMarginDeg = 0.1
+
+// checkStaticSanity decides whether freshly parsed stations may replace what the store holds
+// The first load only has to be non-empty; later loads are compared against the current data,
+// since a fixed minimum would have to know how big every feed is, and against the absolute floors
+func (m *Manager) checkStaticSanity(stations map[string]*models.Station) error {
+	if len(stations) == 0 {
+		return fmt.Errorf("parsed no stations")
+	}
+
+	currentStations, currentRoutes, _ := m.store.Counts()
+	if currentStations == 0 {
+		return nil
+	}
+
+	if need := int(float64(currentStations) * minStaticRetainRatio); len(stations) < need {
+		return fmt.Errorf("parsed %d stations, fewer than %d (half of the %d loaded)", len(stations), need, currentStations)
+	}
+	if currentStations > minStaticStations && len(stations) <= minStaticStations {
+		return fmt.Errorf("parsed %d stations, no more than the minimum of %d", len(stations), minStaticStations)
+	}
+
+	routes := make(map[string]bool)
+	for _, station := range stations {
+		for _, route := range station.Routes {
+			routes[route] = true
+		}
+	}
+	if need := int(float64(currentRoutes) * minStaticRetainRatio); len(routes) < need {
+		return fmt.Errorf("parsed %d routes, fewer than %d (half of the %d loaded)", len(routes), need, currentRoutes)
+	}
+	if currentRoutes > minStaticRoutes && len(routes) <= minStaticRoutes {
+		return fmt.Errorf("parsed %d routes, no more than the minimum of %d", len(routes), minStaticRoutes)
+	}
+
+	minLat, minLon, maxLat, maxLon, ok := m.store.GetBounds()
+	if !ok {
+		return nil
+	}
+	current := models.Bounds{
+		MinLat: minLat - staticBoundsMarginDeg,
+		MinLon: minLon - staticBoundsMarginDeg,
+		MaxLat: maxLat + staticBoundsMarginDeg,
+		MaxLon: maxLon + staticBoundsMarginDeg,
+	}
+	outside := 0
+	for _, station := range stations {
+		loc := station.Location
+		if loc.Lat < current.MinLat || loc.Lat > current.MaxLat || loc.Lon < current.MinLon || loc.Lon > current.MaxLon {
+			outside++
+		}
+	}
+	// Swapped or zeroed coordinates move most stations at once; a new line moves a few
+	if float64(outside) > float64(len(stations))*(1-minStaticRetainRatio) {
+		return fmt.Errorf("%d of %d parsed stations lie outside the current bounds", outside, len(stations))
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+package feed
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jusunglee/mta-go/internal/models"
+	"github.com/jusunglee/mta-go/internal/store"
+)
+
+func TestStaticReloadSanityCheck(t *testing.T) {
+	// A loaded system of ten midtown stations across four routes
+	loaded := func() *store.Store {
+		s := store.NewStore()
+		stations := make(map[string]*models.Station)
+		for i := 0; i < 10; i++ {
+			id := fmt.Sprintf("S%02d", i)
+			stations[id] = &models.Station{
+				ID:       id,
+				Name:     id,
+				Location: models.Location{Lat: 40.74 + float64(i)*0.002, Lon: -73.99 + float64(i)*0.002},
+				Routes:   []string{[]string{"1", "6", "A", "N"}[i%4]},
+			}
+		}
+		s.UpdateStations(stations)
+		return s
+	}
+
+	swapped := goodGTFSFiles()
+	swapped["stops.txt"] = "stop_id,stop_name,stop_lat,stop_lon,location_type,parent_station\n" +
+		"127,Times Sq-42 St,-73.987495,40.75529,1,\n" +
+		"631,Grand Central-42 St,-73.976848,40.751776,1,\n"
+
+	tests := []struct {
+		name    string
+		store   *store.Store
+		files   map[string]string
+		wantErr string
+	}{
+		{"first load only needs stations", store.NewStore(), goodGTFSFiles(), ""},
+		{"too few stations rejected", loaded(), goodGTFSFiles(), "stations"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := len(tt.store.GetAllStations())
+			m := NewManager("test-key", tt.store, time.Minute)
+
+			err := m.parseGTFSData(writeGTFSDir(t, tt.files))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				return
+			}
+
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Expected sanity error mentioning %q, got %v", tt.wantErr, err)
+			}
+			if after := len(tt.store.GetAllStations()); after != before {
+				t.Errorf("Expected the previous %d stations to be kept, got %d", before, after)
+			}
+		})
+	}
+
+	t.Run("bounds mismatch rejected", func(t *testing.T) {
+		s := store.NewStore()
+		m := NewManager("test-key", s, time.Minute)
+		// Swapped coordinates are also outside the service area; turn that off to reach the reload check
+		m.SetServiceArea(nil)
+		if err := m.parseGTFSData(writeGTFSDir(t, goodGTFSFiles())); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		err := m.parseGTFSData(writeGTFSDir(t, swapped))
+		if err == nil || !strings.Contains(err.Error(), "outside the current bounds") {
+			t.Fatalf("Expected a bounds error, got %v", err)
+		}
+		stations, err := s.GetStationsByIDs([]string{"127"})
+		if err != nil || stations[0].Location.Lat != 40.75529 {
+			t.Errorf("Expected the original Times Sq location to be kept, got %v (err %v)", stations, err)
+		}
+	})
+
+	t.Run("absolute floors", func(t *testing.T) {
+		// stationSet builds n stations spread over the given number of routes
+		stationSet := func(n, routes int) map[string]*models.Station {
+			stations := make(map[string]*models.Station, n)
+			for i := range n {
+				id := fmt.Sprintf("S%03d", i)
+				stations[id] = &models.Station{
+					ID:       id,
+					Location: models.Location{Lat: 40.70 + float64(i)*0.001, Lon: -73.99},
+					Routes:   []string{fmt.Sprintf("R%d", i%routes)},
+				}
+			}
+			return stations
+		}
+
+		floorTests := []struct {
+			name    string
+			parsed  map[string]*models.Station
+			wantErr string
+		}{
+			// Both keep over half of the 150 stations and 12 routes loaded
+			{"stations at the floor rejected", stationSet(minStaticStations, 12), "minimum of 100"},
+			{"routes at the floor rejected", stationSet(140, minStaticRoutes), "minimum of 10"},
+			{"above both floors accepted", stationSet(minStaticStations+1, minStaticRoutes+1), ""},
+		}
+		for _, tt := range floorTests {
+			t.Run(tt.name, func(t *testing.T) {
+				s := store.NewStore()
+				s.UpdateStations(stationSet(150, 12))
+				m := NewManager("test-key", s, time.Minute)
+
+				err := m.checkStaticSanity(tt.parsed)
+				if tt.wantErr == "" {
+					if err != nil {
+						t.Errorf("Unexpected error: %v", err)
+					}
+				} else if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Expected an error mentioning %q, got %v", tt.wantErr, err)
+				}
+			})
+		}
+
+		// A store already under the floors only gets the ratio check
+		s := store.NewStore()
+		s.UpdateStations(stationSet(20, 4))
+		if err := NewManager("test-key", s, time.Minute).checkStaticSanity(stationSet(15, 4)); err != nil {
+			t.Errorf("Expected a small system's reload accepted, got %v", err)
+		}
+	})
+
+	t.Run("ordinary reload accepted", func(t *testing.T) {
+		s := store.NewStore()
+		m := NewManager("test-key", s, time.Minute)
+		for i := 0; i < 2; i++ {
+			if err := m.parseGTFSData(writeGTFSDir(t, goodGTFSFiles())); err != nil {
+				t.Fatalf("Load %d: unexpected error: %v", i+1, err)
+			}
+		}
+	})
+}
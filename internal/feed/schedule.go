@@ -0,0 +1,380 @@
+package feed
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jusunglee/mta-go/internal/models"
+)
+
+// scheduledArrivalsPerDirection is how many scheduled arrivals fill an empty direction
+// Enough to plan around without implying the schedule is as reliable as real-time data
+const scheduledArrivalsPerDirection = 3
+
+// serviceTimeZone is the agency timezone that GTFS stop times are expressed in
+const serviceTimeZone = "America/New_York"
+
+// stopEvent is one scheduled arrival at a stop, relative to the start of its service day
+// offset may exceed 24h for trips that run past midnight
+type stopEvent struct {
+	offset    time.Duration
+	route     string
+	serviceID string
+	sequence  uint32 // stop_sequence, zero when the column is missing
+}
+
+// serviceCalendar is a calendar.txt row
+type serviceCalendar struct {
+	days       [7]bool // Indexed by time.Weekday
+	start, end string  // YYYYMMDD, inclusive; compared lexically
+}
+
+// schedule holds the static timetable needed to fill boards when real-time data is missing
+type schedule struct {
+	loc        *time.Location
+	dayCutoff  time.Duration              // Stop times earlier than this belong to the previous service day
+	stops      map[string][]stopEvent     // stop_id -> events sorted by offset
+	calendars  map[string]serviceCalendar // service_id -> weekly pattern
+	exceptions map[string]map[string]bool // YYYYMMDD -> service_id -> added (true) or removed (false)
+}
+
+// SetScheduleFallback enables filling directions without real-time arrivals from the static timetable
+// Takes effect on the next static GTFS load, since the timetable is only parsed when enabled
+func (m *Manager) SetScheduleFallback(enabled bool) {
+	m.scheduleFallback = enabled
+}
+
+// SetServiceDayCutoff treats stop times earlier than cutoff as belonging to the previous service day
+// Some feeds write a 1:30am train as 01:30:00 rather than the GTFS-standard 25:30:00, which would
+// otherwise match it against the wrong day's calendar (a Saturday-morning train on Friday's weekday
+// service, say). Zero, the default, keeps strict GTFS semantics. Takes effect on the next static GTFS load
+func (m *Manager) SetServiceDayCutoff(cutoff time.Duration) {
+	m.serviceDayCutoff = cutoff
+}
+
+// loadSchedule parses trips, stop_times and the service calendar from a GTFS directory
+// dayCutoff is as for SetServiceDayCutoff
+func loadSchedule(gtfsDir string, routes map[string]models.RouteInfo, dayCutoff time.Duration) (*schedule, error) {
+	loc, err := time.LoadLocation(serviceTimeZone)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load timezone %s: %w", serviceTimeZone, err)
+	}
+
+	sc := &schedule{
+		loc:        loc,
+		dayCutoff:  dayCutoff,
+		stops:      make(map[string][]stopEvent),
+		calendars:  make(map[string]serviceCalendar),
+		exceptions: make(map[string]map[string]bool),
+	}
+
+	if err := sc.parseCalendar(filepath.Join(gtfsDir, "calendar.txt")); err != nil {
+		return nil, fmt.Errorf("failed to parse calendar: %w", err)
+	}
+	// calendar_dates.txt is optional; feeds that only use calendar.txt omit it
+	if err := sc.parseCalendarDates(filepath.Join(gtfsDir, "calendar_dates.txt")); err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to parse calendar dates: %w", err)
+	}
+
+	trips, err := parseScheduleTrips(filepath.Join(gtfsDir, "trips.txt"), routes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse trips: %w", err)
+	}
+	if err := sc.parseStopTimes(filepath.Join(gtfsDir, "stop_times.txt"), trips); err != nil {
+		return nil, fmt.Errorf("failed to parse stop_times: %w", err)
+	}
+
+	for stopID := range sc.stops {
+		events := sc.stops[stopID]
+		sort.Slice(events, func(i, j int) bool {
+			return events[i].offset < events[j].offset
+		})
+	}
+
+	return sc, nil
+}
+
+// scheduleCSV opens a GTFS file and returns its reader and column index
+// The reader doesn't enforce field counts, so read skips rows too short to hold every required column
+func scheduleCSV(path string, required ...string) (*os.File, func() ([]string, error), map[string]int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	reader := newCSVReader(file)
+	reader.ReuseRecord = true
+	header, err := reader.Read()
+	if err != nil {
+		file.Close()
+		return nil, nil, nil, fmt.Errorf("failed to read header: %w", err)
+	}
+
+	columns := headerColumns(header)
+	for _, col := range required {
+		if _, ok := columns[col]; !ok {
+			file.Close()
+			return nil, nil, nil, fmt.Errorf("missing %s column", col)
+		}
+	}
+
+	minFields := 0
+	for _, col := range required {
+		minFields = max(minFields, columns[col]+1)
+	}
+	read := func() ([]string, error) {
+		for {
+			record, err := reader.Read()
+			if err != nil || len(record) >= minFields {
+				return record, err
+			}
+		}
+	}
+	return file, read, columns, nil
+}
+
+func (sc *schedule) parseCalendar(path string) error {
+	days := []string{"sunday", "monday", "tuesday", "wednesday", "thursday", "friday", "saturday"}
+	file, read, columns, err := scheduleCSV(path, append([]string{"service_id", "start_date", "end_date"}, days...)...)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	for {
+		record, err := read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		cal := serviceCalendar{
+			start: record[columns["start_date"]],
+			end:   record[columns["end_date"]],
+		}
+		for weekday, day := range days {
+			cal.days[weekday] = record[columns[day]] == "1"
+		}
+		sc.calendars[record[columns["service_id"]]] = cal
+	}
+}
+
+func (sc *schedule) parseCalendarDates(path string) error {
+	file, read, columns, err := scheduleCSV(path, "service_id", "date", "exception_type")
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	for {
+		record, err := read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		date := record[columns["date"]]
+		if sc.exceptions[date] == nil {
+			sc.exceptions[date] = make(map[string]bool)
+		}
+		// exception_type 1 adds service on the date, 2 removes it
+		sc.exceptions[date][record[columns["service_id"]]] = record[columns["exception_type"]] == "1"
+	}
+}
+
+// scheduledTrip is the part of a trips.txt row the timetable needs
+type scheduledTrip struct {
+	route     string
+	serviceID string
+}
+
+// parseScheduleTrips maps trip_id to its route short name and service
+func parseScheduleTrips(path string, routes map[string]models.RouteInfo) (map[string]scheduledTrip, error) {
+	file, read, columns, err := scheduleCSV(path, "route_id", "service_id", "trip_id")
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	trips := make(map[string]scheduledTrip)
+	for {
+		record, err := read()
+		if err == io.EOF {
+			return trips, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		route := record[columns["route_id"]]
+		if info, ok := routes[route]; ok && info.ShortName != "" {
+			route = info.ShortName
+		}
+		trips[record[columns["trip_id"]]] = scheduledTrip{
+			route:     route,
+			serviceID: record[columns["service_id"]],
+		}
+	}
+}
+
+func (sc *schedule) parseStopTimes(path string, trips map[string]scheduledTrip) error {
+	file, read, columns, err := scheduleCSV(path, "trip_id", "stop_id", "arrival_time")
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	for {
+		record, err := read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		trip, ok := trips[record[columns["trip_id"]]]
+		if !ok {
+			continue
+		}
+		offset, err := parseGTFSTime(record[columns["arrival_time"]])
+		if err != nil {
+			continue
+		}
+		if offset < sc.dayCutoff {
+			// Equivalent to the 24:00:00+ form, so nextArrivals checks the prior day's calendar
+			offset += 24 * time.Hour
+		}
+
+		var sequence uint32
+		if n, err := strconv.ParseUint(csvField(record, columns, "stop_sequence"), 10, 32); err == nil {
+			sequence = uint32(n)
+		}
+
+		// Clone so the map key doesn't pin the whole CSV line in memory
+		stopID := strings.Clone(record[columns["stop_id"]])
+		sc.stops[stopID] = append(sc.stops[stopID], stopEvent{
+			offset:    offset,
+			route:     trip.route,
+			serviceID: trip.serviceID,
+			sequence:  sequence,
+		})
+	}
+}
+
+// parseGTFSTime parses an HH:MM:SS stop time, where hours may run past 24 for after-midnight trips
+func parseGTFSTime(s string) (time.Duration, error) {
+	parts := strings.Split(strings.TrimSpace(s), ":")
+	if len(parts) != 3 {
+		return 0, fmt.Errorf("invalid GTFS time %q", s)
+	}
+
+	var fields [3]int
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid GTFS time %q", s)
+		}
+		fields[i] = n
+	}
+
+	return time.Duration(fields[0])*time.Hour + time.Duration(fields[1])*time.Minute + time.Duration(fields[2])*time.Second, nil
+}
+
+// serviceActive reports whether serviceID runs on the service day starting at day
+func (sc *schedule) serviceActive(serviceID string, day time.Time) bool {
+	date := day.Format("20060102")
+	if added, ok := sc.exceptions[date][serviceID]; ok {
+		return added
+	}
+
+	cal, ok := sc.calendars[serviceID]
+	if !ok {
+		return false
+	}
+	return date >= cal.start && date <= cal.end && cal.days[day.Weekday()]
+}
+
+// nextArrivals returns up to n scheduled arrivals at stopID at or after now
+// Yesterday's service day is included because its after-midnight trips run in the early morning
+func (sc *schedule) nextArrivals(stopID string, now time.Time, n int) []models.Train {
+	events := sc.stops[stopID]
+	if len(events) == 0 {
+		return nil
+	}
+
+	local := now.In(sc.loc)
+	var result []models.Train
+	for dayOffset := -1; dayOffset <= 1; dayOffset++ {
+		// GTFS service days start at "noon minus 12h", which differs from midnight on DST changes
+		noon := time.Date(local.Year(), local.Month(), local.Day()+dayOffset, 12, 0, 0, 0, sc.loc)
+		start := noon.Add(-12 * time.Hour)
+
+		first := sort.Search(len(events), func(i int) bool {
+			return !start.Add(events[i].offset).Before(now)
+		})
+
+		found := 0
+		for _, event := range events[first:] {
+			if found == n {
+				break
+			}
+			if !sc.serviceActive(event.serviceID, noon) {
+				continue
+			}
+			result = append(result, models.Train{
+				Route:        event.route,
+				Time:         start.Add(event.offset),
+				Scheduled:    true,
+				StopSequence: event.sequence,
+			})
+			found++
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Time.Before(result[j].Time)
+	})
+	if len(result) > n {
+		result = result[:n]
+	}
+	return result
+}
+
+// fillScheduledArrivals adds scheduled arrivals to any direction of the station with no real-time data
+func (m *Manager) fillScheduledArrivals(station *models.Station, now time.Time) {
+	if len(station.Trains.North) == 0 {
+		station.Trains.North = m.schedule.nextArrivals(station.ID+"N", now, scheduledArrivalsPerDirection)
+	}
+	if len(station.Trains.South) == 0 {
+		station.Trains.South = m.schedule.nextArrivals(station.ID+"S", now, scheduledArrivalsPerDirection)
+	}
+}
+
+// loadScheduleIfEnabled refreshes the timetable after a static load when the fallback is on
+// A failure only disables the fallback; real-time data is unaffected
+func (m *Manager) loadScheduleIfEnabled(gtfsDir string, routes map[string]models.RouteInfo) {
+	if !m.scheduleFallback {
+		return
+	}
+
+	sc, err := loadSchedule(gtfsDir, routes, m.serviceDayCutoff)
+	if err != nil {
+		slog.Warn("Failed to load schedule for fallback arrivals", "error", err)
+		return
+	}
+
+	m.schedule = sc
+	slog.Info("Loaded static schedule for fallback arrivals", "stops", len(sc.stops))
+}
@@ -0,0 +1,266 @@
+package feed
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jusunglee/mta-go/internal/clock"
+	"github.com/jusunglee/mta-go/internal/gtfsrt"
+	"github.com/jusunglee/mta-go/internal/models"
+	"github.com/jusunglee/mta-go/internal/store"
+	"google.golang.org/protobuf/proto"
+)
+
+// scheduleGTFSFiles is a timetable around testNow, which is 03:00 on Sunday 2024-12-01 in New York
+func scheduleGTFSFiles() map[string]string {
+	return map[string]string{
+		"calendar.txt": "service_id,monday,tuesday,wednesday,thursday,friday,saturday,sunday,start_date,end_date\n" +
+			"Weekday,1,1,1,1,1,0,0,20240101,20251231\n" +
+			"Saturday,0,0,0,0,0,1,0,20240101,20251231\n" +
+			"Sunday,0,0,0,0,0,0,1,20240101,20251231\n",
+		"calendar_dates.txt": "service_id,date,exception_type\n" +
+			"Extra,20241201,1\n",
+		"routes.txt": "route_id,route_short_name\n" +
+			"1,1\n" +
+			"GS,S\n",
+		"trips.txt": "route_id,service_id,trip_id\n" +
+			"1,Sunday,SUN1\n" +
+			"1,Sunday,SUN2\n" +
+			"1,Weekday,WKD1\n" +
+			"1,Saturday,SAT1\n" +
+			"GS,Extra,EXTRA1\n",
+		"stop_times.txt": "trip_id,arrival_time,departure_time,stop_id,stop_sequence\n" +
+			"SUN1,03:10:00,03:10:00,127N,1\n" +
+			"SUN2,03:30:00,03:30:00,127N,1\n" +
+			"WKD1,03:05:00,03:05:00,127N,1\n" +
+			"SAT1,26:30:00,26:30:00,127N,1\n" +
+			"SAT1,27:15:00,27:15:00,127N,2\n" +
+			"EXTRA1,03:40:00,03:40:00,127N,1\n",
+	}
+}
+
+func TestParseGTFSTime(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected time.Duration
+		wantErr  bool
+	}{
+		{"03:10:00", 3*time.Hour + 10*time.Minute, false},
+		{" 8:05:30", 8*time.Hour + 5*time.Minute + 30*time.Second, false},
+		{"25:15:00", 25*time.Hour + 15*time.Minute, false},
+		{"", 0, true},
+		{"03:10", 0, true},
+		{"aa:10:00", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := parseGTFSTime(tt.input)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Expected error for %q", tt.input)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestScheduleNextArrivals(t *testing.T) {
+	m := &Manager{}
+	dir := writeGTFSDir(t, scheduleGTFSFiles())
+	routes, err := m.parseRoutesFile(dir + "/routes.txt")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	sc, err := loadSchedule(dir, routes, 0)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// Weekday service is excluded on a Sunday, and Saturday's 26:30 trip has already left
+	trains := sc.nextArrivals("127N", testNow, 4)
+	expected := []struct {
+		route string
+		local string
+	}{
+		{"1", "03:10"},
+		{"1", "03:15"}, // Saturday service day, 27:15
+		{"1", "03:30"},
+		{"S", "03:40"}, // Added by calendar_dates.txt
+	}
+
+	if len(trains) != len(expected) {
+		t.Fatalf("Expected %d arrivals, got %d: %v", len(expected), len(trains), trains)
+	}
+	for i, want := range expected {
+		got := trains[i]
+		if got.Route != want.route || got.Time.In(sc.loc).Format("15:04") != want.local || !got.Scheduled {
+			t.Errorf("Arrival %d: expected %s at %s (scheduled), got %s at %s (scheduled=%v)",
+				i, want.route, want.local, got.Route, got.Time.In(sc.loc).Format("15:04"), got.Scheduled)
+		}
+	}
+
+	// Once today's service runs out, the next service day follows
+	trains = sc.nextArrivals("127N", testNow, 5)
+	if len(trains) != 5 || trains[4].Time.In(sc.loc).Format("Mon 15:04") != "Mon 03:05" {
+		t.Errorf("Expected Monday's 03:05 weekday trip fifth, got %v", trains)
+	}
+}
+
+func TestLoadScheduleSkipsShortRows(t *testing.T) {
+	files := scheduleGTFSFiles()
+	files["calendar.txt"] += "Short,1\n"
+	files["calendar_dates.txt"] += "Extra,20241202\n"
+	files["trips.txt"] += "1,Sunday\n"
+	files["stop_times.txt"] += "SUN1,03:20:00\n"
+	dir := writeGTFSDir(t, files)
+
+	m := &Manager{}
+	routes, err := m.parseRoutesFile(dir + "/routes.txt")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	sc, err := loadSchedule(dir, routes, 0)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if trains := sc.nextArrivals("127N", testNow, 4); len(trains) != 4 || trains[0].Time.In(sc.loc).Format("15:04") != "03:10" {
+		t.Errorf("Expected the well-formed timetable with short rows skipped, got %v", trains)
+	}
+}
+
+func TestScheduleServiceDayCutoff(t *testing.T) {
+	// A Friday-night weekday train written as 01:30 rather than 25:30
+	files := scheduleGTFSFiles()
+	files["stop_times.txt"] = "trip_id,arrival_time,departure_time,stop_id,stop_sequence\n" +
+		"WKD1,01:30:00,01:30:00,127N,1\n"
+
+	m := &Manager{}
+	dir := writeGTFSDir(t, files)
+	routes, err := m.parseRoutesFile(dir + "/routes.txt")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// 01:20 on Saturday 2024-11-30 in New York
+	saturday := time.Date(2024, 11, 30, 6, 20, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		cutoff   time.Duration
+		expected int
+	}{
+		// Strict GTFS reads 01:30 as Saturday's service day, which has no weekday service
+		{"strict GTFS", 0, 0},
+		// With the cutoff it's Friday's service day, where weekday service runs
+		{"4h cutoff", 4 * time.Hour, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sc, err := loadSchedule(dir, routes, tt.cutoff)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			trains := sc.nextArrivals("127N", saturday, 3)
+			if len(trains) != tt.expected {
+				t.Fatalf("Expected %d arrivals, got %v", tt.expected, trains)
+			}
+			if tt.expected > 0 && trains[0].Time.In(sc.loc).Format("Mon 15:04") != "Sat 01:30" {
+				t.Errorf("Expected the train at Sat 01:30, got %s", trains[0].Time.In(sc.loc).Format("Mon 15:04"))
+			}
+		})
+	}
+}
+
+func TestUpdateRealTimeDataScheduleFallback(t *testing.T) {
+	// Real-time data only covers the southbound platform
+	routeID := "1"
+	stopID := "127S"
+	entityID := "1"
+	arrivalTime := testNow.Add(4 * time.Minute).Unix()
+	data, err := proto.Marshal(&gtfsrt.FeedMessage{
+		Header: &gtfsrt.FeedHeader{GtfsRealtimeVersion: proto.String("1.0")},
+		Entity: []*gtfsrt.FeedEntity{
+			{
+				Id: &entityID,
+				TripUpdate: &gtfsrt.TripUpdate{
+					Trip: &gtfsrt.TripDescriptor{RouteId: &routeID},
+					StopTimeUpdate: []*gtfsrt.StopTimeUpdate{
+						{StopId: &stopID, Arrival: &gtfsrt.StopTimeEvent{Time: &arrivalTime}},
+					},
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to marshal feed: %v", err)
+	}
+
+	s := store.NewStore()
+	s.UpdateStations(map[string]*models.Station{
+		"127": {ID: "127", Name: "Times Sq-42 St", Routes: []string{"1"}},
+	})
+
+	m := NewManager("test-key", s, time.Minute)
+	m.SetClock(clock.NewFake(testNow))
+	m.SetFetcher(&mapFetcher{data: map[string][]byte{FeedGroups["1234567"]: data}})
+	m.SetScheduleFallback(true)
+	dir := writeGTFSDir(t, scheduleGTFSFiles())
+	m.loadScheduleIfEnabled(dir, routesIn(t, dir))
+	if m.schedule == nil {
+		t.Fatal("Expected schedule to be loaded")
+	}
+
+	if err := m.updateRealTimeData(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	stations, err := s.GetStationsByIDs([]string{"127"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	station := stations[0]
+
+	if len(station.Trains.North) != scheduledArrivalsPerDirection {
+		t.Fatalf("Expected %d scheduled northbound arrivals, got %v", scheduledArrivalsPerDirection, station.Trains.North)
+	}
+	for _, train := range station.Trains.North {
+		if !train.Scheduled || train.Realtime {
+			t.Errorf("Expected northbound fallback arrival to be flagged scheduled and not realtime, got %+v", train)
+		}
+	}
+
+	if len(station.Trains.South) != 1 || station.Trains.South[0].Scheduled || !station.Trains.South[0].Realtime {
+		t.Errorf("Expected the single real-time southbound arrival untouched and flagged realtime, got %v", station.Trains.South)
+	}
+
+	// The flag is sent even when false, so clients can rely on it being present
+	body, err := json.Marshal(station.ConvertToResponse())
+	if err != nil {
+		t.Fatalf("Failed to marshal response: %v", err)
+	}
+	if !strings.Contains(string(body), `"realtime":false`) || !strings.Contains(string(body), `"realtime":true`) {
+		t.Errorf("Expected realtime flags in station response, got %s", body)
+	}
+}
+
+func TestScheduleFallbackDisabledByDefault(t *testing.T) {
+	m := &Manager{}
+	dir := writeGTFSDir(t, scheduleGTFSFiles())
+	m.loadScheduleIfEnabled(dir, routesIn(t, dir))
+	if m.schedule != nil {
+		t.Error("Expected no schedule to be loaded when fallback is disabled")
+	}
+}
@@ -0,0 +1,55 @@
+package feed
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jusunglee/mta-go/internal/models"
+)
+
+// DefaultServiceArea is the box stops.txt coordinates must fall in unless SetServiceArea changes it
+// It is the NYC subway area the validator also checks against
+var DefaultServiceArea = models.Bounds{MinLat: minValidLat, MinLon: minValidLon, MaxLat: maxValidLat, MaxLon: maxValidLon}
+
+// SetServiceArea sets the bounds stops must fall within to be loaded; nil disables the check
+// A stop at (0,0) or with swapped coordinates would otherwise win nearest-station queries for wherever it landed
+func (m *Manager) SetServiceArea(area *models.Bounds) {
+	m.serviceArea = area
+}
+
+// inServiceArea reports whether a stop at lat, lon should be loaded
+func (m *Manager) inServiceArea(lat, lon float64) bool {
+	area := m.serviceArea
+	return area == nil || (lat >= area.MinLat && lat <= area.MaxLat && lon >= area.MinLon && lon <= area.MaxLon)
+}
+
+// ParseServiceArea parses "minLat,minLon,maxLat,maxLon", "" for DefaultServiceArea or "off" to disable the check
+func ParseServiceArea(s string) (*models.Bounds, error) {
+	s = strings.TrimSpace(s)
+	switch strings.ToLower(s) {
+	case "":
+		area := DefaultServiceArea
+		return &area, nil
+	case "off":
+		return nil, nil
+	}
+
+	parts := strings.Split(s, ",")
+	if len(parts) != 4 {
+		return nil, fmt.Errorf("service area %q must be minLat,minLon,maxLat,maxLon or off", s)
+	}
+	var values [4]float64
+	for i, part := range parts {
+		v, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return nil, fmt.Errorf("service area %q has an invalid coordinate %q", s, part)
+		}
+		values[i] = v
+	}
+	area := models.Bounds{MinLat: values[0], MinLon: values[1], MaxLat: values[2], MaxLon: values[3]}
+	if area.MinLat > area.MaxLat || area.MinLon > area.MaxLon {
+		return nil, fmt.Errorf("service area %q has a minimum above its maximum", s)
+	}
+	return &area, nil
+}
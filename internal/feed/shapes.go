@@ -0,0 +1,157 @@
+package feed
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"path/filepath"
+	"sort"
+	"strconv"
+
+	"github.com/jusunglee/mta-go/internal/models"
+)
+
+// shapePoint is one vertex of a shapes.txt polyline
+type shapePoint struct {
+	seq int
+	loc models.Location
+}
+
+// loadRouteShapes reads shapes.txt and trips.txt into route short name -> polylines
+// shapes.txt is optional in GTFS, so a missing file yields no shapes rather than an error.
+// A route gets one polyline per distinct shape its trips use, so branches and shuttles
+// sharing a short name come out as several lines
+func (m *Manager) loadRouteShapes(gtfsDir string, routes map[string]models.RouteInfo) (map[string][][]models.Location, error) {
+	shapes, err := parseShapesFile(filepath.Join(gtfsDir, "shapes.txt"))
+	if errors.Is(err, fs.ErrNotExist) {
+		slog.Info("No shapes.txt in static GTFS, route shapes unavailable")
+		return map[string][][]models.Location{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse shapes file: %w", err)
+	}
+
+	routeShapeIDs, err := parseTripShapes(filepath.Join(gtfsDir, "trips.txt"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse trips file: %w", err)
+	}
+
+	// Group shape IDs by short name, since several route_ids can share one
+	byName := make(map[string]map[string]bool)
+	for routeID, shapeIDs := range routeShapeIDs {
+		info, ok := routes[routeID]
+		if !ok {
+			continue
+		}
+		if byName[info.ShortName] == nil {
+			byName[info.ShortName] = make(map[string]bool)
+		}
+		for shapeID := range shapeIDs {
+			byName[info.ShortName][shapeID] = true
+		}
+	}
+
+	result := make(map[string][][]models.Location, len(byName))
+	for name, shapeIDs := range byName {
+		// Sorted so the response doesn't depend on map iteration order
+		ids := make([]string, 0, len(shapeIDs))
+		for id := range shapeIDs {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+
+		for _, id := range ids {
+			if line, ok := shapes[id]; ok && len(line) >= 2 {
+				result[name] = append(result[name], line)
+			}
+		}
+	}
+	return result, nil
+}
+
+// parseShapesFile reads shapes.txt into shape_id -> points ordered by shape_pt_sequence
+func parseShapesFile(shapesFile string) (map[string][]models.Location, error) {
+	file, read, columns, err := scheduleCSV(shapesFile, "shape_id", "shape_pt_lat", "shape_pt_lon", "shape_pt_sequence")
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	points := make(map[string][]shapePoint)
+	for {
+		record, err := read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(record) <= columns["shape_pt_sequence"] || len(record) <= columns["shape_pt_lat"] ||
+			len(record) <= columns["shape_pt_lon"] || len(record) <= columns["shape_id"] {
+			continue
+		}
+
+		lat, latErr := strconv.ParseFloat(record[columns["shape_pt_lat"]], 64)
+		lon, lonErr := strconv.ParseFloat(record[columns["shape_pt_lon"]], 64)
+		seq, seqErr := strconv.Atoi(record[columns["shape_pt_sequence"]])
+		if latErr != nil || lonErr != nil || seqErr != nil {
+			continue
+		}
+
+		id := record[columns["shape_id"]]
+		points[id] = append(points[id], shapePoint{seq: seq, loc: models.Location{Lat: lat, Lon: lon}})
+	}
+
+	shapes := make(map[string][]models.Location, len(points))
+	for id, pts := range points {
+		// GTFS only requires sequences to increase, not rows to be written in order
+		sort.SliceStable(pts, func(i, j int) bool { return pts[i].seq < pts[j].seq })
+		line := make([]models.Location, len(pts))
+		for i, p := range pts {
+			line[i] = p.loc
+		}
+		shapes[id] = line
+	}
+	return shapes, nil
+}
+
+// parseTripShapes reads trips.txt into route_id -> set of shape_ids
+// Trips without a shape_id (the column is optional) are ignored
+func parseTripShapes(tripsFile string) (map[string]map[string]bool, error) {
+	file, read, columns, err := scheduleCSV(tripsFile, "route_id")
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	routeShapes := make(map[string]map[string]bool)
+	shapeCol, ok := columns["shape_id"]
+	if !ok {
+		return routeShapes, nil
+	}
+	routeCol := columns["route_id"]
+
+	for {
+		record, err := read()
+		if err == io.EOF {
+			return routeShapes, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(record) <= shapeCol || len(record) <= routeCol {
+			continue
+		}
+
+		routeID, shapeID := record[routeCol], record[shapeCol]
+		if routeID == "" || shapeID == "" {
+			continue
+		}
+		if routeShapes[routeID] == nil {
+			routeShapes[routeID] = make(map[string]bool)
+		}
+		routeShapes[routeID][shapeID] = true
+	}
+}
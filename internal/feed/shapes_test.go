@@ -0,0 +1,107 @@
+package feed
+
+import (
+	"testing"
+
+	"github.com/jusunglee/mta-go/internal/models"
+	"github.com/jusunglee/mta-go/internal/store"
+)
+
+func shapeGTFSFiles() map[string]string {
+	files := goodGTFSFiles()
+	files["routes.txt"] = "route_id,route_short_name\n" +
+		"1,1\n" +
+		"GS,S\n" +
+		"FS,S\n" +
+		"6,6\n"
+	files["trips.txt"] = "route_id,trip_id,shape_id\n" +
+		"1,T1a,1..N03R\n" +
+		"1,T1b,1..N03R\n" +
+		"GS,TGS,GS.N01R\n" +
+		"FS,TFS,FS.N01R\n" +
+		"6,T6,\n"
+	// Rows are deliberately out of sequence order
+	files["shapes.txt"] = "shape_id,shape_pt_lat,shape_pt_lon,shape_pt_sequence\n" +
+		"1..N03R,40.70,-74.01,2\n" +
+		"1..N03R,40.68,-74.02,0\n" +
+		"1..N03R,40.69,-74.015,1\n" +
+		"GS.N01R,40.755,-73.987,0\n" +
+		"GS.N01R,40.752,-73.977,1\n" +
+		"FS.N01R,40.68,-73.95,0\n" +
+		"FS.N01R,40.66,-73.96,1\n" +
+		"BAD,not-a-number,-73.0,0\n"
+	return files
+}
+
+func TestLoadRouteShapes(t *testing.T) {
+	dir := writeGTFSDir(t, shapeGTFSFiles())
+
+	m := &Manager{}
+	shapes, err := m.loadRouteShapes(dir, routesIn(t, dir))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// Trips sharing a shape yield one line, ordered by shape_pt_sequence
+	line := shapes["1"]
+	if len(line) != 1 {
+		t.Fatalf("Expected 1 line for route 1, got %d", len(line))
+	}
+	expected := []models.Location{{Lat: 40.68, Lon: -74.02}, {Lat: 40.69, Lon: -74.015}, {Lat: 40.70, Lon: -74.01}}
+	for i, loc := range expected {
+		if line[0][i] != loc {
+			t.Errorf("Point %d: expected %v, got %v", i, loc, line[0][i])
+		}
+	}
+
+	// Route IDs sharing a short name become separate lines of one route
+	if len(shapes["S"]) != 2 {
+		t.Errorf("Expected 2 lines for the S shuttles, got %d", len(shapes["S"]))
+	}
+
+	if _, ok := shapes["6"]; ok {
+		t.Error("Expected no shape for a route whose trips have no shape_id")
+	}
+}
+
+func TestLoadRouteShapesMissingFile(t *testing.T) {
+	files := goodGTFSFiles()
+	delete(files, "shapes.txt")
+	dir := writeGTFSDir(t, files)
+
+	s := store.NewStore()
+	m := &Manager{store: s}
+	if err := m.parseGTFSData(dir); err != nil {
+		t.Fatalf("Missing shapes.txt should not fail static loading: %v", err)
+	}
+
+	lines, err := s.GetRouteShape("1")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(lines) != 0 {
+		t.Errorf("Expected no shape data, got %v", lines)
+	}
+}
+
+func TestParseGTFSDataStoresShapes(t *testing.T) {
+	dir := writeGTFSDir(t, shapeGTFSFiles())
+
+	s := store.NewStore()
+	m := &Manager{store: s}
+	if err := m.parseGTFSData(dir); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	lines, err := s.GetRouteShape("s")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(lines) != 2 {
+		t.Errorf("Expected 2 lines for route S, got %d", len(lines))
+	}
+
+	if _, err := s.GetRouteShape("XYZ"); err == nil {
+		t.Error("Expected error for unknown route")
+	}
+}
@@ -0,0 +1,140 @@
+package feed
+
+import (
+	"sort"
+	"time"
+
+	"github.com/jusunglee/mta-go/internal/models"
+)
+
+// feedStat accumulates fetch outcomes for a single feed
+type feedStat struct {
+	fetches     int
+	failures    int
+	lastSuccess time.Time
+	lastError   string
+	lastErrorAt time.Time
+	lastParse   time.Duration
+}
+
+// managerStats is guarded by Manager.statsMu
+// Static timing is copied here rather than read from lastStaticUpdate, which only the update goroutine may touch
+type managerStats struct {
+	feeds            map[string]*feedStat // By feed URL
+	updateCycles     int
+	panics           int
+	lastStaticUpdate time.Time
+	staticParse      time.Duration
+}
+
+// feedStatLocked returns the entry for url, creating it on first use; callers must hold statsMu
+func (m *Manager) feedStatLocked(url string) *feedStat {
+	if m.stats.feeds == nil {
+		m.stats.feeds = make(map[string]*feedStat)
+	}
+	stat, ok := m.stats.feeds[url]
+	if !ok {
+		stat = &feedStat{}
+		m.stats.feeds[url] = stat
+	}
+	return stat
+}
+
+// recordFeedResult counts one fetch-and-process attempt of a feed
+func (m *Manager) recordFeedResult(url string, err error) {
+	now := m.now()
+
+	m.statsMu.Lock()
+	defer m.statsMu.Unlock()
+
+	stat := m.feedStatLocked(url)
+	stat.fetches++
+	if err != nil {
+		stat.failures++
+		stat.lastError = err.Error()
+		stat.lastErrorAt = now
+		return
+	}
+	stat.lastSuccess = now
+}
+
+// recordParseDuration stores how long the latest response from url took to decode and apply
+func (m *Manager) recordParseDuration(url string, d time.Duration) {
+	m.statsMu.Lock()
+	defer m.statsMu.Unlock()
+
+	m.feedStatLocked(url).lastParse = d
+}
+
+// recordUpdateCycle counts a completed real-time update pass
+func (m *Manager) recordUpdateCycle() {
+	m.statsMu.Lock()
+	defer m.statsMu.Unlock()
+
+	m.stats.updateCycles++
+}
+
+// recordPanic counts a panic recovered during an update
+func (m *Manager) recordPanic() {
+	m.statsMu.Lock()
+	defer m.statsMu.Unlock()
+
+	m.stats.panics++
+}
+
+// recordStaticParse stores the duration and completion time of a successful static GTFS parse
+func (m *Manager) recordStaticParse(d time.Duration) {
+	now := m.now()
+
+	m.statsMu.Lock()
+	defer m.statsMu.Unlock()
+
+	m.stats.staticParse = d
+	m.stats.lastStaticUpdate = now
+}
+
+// Stats returns feed activity counters alongside current store counts
+// Feeds are sorted by name; a feed appears once it has been attempted
+func (m *Manager) Stats() models.Stats {
+	stations, routes, alerts := m.store.Counts()
+	result := models.Stats{
+		LoadStatus: m.LoadStatus().String(),
+		Stations:   stations,
+		Routes:     routes,
+		Alerts:     alerts,
+		LastUpdate: optionalTime(m.store.GetLastUpdate()),
+	}
+
+	m.statsMu.Lock()
+	defer m.statsMu.Unlock()
+
+	result.UpdateCycles = m.stats.updateCycles
+	result.Panics = m.stats.panics
+	result.LastStaticUpdate = optionalTime(m.stats.lastStaticUpdate)
+	result.StaticParseMs = durationMs(m.stats.staticParse)
+	result.Feeds = make([]models.FeedStats, 0, len(m.stats.feeds))
+	for url, stat := range m.stats.feeds {
+		result.Feeds = append(result.Feeds, models.FeedStats{
+			Feed:        feedGroupName(url),
+			Fetches:     stat.fetches,
+			Failures:    stat.failures,
+			LastSuccess: optionalTime(stat.lastSuccess),
+			LastError:   stat.lastError,
+			LastErrorAt: optionalTime(stat.lastErrorAt),
+			LastParseMs: durationMs(stat.lastParse),
+		})
+	}
+
+	sort.Slice(result.Feeds, func(i, j int) bool {
+		return result.Feeds[i].Feed < result.Feeds[j].Feed
+	})
+	return result
+}
+
+// optionalTime maps the zero time to nil so JSON omits events that haven't happened
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
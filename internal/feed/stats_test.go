@@ -0,0 +1,106 @@
+package feed
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jusunglee/mta-go/internal/clock"
+	"github.com/jusunglee/mta-go/internal/gtfsrt"
+	"github.com/jusunglee/mta-go/internal/store"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestManagerStatsAfterUpdate(t *testing.T) {
+	routeID := "1"
+	stopID := "127S"
+	arrivalTime := testNow.Add(3 * time.Minute).Unix()
+	feed, err := proto.Marshal(&gtfsrt.FeedMessage{
+		Header: &gtfsrt.FeedHeader{GtfsRealtimeVersion: proto.String("1.0")},
+		Entity: []*gtfsrt.FeedEntity{
+			{
+				Id: proto.String("1"),
+				TripUpdate: &gtfsrt.TripUpdate{
+					Trip: &gtfsrt.TripDescriptor{RouteId: &routeID},
+					StopTimeUpdate: []*gtfsrt.StopTimeUpdate{
+						{StopId: &stopID, Arrival: &gtfsrt.StopTimeEvent{Time: &arrivalTime}},
+					},
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to marshal feed: %v", err)
+	}
+
+	// The ace feed is left out of the fetcher so it fails like a 404 on every cycle
+	m := NewManager("test-key", store.NewStore(), time.Minute)
+	m.SetClock(clock.NewFake(testNow))
+	m.SetGTFSDataDir(t.TempDir())
+	m.SetFetcher(&mapFetcher{data: map[string][]byte{
+		GTFSSupplementedURL:   zipGTFS(t, goodGTFSFiles()),
+		FeedGroups["1234567"]: feed,
+	}})
+	if err := m.SetFeedGroups([]string{"1234567", "ace"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	stats := m.Stats()
+	if stats.UpdateCycles != 0 || len(stats.Feeds) != 0 || stats.LastStaticUpdate != nil || stats.LoadStatus != "loading" {
+		t.Fatalf("Expected empty stats before any update, got %+v", stats)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := m.update(); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	stats = m.Stats()
+	if stats.Stations != 2 || stats.Routes != 2 || stats.Alerts != 0 {
+		t.Errorf("Expected 2 stations, 2 routes, 0 alerts, got %d, %d, %d", stats.Stations, stats.Routes, stats.Alerts)
+	}
+	if stats.UpdateCycles != 2 {
+		t.Errorf("Expected 2 update cycles, got %d", stats.UpdateCycles)
+	}
+	if stats.LoadStatus != "degraded" {
+		t.Errorf("Expected load status degraded with the ace feed failing, got %q", stats.LoadStatus)
+	}
+	if stats.LastUpdate == nil || !stats.LastUpdate.Equal(testNow) {
+		t.Errorf("Expected last update %v, got %v", testNow, stats.LastUpdate)
+	}
+	if stats.LastStaticUpdate == nil || !stats.LastStaticUpdate.Equal(testNow) {
+		t.Errorf("Expected last static update %v, got %v", testNow, stats.LastStaticUpdate)
+	}
+	if stats.StaticParseMs <= 0 {
+		t.Errorf("Expected a static parse duration, got %v", stats.StaticParseMs)
+	}
+
+	if len(stats.Feeds) != 2 {
+		t.Fatalf("Expected stats for 2 feeds, got %+v", stats.Feeds)
+	}
+	good, bad := stats.Feeds[0], stats.Feeds[1]
+	if good.Feed != "1234567" || bad.Feed != "ace" {
+		t.Fatalf("Expected feeds sorted by name, got %s, %s", good.Feed, bad.Feed)
+	}
+
+	if good.Fetches != 2 || good.Failures != 0 || good.LastError != "" {
+		t.Errorf("Expected 2 clean fetches of 1234567, got %+v", good)
+	}
+	if good.LastSuccess == nil || !good.LastSuccess.Equal(testNow) {
+		t.Errorf("Expected 1234567 last success %v, got %v", testNow, good.LastSuccess)
+	}
+	if good.LastParseMs <= 0 {
+		t.Errorf("Expected a parse duration for 1234567, got %v", good.LastParseMs)
+	}
+
+	if bad.Fetches != 2 || bad.Failures != 2 || bad.LastSuccess != nil {
+		t.Errorf("Expected 2 failed fetches of ace, got %+v", bad)
+	}
+	if !strings.Contains(bad.LastError, "404") {
+		t.Errorf("Expected ace last error to mention 404, got %q", bad.LastError)
+	}
+	if bad.LastErrorAt == nil || !bad.LastErrorAt.Equal(testNow) {
+		t.Errorf("Expected ace last error at %v, got %v", testNow, bad.LastErrorAt)
+	}
+}
@@ -0,0 +1,50 @@
+package feed
+
+import "log/slog"
+
+// LoadStatus summarizes whether the manager has usable data
+// Lets embedders wait for or display readiness instead of sleeping after Start
+type LoadStatus int32
+
+const (
+	// LoadStatusLoading means the initial update hasn't finished yet
+	LoadStatusLoading LoadStatus = iota
+	// LoadStatusReady means static data is loaded and every real-time feed succeeded in the last update
+	LoadStatusReady
+	// LoadStatusDegraded means static data is loaded but a static refresh or a real-time feed failed,
+	// so some data is stale or missing
+	LoadStatusDegraded
+	// LoadStatusFailed means static data failed to load and the store has no stations to serve.
+	// A manager whose first load fails over stations already in the store reports Degraded instead
+	LoadStatusFailed
+)
+
+func (s LoadStatus) String() string {
+	switch s {
+	case LoadStatusReady:
+		return "ready"
+	case LoadStatusDegraded:
+		return "degraded"
+	case LoadStatusFailed:
+		return "failed"
+	default:
+		return "loading"
+	}
+}
+
+// LoadStatus reports the outcome of the most recent update
+func (m *Manager) LoadStatus() LoadStatus {
+	return LoadStatus(m.loadStatus.Load())
+}
+
+// Ready returns a channel closed once the initial update has finished, whether or not it succeeded
+// Check LoadStatus afterwards to see what was loaded
+func (m *Manager) Ready() <-chan struct{} {
+	return m.ready
+}
+
+func (m *Manager) setLoadStatus(status LoadStatus) {
+	if prev := LoadStatus(m.loadStatus.Swap(int32(status))); prev != status {
+		slog.Info("Feed load status changed", "from", prev, "to", status)
+	}
+}
@@ -0,0 +1,101 @@
+package feed
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jusunglee/mta-go/internal/clock"
+	"github.com/jusunglee/mta-go/internal/gtfsrt"
+	"github.com/jusunglee/mta-go/internal/models"
+	"github.com/jusunglee/mta-go/internal/store"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestLoadStatusTransitions(t *testing.T) {
+	emptyFeed, err := proto.Marshal(&gtfsrt.FeedMessage{
+		Header: &gtfsrt.FeedHeader{GtfsRealtimeVersion: proto.String("1.0")},
+	})
+	if err != nil {
+		t.Fatalf("Failed to marshal feed: %v", err)
+	}
+
+	fetcher := &mapFetcher{data: map[string][]byte{}}
+	m := NewManager("test-key", store.NewStore(), time.Minute)
+	m.SetClock(clock.NewFake(testNow))
+	m.SetGTFSDataDir(t.TempDir())
+	m.SetFetcher(fetcher)
+	if err := m.SetFeedGroups([]string{"l"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if status := m.LoadStatus(); status != LoadStatusLoading {
+		t.Fatalf("Expected %v before any update, got %v", LoadStatusLoading, status)
+	}
+
+	steps := []struct {
+		name     string
+		data     map[string][]byte
+		expected LoadStatus
+	}{
+		{"static download fails", map[string][]byte{}, LoadStatusFailed},
+		{"static loads but real-time fails", map[string][]byte{
+			GTFSSupplementedURL: zipGTFS(t, goodGTFSFiles()),
+		}, LoadStatusDegraded},
+		{"real-time recovers", map[string][]byte{
+			FeedGroups["l"]: emptyFeed,
+		}, LoadStatusReady},
+		{"real-time fails again", map[string][]byte{}, LoadStatusDegraded},
+	}
+
+	for _, step := range steps {
+		fetcher.mu.Lock()
+		fetcher.data = step.data
+		fetcher.mu.Unlock()
+
+		m.update()
+		if status := m.LoadStatus(); status != step.expected {
+			t.Fatalf("%s: expected %v, got %v", step.name, step.expected, status)
+		}
+	}
+}
+
+func TestLoadStatusFailedStaticWithStoredStations(t *testing.T) {
+	// A restarted client's new manager shares a store the previous one already filled
+	s := store.NewStore()
+	s.UpdateStations(map[string]*models.Station{"127": {ID: "127", Routes: []string{"1"}}})
+	m := NewManager("test-key", s, time.Minute)
+	m.SetClock(clock.NewFake(testNow))
+	m.SetGTFSDataDir(t.TempDir())
+	m.SetFetcher(&mapFetcher{data: map[string][]byte{}})
+
+	if err := m.update(); err != nil {
+		t.Fatalf("Expected the update to continue with stored stations, got %v", err)
+	}
+	if status := m.LoadStatus(); status != LoadStatusDegraded {
+		t.Errorf("Expected %v with stations still served, got %v", LoadStatusDegraded, status)
+	}
+}
+
+func TestReadyClosesAfterInitialUpdate(t *testing.T) {
+	m := NewManager("test-key", store.NewStore(), time.Hour)
+	m.SetGTFSDataDir(t.TempDir())
+	m.SetFetcher(&mapFetcher{})
+
+	select {
+	case <-m.Ready():
+		t.Fatal("Ready closed before Start")
+	default:
+	}
+
+	m.Start()
+	defer m.Stop()
+
+	select {
+	case <-m.Ready():
+	case <-time.After(5 * time.Second):
+		t.Fatal("Ready not closed after initial update")
+	}
+	if status := m.LoadStatus(); status != LoadStatusFailed {
+		t.Errorf("Expected %v with no static data, got %v", LoadStatusFailed, status)
+	}
+}
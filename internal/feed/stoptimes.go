@@ -0,0 +1,96 @@
+package feed
+
+import (
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// staticStopTime is one stop of a trip from stop_times.txt
+type staticStopTime struct {
+	sequence uint32        // stop_sequence, zero when the column is missing
+	offset   time.Duration // arrival_time from the start of the service day; may pass 24h
+	timed    bool          // Whether arrival_time was readable; GTFS lets intermediate stops leave it out
+}
+
+// tripStopTimes is stop_times.txt indexed by trip_id then stop_id
+type tripStopTimes map[string]map[string]staticStopTime
+
+// staticStopTimes looks up the timetable entry behind a real-time stop time update
+// NYCT real-time trip IDs are the tail of the static ones, e.g. 046400_N..N for
+// AFA23GEN-N058-Weekday-00_046400_N..N, so trips are also indexed by everything after the first underscore.
+// Day types share tails; any of them will do, since the tail encodes the trip's origin time
+type staticStopTimes struct {
+	trips  tripStopTimes
+	byTail map[string]string // Real-time trip ID -> a static trip ID ending in it
+	loc    *time.Location    // Agency time zone stop times are in; nil if it couldn't be loaded
+}
+
+// newStaticStopTimes indexes trips for lookup by static or NYCT real-time trip ID
+func newStaticStopTimes(trips tripStopTimes) *staticStopTimes {
+	st := &staticStopTimes{trips: trips, byTail: make(map[string]string)}
+	for tripID := range trips {
+		_, tail, ok := strings.Cut(tripID, "_")
+		if !ok || tail == "" {
+			continue
+		}
+		// The lowest static ID wins so lookups don't depend on map order
+		if chosen, ok := st.byTail[tail]; !ok || tripID < chosen {
+			st.byTail[tail] = tripID
+		}
+	}
+
+	loc, err := time.LoadLocation(serviceTimeZone)
+	if err != nil {
+		slog.Warn("Failed to load service time zone, scheduled times unavailable for real-time arrivals", "error", err)
+	} else {
+		st.loc = loc
+	}
+	return st
+}
+
+// lookup returns the static stop time for a real-time trip at stopID
+// Safe on a nil receiver, before static data has loaded
+func (st *staticStopTimes) lookup(tripID, stopID string) (staticStopTime, bool) {
+	if st == nil || tripID == "" {
+		return staticStopTime{}, false
+	}
+	stops, ok := st.trips[tripID]
+	if !ok {
+		stops, ok = st.trips[st.byTail[tripID]]
+	}
+	if !ok {
+		return staticStopTime{}, false
+	}
+	stop, ok := stops[stopID]
+	return stop, ok
+}
+
+// scheduledAt returns when the timetable has stop, on the service day startDate (YYYYMMDD) names
+// Without a start date it takes whichever of the surrounding service days puts the stop nearest near
+func (st *staticStopTimes) scheduledAt(stop staticStopTime, startDate string, near time.Time) (time.Time, bool) {
+	if st == nil || st.loc == nil || !stop.timed {
+		return time.Time{}, false
+	}
+
+	if day, err := time.ParseInLocation("20060102", startDate, st.loc); err == nil {
+		return serviceDayStart(day).Add(stop.offset), true
+	}
+
+	local := near.In(st.loc)
+	var best time.Time
+	for dayOffset := -1; dayOffset <= 1; dayOffset++ {
+		day := time.Date(local.Year(), local.Month(), local.Day()+dayOffset, 0, 0, 0, 0, st.loc)
+		candidate := serviceDayStart(day).Add(stop.offset)
+		if best.IsZero() || candidate.Sub(near).Abs() < best.Sub(near).Abs() {
+			best = candidate
+		}
+	}
+	return best, true
+}
+
+// serviceDayStart returns the start of day's GTFS service day, "noon minus 12h" in day's location
+// It differs from midnight on DST changes
+func serviceDayStart(day time.Time) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day(), 12, 0, 0, 0, day.Location()).Add(-12 * time.Hour)
+}
@@ -18,14 +18,14 @@ func CreateMockStations() map[string]*models.Station {
 			Routes:   []string{"N", "Q", "R", "W", "S", "1", "2", "3", "7"},
 			Trains: models.TrainsByDirection{
 				North: []models.Train{
-					{Route: "N", Time: now.Add(2 * time.Minute)},
-					{Route: "Q", Time: now.Add(5 * time.Minute)},
-					{Route: "1", Time: now.Add(3 * time.Minute)},
+					{Route: "N", Time: now.Add(2 * time.Minute), Realtime: true, Assigned: true},
+					{Route: "Q", Time: now.Add(5 * time.Minute), Realtime: true, Assigned: true},
+					{Route: "1", Time: now.Add(3 * time.Minute), Realtime: true, Assigned: true},
 				},
 				South: []models.Train{
-					{Route: "R", Time: now.Add(1 * time.Minute)},
-					{Route: "W", Time: now.Add(4 * time.Minute)},
-					{Route: "2", Time: now.Add(6 * time.Minute)},
+					{Route: "R", Time: now.Add(1 * time.Minute), Realtime: true, Assigned: true},
+					{Route: "W", Time: now.Add(4 * time.Minute), Realtime: true, Assigned: true},
+					{Route: "2", Time: now.Add(6 * time.Minute), Realtime: true, Assigned: true},
 				},
 			},
 			Stops: map[string]models.Location{
@@ -41,13 +41,13 @@ func CreateMockStations() map[string]*models.Station {
 			Routes:   []string{"4", "5", "6", "7", "S"},
 			Trains: models.TrainsByDirection{
 				North: []models.Train{
-					{Route: "4", Time: now.Add(3 * time.Minute)},
-					{Route: "5", Time: now.Add(5 * time.Minute)},
-					{Route: "6", Time: now.Add(2 * time.Minute)},
+					{Route: "4", Time: now.Add(3 * time.Minute), Realtime: true, Assigned: true},
+					{Route: "5", Time: now.Add(5 * time.Minute), Realtime: true, Assigned: true},
+					{Route: "6", Time: now.Add(2 * time.Minute), Realtime: true, Assigned: true},
 				},
 				South: []models.Train{
-					{Route: "4", Time: now.Add(4 * time.Minute)},
-					{Route: "6", Time: now.Add(1 * time.Minute)},
+					{Route: "4", Time: now.Add(4 * time.Minute), Realtime: true, Assigned: true},
+					{Route: "6", Time: now.Add(1 * time.Minute), Realtime: true, Assigned: true},
 				},
 			},
 			Stops: map[string]models.Location{
@@ -63,13 +63,13 @@ func CreateMockStations() map[string]*models.Station {
 			Routes:   []string{"N", "Q", "R", "W", "4", "5", "6", "L"},
 			Trains: models.TrainsByDirection{
 				North: []models.Train{
-					{Route: "N", Time: now.Add(2 * time.Minute)},
-					{Route: "4", Time: now.Add(4 * time.Minute)},
-					{Route: "L", Time: now.Add(3 * time.Minute)},
+					{Route: "N", Time: now.Add(2 * time.Minute), Realtime: true, Assigned: true},
+					{Route: "4", Time: now.Add(4 * time.Minute), Realtime: true, Assigned: true},
+					{Route: "L", Time: now.Add(3 * time.Minute), Realtime: true, Assigned: true},
 				},
 				South: []models.Train{
-					{Route: "Q", Time: now.Add(5 * time.Minute)},
-					{Route: "6", Time: now.Add(2 * time.Minute)},
+					{Route: "Q", Time: now.Add(5 * time.Minute), Realtime: true, Assigned: true},
+					{Route: "6", Time: now.Add(2 * time.Minute), Realtime: true, Assigned: true},
 				},
 			},
 			Stops: map[string]models.Location{
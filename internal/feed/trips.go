@@ -0,0 +1,170 @@
+package feed
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/jusunglee/mta-go/internal/gtfsrt"
+	"github.com/jusunglee/mta-go/internal/models"
+)
+
+// vehicleReport is one GTFS-RT vehicle position, reduced to what trip progress needs
+type vehicleReport struct {
+	route     string
+	stationID string
+	direction models.Direction
+	status    string
+	updated   time.Time
+}
+
+// vehicleSet collects one update cycle's vehicle positions by trip ID
+// Feed entities are processed concurrently, so adds are locked
+type vehicleSet struct {
+	mu     sync.Mutex
+	byTrip map[string]vehicleReport
+}
+
+func newVehicleSet() *vehicleSet {
+	return &vehicleSet{byTrip: make(map[string]vehicleReport)}
+}
+
+func (v *vehicleSet) add(tripID string, report vehicleReport) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.byTrip[tripID] = report
+}
+
+// processVehicle records where a trip's train currently is
+// Positions without a trip or stop ID are skipped rather than reported as errors,
+// since an error here would mark the whole feed as failed over data that is only a bonus
+func (m *Manager) processVehicle(vehicle *gtfsrt.VehiclePosition, vehicles *vehicleSet) {
+	tripID := vehicle.GetTrip().GetTripId()
+	if vehicles == nil || tripID == "" || vehicle.GetStopId() == "" {
+		return
+	}
+
+	stationID, direction := m.resolveStop(vehicle.GetStopId())
+	report := vehicleReport{
+		route:     m.extractRouteFromID(vehicle.GetTrip().GetRouteId()),
+		stationID: stationID,
+		direction: direction,
+		// GetCurrentStatus applies the spec default, IN_TRANSIT_TO, when the field is unset
+		status: vehicle.GetCurrentStatus().String(),
+	}
+	if ts := vehicle.GetTimestamp(); ts > 0 {
+		report.updated = time.Unix(int64(ts), 0)
+	}
+	vehicles.add(tripID, report)
+}
+
+// splitStopID separates a directional stop ID like "R16N" into its parent station and direction
+// IDs without an N/S suffix are returned whole with DirectionUnknown
+func splitStopID(stopID string) (stationID string, direction models.Direction) {
+	n := len(stopID)
+	if n == 0 {
+		return stopID, models.DirectionUnknown
+	}
+	switch stopID[n-1] {
+	case 'N':
+		return stopID[:n-1], models.DirectionNorth
+	case 'S':
+		return stopID[:n-1], models.DirectionSouth
+	default:
+		return stopID, models.DirectionUnknown
+	}
+}
+
+// resolveStop maps a GTFS-RT stop ID to its parent station and direction
+// The parent comes from stops.txt parent_station when static data lists the stop, since not every
+// platform ID is its parent's ID plus N/S; otherwise the suffix is stripped. Direction is always the suffix
+func (m *Manager) resolveStop(stopID string) (stationID string, direction models.Direction) {
+	stationID, direction = splitStopID(stopID)
+	if parent, ok := m.platformParents[stopID]; ok {
+		stationID = parent
+	}
+	return stationID, direction
+}
+
+// platformParents indexes every platform stop of the parsed stations by ID
+func platformParents(stations map[string]*models.Station) map[string]string {
+	parents := make(map[string]string)
+	for _, station := range stations {
+		for stopID := range station.Stops {
+			parents[stopID] = station.ID
+		}
+	}
+	return parents
+}
+
+// buildTripProgress indexes this cycle's arrivals by trip and attaches vehicle positions
+// Must run before arrivals are trimmed to the retention limit, which would drop a trip's later stops at busy stations
+func buildTripProgress(stations map[string]*models.Station, vehicles *vehicleSet) map[string]models.TripProgress {
+	trips := make(map[string]models.TripProgress)
+	add := func(station *models.Station, direction models.Direction, trains []models.Train) {
+		for _, train := range trains {
+			if train.TripID == "" || train.Scheduled {
+				continue
+			}
+			trip := trips[train.TripID]
+			trip.TripID = train.TripID
+			trip.Route = train.Route
+			trip.Direction = direction
+			trip.Upcoming = append(trip.Upcoming, models.TripStop{
+				StationID: station.ID,
+				Name:      station.Name,
+				Time:      train.Time,
+			})
+			trips[train.TripID] = trip
+		}
+	}
+	for _, station := range stations {
+		add(station, models.DirectionNorth, station.Trains.North)
+		add(station, models.DirectionSouth, station.Trains.South)
+	}
+
+	for id, trip := range trips {
+		sort.SliceStable(trip.Upcoming, func(i, j int) bool {
+			return trip.Upcoming[i].Time.Before(trip.Upcoming[j].Time)
+		})
+		trips[id] = trip
+	}
+
+	if vehicles == nil {
+		return trips
+	}
+	for tripID, report := range vehicles.byTrip {
+		trip, ok := trips[tripID]
+		if !ok {
+			// A train at its last stop may have no predictions left but is still worth locating
+			trip = models.TripProgress{TripID: tripID, Route: report.route, Upcoming: []models.TripStop{}}
+		}
+
+		if trip.Direction == models.DirectionUnknown {
+			trip.Direction = report.direction
+		}
+		current := &models.TripPosition{StationID: report.stationID, Status: report.status, Updated: report.updated}
+		if station, ok := stations[report.stationID]; ok {
+			current.Name = station.Name
+		}
+		trip.Current = current
+		trip.Upcoming = stopsAhead(trip.Upcoming, current)
+		trips[tripID] = trip
+	}
+	return trips
+}
+
+// stopsAhead drops predicted stops the train has already reached
+// Arrivals linger for the past-arrival cutoff, so the list can still start before the vehicle's stop
+func stopsAhead(stops []models.TripStop, current *models.TripPosition) []models.TripStop {
+	for i, stop := range stops {
+		if stop.StationID != current.StationID {
+			continue
+		}
+		if current.Status == gtfsrt.VehiclePosition_STOPPED_AT.String() {
+			i++
+		}
+		return stops[i:]
+	}
+	return stops
+}
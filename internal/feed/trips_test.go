@@ -0,0 +1,138 @@
+package feed
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jusunglee/mta-go/internal/clock"
+	"github.com/jusunglee/mta-go/internal/gtfsrt"
+	"github.com/jusunglee/mta-go/internal/models"
+	"github.com/jusunglee/mta-go/internal/store"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestTripProgress(t *testing.T) {
+	tripUpdate := func(id, tripID string, stops map[string]time.Time) *gtfsrt.FeedEntity {
+		update := &gtfsrt.TripUpdate{
+			Trip: &gtfsrt.TripDescriptor{TripId: proto.String(tripID), RouteId: proto.String("N")},
+		}
+		for stopID, at := range stops {
+			update.StopTimeUpdate = append(update.StopTimeUpdate, &gtfsrt.StopTimeUpdate{
+				StopId:  proto.String(stopID),
+				Arrival: &gtfsrt.StopTimeEvent{Time: proto.Int64(at.Unix())},
+			})
+		}
+		return &gtfsrt.FeedEntity{Id: proto.String(id), TripUpdate: update}
+	}
+	vehicle := func(id, tripID, stopID string, status gtfsrt.VehiclePosition_VehicleStopStatus) *gtfsrt.FeedEntity {
+		return &gtfsrt.FeedEntity{Id: proto.String(id), Vehicle: &gtfsrt.VehiclePosition{
+			Trip:          &gtfsrt.TripDescriptor{TripId: proto.String(tripID), RouteId: proto.String("N")},
+			StopId:        proto.String(stopID),
+			CurrentStatus: status.Enum(),
+			Timestamp:     proto.Uint64(uint64(testNow.Add(-10 * time.Second).Unix())),
+		}}
+	}
+
+	data, err := proto.Marshal(&gtfsrt.FeedMessage{
+		Header: &gtfsrt.FeedHeader{GtfsRealtimeVersion: proto.String("1.0")},
+		Entity: []*gtfsrt.FeedEntity{
+			// Stopped at 34 St; the passed 14 St arrival is still inside the past-arrival cutoff
+			tripUpdate("1", "T1", map[string]time.Time{
+				"R20N": testNow.Add(-30 * time.Second),
+				"R17N": testNow.Add(30 * time.Second),
+				"R16N": testNow.Add(3 * time.Minute),
+			}),
+			vehicle("2", "T1", "R17N", gtfsrt.VehiclePosition_STOPPED_AT),
+			// Predictions but no vehicle position
+			tripUpdate("3", "T2", map[string]time.Time{
+				"R16N": testNow.Add(7 * time.Minute),
+				"R17N": testNow.Add(5 * time.Minute),
+			}),
+			// A vehicle with no predictions left
+			vehicle("4", "T3", "R16N", gtfsrt.VehiclePosition_INCOMING_AT),
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to marshal feed: %v", err)
+	}
+
+	s := store.NewStore()
+	s.UpdateStations(map[string]*models.Station{
+		"R16": {ID: "R16", Name: "Times Sq-42 St", Routes: []string{"N"}},
+		"R17": {ID: "R17", Name: "34 St-Herald Sq", Routes: []string{"N"}},
+		"R20": {ID: "R20", Name: "14 St-Union Sq", Routes: []string{"N"}},
+	})
+	m := NewManager("test-key", s, time.Minute)
+	m.SetClock(clock.NewFake(testNow))
+	m.SetFetcher(&mapFetcher{data: map[string][]byte{FeedGroups["nqrw"]: data}})
+	if err := m.SetFeedGroups([]string{"nqrw"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if err := m.updateRealTimeData(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	updated := time.Unix(testNow.Add(-10*time.Second).Unix(), 0)
+	tests := []struct {
+		tripID   string
+		expected models.TripProgress
+	}{
+		{"T1", models.TripProgress{
+			TripID: "T1", Route: "N", Direction: "N",
+			Current: &models.TripPosition{StationID: "R17", Name: "34 St-Herald Sq", Status: "STOPPED_AT", Updated: updated},
+			Upcoming: []models.TripStop{
+				{StationID: "R16", Name: "Times Sq-42 St", Time: time.Unix(testNow.Add(3*time.Minute).Unix(), 0)},
+			},
+		}},
+		{"T2", models.TripProgress{
+			TripID: "T2", Route: "N", Direction: "N",
+			Upcoming: []models.TripStop{
+				{StationID: "R17", Name: "34 St-Herald Sq", Time: time.Unix(testNow.Add(5*time.Minute).Unix(), 0)},
+				{StationID: "R16", Name: "Times Sq-42 St", Time: time.Unix(testNow.Add(7*time.Minute).Unix(), 0)},
+			},
+		}},
+		{"T3", models.TripProgress{
+			TripID: "T3", Route: "N", Direction: "N",
+			Current:  &models.TripPosition{StationID: "R16", Name: "Times Sq-42 St", Status: "INCOMING_AT", Updated: updated},
+			Upcoming: []models.TripStop{},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.tripID, func(t *testing.T) {
+			got, err := s.GetTripProgress(tt.tripID)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Expected %+v, got %+v", tt.expected, got)
+			}
+		})
+	}
+
+	if _, err := s.GetTripProgress("T9"); err == nil {
+		t.Error("Expected error for unknown trip")
+	}
+}
+
+func TestSplitStopID(t *testing.T) {
+	tests := []struct {
+		stopID    string
+		station   string
+		direction models.Direction
+	}{
+		{"R16N", "R16", models.DirectionNorth},
+		{"127S", "127", models.DirectionSouth},
+		{"R16", "R16", models.DirectionUnknown},
+		{"", "", models.DirectionUnknown},
+	}
+
+	for _, tt := range tests {
+		station, direction := splitStopID(tt.stopID)
+		if station != tt.station || direction != tt.direction {
+			t.Errorf("splitStopID(%q) = %q, %q; expected %q, %q", tt.stopID, station, direction, tt.station, tt.direction)
+		}
+	}
+}
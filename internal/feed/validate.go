@@ -0,0 +1,141 @@
+package feed
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// requiredGTFSFiles must exist for the static load to succeed
+var requiredGTFSFiles = []string{"stops.txt", "routes.txt", "trips.txt", "stop_times.txt"}
+
+// optionalGTFSFiles are published by the MTA but not needed for station data
+var optionalGTFSFiles = []string{"agency.txt", "calendar.txt", "calendar_dates.txt", "shapes.txt", "transfers.txt"}
+
+// NYC subway service area; stops outside it almost certainly have swapped or corrupt coordinates
+const (
+	minValidLat = 40.4
+	maxValidLat = 41.0
+	minValidLon = -74.5
+	maxValidLon = -73.5
+)
+
+// ValidationReport summarizes a static GTFS directory without loading it into the store
+type ValidationReport struct {
+	Stations  int      `json:"stations"`
+	Routes    int      `json:"routes"`
+	Trips     int      `json:"trips"`
+	StopTimes int      `json:"stop_times"`
+	Warnings  []string `json:"warnings"`
+}
+
+// ValidateStaticGTFS parses a GTFS directory the same way a live load would and reports what it found
+// Returns an error only when the data could not be loaded at all; recoverable problems become warnings.
+// The store is never touched, so this is safe to run against a release candidate
+func (m *Manager) ValidateStaticGTFS(dir string) (*ValidationReport, error) {
+	report := &ValidationReport{Warnings: []string{}}
+
+	for _, name := range requiredGTFSFiles {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			return nil, fmt.Errorf("missing required file %s: %w", name, err)
+		}
+	}
+	for _, name := range optionalGTFSFiles {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			report.Warnings = append(report.Warnings, fmt.Sprintf("missing optional file %s", name))
+		}
+	}
+
+	stopWarnings, err := validateStopRows(filepath.Join(dir, "stops.txt"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan stops: %w", err)
+	}
+	report.Warnings = append(report.Warnings, stopWarnings...)
+
+	stations, err := m.parseStops(filepath.Join(dir, "stops.txt"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse stops: %w", err)
+	}
+	report.Stations = len(stations)
+
+	routes, err := m.parseRoutesFile(filepath.Join(dir, "routes.txt"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse routes: %w", err)
+	}
+	report.Routes = len(routes)
+
+	routeTrips, _, err := m.parseTripsFile(filepath.Join(dir, "trips.txt"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse trips: %w", err)
+	}
+	for routeID, trips := range routeTrips {
+		report.Trips += len(trips)
+		if _, ok := routes[routeID]; !ok {
+			report.Warnings = append(report.Warnings, fmt.Sprintf("trips.txt references unknown route_id %s", routeID))
+		}
+	}
+
+	tripStops, err := m.parseStopTimesFile(filepath.Join(dir, "stop_times.txt"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse stop_times: %w", err)
+	}
+	for _, stops := range tripStops {
+		report.StopTimes += len(stops)
+	}
+
+	if report.Stations == 0 {
+		report.Warnings = append(report.Warnings, "no parent stations found in stops.txt")
+	}
+
+	return report, nil
+}
+
+// validateStopRows reports stops.txt rows the parser would silently skip or accept with suspect data
+// Reads record by record so every bad row is reported rather than just the first
+func validateStopRows(stopsFile string) ([]string, error) {
+	file, err := os.Open(stopsFile)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader := newCSVReader(file)
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read header: %w", err)
+	}
+	columns := headerColumns(header)
+
+	var warnings []string
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := reader.FieldPos(0)
+
+		if len(record) != len(header) {
+			warnings = append(warnings, fmt.Sprintf("stops.txt line %d: expected %d fields, got %d", line, len(header), len(record)))
+			continue
+		}
+
+		stopID := record[columns["stop_id"]]
+		lat, latErr := strconv.ParseFloat(record[columns["stop_lat"]], 64)
+		lon, lonErr := strconv.ParseFloat(record[columns["stop_lon"]], 64)
+		if latErr != nil || lonErr != nil {
+			warnings = append(warnings, fmt.Sprintf("stops.txt line %d: stop %s has unparseable coordinates", line, stopID))
+			continue
+		}
+		if lat < minValidLat || lat > maxValidLat || lon < minValidLon || lon > maxValidLon {
+			warnings = append(warnings, fmt.Sprintf("stops.txt line %d: stop %s coordinates (%f, %f) out of bounds", line, stopID, lat, lon))
+		}
+	}
+
+	return warnings, nil
+}
@@ -0,0 +1,124 @@
+package feed
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jusunglee/mta-go/internal/models"
+	"github.com/jusunglee/mta-go/internal/store"
+)
+
+// writeGTFSDir writes a minimal GTFS directory from file name -> contents
+func writeGTFSDir(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	return dir
+}
+
+// routesIn parses routes.txt in dir, for loaders that take already-parsed routes
+func routesIn(t *testing.T, dir string) map[string]models.RouteInfo {
+	t.Helper()
+	routes, err := (&Manager{}).parseRoutesFile(filepath.Join(dir, "routes.txt"))
+	if err != nil {
+		t.Fatalf("Failed to parse routes: %v", err)
+	}
+	return routes
+}
+
+func goodGTFSFiles() map[string]string {
+	return map[string]string{
+		"agency.txt":         "agency_id,agency_name\nMTA NYCT,MTA New York City Transit\n",
+		"calendar.txt":       "service_id,monday\nWeekday,1\n",
+		"calendar_dates.txt": "service_id,date,exception_type\n",
+		"shapes.txt":         "shape_id,shape_pt_lat,shape_pt_lon,shape_pt_sequence\n",
+		"transfers.txt":      "from_stop_id,to_stop_id,transfer_type\n",
+		"stops.txt": "stop_id,stop_name,stop_lat,stop_lon,location_type,parent_station\n" +
+			"127,Times Sq-42 St,40.75529,-73.987495,1,\n" +
+			"127N,Times Sq-42 St,40.75529,-73.987495,,127\n" +
+			"127S,Times Sq-42 St,40.75529,-73.987495,,127\n" +
+			"631,Grand Central-42 St,40.751776,-73.976848,1,\n" +
+			"631N,Grand Central-42 St,40.751776,-73.976848,,631\n",
+		"routes.txt": "route_id,route_short_name,route_long_name\n" +
+			"1,1,Broadway - 7 Avenue Local\n" +
+			"6,6,Lexington Avenue Local\n",
+		"trips.txt": "route_id,trip_id\n" +
+			"1,T1\n" +
+			"6,T6\n",
+		"stop_times.txt": "trip_id,stop_id\n" +
+			"T1,127N\n" +
+			"T1,127S\n" +
+			"T6,631N\n",
+	}
+}
+
+func TestValidateStaticGTFS(t *testing.T) {
+	t.Run("good fixture", func(t *testing.T) {
+		s := store.NewStore()
+		m := &Manager{store: s}
+
+		report, err := m.ValidateStaticGTFS(writeGTFSDir(t, goodGTFSFiles()))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		if report.Stations != 2 || report.Routes != 2 || report.Trips != 2 || report.StopTimes != 3 {
+			t.Errorf("Unexpected counts: %+v", report)
+		}
+		if len(report.Warnings) != 0 {
+			t.Errorf("Expected no warnings, got %v", report.Warnings)
+		}
+		if len(s.GetRoutes()) != 0 {
+			t.Error("Validation must not modify the live store")
+		}
+	})
+
+	t.Run("broken fixture", func(t *testing.T) {
+		files := goodGTFSFiles()
+		delete(files, "shapes.txt")
+		files["stops.txt"] = "stop_id,stop_name,stop_lat,stop_lon,location_type,parent_station\n" +
+			"127,Times Sq-42 St,40.75529,-73.987495,1,\n" +
+			"127N,Times Sq-42 St,not-a-lat,-73.987495,,127\n" +
+			"128,\"Two\nLines\",40.75,-73.98,1,\n" +
+			"999,Null Island,0,0,1,\n" +
+			"130,Short,40.75\n"
+		files["trips.txt"] = "route_id,trip_id\n1,T1\nX,TX\n"
+
+		m := &Manager{}
+		report, err := m.ValidateStaticGTFS(writeGTFSDir(t, files))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		expected := []string{
+			"missing optional file shapes.txt",
+			"stop 127N has unparseable coordinates",
+			"stop 999 coordinates (0.000000, 0.000000) out of bounds",
+			// The quoted name spans lines 4 and 5, so the short row is on line 7
+			"stops.txt line 7: expected 6 fields, got 3",
+			"unknown route_id X",
+		}
+		all := strings.Join(report.Warnings, "\n")
+		for _, want := range expected {
+			if !strings.Contains(all, want) {
+				t.Errorf("Expected warning containing %q, got:\n%s", want, all)
+			}
+		}
+	})
+
+	t.Run("missing required file", func(t *testing.T) {
+		files := goodGTFSFiles()
+		delete(files, "stop_times.txt")
+
+		m := &Manager{}
+		if _, err := m.ValidateStaticGTFS(writeGTFSDir(t, files)); err == nil {
+			t.Error("Expected error for missing stop_times.txt")
+		}
+	})
+}
@@ -0,0 +1,49 @@
+package feed
+
+import "log/slog"
+
+// DefaultGTFSRealtimeVersion is the gtfs_realtime_version the MTA feeds declare and this parser is tested against
+const DefaultGTFSRealtimeVersion = "1.0"
+
+// SetExpectedRealtimeVersion sets the GTFS-RT version feeds are expected to declare
+// Feeds declaring anything else are still parsed, but logged and flagged in /feed-info.
+// Empty restores DefaultGTFSRealtimeVersion
+func (m *Manager) SetExpectedRealtimeVersion(version string) {
+	m.expectedVersion = version
+}
+
+// realtimeVersion returns the effective expected GTFS-RT version
+func (m *Manager) realtimeVersion() string {
+	if m.expectedVersion == "" {
+		return DefaultGTFSRealtimeVersion
+	}
+	return m.expectedVersion
+}
+
+// checkFeedVersion records the version a feed declared and warns when it isn't the expected one
+// A new version can move or reinterpret fields without failing to parse, so this is the only sign
+// that arrivals may be misread. Logs only when a feed's version changes, not on every fetch
+func (m *Manager) checkFeedVersion(url, version string) {
+	m.latencyMu.Lock()
+	if m.latencies == nil {
+		m.latencies = make(map[string]*feedLatency)
+	}
+	stats, ok := m.latencies[url]
+	if !ok {
+		stats = &feedLatency{}
+		m.latencies[url] = stats
+	}
+	changed := !stats.parsed || stats.version != version
+	stats.version, stats.parsed = version, true
+	m.latencyMu.Unlock()
+
+	if !changed {
+		return
+	}
+	expected := m.realtimeVersion()
+	if version != expected {
+		slog.Warn("Feed declares an untested GTFS-RT version", "feed", feedGroupName(url), "version", version, "expected", expected)
+		return
+	}
+	slog.Info("Feed GTFS-RT version", "feed", feedGroupName(url), "version", version)
+}
@@ -0,0 +1,85 @@
+package models
+
+import (
+	"maps"
+	"strings"
+)
+
+// routeDirectionLabels names where trains head on routes whose N and S platform suffixes mislead riders
+// Feeds still file these trains under N and S; only the wording shown to riders changes
+// Never modified; callers get copies from DirectionLabelsFor
+var routeDirectionLabels = map[string]map[Direction]string{
+	// The G runs crosstown between Queens and Brooklyn, mostly east-west: N is Court Sq-bound, S Church Av-bound
+	"G": {DirectionNorth: "Court Sq", DirectionSouth: "Church Av"},
+}
+
+// DirectionLabelsFor returns the direction labels of route, matching case-insensitively
+// Nil means north and south are accurate enough for that route; otherwise the map is the caller's own copy
+func DirectionLabelsFor(route string) map[Direction]string {
+	return maps.Clone(routeDirectionLabels[strings.ToUpper(route)])
+}
+
+// directionLabels returns the labels every route at the station shares, nil if any route has none or they disagree
+// A station shared with a north-south route keeps plain N and S, since one label can't describe both
+// The result is a copy, safe to hand out in responses
+func (s *Station) directionLabels() map[Direction]string {
+	var labels map[Direction]string
+	for i, route := range s.Routes {
+		routeLabels := routeDirectionLabels[strings.ToUpper(route)]
+		if routeLabels == nil {
+			return nil
+		}
+		if i == 0 {
+			labels = routeLabels
+			continue
+		}
+		if routeLabels[DirectionNorth] != labels[DirectionNorth] || routeLabels[DirectionSouth] != labels[DirectionSouth] {
+			return nil
+		}
+	}
+	return maps.Clone(labels)
+}
+
+// RouteDirections is the direction choice offered for one route, e.g. for a route picker
+type RouteDirections struct {
+	Route      string           `json:"route"`
+	Directions []RouteDirection `json:"directions"`
+	// Loop is set when both directions show the same headsign, so it can't tell them apart
+	Loop bool `json:"loop,omitempty"`
+}
+
+// RouteDirection is one direction a route runs in
+// Label is what to show riders: the headsign, unless a label from DirectionLabelsFor or the compass reads better
+type RouteDirection struct {
+	Direction Direction `json:"direction"`
+	Headsign  string    `json:"headsign,omitempty"`
+	Label     string    `json:"label"`
+}
+
+// compassLabels are the labels of last resort, for feeds without headsigns and for loops
+var compassLabels = map[Direction]string{DirectionNorth: "Northbound", DirectionSouth: "Southbound"}
+
+// Directions lists the directions the route runs in, northbound first
+// Only directions its static trips run in are listed, so a one-way loop has one; without any
+// headsign data both are listed under their compass or DirectionLabelsFor names
+func (r RouteInfo) Directions() RouteDirections {
+	result := RouteDirections{Route: r.ShortName, Directions: []RouteDirection{}}
+	north, south := r.Headsigns[DirectionNorth], r.Headsigns[DirectionSouth]
+	result.Loop = north != "" && north == south
+
+	for _, direction := range []Direction{DirectionNorth, DirectionSouth} {
+		headsign, ok := r.Headsigns[direction]
+		if !ok && len(r.Headsigns) > 0 {
+			continue
+		}
+		label := headsign
+		if result.Loop || label == "" {
+			label = compassLabels[direction]
+		}
+		if custom, ok := r.DirectionLabels[direction]; ok {
+			label = custom
+		}
+		result.Directions = append(result.Directions, RouteDirection{Direction: direction, Headsign: headsign, Label: label})
+	}
+	return result
+}
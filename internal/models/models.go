@@ -1,6 +1,9 @@
 package models
 
 import (
+	"math"
+	"sort"
+	"strings"
 	"time"
 )
 
@@ -9,11 +12,63 @@ type Location struct {
 	Lon float64 `json:"lon"`
 }
 
+// DistanceKm calculates the distance to another point using the Haversine formula
+// Assumes Earth radius of 6371km
+func (l Location) DistanceKm(to Location) float64 {
+	const R = 6371 // Earth's radius in kilometers
+
+	lat1Rad := l.Lat * math.Pi / 180
+	lat2Rad := to.Lat * math.Pi / 180
+	deltaLat := (to.Lat - l.Lat) * math.Pi / 180
+	deltaLon := (to.Lon - l.Lon) * math.Pi / 180
+
+	a := math.Sin(deltaLat/2)*math.Sin(deltaLat/2) +
+		math.Cos(lat1Rad)*math.Cos(lat2Rad)*
+			math.Sin(deltaLon/2)*math.Sin(deltaLon/2)
+	c := 2 * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
+
+	return R * c
+}
+
+// Train is a single predicted arrival
+// Source is the feed group that reported it, only exposed in debug responses
 type Train struct {
-	Route string    `json:"route"`
-	Time  time.Time `json:"time"`
+	Route  string    `json:"route"`
+	Time   time.Time `json:"time"`
+	Source string    `json:"source,omitempty"`
+	// TripID identifies the GTFS-RT trip; used to deduplicate arrivals and not part of the API
+	TripID string `json:"-"`
+	// UnlistedRoute marks an arrival for a route the station doesn't list in static data, e.g. a diversion
+	UnlistedRoute bool `json:"unlisted_route,omitempty"`
+	// Scheduled marks an arrival taken from the static timetable because real-time data was missing
+	Scheduled bool `json:"scheduled,omitempty"`
+	// Realtime is true for GTFS-RT predictions; always sent so clients needn't infer it from a missing field
+	Realtime bool `json:"realtime"`
+	// Assigned is false for predictions NYCT hasn't yet matched to a physical train, which are less reliable
+	Assigned bool `json:"assigned"`
+	// Direction is only set in a timeline, where the list no longer says it
+	Direction Direction `json:"direction,omitempty"`
+
+	// The remaining fields are only sent with ?verbose=true, and each only when the data has it
+	// StopSequence is the stop's position in its trip, from the real-time feed or the static timetable
+	StopSequence uint32 `json:"stop_sequence,omitempty"`
+	// ScheduledTrack and ActualTrack come from the NYCT extension; they differ when a train is sent down another track
+	ScheduledTrack string `json:"scheduled_track,omitempty"`
+	ActualTrack    string `json:"actual_track,omitempty"`
+	// ScheduledTime is Time less the delay the feed reports, so only set for arrivals with a delay
+	ScheduledTime *time.Time `json:"scheduled_time,omitempty"`
 }
 
+// Direction is a train's direction of travel, spelled as the suffix of a GTFS-RT stop ID ("127N")
+// A string type so it serializes as N/S without custom marshaling; DirectionUnknown is empty and omitted by omitempty
+type Direction string
+
+const (
+	DirectionUnknown Direction = ""
+	DirectionNorth   Direction = "N"
+	DirectionSouth   Direction = "S"
+)
+
 // TrainsByDirection separates trains by subway direction (North/South)
 // This mirrors the MTA's directional conventions for NYC subway
 type TrainsByDirection struct {
@@ -24,26 +79,160 @@ type TrainsByDirection struct {
 // Station represents a subway station with real-time data
 // Trains field uses json:"-" to exclude from JSON serialization - use ConvertToResponse for API output
 type Station struct {
-	ID         string              `json:"id"`
-	Name       string              `json:"name"`
-	Location   Location            `json:"location"`
-	Routes     []string            `json:"routes"`
-	Trains     TrainsByDirection   `json:"-"`
-	Stops      map[string]Location `json:"stops"`
-	LastUpdate time.Time           `json:"last_update"`
+	ID       string   `json:"id"`
+	Name     string   `json:"name"`
+	Location Location `json:"location"`
+	Routes   []string `json:"routes"`
+	// OneWayRoutes maps routes that only stop here in one direction to that direction, e.g. {"5": "S"}
+	// Taken from the static stop patterns; routes stopping both ways aren't listed
+	OneWayRoutes map[string]Direction `json:"one_way_routes,omitempty"`
+	Trains       TrainsByDirection    `json:"-"`
+	Stops        map[string]Location  `json:"stops"`
+	LastUpdate   time.Time            `json:"last_update"`
+}
+
+// StationSummary is a station without its stops or arrivals, for list views that only need to name and place it
+// Location is [lat, lon] as in StationResponse, so it can be served as is
+type StationSummary struct {
+	ID       string     `json:"id"`
+	Name     string     `json:"name"`
+	Location [2]float64 `json:"location"`
+	Routes   []string   `json:"routes"`
 }
 
 // StationResponse is the API response format for a station
 // Uses [2]float64 arrays instead of Location structs for more compact JSON output
 type StationResponse struct {
-	ID         string                `json:"id"`
-	Name       string                `json:"name"`
-	Location   [2]float64            `json:"location"`
-	Routes     []string              `json:"routes"`
-	N          []Train               `json:"N"`
-	S          []Train               `json:"S"`
+	ID       string     `json:"id"`
+	Name     string     `json:"name"`
+	Location [2]float64 `json:"location"`
+	Routes   []string   `json:"routes"`
+	// OneWayRoutes are the routes that only stop here northbound or southbound, with that direction
+	OneWayRoutes map[string]Direction `json:"one_way_routes,omitempty"`
+	// DirectionLabels say where N and S trains head when compass directions mislead, e.g. Court Sq and Church Av on the G
+	DirectionLabels map[Direction]string `json:"direction_labels,omitempty"`
+	// ActiveRoutes are the routes with upcoming real-time arrivals, a subset of Routes outside diversions
+	ActiveRoutes []string `json:"active_routes"`
+	N            []Train  `json:"N"`
+	S            []Train  `json:"S"`
+	// Timeline merges N and S in arrival order for single-column boards, only with ?layout=timeline
+	// N and S are null in that layout, and timeline itself is left out when there are no arrivals
+	Timeline   []Train               `json:"timeline,omitempty"`
 	Stops      map[string][2]float64 `json:"stops"`
 	LastUpdate time.Time             `json:"last_update"`
+	// Stale is set when LastUpdate is older than the server's stale-after TTL, so a map can flag the station
+	Stale bool `json:"stale,omitempty"`
+	// Distance from the queried point, only set by location queries
+	Distance *Distance `json:"distance,omitempty"`
+	// WalkMinutes is the straight-line walk from the queried point in whole minutes, only set by /reachable
+	WalkMinutes int `json:"walk_minutes,omitempty"`
+}
+
+// Distance is a length in the units the client asked for
+type Distance struct {
+	Value float64 `json:"value"`
+	Unit  string  `json:"unit"` // "m", "km", "ft" or "mi"
+}
+
+// StationRouteArrivalsResponse groups a station's arrivals by route within each direction
+// Lets UIs render "N: 2, 7 min / Q: 4 min" without regrouping the flat arrays
+type StationRouteArrivalsResponse struct {
+	ID         string                 `json:"id"`
+	Name       string                 `json:"name"`
+	N          map[string][]time.Time `json:"N"`
+	S          map[string][]time.Time `json:"S"`
+	LastUpdate time.Time              `json:"last_update"`
+}
+
+// TripProgress is where an in-flight train is and the stops still ahead of it
+// Current is nil when the feed had no vehicle position for the trip; Upcoming then holds only arrival predictions
+type TripProgress struct {
+	TripID    string        `json:"trip_id"`
+	Route     string        `json:"route"`
+	Direction Direction     `json:"direction,omitempty"`
+	Current   *TripPosition `json:"current,omitempty"`
+	Upcoming  []TripStop    `json:"upcoming"`
+}
+
+// TripPosition is a train's reported position relative to a station
+// Status is the GTFS-RT stop status: STOPPED_AT, INCOMING_AT or IN_TRANSIT_TO
+type TripPosition struct {
+	StationID string    `json:"station_id"`
+	Name      string    `json:"name"`
+	Status    string    `json:"status"`
+	Updated   time.Time `json:"updated,omitempty"`
+}
+
+// TripStop is one predicted stop on a trip, in travel order
+type TripStop struct {
+	StationID string    `json:"station_id"`
+	Name      string    `json:"name"`
+	Time      time.Time `json:"time"`
+}
+
+// GeoJSONFeatureCollection is a RFC 7946 FeatureCollection for web map clients
+// Truncated is a foreign member, which the spec permits, set when the station cap was hit
+type GeoJSONFeatureCollection struct {
+	Type      string           `json:"type"`
+	Features  []GeoJSONFeature `json:"features"`
+	Truncated bool             `json:"truncated,omitempty"`
+}
+
+type GeoJSONFeature struct {
+	Type       string                 `json:"type"`
+	Geometry   GeoJSONGeometry        `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+// GeoJSONGeometry holds a Point geometry
+// Coordinates are [lon, lat] per the GeoJSON spec, the reverse of our [lat, lon] responses
+type GeoJSONGeometry struct {
+	Type        string     `json:"type"`
+	Coordinates [2]float64 `json:"coordinates"`
+}
+
+// GeoJSONLineFeature is a route geometry Feature
+// Geometry is a LineString for a single shape or a MultiLineString when the route has branches
+type GeoJSONLineFeature struct {
+	Type       string                 `json:"type"`
+	Geometry   GeoJSONLineGeometry    `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+// GeoJSONLineGeometry holds a LineString ([][2]float64) or MultiLineString ([][][2]float64) in [lon, lat] order
+type GeoJSONLineGeometry struct {
+	Type        string      `json:"type"`
+	Coordinates interface{} `json:"coordinates"`
+}
+
+// NewRouteShapeFeature converts route polylines to GeoJSON, swapping to [lon, lat]
+func NewRouteShapeFeature(route string, lines [][]Location) GeoJSONLineFeature {
+	coords := make([][][2]float64, len(lines))
+	for i, line := range lines {
+		coords[i] = make([][2]float64, len(line))
+		for j, loc := range line {
+			coords[i][j] = [2]float64{loc.Lon, loc.Lat}
+		}
+	}
+
+	geometry := GeoJSONLineGeometry{Type: "MultiLineString", Coordinates: coords}
+	if len(coords) == 1 {
+		geometry = GeoJSONLineGeometry{Type: "LineString", Coordinates: coords[0]}
+	}
+
+	return GeoJSONLineFeature{
+		Type:       "Feature",
+		Geometry:   geometry,
+		Properties: map[string]interface{}{"route": route},
+	}
+}
+
+// Bounds is a latitude/longitude bounding box
+type Bounds struct {
+	MinLat float64 `json:"min_lat"`
+	MinLon float64 `json:"min_lon"`
+	MaxLat float64 `json:"max_lat"`
+	MaxLon float64 `json:"max_lon"`
 }
 
 type Alert struct {
@@ -53,6 +242,44 @@ type Alert struct {
 	Routes        []string     `json:"routes"`
 	Stations      []string     `json:"stations"`
 	ActivePeriods []TimePeriod `json:"active_periods"`
+	FirstSeen     time.Time    `json:"-"` // When this service first received the alert; zero if unknown
+}
+
+// IsActiveAt reports whether any active period covers t
+// GTFS-RT treats an alert without active periods as always in effect
+func (a Alert) IsActiveAt(t time.Time) bool {
+	if len(a.ActivePeriods) == 0 {
+		return true
+	}
+	for _, period := range a.ActivePeriods {
+		if period.Start != nil && t.Before(*period.Start) {
+			continue
+		}
+		if period.End != nil && !t.Before(*period.End) {
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+// OverlapsRange reports whether any active period overlaps [start, end)
+// A zero start or end leaves that side of the range unbounded; periods are half-open
+// like IsActiveAt, so a period ending exactly at start doesn't overlap
+func (a Alert) OverlapsRange(start, end time.Time) bool {
+	if len(a.ActivePeriods) == 0 {
+		return true
+	}
+	for _, period := range a.ActivePeriods {
+		if period.Start != nil && !end.IsZero() && !period.Start.Before(end) {
+			continue
+		}
+		if period.End != nil && !start.IsZero() && !start.Before(*period.End) {
+			continue
+		}
+		return true
+	}
+	return false
 }
 
 // TimePeriod represents a time range
@@ -62,6 +289,121 @@ type TimePeriod struct {
 	End   *time.Time `json:"end,omitempty"`
 }
 
+// RouteInfo describes a subway route from GTFS routes.txt
+// StationCount is derived from the store's route index rather than the static file
+type RouteInfo struct {
+	ShortName        string `json:"short_name"`
+	LongName         string `json:"long_name"`
+	Description      string `json:"description"`
+	Color            string `json:"color"`
+	StationCount     int    `json:"station_count"`
+	ActiveAlertCount int    `json:"active_alert_count"`
+	// DirectionLabels name where N and S trains head on routes where compass directions mislead
+	DirectionLabels map[Direction]string `json:"direction_labels,omitempty"`
+	// Headsigns are the trip_headsign most of the route's static trips show in each direction
+	Headsigns map[Direction]string `json:"headsigns,omitempty"`
+}
+
+// Fare is a fare from GTFS fare_attributes.txt and the fare_rules.txt rows saying where it applies
+// A fare without rules applies across the whole system, which is how flat-fare feeds like NYC's describe it
+type Fare struct {
+	ID       string     `json:"id"`
+	Price    float64    `json:"price"`
+	Currency string     `json:"currency"`
+	Rules    []FareRule `json:"rules,omitempty"`
+}
+
+// FareRule limits a fare to a route and/or fare zones; empty fields match anything
+// Route is the route short name, as elsewhere in the API, rather than the GTFS route_id
+type FareRule struct {
+	Route           string `json:"route,omitempty"`
+	OriginZone      string `json:"origin_zone,omitempty"`
+	DestinationZone string `json:"destination_zone,omitempty"`
+	ContainsZone    string `json:"contains_zone,omitempty"`
+}
+
+// AppliesTo reports whether the fare can be charged on route or in zone; an empty argument matches anything
+func (f Fare) AppliesTo(route, zone string) bool {
+	if len(f.Rules) == 0 {
+		return true
+	}
+	for _, rule := range f.Rules {
+		if rule.matches(route, zone) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r FareRule) matches(route, zone string) bool {
+	if route != "" && r.Route != "" && !strings.EqualFold(r.Route, route) {
+		return false
+	}
+	if zone == "" || (r.OriginZone == "" && r.DestinationZone == "" && r.ContainsZone == "") {
+		return true
+	}
+	return r.OriginZone == zone || r.DestinationZone == zone || r.ContainsZone == zone
+}
+
+// FeedLatency reports how long fetches of a single GTFS-RT feed take, the GTFS-RT version it declares and whether fetches are being skipped
+// AverageMs is an exponential moving average so it follows sustained changes without jumping on one slow fetch
+type FeedLatency struct {
+	Feed              string  `json:"feed"`
+	LastMs            float64 `json:"last_ms"`
+	AverageMs         float64 `json:"average_ms"`
+	Samples           int     `json:"samples"`
+	Version           string  `json:"gtfs_realtime_version,omitempty"`
+	UnexpectedVersion bool    `json:"unexpected_version,omitempty"`
+	Breaker           string  `json:"breaker"`          // Circuit breaker state of the feed's host: closed, open or half-open
+	Status            string  `json:"status,omitempty"` // Latest fetch outcome: ok, auth_failed (HTTP 401/403) or error
+}
+
+// Stats is a quick view of feed activity and store contents for ad-hoc debugging
+// Deliberately small so it works without a metrics stack; times are omitted until the event first happens
+type Stats struct {
+	LoadStatus       string      `json:"load_status"` // loading until the initial load finishes, then ready, degraded or failed
+	Stations         int         `json:"stations"`
+	Routes           int         `json:"routes"`
+	Alerts           int         `json:"alerts"`
+	UpdateCycles     int         `json:"update_cycles"`
+	Panics           int         `json:"panics"` // Recovered panics; any nonzero value points at malformed upstream data or a bug
+	LastUpdate       *time.Time  `json:"last_update,omitempty"`
+	LastStaticUpdate *time.Time  `json:"last_static_update,omitempty"`
+	StaticParseMs    float64     `json:"static_parse_ms"`
+	Feeds            []FeedStats `json:"feeds"`
+}
+
+// FeedStats counts fetches of a single GTFS-RT feed and keeps its most recent error
+// LastParseMs covers unmarshalling and entity processing, not the network fetch
+type FeedStats struct {
+	Feed        string     `json:"feed"`
+	Fetches     int        `json:"fetches"`
+	Failures    int        `json:"failures"`
+	LastSuccess *time.Time `json:"last_success,omitempty"`
+	LastError   string     `json:"last_error,omitempty"`
+	LastErrorAt *time.Time `json:"last_error_at,omitempty"`
+	LastParseMs float64    `json:"last_parse_ms"`
+}
+
+// RouteStatus summarizes how long riders wait for the next train across every station on a route
+// Waits only count real-time arrivals; the minute fields are omitted when no station has one
+type RouteStatus struct {
+	Route              string   `json:"route"`
+	Stations           int      `json:"stations"`
+	StationsWithTrains int      `json:"stations_with_trains"`
+	MedianMinutes      *float64 `json:"median_minutes,omitempty"`
+	P90Minutes         *float64 `json:"p90_minutes,omitempty"`
+}
+
+// CoverageReport lists stations that received no real-time arrivals in the latest update cycle
+// Persistent entries usually point at feed gaps or stop ID mapping bugs rather than quiet stations
+type CoverageReport struct {
+	TotalStations  int                 `json:"total_stations"`
+	MissingCount   int                 `json:"missing_count"`
+	Missing        []string            `json:"missing"`          // Station IDs, sorted
+	MissingByRoute map[string][]string `json:"missing_by_route"` // Route -> station IDs, sorted
+}
+
 type FeedInfo struct {
 	LastUpdate time.Time `json:"last_update"`
 	Routes     []string  `json:"routes"`
@@ -77,13 +419,112 @@ func (s *Station) ConvertToResponse() StationResponse {
 	}
 
 	return StationResponse{
+		ID:           s.ID,
+		Name:         s.Name,
+		Location:     [2]float64{s.Location.Lat, s.Location.Lon},
+		Routes:       s.Routes,
+		OneWayRoutes: s.OneWayRoutes,
+		// A copy, so changing the response can't change another station's labels
+		DirectionLabels: s.directionLabels(),
+		ActiveRoutes:    s.activeRoutes(),
+		N:               s.Trains.North,
+		S:               s.Trains.South,
+		Stops:           stops,
+		LastUpdate:      s.LastUpdate,
+	}
+}
+
+// ConvertToTimelineResponse is ConvertToResponse with both directions merged into Timeline
+func (s *Station) ConvertToTimelineResponse() StationResponse {
+	resp := s.ConvertToResponse()
+	resp.Timeline = s.Trains.Timeline()
+	resp.N, resp.S = nil, nil
+	return resp
+}
+
+// Timeline returns every arrival in time order, each labeled with its direction
+// Trains are copied, so labeling never touches the store's shared slices
+func (t TrainsByDirection) Timeline() []Train {
+	timeline := make([]Train, 0, len(t.North)+len(t.South))
+	for _, train := range t.North {
+		train.Direction = DirectionNorth
+		timeline = append(timeline, train)
+	}
+	for _, train := range t.South {
+		train.Direction = DirectionSouth
+		timeline = append(timeline, train)
+	}
+	sort.SliceStable(timeline, func(i, j int) bool {
+		return timeline[i].Time.Before(timeline[j].Time)
+	})
+	return timeline
+}
+
+// activeRoutes lists the routes with arrivals in either direction, in the station's route order
+// Routes the station doesn't list statically (diversions) follow, sorted; empty rather than nil so JSON shows [].
+// Scheduled fallback arrivals don't count, since the timetable says nothing about what is running now
+func (s *Station) activeRoutes() []string {
+	running := make(map[string]bool)
+	for _, trains := range [][]Train{s.Trains.North, s.Trains.South} {
+		for _, train := range trains {
+			if !train.Scheduled {
+				running[train.Route] = true
+			}
+		}
+	}
+
+	active := make([]string, 0, len(running))
+	for _, route := range s.Routes {
+		if running[route] {
+			active = append(active, route)
+			delete(running, route)
+		}
+	}
+	unlisted := make([]string, 0, len(running))
+	for route := range running {
+		unlisted = append(unlisted, route)
+	}
+	sort.Strings(unlisted)
+	return append(active, unlisted...)
+}
+
+// ConvertToRouteArrivals converts internal Station to the grouped-by-route response format
+func (s *Station) ConvertToRouteArrivals() StationRouteArrivalsResponse {
+	return StationRouteArrivalsResponse{
 		ID:         s.ID,
 		Name:       s.Name,
-		Location:   [2]float64{s.Location.Lat, s.Location.Lon},
-		Routes:     s.Routes,
-		N:          s.Trains.North,
-		S:          s.Trains.South,
-		Stops:      stops,
+		N:          groupByRoute(s.Trains.North),
+		S:          groupByRoute(s.Trains.South),
 		LastUpdate: s.LastUpdate,
 	}
 }
+
+// groupByRoute maps each route to its arrival times in chronological order
+func groupByRoute(trains []Train) map[string][]time.Time {
+	grouped := make(map[string][]time.Time)
+	for _, train := range trains {
+		grouped[train.Route] = append(grouped[train.Route], train.Time)
+	}
+	for _, times := range grouped {
+		sort.Slice(times, func(i, j int) bool {
+			return times[i].Before(times[j])
+		})
+	}
+	return grouped
+}
+
+// ConvertToGeoJSONFeature converts internal Station to a GeoJSON Point feature
+func (s *Station) ConvertToGeoJSONFeature() GeoJSONFeature {
+	return GeoJSONFeature{
+		Type: "Feature",
+		Geometry: GeoJSONGeometry{
+			Type:        "Point",
+			Coordinates: [2]float64{s.Location.Lon, s.Location.Lat},
+		},
+		Properties: map[string]interface{}{
+			"id":     s.ID,
+			"name":   s.Name,
+			"routes": s.Routes,
+		},
+	}
+}
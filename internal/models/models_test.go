@@ -1,6 +1,9 @@
 package models
 
 import (
+	"encoding/json"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 )
@@ -69,6 +72,47 @@ func TestStationConvertToResponse(t *testing.T) {
 	}
 }
 
+func TestStationActiveRoutes(t *testing.T) {
+	now := time.Now()
+	station := &Station{
+		ID:     "R16",
+		Routes: []string{"N", "Q", "R", "W", "7"},
+		Trains: TrainsByDirection{
+			North: []Train{{Route: "Q", Time: now.Add(time.Minute)}, {Route: "N", Time: now.Add(3 * time.Minute)}},
+			South: []Train{{Route: "Q", Time: now.Add(2 * time.Minute)}},
+		},
+	}
+
+	response := station.ConvertToResponse()
+	if expected := []string{"N", "Q"}; !reflect.DeepEqual(response.ActiveRoutes, expected) {
+		t.Errorf("Expected active routes %v in station route order, got %v", expected, response.ActiveRoutes)
+	}
+	if len(response.Routes) != 5 {
+		t.Errorf("Expected all 5 static routes kept, got %v", response.Routes)
+	}
+
+	// A diverted route is active even though static data doesn't list it here
+	station.Trains.South = append(station.Trains.South, Train{Route: "D", Time: now.Add(4 * time.Minute)})
+	if expected := []string{"N", "Q", "D"}; !reflect.DeepEqual(station.ConvertToResponse().ActiveRoutes, expected) {
+		t.Errorf("Expected active routes %v, got %v", expected, station.ConvertToResponse().ActiveRoutes)
+	}
+
+	// Timetable fallback arrivals aren't evidence a route is running
+	station.Trains.North = append(station.Trains.North, Train{Route: "W", Time: now.Add(5 * time.Minute), Scheduled: true})
+	if expected := []string{"N", "Q", "D"}; !reflect.DeepEqual(station.ConvertToResponse().ActiveRoutes, expected) {
+		t.Errorf("Expected scheduled arrivals left out of active routes %v, got %v", expected, station.ConvertToResponse().ActiveRoutes)
+	}
+
+	station.Trains = TrainsByDirection{}
+	data, err := json.Marshal(station.ConvertToResponse())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(string(data), `"active_routes":[]`) {
+		t.Errorf("Expected an empty active_routes list with no trains, got %s", data)
+	}
+}
+
 func TestTimePeriod(t *testing.T) {
 	now := time.Now()
 	future := now.Add(1 * time.Hour)
@@ -86,3 +130,157 @@ func TestTimePeriod(t *testing.T) {
 		t.Error("End time should be after start time")
 	}
 }
+
+func TestStationConvertToRouteArrivals(t *testing.T) {
+	now := time.Date(2024, 12, 1, 8, 0, 0, 0, time.UTC)
+	station := &Station{
+		ID:   "R16",
+		Name: "Times Sq-42 St",
+		Trains: TrainsByDirection{
+			North: []Train{
+				{Route: "N", Time: now.Add(7 * time.Minute)},
+				{Route: "Q", Time: now.Add(4 * time.Minute)},
+				{Route: "N", Time: now.Add(2 * time.Minute)},
+			},
+		},
+	}
+
+	response := station.ConvertToRouteArrivals()
+
+	if len(response.N) != 2 {
+		t.Fatalf("Expected 2 northbound routes, got %d", len(response.N))
+	}
+	if n := response.N["N"]; len(n) != 2 || !n[0].Equal(now.Add(2*time.Minute)) || !n[1].Equal(now.Add(7*time.Minute)) {
+		t.Errorf("Expected N arrivals at +2m, +7m, got %v", n)
+	}
+	if q := response.N["Q"]; len(q) != 1 || !q[0].Equal(now.Add(4*time.Minute)) {
+		t.Errorf("Expected Q arrival at +4m, got %v", q)
+	}
+	if response.S == nil || len(response.S) != 0 {
+		t.Errorf("Expected empty southbound map, got %v", response.S)
+	}
+}
+
+func TestDirectionJSON(t *testing.T) {
+	tests := []struct {
+		direction Direction
+		expected  string
+	}{
+		{DirectionNorth, `{"trip_id":"T1","route":"N","direction":"N","upcoming":null}`},
+		{DirectionSouth, `{"trip_id":"T1","route":"N","direction":"S","upcoming":null}`},
+		{DirectionUnknown, `{"trip_id":"T1","route":"N","upcoming":null}`},
+	}
+
+	for _, tt := range tests {
+		data, err := json.Marshal(TripProgress{TripID: "T1", Route: "N", Direction: tt.direction})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if string(data) != tt.expected {
+			t.Errorf("Direction %q: expected %s, got %s", tt.direction, tt.expected, data)
+		}
+	}
+}
+
+func TestStationDirectionLabels(t *testing.T) {
+	tests := []struct {
+		name     string
+		routes   []string
+		expected map[Direction]string
+	}{
+		{"G only", []string{"G"}, map[Direction]string{DirectionNorth: "Court Sq", DirectionSouth: "Church Av"}},
+		{"shared with the F", []string{"F", "G"}, nil},
+		{"north-south routes", []string{"1", "2", "3"}, nil},
+		{"no routes", nil, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			station := Station{ID: "X", Routes: tt.routes}
+			if got := station.directionLabels(); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Expected labels %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestDirectionLabelsAreCopies(t *testing.T) {
+	DirectionLabelsFor("g")[DirectionNorth] = "Queens"
+	station := Station{ID: "G22", Routes: []string{"G"}}
+	station.ConvertToResponse().DirectionLabels[DirectionSouth] = "Brooklyn"
+
+	expected := map[Direction]string{DirectionNorth: "Court Sq", DirectionSouth: "Church Av"}
+	if got := DirectionLabelsFor("G"); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected labels %v to be unchanged, got %v", expected, got)
+	}
+}
+
+func TestRouteInfoDirections(t *testing.T) {
+	north := func(headsign, label string) RouteDirection {
+		return RouteDirection{Direction: DirectionNorth, Headsign: headsign, Label: label}
+	}
+	south := func(headsign, label string) RouteDirection {
+		return RouteDirection{Direction: DirectionSouth, Headsign: headsign, Label: label}
+	}
+
+	tests := []struct {
+		name     string
+		info     RouteInfo
+		expected []RouteDirection
+		loop     bool
+	}{
+		{"shuttle", RouteInfo{ShortName: "S", Headsigns: map[Direction]string{DirectionNorth: "Times Sq-42 St", DirectionSouth: "Grand Central-42 St"}},
+			[]RouteDirection{north("Times Sq-42 St", "Times Sq-42 St"), south("Grand Central-42 St", "Grand Central-42 St")}, false},
+		{"one-way loop", RouteInfo{ShortName: "X", Headsigns: map[Direction]string{DirectionSouth: "Loop"}},
+			[]RouteDirection{south("Loop", "Loop")}, false},
+		{"loop with one headsign both ways", RouteInfo{ShortName: "X", Headsigns: map[Direction]string{DirectionNorth: "Loop", DirectionSouth: "Loop"}},
+			[]RouteDirection{north("Loop", "Northbound"), south("Loop", "Southbound")}, true},
+		{"no headsigns", RouteInfo{ShortName: "1"}, []RouteDirection{north("", "Northbound"), south("", "Southbound")}, false},
+		{"direction labels win", RouteInfo{ShortName: "G", DirectionLabels: DirectionLabelsFor("G")},
+			[]RouteDirection{north("", "Court Sq"), south("", "Church Av")}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.info.Directions()
+			if got.Route != tt.info.ShortName || got.Loop != tt.loop || !reflect.DeepEqual(got.Directions, tt.expected) {
+				t.Errorf("Expected %v (loop %v), got %+v", tt.expected, tt.loop, got)
+			}
+		})
+	}
+}
+
+func TestTrunkOf(t *testing.T) {
+	tests := []struct {
+		route    string
+		expected string
+		found    bool
+	}{
+		{"1", "red", true},
+		{"6X", "green", true},
+		{"n", "yellow", true},
+		{"GS", "dark-gray", true},
+		{"L", "gray", true},
+		{"T", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.route, func(t *testing.T) {
+			trunk, found := TrunkOf(tt.route)
+			if trunk != tt.expected || found != tt.found {
+				t.Errorf("TrunkOf(%q) = %q, %v, want %q, %v", tt.route, trunk, found, tt.expected, tt.found)
+			}
+		})
+	}
+
+	// A route in two trunks would make TrunkOf depend on map iteration order
+	seen := make(map[string]string)
+	for trunk, routes := range TrunkRoutes {
+		for _, route := range routes {
+			if other, dup := seen[route]; dup {
+				t.Errorf("Route %s is in both %s and %s", route, other, trunk)
+			}
+			seen[route] = trunk
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"sort"
+	"strings"
+)
+
+// TrunkRoutes maps each trunk line, named by its MTA bullet color, to the routes sharing it
+// A static table rather than route_color, since GTFS colors are hex codes that differ
+// slightly between feed releases and don't name the trunk riders know
+var TrunkRoutes = map[string][]string{
+	"red":       {"1", "2", "3"},
+	"green":     {"4", "5", "6", "6X"},
+	"purple":    {"7", "7X"},
+	"blue":      {"A", "C", "E"},
+	"orange":    {"B", "D", "F", "FX", "M"},
+	"lime":      {"G"},
+	"brown":     {"J", "Z"},
+	"gray":      {"L"},
+	"yellow":    {"N", "Q", "R", "W"},
+	"dark-gray": {"S", "GS", "FS", "H"},
+	"sir":       {"SI", "SIR"},
+}
+
+// TrunkOf returns the trunk a route belongs to, matching case-insensitively
+func TrunkOf(route string) (string, bool) {
+	route = strings.ToUpper(route)
+	for trunk, routes := range TrunkRoutes {
+		for _, r := range routes {
+			if r == route {
+				return trunk, true
+			}
+		}
+	}
+	return "", false
+}
+
+// TrunkNames returns the valid trunk names in alphabetical order
+func TrunkNames() []string {
+	names := make([]string, 0, len(TrunkRoutes))
+	for name := range TrunkRoutes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,95 @@
+package store
+
+import (
+	"log/slog"
+	"slices"
+	"sort"
+
+	"github.com/jusunglee/mta-go/internal/models"
+)
+
+// auditMaxIDs caps the IDs listed per change in an audit entry
+// Counts are always exact; a full static reload would otherwise log hundreds of IDs in one line
+const auditMaxIDs = 20
+
+// SetAuditLogger logs a structured summary of every station and alert update to logger
+// Each entry counts what was added, removed or had its routes changed, for tracing why a
+// station's data changed between two requests. Nil, the default, disables it: real-time
+// updates replace every station each cycle, which is too noisy to log unasked
+func (s *Store) SetAuditLogger(logger *slog.Logger) {
+	s.audit.Store(logger)
+}
+
+// auditStations logs how next differs from prev, if audit logging is on
+func (s *Store) auditStations(prev, next *snapshot) {
+	logger := s.audit.Load()
+	if logger == nil {
+		return
+	}
+
+	var added, removed, routesChanged []string
+	for id, station := range next.stations {
+		old, ok := prev.stations[id]
+		if !ok {
+			added = append(added, id)
+		} else if !slices.Equal(old.Routes, station.Routes) {
+			routesChanged = append(routesChanged, id)
+		}
+	}
+	for id := range prev.stations {
+		if _, ok := next.stations[id]; !ok {
+			removed = append(removed, id)
+		}
+	}
+
+	logger.Info("Store stations updated",
+		"updated", next.lastUpdate,
+		"stations", len(next.stations),
+		"routes", len(next.routes),
+		auditChange("added", added),
+		auditChange("removed", removed),
+		auditChange("routes_changed", routesChanged),
+	)
+}
+
+// auditAlerts logs how next differs from prev by alert ID, if audit logging is on
+func (s *Store) auditAlerts(prev, next []models.Alert) {
+	logger := s.audit.Load()
+	if logger == nil {
+		return
+	}
+
+	prevIDs := make(map[string]bool, len(prev))
+	for _, alert := range prev {
+		prevIDs[alert.ID] = true
+	}
+	nextIDs := make(map[string]bool, len(next))
+	var added, removed []string
+	for _, alert := range next {
+		nextIDs[alert.ID] = true
+		if !prevIDs[alert.ID] {
+			added = append(added, alert.ID)
+		}
+	}
+	for id := range prevIDs {
+		if !nextIDs[id] {
+			removed = append(removed, id)
+		}
+	}
+
+	logger.Info("Store alerts updated",
+		"alerts", len(next),
+		auditChange("added", added),
+		auditChange("removed", removed),
+	)
+}
+
+// auditChange groups a change's count with its sorted IDs, at most auditMaxIDs of them
+func auditChange(name string, ids []string) slog.Attr {
+	count := len(ids)
+	sort.Strings(ids)
+	if count > auditMaxIDs {
+		ids = ids[:auditMaxIDs]
+	}
+	return slog.Group(name, "count", count, "ids", ids)
+}
@@ -0,0 +1,132 @@
+package store
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jusunglee/mta-go/internal/models"
+)
+
+// auditEntries decodes the JSON log lines written to buf
+func auditEntries(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var entries []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var entry map[string]any
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("Failed to decode audit entry %q: %v", line, err)
+		}
+		entries = append(entries, entry)
+	}
+	buf.Reset()
+	return entries
+}
+
+func TestAuditLog(t *testing.T) {
+	s := NewStore()
+	s.UpdateStations(map[string]*models.Station{
+		"127": {ID: "127", Routes: []string{"1", "2", "3"}},
+		"631": {ID: "631", Routes: []string{"4", "5", "6"}},
+	})
+
+	var buf bytes.Buffer
+	s.SetAuditLogger(slog.New(slog.NewJSONHandler(&buf, nil)))
+
+	updated := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+	s.UpdateStationsAt(map[string]*models.Station{
+		"127": {ID: "127", Routes: []string{"1", "2"}},
+		"635": {ID: "635", Routes: []string{"4", "5", "6"}},
+	}, updated)
+
+	entries := auditEntries(t, &buf)
+	if len(entries) != 1 {
+		t.Fatalf("Expected one audit entry, got %v", entries)
+	}
+	entry := entries[0]
+	expected := map[string]any{
+		"msg":            "Store stations updated",
+		"updated":        "2024-01-15T12:00:00Z",
+		"stations":       2.0,
+		"routes":         5.0,
+		"added":          map[string]any{"count": 1.0, "ids": []any{"635"}},
+		"removed":        map[string]any{"count": 1.0, "ids": []any{"631"}},
+		"routes_changed": map[string]any{"count": 1.0, "ids": []any{"127"}},
+	}
+	for key, want := range expected {
+		if !reflect.DeepEqual(entry[key], want) {
+			t.Errorf("Expected %s %v, got %v", key, want, entry[key])
+		}
+	}
+
+	t.Run("alerts", func(t *testing.T) {
+		s.UpdateAlerts([]models.Alert{{ID: "a1"}, {ID: "a2"}})
+		s.UpdateAlerts([]models.Alert{{ID: "a2"}, {ID: "a3"}})
+
+		entries := auditEntries(t, &buf)
+		if len(entries) != 2 {
+			t.Fatalf("Expected two audit entries, got %v", entries)
+		}
+		last := entries[1]
+		if last["msg"] != "Store alerts updated" || last["alerts"] != 2.0 {
+			t.Errorf("Expected an alerts entry counting 2, got %v", last)
+		}
+		if added := last["added"].(map[string]any); !reflect.DeepEqual(added["ids"], []any{"a3"}) {
+			t.Errorf("Expected a3 added, got %v", added)
+		}
+		if removed := last["removed"].(map[string]any); !reflect.DeepEqual(removed["ids"], []any{"a1"}) {
+			t.Errorf("Expected a1 removed, got %v", removed)
+		}
+	})
+
+	t.Run("upserts audited with the next update", func(t *testing.T) {
+		s.UpsertAlert(models.Alert{ID: "a4"})
+		s.UpsertAlert(models.Alert{ID: "a5"})
+		if entries := auditEntries(t, &buf); len(entries) != 0 {
+			t.Fatalf("Expected no audit entry per upsert, got %v", entries)
+		}
+
+		s.UpdateAlerts([]models.Alert{{ID: "a3"}, {ID: "a4"}, {ID: "a5"}})
+		entries := auditEntries(t, &buf)
+		if len(entries) != 1 {
+			t.Fatalf("Expected one audit entry for the cycle, got %v", entries)
+		}
+		if added := entries[0]["added"].(map[string]any); !reflect.DeepEqual(added["ids"], []any{"a4", "a5"}) {
+			t.Errorf("Expected the upserted a4 and a5 reported as added, got %v", added)
+		}
+		if removed := entries[0]["removed"].(map[string]any); !reflect.DeepEqual(removed["ids"], []any{"a2"}) {
+			t.Errorf("Expected a2 removed, got %v", removed)
+		}
+	})
+
+	t.Run("ids capped but counted", func(t *testing.T) {
+		stations := make(map[string]*models.Station)
+		for i := 0; i < auditMaxIDs+5; i++ {
+			id := fmt.Sprintf("X%02d", i)
+			stations[id] = &models.Station{ID: id}
+		}
+		s.UpdateStations(stations)
+
+		added := auditEntries(t, &buf)[0]["added"].(map[string]any)
+		if added["count"] != float64(auditMaxIDs+5) || len(added["ids"].([]any)) != auditMaxIDs {
+			t.Errorf("Expected %d added with %d IDs listed, got %v", auditMaxIDs+5, auditMaxIDs, added)
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		s.SetAuditLogger(nil)
+		s.UpdateStations(map[string]*models.Station{})
+		s.UpdateAlerts(nil)
+		if buf.Len() != 0 {
+			t.Errorf("Expected nothing logged once disabled, got %s", buf.String())
+		}
+	})
+}
@@ -0,0 +1,123 @@
+package store
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jusunglee/mta-go/internal/models"
+)
+
+// snapshotVersion is bumped whenever snapshotFile changes incompatibly
+const snapshotVersion = 1
+
+// gzipMagic starts every gzip stream, so a compressed snapshot is recognized whatever it is named
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// snapshotFile is the on-disk form of the static station data
+// Arrivals are left out (Station.Trains isn't serialized); they are stale by the time a snapshot is read
+type snapshotFile struct {
+	Version   int                         `json:"version"`
+	Updated   time.Time                   `json:"updated"`
+	Stations  []models.Station            `json:"stations"`
+	RouteInfo map[string]models.RouteInfo `json:"route_info,omitempty"`
+}
+
+// SaveSnapshot writes the stations and route metadata to path, gzip-compressed when path ends in .gz
+// Plain JSON is kept for debugging; the full NYC data is several times smaller compressed.
+// The file is replaced atomically so a crash mid-write leaves the previous snapshot intact
+func (s *Store) SaveSnapshot(path string) error {
+	s.mu.RLock()
+	file := snapshotFile{
+		Version:   snapshotVersion,
+		Updated:   s.GetLastUpdate(),
+		Stations:  s.GetAllStations(),
+		RouteInfo: s.routeInfo,
+	}
+	var buf bytes.Buffer
+	var err error
+	if strings.HasSuffix(path, ".gz") {
+		zw := gzip.NewWriter(&buf)
+		if err = json.NewEncoder(zw).Encode(file); err == nil {
+			err = zw.Close()
+		}
+	} else {
+		err = json.NewEncoder(&buf).Encode(file)
+	}
+	s.mu.RUnlock()
+	if err != nil {
+		return fmt.Errorf("failed to encode snapshot: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	if _, err := tmp.Write(buf.Bytes()); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	return nil
+}
+
+// LoadSnapshot replaces the stations and route metadata with a snapshot written by SaveSnapshot
+// Compression is detected from the gzip magic bytes rather than the name, so a renamed file still loads
+func (s *Store) LoadSnapshot(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open snapshot: %w", err)
+	}
+	defer f.Close()
+
+	br := bufio.NewReader(f)
+	var r io.Reader = br
+	if magic, _ := br.Peek(len(gzipMagic)); bytes.Equal(magic, gzipMagic) {
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			return fmt.Errorf("invalid snapshot %s: %w", path, err)
+		}
+		defer zr.Close()
+		r = zr
+	}
+
+	var file snapshotFile
+	if err := json.NewDecoder(r).Decode(&file); err != nil {
+		return fmt.Errorf("invalid snapshot %s: %w", path, err)
+	}
+	if file.Version != snapshotVersion {
+		return fmt.Errorf("snapshot %s has version %d, expected %d", path, file.Version, snapshotVersion)
+	}
+
+	stations := make(map[string]*models.Station, len(file.Stations))
+	for i := range file.Stations {
+		station := &file.Stations[i]
+		if station.Stops == nil {
+			station.Stops = make(map[string]models.Location)
+		}
+		stations[station.ID] = station
+	}
+	routeInfo := file.RouteInfo
+	if routeInfo == nil {
+		routeInfo = make(map[string]models.RouteInfo)
+	}
+
+	s.UpdateStationsAt(stations, file.Updated)
+	s.UpdateRouteInfo(routeInfo)
+	return nil
+}
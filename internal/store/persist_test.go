@@ -0,0 +1,101 @@
+package store
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jusunglee/mta-go/internal/models"
+)
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	updated := time.Date(2024, 12, 1, 8, 0, 0, 0, time.UTC)
+	original := NewStore()
+	original.UpdateStationsAt(benchStations(50), updated)
+	original.UpdateRouteInfo(map[string]models.RouteInfo{"N": {ShortName: "N", LongName: "Broadway Express", Color: "FCCC0A"}})
+
+	dir := t.TempDir()
+	tests := []struct {
+		name       string
+		path       string
+		compressed bool
+	}{
+		{"plain JSON", filepath.Join(dir, "stations.json"), false},
+		{"gzip", filepath.Join(dir, "stations.json.gz"), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := original.SaveSnapshot(tt.path); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			data, err := os.ReadFile(tt.path)
+			if err != nil {
+				t.Fatalf("Failed to read snapshot: %v", err)
+			}
+			if got := bytes.HasPrefix(data, gzipMagic); got != tt.compressed {
+				t.Errorf("Expected compressed %v, got %v", tt.compressed, got)
+			}
+
+			loaded := NewStore()
+			if err := loaded.LoadSnapshot(tt.path); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			assertSameStatic(t, original, loaded)
+		})
+	}
+
+	t.Run("compression detected by content", func(t *testing.T) {
+		renamed := filepath.Join(dir, "snapshot.bin")
+		if err := os.Rename(filepath.Join(dir, "stations.json.gz"), renamed); err != nil {
+			t.Fatalf("Failed to rename: %v", err)
+		}
+		loaded := NewStore()
+		if err := loaded.LoadSnapshot(renamed); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		assertSameStatic(t, original, loaded)
+	})
+
+	t.Run("corrupt snapshot rejected", func(t *testing.T) {
+		path := filepath.Join(dir, "corrupt.json.gz")
+		if err := os.WriteFile(path, append(append([]byte{}, gzipMagic...), "not gzip"...), 0644); err != nil {
+			t.Fatalf("Failed to write fixture: %v", err)
+		}
+		loaded := NewStore()
+		if err := loaded.LoadSnapshot(path); err == nil {
+			t.Error("Expected an error for a corrupt snapshot")
+		}
+		if len(loaded.GetAllStations()) != 0 {
+			t.Error("Expected a failed load to leave the store empty")
+		}
+	})
+}
+
+// assertSameStatic compares the parts of two stores a snapshot carries
+func assertSameStatic(t *testing.T, want, got *Store) {
+	t.Helper()
+	wantStations, gotStations := want.GetAllStations(), got.GetAllStations()
+	if len(gotStations) != len(wantStations) {
+		t.Fatalf("Expected %d stations, got %d", len(wantStations), len(gotStations))
+	}
+	for i := range wantStations {
+		w, g := wantStations[i], gotStations[i]
+		if g.ID != w.ID || g.Name != w.Name || g.Location != w.Location || !reflect.DeepEqual(g.Routes, w.Routes) {
+			t.Errorf("Station %s: expected %+v, got %+v", w.ID, w, g)
+		}
+	}
+	if !got.GetLastUpdate().Equal(want.GetLastUpdate()) {
+		t.Errorf("Expected last update %v, got %v", want.GetLastUpdate(), got.GetLastUpdate())
+	}
+	if !reflect.DeepEqual(got.GetRoutes(), want.GetRoutes()) {
+		t.Errorf("Expected routes %v, got %v", want.GetRoutes(), got.GetRoutes())
+	}
+	info, err := got.GetRouteInfo("N")
+	if err != nil || info.LongName != "Broadway Express" {
+		t.Errorf("Expected N route info to survive, got %+v (err %v)", info, err)
+	}
+}
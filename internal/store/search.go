@@ -0,0 +1,172 @@
+package store
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/jusunglee/mta-go/internal/models"
+)
+
+// minFuzzyScore is the lowest average token score treated as a match
+// Tuned so a single-character typo in one word still matches but unrelated names sharing "St" don't
+const minFuzzyScore = 0.6
+
+// abbreviations maps spelled-out words to the forms used in MTA station names
+var abbreviations = map[string]string{
+	"street":    "st",
+	"avenue":    "av",
+	"ave":       "av",
+	"square":    "sq",
+	"place":     "pl",
+	"road":      "rd",
+	"boulevard": "blvd",
+	"parkway":   "pkwy",
+	"junction":  "jct",
+	"heights":   "hts",
+	"center":    "ctr",
+}
+
+// SearchStations finds stations whose name matches query
+// The default substring mode is cheap and exact; fuzzy mode tolerates typos,
+// ordinals ("42nd") and spelled-out abbreviations ("street") and ranks by score
+func (s *Store) SearchStations(query string, fuzzy bool, limit int) []models.Station {
+	snap := s.snapshot()
+
+	type scored struct {
+		station *models.Station
+		score   float64
+	}
+
+	var matches []scored
+	if fuzzy {
+		queryTokens := nameTokens(query)
+		if len(queryTokens) == 0 {
+			return []models.Station{}
+		}
+		for _, station := range snap.stations {
+			if score := fuzzyScore(queryTokens, nameTokens(station.Name)); score >= minFuzzyScore {
+				matches = append(matches, scored{station, score})
+			}
+		}
+	} else {
+		q := strings.ToLower(strings.TrimSpace(query))
+		if q == "" {
+			return []models.Station{}
+		}
+		for _, station := range snap.stations {
+			name := strings.ToLower(station.Name)
+			if !strings.Contains(name, q) {
+				continue
+			}
+			// Prefix matches rank above mid-name matches
+			score := 0.5
+			if strings.HasPrefix(name, q) {
+				score = 1
+			}
+			matches = append(matches, scored{station, score})
+		}
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].score != matches[j].score {
+			return matches[i].score > matches[j].score
+		}
+		if matches[i].station.Name != matches[j].station.Name {
+			return matches[i].station.Name < matches[j].station.Name
+		}
+		return matches[i].station.ID < matches[j].station.ID
+	})
+
+	result := make([]models.Station, 0, min(limit, len(matches)))
+	for i := 0; i < limit && i < len(matches); i++ {
+		result = append(result, *matches[i].station)
+	}
+	return result
+}
+
+// nameTokens lowercases a station name or query and splits it into comparable words
+// "42nd Street" and "42 St" both become ["42", "st"]
+func nameTokens(name string) []string {
+	words := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	tokens := make([]string, 0, len(words))
+	for _, word := range words {
+		if abbr, ok := abbreviations[word]; ok {
+			word = abbr
+		}
+		tokens = append(tokens, stripOrdinal(word))
+	}
+	return tokens
+}
+
+// stripOrdinal turns "42nd" into "42" since MTA names never use ordinal suffixes
+func stripOrdinal(word string) string {
+	for _, suffix := range []string{"st", "nd", "rd", "th"} {
+		if len(word) > len(suffix) && strings.HasSuffix(word, suffix) {
+			num := word[:len(word)-len(suffix)]
+			if strings.IndexFunc(num, func(r rune) bool { return !unicode.IsDigit(r) }) == -1 {
+				return num
+			}
+		}
+	}
+	return word
+}
+
+// fuzzyScore averages, over query tokens, the best similarity against any name token
+func fuzzyScore(queryTokens, nameTokens []string) float64 {
+	var total float64
+	for _, q := range queryTokens {
+		best := 0.0
+		for _, n := range nameTokens {
+			if sim := tokenSimilarity(q, n); sim > best {
+				best = sim
+			}
+		}
+		total += best
+	}
+	return total / float64(len(queryTokens))
+}
+
+// tokenSimilarity scores two words from 0 to 1
+// Prefixes count as strong matches so partially typed queries work; otherwise up to
+// one edit per three characters is tolerated
+func tokenSimilarity(q, n string) float64 {
+	if q == n {
+		return 1
+	}
+	if len(q) >= 3 && strings.HasPrefix(n, q) {
+		return 0.9
+	}
+
+	maxLen := max(len(q), len(n))
+	dist := levenshtein(q, n)
+	if dist > max(1, maxLen/3) {
+		return 0
+	}
+	return 1 - float64(dist)/float64(maxLen)
+}
+
+func levenshtein(a, b string) int {
+	ar, br := []rune(a), []rune(b)
+	prev := make([]int, len(br)+1)
+	curr := make([]int, len(br)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ar); i++ {
+		curr[0] = i
+		for j := 1; j <= len(br); j++ {
+			cost := 1
+			if ar[i-1] == br[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(br)]
+}
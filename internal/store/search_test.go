@@ -0,0 +1,113 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/jusunglee/mta-go/internal/models"
+)
+
+// newSearchStore seeds a store with the same station names as feed.CreateMockStations
+func newSearchStore() *Store {
+	s := NewStore()
+	s.UpdateStations(map[string]*models.Station{
+		"127": {ID: "127", Name: "Times Sq-42 St", Routes: []string{"1", "2", "3"}},
+		"631": {ID: "631", Name: "Grand Central-42 St", Routes: []string{"4", "5", "6"}},
+		"635": {ID: "635", Name: "14 St-Union Sq", Routes: []string{"4", "5", "6"}},
+		"A28": {ID: "A28", Name: "34 St-Penn Station", Routes: []string{"A", "C", "E"}},
+	})
+	return s
+}
+
+func TestSearchStationsSubstring(t *testing.T) {
+	s := newSearchStore()
+
+	results := s.SearchStations("grand", false, 10)
+	if len(results) != 1 || results[0].ID != "631" {
+		t.Errorf("Expected Grand Central, got %v", results)
+	}
+
+	// Prefix matches rank ahead of mid-name matches
+	results = s.SearchStations("14 st", false, 10)
+	if len(results) != 1 || results[0].ID != "635" {
+		t.Errorf("Expected 14 St-Union Sq, got %v", results)
+	}
+
+	if results := s.SearchStations("grand cetral", false, 10); len(results) != 0 {
+		t.Errorf("Substring mode should not match typos, got %v", results)
+	}
+
+	if results := s.SearchStations("  ", false, 10); len(results) != 0 {
+		t.Errorf("Blank query should match nothing, got %v", results)
+	}
+}
+
+func TestSearchStationsFuzzy(t *testing.T) {
+	s := newSearchStore()
+
+	tests := []struct {
+		query    string
+		expected string
+	}{
+		{"grand cetral", "631"},
+		{"Grand Centrl", "631"},
+		{"times square", "127"},
+		{"tims sq", "127"},
+		{"union square", "635"},
+		{"14th street union", "635"},
+		{"penn", "A28"},
+		{"34th st penn", "A28"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			results := s.SearchStations(tt.query, true, 10)
+			if len(results) == 0 {
+				t.Fatalf("Expected a match for %q", tt.query)
+			}
+			if results[0].ID != tt.expected {
+				t.Errorf("Expected %s ranked first for %q, got %v", tt.expected, tt.query, results)
+			}
+		})
+	}
+
+	t.Run("ordinals match both 42 St stations", func(t *testing.T) {
+		results := s.SearchStations("42nd st.", true, 10)
+		if len(results) != 2 {
+			t.Fatalf("Expected 2 matches, got %v", results)
+		}
+		for _, r := range results {
+			if r.ID != "127" && r.ID != "631" {
+				t.Errorf("Unexpected match %s", r.ID)
+			}
+		}
+	})
+
+	t.Run("unrelated query", func(t *testing.T) {
+		if results := s.SearchStations("coney island", true, 10); len(results) != 0 {
+			t.Errorf("Expected no matches, got %v", results)
+		}
+	})
+
+	t.Run("limit", func(t *testing.T) {
+		if results := s.SearchStations("42 st", true, 1); len(results) != 1 {
+			t.Errorf("Expected 1 result with limit 1, got %d", len(results))
+		}
+	})
+}
+
+func TestLevenshtein(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"central", "cetral", 1},
+		{"kitten", "sitting", 3},
+		{"sq", "sq", 0},
+	}
+	for _, tt := range tests {
+		if got := levenshtein(tt.a, tt.b); got != tt.want {
+			t.Errorf("levenshtein(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -2,115 +2,430 @@ package store
 
 import (
 	"fmt"
+	"iter"
+	"log/slog"
 	"math"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/jusunglee/mta-go/internal/clock"
 	"github.com/jusunglee/mta-go/internal/models"
 )
 
 // Store manages in-memory station and alert data
-// Uses read-write mutexes for concurrent access - writes are infrequent but reads are high-volume
+// Station data lives in an immutable snapshot swapped atomically on update, so the
+// high-volume read path never blocks on a lock. Alerts and route metadata are small and stay behind mu
 type Store struct {
-	mu              sync.RWMutex
+	snap      atomic.Pointer[snapshot]
+	mu        sync.RWMutex
+	alerts    []models.Alert
+	routeInfo map[string]models.RouteInfo
+	shapes    map[string][][]models.Location // Route short name -> polylines from shapes.txt
+	fares     []models.Fare                  // From fare_attributes.txt and fare_rules.txt, sorted by ID
+	trips     map[string]models.TripProgress // Trip ID -> progress as of the latest real-time update
+	audit     atomic.Pointer[slog.Logger]    // Receives a summary of each update; nil disables auditing
+	clock     clock.Clock
+	audited   []models.Alert // Alerts as of the last audit; upserts since are summarised by the next UpdateAlerts
+}
+
+// snapshot is one generation of station data and its indices
+// Never modified after publication; readers may hold it for as long as they like
+type snapshot struct {
 	stations        map[string]*models.Station
 	stationsByRoute map[string][]*models.Station
-	alerts          []models.Alert
-	lastUpdate      time.Time
 	routes          []string
+	lastUpdate      time.Time
+	bounds          models.Bounds // Box around every station; meaningless when stations is empty
 }
 
 func NewStore() *Store {
-	return &Store{
+	s := &Store{
+		alerts:    []models.Alert{},
+		routeInfo: make(map[string]models.RouteInfo),
+		clock:     clock.Real{},
+	}
+	s.snap.Store(&snapshot{
 		stations:        make(map[string]*models.Station),
 		stationsByRoute: make(map[string][]*models.Station),
-		alerts:          []models.Alert{},
-	}
+	})
+	return s
+}
+
+// SetClock replaces the time source for update stamps and query-time alert checks
+// Intended for tests that need deterministic control over "now"; set it before the store is shared
+func (s *Store) SetClock(c clock.Clock) {
+	s.clock = c
 }
 
-// UpdateStations updates the station data
-// Rebuilds secondary indices (routes, sorted stations) for efficient querying
+// snapshot returns the current station data generation
+func (s *Store) snapshot() *snapshot {
+	return s.snap.Load()
+}
+
+// UpdateStations publishes a new station data generation stamped with the current time
 func (s *Store) UpdateStations(stations map[string]*models.Station) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	s.UpdateStationsAt(stations, s.clock.Now())
+}
 
-	s.stations = stations
-	s.lastUpdate = time.Now()
+// UpdateStationsAt publishes a new station data generation reported as last updated at updated
+// Rebuilds secondary indices (routes, sorted stations) before the swap so readers never see a partial index.
+// The store takes ownership of stations; callers must not modify them afterwards
+func (s *Store) UpdateStationsAt(stations map[string]*models.Station, updated time.Time) {
+	next := &snapshot{
+		stations:        stations,
+		stationsByRoute: make(map[string][]*models.Station),
+		lastUpdate:      updated,
+	}
 
 	// Rebuild secondary indices for efficient route-based queries
-	s.stationsByRoute = make(map[string][]*models.Station)
 	routeSet := make(map[string]bool)
-
+	first := true
 	for _, station := range stations {
+		next.bounds = extendBounds(next.bounds, station.Location, first)
+		first = false
+
 		for _, route := range station.Routes {
-			s.stationsByRoute[route] = append(s.stationsByRoute[route], station)
+			next.stationsByRoute[route] = append(next.stationsByRoute[route], station)
 			routeSet[route] = true
 		}
 	}
 
 	// Sort stations alphabetically for consistent API responses
-	for route := range s.stationsByRoute {
-		sort.Slice(s.stationsByRoute[route], func(i, j int) bool {
-			return s.stationsByRoute[route][i].Name < s.stationsByRoute[route][j].Name
+	for route := range next.stationsByRoute {
+		sort.Slice(next.stationsByRoute[route], func(i, j int) bool {
+			return next.stationsByRoute[route][i].Name < next.stationsByRoute[route][j].Name
 		})
 	}
 
 	// Update routes list
-	s.routes = make([]string, 0, len(routeSet))
+	next.routes = make([]string, 0, len(routeSet))
 	for route := range routeSet {
-		s.routes = append(s.routes, route)
+		next.routes = append(next.routes, route)
 	}
-	sort.Strings(s.routes)
+	sort.Slice(next.routes, func(i, j int) bool {
+		return routeLess(next.routes[i], next.routes[j])
+	})
+
+	prev := s.snap.Swap(next)
+	s.auditStations(prev, next)
 }
 
-func (s *Store) UpdateAlerts(alerts []models.Alert) {
+// UpdateRouteInfo replaces the static route metadata keyed by route short name
+// Kept separate from UpdateStations since real-time updates don't carry route metadata
+func (s *Store) UpdateRouteInfo(routeInfo map[string]models.RouteInfo) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	s.alerts = alerts
+	s.routeInfo = routeInfo
 }
 
-// GetStationsByLocation returns stations near a location
-// Uses Haversine formula for distance calculation and sorts by proximity
-func (s *Store) GetStationsByLocation(lat, lon float64, limit int) []models.Station {
+// UpdateRouteShapes replaces the route geometry keyed by route short name
+func (s *Store) UpdateRouteShapes(shapes map[string][][]models.Location) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.shapes = shapes
+}
+
+// GetRouteShape returns the polylines drawn for a route, one per branch or variant
+// A known route without shape data returns an empty result, not an error
+func (s *Store) GetRouteShape(route string) ([][]models.Location, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	route = strings.ToUpper(route)
+	_, hasInfo := s.routeInfo[route]
+	_, hasStations := s.snapshot().stationsByRoute[route]
+	if !hasInfo && !hasStations {
+		return nil, fmt.Errorf("route %s not found", route)
+	}
+	return s.shapes[route], nil
+}
+
+// UpdateFares replaces the static fares
+func (s *Store) UpdateFares(fares []models.Fare) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fares = fares
+}
+
+// GetFares returns the fares applying to route or zone; empty arguments match any
+// Empty, not an error, when the feed publishes no fares
+func (s *Store) GetFares(route, zone string) []models.Fare {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := []models.Fare{}
+	for _, fare := range s.fares {
+		if fare.AppliesTo(route, zone) {
+			result = append(result, fare)
+		}
+	}
+	return result
+}
+
+// UpdateTrips replaces in-flight trip progress keyed by GTFS-RT trip ID
+func (s *Store) UpdateTrips(trips map[string]models.TripProgress) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.trips = trips
+}
+
+// GetTripProgress returns the latest known position and upcoming stops for a trip
+func (s *Store) GetTripProgress(tripID string) (models.TripProgress, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	// Temporary struct for sorting stations by proximity
-	type stationDist struct {
-		station  *models.Station
-		distance float64
+	trip, ok := s.trips[tripID]
+	if !ok {
+		return models.TripProgress{}, fmt.Errorf("trip %s not found", tripID)
 	}
+	return trip, nil
+}
+
+// UpdateAlerts replaces every alert, auditing the change along with any upserts since the last update
+func (s *Store) UpdateAlerts(alerts []models.Alert) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.auditAlerts(s.audited, alerts)
+	s.alerts = alerts
+	s.audited = alerts
+}
+
+// UpsertAlert replaces the alert with alert's ID, or adds it if there is none
+// A replaced alert keeps its FirstSeen, so the maximum alert age counts from when it first appeared
+// rather than from its latest sighting. Done under one lock since feed entities are processed concurrently.
+// Upserts aren't audited one by one; the next UpdateAlerts reports them together
+func (s *Store) UpsertAlert(alert models.Alert) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	next := make([]models.Alert, 0, len(s.alerts)+1)
+	replaced := false
+	for _, existing := range s.alerts {
+		if existing.ID == alert.ID {
+			if !existing.FirstSeen.IsZero() {
+				alert.FirstSeen = existing.FirstSeen
+			}
+			next = append(next, alert)
+			replaced = true
+			continue
+		}
+		next = append(next, existing)
+	}
+	if !replaced {
+		next = append(next, alert)
+	}
+	s.alerts = next
+}
+
+// stationDist pairs a station with its distance in km from a query point
+type stationDist struct {
+	station  *models.Station
+	distance float64
+}
+
+// nearestStations returns the stations whose distance from a location filter accepts, nearest first
+// A nil filter keeps every station
+func (s *Store) nearestStations(lat, lon float64, filter func(distance float64) bool) []stationDist {
+	snap := s.snapshot()
 
-	// Calculate distance to all stations for sorting
 	var stations []stationDist
-	for _, station := range s.stations {
+	for _, station := range snap.stations {
 		dist := distance(lat, lon, station.Location.Lat, station.Location.Lon)
-		stations = append(stations, stationDist{station, dist})
+		if filter == nil || filter(dist) {
+			stations = append(stations, stationDist{station, dist})
+		}
 	}
 
 	sort.Slice(stations, func(i, j int) bool {
 		return stations[i].distance < stations[j].distance
 	})
+	return stations
+}
+
+// GetStationsByLocation returns stations near a location
+// Uses Haversine formula for distance calculation and keeps only the closest limit in order,
+// so batch lookups don't sort every station once per point
+func (s *Store) GetStationsByLocation(lat, lon float64, limit int) []models.Station {
+	snap := s.snapshot()
+
+	nearest := make([]stationDist, 0, max(0, min(limit, len(snap.stations))))
+	for _, station := range snap.stations {
+		dist := distance(lat, lon, station.Location.Lat, station.Location.Lon)
+		if len(nearest) == cap(nearest) && (len(nearest) == 0 || dist >= nearest[len(nearest)-1].distance) {
+			continue
+		}
+		i := sort.Search(len(nearest), func(i int) bool { return nearest[i].distance > dist })
+		if len(nearest) < cap(nearest) {
+			nearest = append(nearest, stationDist{})
+		}
+		copy(nearest[i+1:], nearest[i:])
+		nearest[i] = stationDist{station, dist}
+	}
+
+	result := make([]models.Station, len(nearest))
+	for i, sd := range nearest {
+		result[i] = *sd.station
+	}
+	return result
+}
+
+// GetStationsWithinRadius returns every station within radiusKm of a location, nearest first
+func (s *Store) GetStationsWithinRadius(lat, lon, radiusKm float64) []models.Station {
+	nearby := s.nearestStations(lat, lon, func(dist float64) bool { return dist <= radiusKm })
+
+	result := make([]models.Station, len(nearby))
+	for i, sd := range nearby {
+		result[i] = *sd.station
+	}
+	return result
+}
+
+// GetRoutesNearby returns the routes served by any station within radiusKm, in transit order
+// Answers "what can I catch near me" without the caller unioning station route lists
+func (s *Store) GetRoutesNearby(lat, lon, radiusKm float64) []string {
+	seen := make(map[string]bool)
+	routes := []string{}
+	for _, station := range s.GetStationsWithinRadius(lat, lon, radiusKm) {
+		for _, route := range station.Routes {
+			if !seen[route] {
+				seen[route] = true
+				routes = append(routes, route)
+			}
+		}
+	}
+
+	sort.Slice(routes, func(i, j int) bool {
+		return routeLess(routes[i], routes[j])
+	})
+	return routes
+}
+
+// GetStationsByLocationMerged is GetStationsByLocation with nearby stations collapsed
+// GTFS models transfer complexes (e.g. Times Sq) as separate parent stations, so any
+// station within radiusKm of a closer result is folded into it, unioning routes,
+// stops and arrivals. The limit applies to merged results
+func (s *Store) GetStationsByLocationMerged(lat, lon float64, limit int, radiusKm float64) []models.Station {
+	stations := s.nearestStations(lat, lon, nil)
+
+	var clusters []*models.Station
+	var farthestAnchor float64
+	for _, sd := range stations {
+		// By the triangle inequality nothing further away can fall within radius of an anchor
+		if len(clusters) >= limit && sd.distance > farthestAnchor+radiusKm {
+			break
+		}
+
+		merged := false
+		for _, cluster := range clusters {
+			if distance(cluster.Location.Lat, cluster.Location.Lon, sd.station.Location.Lat, sd.station.Location.Lon) <= radiusKm {
+				mergeStation(cluster, sd.station)
+				merged = true
+				break
+			}
+		}
+		if merged || len(clusters) >= limit {
+			continue
+		}
+
+		clusters = append(clusters, copyStation(sd.station))
+		farthestAnchor = sd.distance
+	}
+
+	result := make([]models.Station, 0, len(clusters))
+	for _, cluster := range clusters {
+		sortTrains(cluster.Trains.North)
+		sortTrains(cluster.Trains.South)
+		result = append(result, *cluster)
+	}
+
+	return result
+}
+
+// GetAllStations returns every station ordered by ID for stable output
+func (s *Store) GetAllStations() []models.Station {
+	snap := s.snapshot()
+
+	result := make([]models.Station, 0, len(snap.stations))
+	for _, station := range snap.stations {
+		result = append(result, *station)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].ID < result[j].ID
+	})
+	return result
+}
+
+// AllStations iterates over every station ordered by ID without copying the whole set
+// Lets callers stream large responses with memory that doesn't grow with station count
+func (s *Store) AllStations() iter.Seq[models.Station] {
+	snap := s.snapshot()
 
-	result := make([]models.Station, 0, limit)
-	// Return up to 'limit' closest stations, dereferencing pointers
-	for i := 0; i < limit && i < len(stations); i++ {
-		result = append(result, *stations[i].station)
+	ids := make([]string, 0, len(snap.stations))
+	for id := range snap.stations {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	return func(yield func(models.Station) bool) {
+		for _, id := range ids {
+			if !yield(*snap.stations[id]) {
+				return
+			}
+		}
 	}
+}
+
+// GetBounds returns the bounding box of all station coordinates, for fitting a map to the whole system
+// Computed once per station update; ok is false when no stations are loaded
+func (s *Store) GetBounds() (minLat, minLon, maxLat, maxLon float64, ok bool) {
+	snap := s.snapshot()
+	if len(snap.stations) == 0 {
+		return 0, 0, 0, 0, false
+	}
+	b := snap.bounds
+	return b.MinLat, b.MinLon, b.MaxLat, b.MaxLon, true
+}
 
+// extendBounds grows b to include loc; first starts a new box at loc
+func extendBounds(b models.Bounds, loc models.Location, first bool) models.Bounds {
+	if first {
+		return models.Bounds{MinLat: loc.Lat, MinLon: loc.Lon, MaxLat: loc.Lat, MaxLon: loc.Lon}
+	}
+	b.MinLat = math.Min(b.MinLat, loc.Lat)
+	b.MinLon = math.Min(b.MinLon, loc.Lon)
+	b.MaxLat = math.Max(b.MaxLat, loc.Lat)
+	b.MaxLon = math.Max(b.MaxLon, loc.Lon)
+	return b
+}
+
+// GetStationsInBounds returns stations inside the bounding box (inclusive), ordered by ID
+func (s *Store) GetStationsInBounds(minLat, minLon, maxLat, maxLon float64) []models.Station {
+	snap := s.snapshot()
+
+	result := make([]models.Station, 0)
+	for _, station := range snap.stations {
+		loc := station.Location
+		if loc.Lat >= minLat && loc.Lat <= maxLat && loc.Lon >= minLon && loc.Lon <= maxLon {
+			result = append(result, *station)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].ID < result[j].ID
+	})
 	return result
 }
 
 // GetStationsByRoute returns all stations on a route
 // Route matching is case-insensitive
 func (s *Store) GetStationsByRoute(route string) ([]models.Station, error) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+	snap := s.snapshot()
 
 	route = strings.ToUpper(route)
-	stations, ok := s.stationsByRoute[route]
+	stations, ok := snap.stationsByRoute[route]
 	if !ok {
 		return nil, fmt.Errorf("route %s not found", route)
 	}
@@ -123,33 +438,298 @@ func (s *Store) GetStationsByRoute(route string) ([]models.Station, error) {
 	return result, nil
 }
 
+// GetStationSummariesByRoute returns the stations on a route as summaries, in the same order as GetStationsByRoute
+// Much lighter than copying whole stations for busy routes, since the stop map and train slices never leave the store
+func (s *Store) GetStationSummariesByRoute(route string) ([]models.StationSummary, error) {
+	snap := s.snapshot()
+
+	route = strings.ToUpper(route)
+	stations, ok := snap.stationsByRoute[route]
+	if !ok {
+		return nil, fmt.Errorf("route %s not found", route)
+	}
+
+	result := make([]models.StationSummary, len(stations))
+	for i, station := range stations {
+		result[i] = models.StationSummary{
+			ID:       station.ID,
+			Name:     station.Name,
+			Location: [2]float64{station.Location.Lat, station.Location.Lon},
+			Routes:   station.Routes,
+		}
+	}
+	return result, nil
+}
+
+// StationsForUpdate returns a copy of every station serving at least one route, keyed by ID, with Trains emptied
+// for a real-time cycle to fill. One pass over the snapshot, where collecting the stations route by route
+// copied a station once per route serving it. Copies are shallow: Routes and Stops are shared with the
+// published snapshot, so callers may replace them but must not modify them in place
+func (s *Store) StationsForUpdate() map[string]*models.Station {
+	snap := s.snapshot()
+
+	result := make(map[string]*models.Station, len(snap.stations))
+	for id, station := range snap.stations {
+		if len(station.Routes) == 0 {
+			continue
+		}
+		c := *station
+		c.Trains = models.TrainsByDirection{North: []models.Train{}, South: []models.Train{}}
+		result[id] = &c
+	}
+	return result
+}
+
+// GetStationsByTrunk returns stations served by any route of a trunk line, ordered by ID
+// Trunk names are matched case-insensitively against models.TrunkRoutes
+func (s *Store) GetStationsByTrunk(trunk string) ([]models.Station, error) {
+	routes, ok := models.TrunkRoutes[strings.ToLower(trunk)]
+	if !ok {
+		return nil, fmt.Errorf("unknown trunk %s", trunk)
+	}
+
+	snap := s.snapshot()
+	seen := make(map[string]bool)
+	result := []models.Station{}
+	for _, route := range routes {
+		for _, station := range snap.stationsByRoute[route] {
+			if !seen[station.ID] {
+				seen[station.ID] = true
+				result = append(result, *station)
+			}
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].ID < result[j].ID
+	})
+	return result, nil
+}
+
+// GetArrivalsByRoute returns upcoming arrivals of a single route at every station it serves, keyed by station ID
+// Route matching is case-insensitive; stations with no arrivals of the route map to empty directions
+func (s *Store) GetArrivalsByRoute(route string) (map[string]models.TrainsByDirection, error) {
+	snap := s.snapshot()
+
+	route = strings.ToUpper(route)
+	stations, ok := snap.stationsByRoute[route]
+	if !ok {
+		return nil, fmt.Errorf("route %s not found", route)
+	}
+
+	result := make(map[string]models.TrainsByDirection, len(stations))
+	for _, station := range stations {
+		result[station.ID] = models.TrainsByDirection{
+			North: filterTrainsByRoute(station.Trains.North, route),
+			South: filterTrainsByRoute(station.Trains.South, route),
+		}
+	}
+
+	return result, nil
+}
+
+// GetRouteStatus summarizes the wait for the next train of each route across its stations at now
+// Each station contributes its soonest upcoming arrival in either direction; routes without any still appear
+func (s *Store) GetRouteStatus(now time.Time) []models.RouteStatus {
+	snap := s.snapshot()
+
+	result := make([]models.RouteStatus, 0, len(snap.routes))
+	for _, route := range snap.routes {
+		stations := snap.stationsByRoute[route]
+		status := models.RouteStatus{Route: route, Stations: len(stations)}
+
+		waits := make([]float64, 0, len(stations))
+		for _, station := range stations {
+			if wait, ok := nextTrainWait(station, route, now); ok {
+				waits = append(waits, wait.Minutes())
+			}
+		}
+		status.StationsWithTrains = len(waits)
+
+		if len(waits) > 0 {
+			sort.Float64s(waits)
+			median := roundTenth(percentile(waits, 0.5))
+			p90 := roundTenth(percentile(waits, 0.9))
+			status.MedianMinutes = &median
+			status.P90Minutes = &p90
+		}
+		result = append(result, status)
+	}
+	return result
+}
+
+// nextTrainWait returns how long until the next real-time arrival of route at station
+// Trains already past now are skipped; they're still listed briefly after arriving
+func nextTrainWait(station *models.Station, route string, now time.Time) (time.Duration, bool) {
+	var next time.Time
+	for _, trains := range [][]models.Train{station.Trains.North, station.Trains.South} {
+		for _, train := range trains {
+			if train.Scheduled || !strings.EqualFold(train.Route, route) || train.Time.Before(now) {
+				continue
+			}
+			if next.IsZero() || train.Time.Before(next) {
+				next = train.Time
+			}
+		}
+	}
+	if next.IsZero() {
+		return 0, false
+	}
+	return next.Sub(now), true
+}
+
+// percentile interpolates between the closest ranks of sorted, which must be non-empty
+func percentile(sorted []float64, p float64) float64 {
+	pos := p * float64(len(sorted)-1)
+	lower := int(pos)
+	if lower+1 >= len(sorted) {
+		return sorted[lower]
+	}
+	frac := pos - float64(lower)
+	return sorted[lower] + frac*(sorted[lower+1]-sorted[lower])
+}
+
+func roundTenth(v float64) float64 {
+	return math.Round(v*10) / 10
+}
+
+// filterTrainsByRoute copies the trains of a single route, never returning nil so JSON renders []
+func filterTrainsByRoute(trains []models.Train, route string) []models.Train {
+	result := []models.Train{}
+	for _, train := range trains {
+		if strings.EqualFold(train.Route, route) {
+			result = append(result, train)
+		}
+	}
+	return result
+}
+
+// GetStationsByIDs returns the stations that exist, ignoring unknown IDs
+// Errors only when none of the IDs match
 func (s *Store) GetStationsByIDs(ids []string) ([]models.Station, error) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+	result, _ := s.FindStationsByIDs(ids)
+	if len(result) == 0 {
+		return nil, fmt.Errorf("no stations found for given IDs")
+	}
+	return result, nil
+}
+
+// FindStationsByIDs returns the stations that exist along with the IDs that matched nothing
+// Lets callers detect partial hits that GetStationsByIDs silently drops
+func (s *Store) FindStationsByIDs(ids []string) ([]models.Station, []string) {
+	snap := s.snapshot()
 
-	// Collect stations that exist, ignore missing IDs
 	result := make([]models.Station, 0, len(ids))
+	var missing []string
 	for _, id := range ids {
-		if station, ok := s.stations[id]; ok {
+		if station, ok := snap.stations[id]; ok {
 			result = append(result, *station)
+		} else {
+			missing = append(missing, id)
 		}
 	}
 
-	// Return error only if no valid stations found
-	if len(result) == 0 {
-		return nil, fmt.Errorf("no stations found for given IDs")
+	return result, missing
+}
+
+// GetStationsByIDPrefix returns stations whose ID starts with prefix, ordered by ID
+// A debugging aid for exploring IDs; no match is an empty result rather than an error
+func (s *Store) GetStationsByIDPrefix(prefix string) []models.Station {
+	snap := s.snapshot()
+
+	result := []models.Station{}
+	for id, station := range snap.stations {
+		if strings.HasPrefix(id, prefix) {
+			result = append(result, *station)
+		}
 	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].ID < result[j].ID
+	})
+	return result
+}
 
-	return result, nil
+// GetCoverage reports stations without real-time arrivals in the current snapshot
+// Scheduled fallback arrivals don't count, since they mask exactly the gaps this is meant to find
+func (s *Store) GetCoverage() models.CoverageReport {
+	snap := s.snapshot()
+
+	report := models.CoverageReport{
+		TotalStations:  len(snap.stations),
+		Missing:        []string{},
+		MissingByRoute: make(map[string][]string),
+	}
+	for _, station := range snap.stations {
+		if hasRealTimeArrival(station.Trains.North) || hasRealTimeArrival(station.Trains.South) {
+			continue
+		}
+		report.Missing = append(report.Missing, station.ID)
+		for _, route := range station.Routes {
+			report.MissingByRoute[route] = append(report.MissingByRoute[route], station.ID)
+		}
+	}
+
+	sort.Strings(report.Missing)
+	for _, ids := range report.MissingByRoute {
+		sort.Strings(ids)
+	}
+	report.MissingCount = len(report.Missing)
+	return report
+}
+
+func hasRealTimeArrival(trains []models.Train) bool {
+	for _, train := range trains {
+		if !train.Scheduled {
+			return true
+		}
+	}
+	return false
 }
 
 func (s *Store) GetRoutes() []string {
+	snap := s.snapshot()
+
+	result := make([]string, len(snap.routes))
+	copy(result, snap.routes)
+	return result
+}
+
+// GetRouteInfo returns metadata for a single route
+// Route matching is case-insensitive. StationCount reflects the current route index
+// and ActiveAlertCount is derived from the alerts in effect at query time
+func (s *Store) GetRouteInfo(route string) (models.RouteInfo, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	result := make([]string, len(s.routes))
-	copy(result, s.routes)
-	return result
+	route = strings.ToUpper(route)
+	info, hasInfo := s.routeInfo[route]
+	stations, hasStations := s.snapshot().stationsByRoute[route]
+	if !hasInfo && !hasStations {
+		return models.RouteInfo{}, fmt.Errorf("route %s not found", route)
+	}
+
+	info.ShortName = route
+	info.StationCount = len(stations)
+	info.DirectionLabels = models.DirectionLabelsFor(route)
+	info.ActiveAlertCount = s.countActiveAlerts(route, s.clock.Now())
+	return info, nil
+}
+
+// countActiveAlerts counts alerts in effect at now that list route; callers must hold s.mu
+func (s *Store) countActiveAlerts(route string, now time.Time) int {
+	count := 0
+	for _, alert := range s.alerts {
+		if !alert.IsActiveAt(now) {
+			continue
+		}
+		for _, r := range alert.Routes {
+			if strings.EqualFold(r, route) {
+				count++
+				break
+			}
+		}
+	}
+	return count
 }
 
 func (s *Store) GetServiceAlerts() []models.Alert {
@@ -161,26 +741,137 @@ func (s *Store) GetServiceAlerts() []models.Alert {
 	return result
 }
 
-func (s *Store) GetLastUpdate() time.Time {
+// GetAlertsInRange returns alerts with an active period overlapping [start, end)
+// Open-ended periods and alerts without periods overlap any range
+func (s *Store) GetAlertsInRange(start, end time.Time) []models.Alert {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	return s.lastUpdate
+
+	result := []models.Alert{}
+	for _, alert := range s.alerts {
+		if alert.OverlapsRange(start, end) {
+			result = append(result, alert)
+		}
+	}
+	return result
 }
 
-// distance calculates the distance between two points using the Haversine formula
-// Returns distance in kilometers. Assumes Earth radius of 6371km
-func distance(lat1, lon1, lat2, lon2 float64) float64 {
-	const R = 6371 // Earth's radius in kilometers
+// Counts returns the number of stations, routes and alerts currently held
+func (s *Store) Counts() (stations, routes, alerts int) {
+	snap := s.snapshot()
 
-	lat1Rad := lat1 * math.Pi / 180
-	lat2Rad := lat2 * math.Pi / 180
-	deltaLat := (lat2 - lat1) * math.Pi / 180
-	deltaLon := (lon2 - lon1) * math.Pi / 180
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(snap.stations), len(snap.routes), len(s.alerts)
+}
+
+func (s *Store) GetLastUpdate() time.Time {
+	return s.snapshot().lastUpdate
+}
+
+// copyStation deep-copies the slices and maps that mergeStation appends to
+// so merging never mutates the shared station records held by the store
+func copyStation(station *models.Station) *models.Station {
+	c := *station
+	c.Routes = append([]string(nil), station.Routes...)
+	if station.OneWayRoutes != nil {
+		c.OneWayRoutes = make(map[string]models.Direction, len(station.OneWayRoutes))
+		for route, direction := range station.OneWayRoutes {
+			c.OneWayRoutes[route] = direction
+		}
+	}
+	c.Trains.North = append([]models.Train(nil), station.Trains.North...)
+	c.Trains.South = append([]models.Train(nil), station.Trains.South...)
+	c.Stops = make(map[string]models.Location, len(station.Stops))
+	for id, loc := range station.Stops {
+		c.Stops[id] = loc
+	}
+	return &c
+}
+
+// mergeStation folds other into dst, keeping dst's identity and location
+func mergeStation(dst, other *models.Station) {
+	for _, route := range other.Routes {
+		found := false
+		for _, existing := range dst.Routes {
+			if existing == route {
+				found = true
+				break
+			}
+		}
+		if !found {
+			dst.Routes = append(dst.Routes, route)
+		}
+
+		// A route is one-way in the merged complex only if every member that has it agrees on the direction
+		direction, oneWay := other.OneWayRoutes[route]
+		switch {
+		case !found && oneWay:
+			if dst.OneWayRoutes == nil {
+				dst.OneWayRoutes = make(map[string]models.Direction)
+			}
+			dst.OneWayRoutes[route] = direction
+		case found && dst.OneWayRoutes[route] != direction:
+			delete(dst.OneWayRoutes, route)
+		}
+	}
+
+	dst.Trains.North = append(dst.Trains.North, other.Trains.North...)
+	dst.Trains.South = append(dst.Trains.South, other.Trains.South...)
 
-	a := math.Sin(deltaLat/2)*math.Sin(deltaLat/2) +
-		math.Cos(lat1Rad)*math.Cos(lat2Rad)*
-			math.Sin(deltaLon/2)*math.Sin(deltaLon/2)
-	c := 2 * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
+	for id, loc := range other.Stops {
+		dst.Stops[id] = loc
+	}
 
-	return R * c
+	if other.LastUpdate.After(dst.LastUpdate) {
+		dst.LastUpdate = other.LastUpdate
+	}
+}
+
+func sortTrains(trains []models.Train) {
+	sort.SliceStable(trains, func(i, j int) bool {
+		return trains[i].Time.Before(trains[j].Time)
+	})
+}
+
+// routeLess orders routes the way riders read them: numbered routes first in
+// numeric order (so "10" follows "7"), then lettered routes alphabetically
+// Variants like "6X" sort right after their numeric base
+func routeLess(a, b string) bool {
+	aNum, aSuffix, aIsNum := splitRouteNumber(a)
+	bNum, bSuffix, bIsNum := splitRouteNumber(b)
+
+	switch {
+	case aIsNum && bIsNum:
+		if aNum != bNum {
+			return aNum < bNum
+		}
+		return aSuffix < bSuffix
+	case aIsNum != bIsNum:
+		return aIsNum
+	default:
+		return a < b
+	}
+}
+
+// splitRouteNumber splits a route into its leading number and remaining suffix
+// Reports false when the route doesn't start with a digit
+func splitRouteNumber(route string) (int, string, bool) {
+	end := 0
+	for end < len(route) && route[end] >= '0' && route[end] <= '9' {
+		end++
+	}
+	if end == 0 {
+		return 0, route, false
+	}
+	n, err := strconv.Atoi(route[:end])
+	if err != nil {
+		return 0, route, false
+	}
+	return n, route[end:], true
+}
+
+// distance calculates the distance between two points in kilometers
+func distance(lat1, lon1, lat2, lon2 float64) float64 {
+	return models.Location{Lat: lat1, Lon: lon1}.DistanceKm(models.Location{Lat: lat2, Lon: lon2})
 }
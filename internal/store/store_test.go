@@ -1,9 +1,15 @@
 package store
 
 import (
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/jusunglee/mta-go/internal/clock"
 	"github.com/jusunglee/mta-go/internal/models"
 )
 
@@ -64,6 +70,28 @@ func TestStore(t *testing.T) {
 		}
 	})
 
+	t.Run("GetStationSummariesByRoute", func(t *testing.T) {
+		full, _ := s.GetStationsByRoute("n")
+		results, err := s.GetStationSummariesByRoute("n")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(results) != len(full) {
+			t.Fatalf("Expected %d summaries, got %d", len(full), len(results))
+		}
+		for i, summary := range results {
+			station := full[i]
+			if summary.ID != station.ID || summary.Name != station.Name ||
+				summary.Location != [2]float64{station.Location.Lat, station.Location.Lon} || len(summary.Routes) != len(station.Routes) {
+				t.Errorf("Summary %+v doesn't match station %+v", summary, station)
+			}
+		}
+
+		if _, err := s.GetStationSummariesByRoute("X"); err == nil {
+			t.Error("Expected error for non-existent route")
+		}
+	})
+
 	t.Run("GetStationsByIDs", func(t *testing.T) {
 		results, err := s.GetStationsByIDs([]string{"123", "456"})
 		if err != nil {
@@ -80,6 +108,79 @@ func TestStore(t *testing.T) {
 		}
 	})
 
+	t.Run("FindStationsByIDs", func(t *testing.T) {
+		tests := []struct {
+			name          string
+			ids           []string
+			expectedFound int
+			expectedMiss  []string
+		}{
+			{"all found", []string{"123", "456"}, 2, nil},
+			{"partial", []string{"123", "999"}, 1, []string{"999"}},
+			{"none found", []string{"998", "999"}, 0, []string{"998", "999"}},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				found, missing := s.FindStationsByIDs(tt.ids)
+				if len(found) != tt.expectedFound {
+					t.Errorf("Expected %d stations, got %d", tt.expectedFound, len(found))
+				}
+				if fmt.Sprint(missing) != fmt.Sprint(tt.expectedMiss) {
+					t.Errorf("Expected missing %v, got %v", tt.expectedMiss, missing)
+				}
+			})
+		}
+	})
+
+	t.Run("GetAllStations", func(t *testing.T) {
+		results := s.GetAllStations()
+		if len(results) != 3 {
+			t.Fatalf("Expected 3 stations, got %d", len(results))
+		}
+		if results[0].ID != "123" || results[1].ID != "456" || results[2].ID != "789" {
+			t.Errorf("Expected stations ordered by ID, got %s, %s, %s", results[0].ID, results[1].ID, results[2].ID)
+		}
+	})
+
+	t.Run("GetStationsInBounds", func(t *testing.T) {
+		// Box around midtown excludes Union Square
+		results := s.GetStationsInBounds(40.75, -73.99, 40.76, -73.97)
+		if len(results) != 2 {
+			t.Fatalf("Expected 2 stations in bounds, got %d", len(results))
+		}
+		if results[0].ID != "123" || results[1].ID != "456" {
+			t.Errorf("Unexpected stations in bounds: %s, %s", results[0].ID, results[1].ID)
+		}
+
+		if results := s.GetStationsInBounds(41, -74, 42, -73); len(results) != 0 {
+			t.Errorf("Expected no stations outside NYC, got %d", len(results))
+		}
+	})
+
+	t.Run("GetBounds", func(t *testing.T) {
+		minLat, minLon, maxLat, maxLon, ok := s.GetBounds()
+		if !ok {
+			t.Fatal("Expected bounds with stations loaded")
+		}
+		// Union Square is the southern and western edge, Times Square the northern, Grand Central the eastern
+		if minLat != 40.735 || minLon != -73.990 || maxLat != 40.755 || maxLon != -73.977 {
+			t.Errorf("Expected box (40.735, -73.990)-(40.755, -73.977), got (%v, %v)-(%v, %v)", minLat, minLon, maxLat, maxLon)
+		}
+
+		// The box is cached per update, so a new station set must replace it
+		other := NewStore()
+		if _, _, _, _, ok := other.GetBounds(); ok {
+			t.Error("Expected ok=false for an empty store")
+		}
+		other.UpdateStations(map[string]*models.Station{
+			"L06": {ID: "L06", Location: models.Location{Lat: 40.730953, Lon: -73.981628}},
+		})
+		if minLat, _, maxLat, _, _ := other.GetBounds(); minLat != 40.730953 || maxLat != 40.730953 {
+			t.Errorf("Expected a single-station box at L06, got lat %v-%v", minLat, maxLat)
+		}
+	})
+
 	t.Run("GetRoutes", func(t *testing.T) {
 		routes := s.GetRoutes()
 		expectedRoutes := []string{"1", "2", "3", "4", "5", "6", "7", "L", "N", "Q", "R", "S", "W"}
@@ -88,6 +189,36 @@ func TestStore(t *testing.T) {
 		}
 	})
 
+	t.Run("GetRouteInfo", func(t *testing.T) {
+		s.UpdateRouteInfo(map[string]models.RouteInfo{
+			"N": {ShortName: "N", LongName: "Broadway Local", Description: "Trains operate between Astoria and Coney Island", Color: "FCCC0A"},
+		})
+
+		info, err := s.GetRouteInfo("n")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if info.LongName != "Broadway Local" {
+			t.Errorf("Expected long name 'Broadway Local', got '%s'", info.LongName)
+		}
+		if info.StationCount != 2 {
+			t.Errorf("Expected 2 stations on route N, got %d", info.StationCount)
+		}
+
+		// Routes served by stations but missing static metadata still resolve
+		info, err = s.GetRouteInfo("L")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if info.ShortName != "L" || info.StationCount != 1 {
+			t.Errorf("Expected L with 1 station, got %+v", info)
+		}
+
+		if _, err := s.GetRouteInfo("X"); err == nil {
+			t.Error("Expected error for non-existent route")
+		}
+	})
+
 	t.Run("UpdateAlerts", func(t *testing.T) {
 		alerts := []models.Alert{
 			{
@@ -108,6 +239,41 @@ func TestStore(t *testing.T) {
 		}
 	})
 
+	t.Run("GetRouteInfoActiveAlertCount", func(t *testing.T) {
+		now := time.Date(2024, 12, 1, 8, 0, 0, 0, time.UTC)
+		s.SetClock(clock.NewFake(now))
+		defer s.SetClock(clock.Real{})
+		past := now.Add(-2 * time.Hour)
+		expired := now.Add(-time.Hour)
+		future := now.Add(time.Hour)
+		s.UpdateAlerts([]models.Alert{
+			{ID: "a1", Routes: []string{"N", "Q"}},
+			{ID: "a2", Routes: []string{"n"}, ActivePeriods: []models.TimePeriod{{Start: &past, End: &future}}},
+			{ID: "expired", Routes: []string{"N"}, ActivePeriods: []models.TimePeriod{{Start: &past, End: &expired}}},
+			{ID: "upcoming", Routes: []string{"N"}, ActivePeriods: []models.TimePeriod{{Start: &future}}},
+		})
+
+		tests := []struct {
+			route    string
+			expected int
+		}{
+			{"N", 2},
+			{"L", 0},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.route, func(t *testing.T) {
+				info, err := s.GetRouteInfo(tt.route)
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				if info.ActiveAlertCount != tt.expected {
+					t.Errorf("Expected %d active alerts, got %d", tt.expected, info.ActiveAlertCount)
+				}
+			})
+		}
+	})
+
 	t.Run("GetLastUpdate", func(t *testing.T) {
 		lastUpdate := s.GetLastUpdate()
 		if time.Since(lastUpdate) > time.Minute {
@@ -116,6 +282,542 @@ func TestStore(t *testing.T) {
 	})
 }
 
+func TestStationsForUpdate(t *testing.T) {
+	now := time.Date(2024, 12, 1, 8, 0, 0, 0, time.UTC)
+	s := NewStore()
+	s.UpdateStations(map[string]*models.Station{
+		"127": {ID: "127", Name: "Times Sq-42 St", Routes: []string{"1"},
+			Trains: models.TrainsByDirection{North: []models.Train{{Route: "1", Time: now}}}},
+		"999": {ID: "999", Name: "Closed station"},
+	})
+
+	stations := s.StationsForUpdate()
+	if len(stations) != 1 || stations["127"] == nil {
+		t.Fatalf("Expected only the station with routes, got %v", stations)
+	}
+	if len(stations["127"].Trains.North) != 0 || stations["127"].Trains.South == nil {
+		t.Errorf("Expected emptied, non-nil arrivals, got %+v", stations["127"].Trains)
+	}
+
+	// Filling the copy must not show through to readers of the store
+	stations["127"].Trains.South = append(stations["127"].Trains.South, models.Train{Route: "1"})
+	stations["127"].Name = "Renamed"
+	current, _ := s.GetStationsByIDs([]string{"127"})
+	if current[0].Name != "Times Sq-42 St" || len(current[0].Trains.South) != 0 || len(current[0].Trains.North) != 1 {
+		t.Errorf("Expected the store unchanged, got %+v", current[0])
+	}
+}
+
+func TestMergeStationOneWayRoutes(t *testing.T) {
+	dst := copyStation(&models.Station{
+		ID: "A", Routes: []string{"1", "2", "3"},
+		OneWayRoutes: map[string]models.Direction{"2": models.DirectionSouth, "3": models.DirectionNorth},
+	})
+	mergeStation(dst, &models.Station{
+		ID: "B", Routes: []string{"1", "2", "3", "7"},
+		OneWayRoutes: map[string]models.Direction{"1": models.DirectionNorth, "2": models.DirectionSouth, "7": models.DirectionSouth},
+	})
+
+	// 1 stops both ways at A, 3 both ways at B, so only the 2 (agreed) and the 7 (B alone) stay one-way
+	expected := map[string]models.Direction{"2": models.DirectionSouth, "7": models.DirectionSouth}
+	if !reflect.DeepEqual(dst.OneWayRoutes, expected) {
+		t.Errorf("Expected one-way routes %v, got %v", expected, dst.OneWayRoutes)
+	}
+}
+
+func TestGetStationsByLocationLimits(t *testing.T) {
+	s := NewStore()
+	stations := make(map[string]*models.Station)
+	for i := range 40 {
+		id := fmt.Sprintf("S%02d", i)
+		// Spread along a diagonal so every station is a distinct distance from the query point
+		stations[id] = &models.Station{ID: id, Location: models.Location{Lat: 40.70 + float64(i)*0.003, Lon: -74.00 + float64(i)*0.002}}
+	}
+	s.UpdateStations(stations)
+
+	var all []string
+	for _, sd := range s.nearestStations(40.76, -73.96, nil) {
+		all = append(all, sd.station.ID)
+	}
+	for _, limit := range []int{0, 1, 5, 40, 100} {
+		var got []string
+		for _, station := range s.GetStationsByLocation(40.76, -73.96, limit) {
+			got = append(got, station.ID)
+		}
+		if expected := all[:min(limit, len(all))]; !slices.Equal(got, expected) {
+			t.Errorf("limit %d: expected %v, got %v", limit, expected, got)
+		}
+	}
+}
+
+func TestGetStationsByLocationMerged(t *testing.T) {
+	now := time.Date(2024, 12, 1, 8, 0, 0, 0, time.UTC)
+
+	// Times Sq complex is four GTFS parent stations within ~150m of each other
+	stations := map[string]*models.Station{
+		"127": {
+			ID: "127", Name: "Times Sq-42 St",
+			Location: models.Location{Lat: 40.75529, Lon: -73.987495},
+			Routes:   []string{"1", "2", "3"},
+			Trains: models.TrainsByDirection{
+				North: []models.Train{{Route: "1", Time: now.Add(4 * time.Minute)}},
+			},
+			Stops: map[string]models.Location{"127N": {Lat: 40.75529, Lon: -73.987495}},
+		},
+		"R16": {
+			ID: "R16", Name: "Times Sq-42 St",
+			Location: models.Location{Lat: 40.754672, Lon: -73.986754},
+			Routes:   []string{"N", "Q", "R", "W"},
+			Trains: models.TrainsByDirection{
+				North: []models.Train{{Route: "N", Time: now.Add(2 * time.Minute)}},
+			},
+			Stops: map[string]models.Location{"R16N": {Lat: 40.754672, Lon: -73.986754}},
+		},
+		"725": {
+			ID: "725", Name: "Times Sq-42 St",
+			Location: models.Location{Lat: 40.755477, Lon: -73.987691},
+			Routes:   []string{"7"},
+			Stops:    map[string]models.Location{},
+		},
+		"902": {
+			ID: "902", Name: "Times Sq-42 St",
+			Location: models.Location{Lat: 40.755983, Lon: -73.986229},
+			Routes:   []string{"S"},
+			Stops:    map[string]models.Location{},
+		},
+		"631": {
+			ID: "631", Name: "Grand Central-42 St",
+			Location: models.Location{Lat: 40.751776, Lon: -73.976848},
+			Routes:   []string{"4", "5", "6"},
+			Stops:    map[string]models.Location{},
+		},
+		"635": {
+			ID: "635", Name: "14 St-Union Sq",
+			Location: models.Location{Lat: 40.734673, Lon: -73.989951},
+			Routes:   []string{"4", "5", "6"},
+			Stops:    map[string]models.Location{},
+		},
+	}
+
+	s := NewStore()
+	s.UpdateStations(stations)
+
+	t.Run("unmerged by default", func(t *testing.T) {
+		results := s.GetStationsByLocation(40.7555, -73.9875, 5)
+		if len(results) != 5 {
+			t.Fatalf("Expected 5 stations, got %d", len(results))
+		}
+	})
+
+	t.Run("collapses complex", func(t *testing.T) {
+		results := s.GetStationsByLocationMerged(40.7555, -73.9875, 5, 0.2)
+		if len(results) != 3 {
+			t.Fatalf("Expected 3 merged stations, got %d: %+v", len(results), results)
+		}
+
+		timesSq := results[0]
+		if timesSq.ID != "725" {
+			t.Errorf("Expected closest station 725 to anchor the merge, got %s", timesSq.ID)
+		}
+		routes := make(map[string]bool)
+		for _, route := range timesSq.Routes {
+			routes[route] = true
+		}
+		for _, route := range []string{"1", "2", "3", "7", "N", "Q", "R", "W", "S"} {
+			if !routes[route] {
+				t.Errorf("Merged Times Sq missing route %s: %v", route, timesSq.Routes)
+			}
+		}
+		if len(timesSq.Trains.North) != 2 || timesSq.Trains.North[0].Route != "N" {
+			t.Errorf("Expected merged northbound arrivals sorted by time, got %v", timesSq.Trains.North)
+		}
+		if len(timesSq.Stops) != 2 {
+			t.Errorf("Expected 2 merged stops, got %d", len(timesSq.Stops))
+		}
+
+		if results[1].ID != "631" || results[2].ID != "635" {
+			t.Errorf("Expected 631 then 635 after merged complex, got %s, %s", results[1].ID, results[2].ID)
+		}
+	})
+
+	t.Run("limit applies to merged results", func(t *testing.T) {
+		results := s.GetStationsByLocationMerged(40.7555, -73.9875, 1, 0.2)
+		if len(results) != 1 {
+			t.Fatalf("Expected 1 merged station, got %d", len(results))
+		}
+		if len(results[0].Routes) != 9 {
+			t.Errorf("Expected all 9 Times Sq routes merged, got %v", results[0].Routes)
+		}
+	})
+
+	t.Run("store data is not mutated", func(t *testing.T) {
+		s.GetStationsByLocationMerged(40.7555, -73.9875, 5, 0.2)
+		original, _ := s.GetStationsByIDs([]string{"725"})
+		if len(original[0].Routes) != 1 || len(original[0].Trains.North) != 0 {
+			t.Errorf("Merging mutated stored station: %+v", original[0])
+		}
+	})
+}
+
+func TestGetRoutesOrdering(t *testing.T) {
+	s := NewStore()
+	s.UpdateStations(map[string]*models.Station{
+		"1": {ID: "1", Name: "One", Routes: []string{"A", "10", "2", "L"}},
+		"2": {ID: "2", Name: "Two", Routes: []string{"1", "7", "6X", "SI"}},
+		"3": {ID: "3", Name: "Three", Routes: []string{"S", "6", "C", "B"}},
+	})
+
+	routes := s.GetRoutes()
+	expected := []string{"1", "2", "6", "6X", "7", "10", "A", "B", "C", "L", "S", "SI"}
+	if len(routes) != len(expected) {
+		t.Fatalf("Expected %d routes, got %d: %v", len(expected), len(routes), routes)
+	}
+	for i, route := range expected {
+		if routes[i] != route {
+			t.Errorf("Expected routes %v, got %v", expected, routes)
+			break
+		}
+	}
+}
+
+func TestUpsertAlert(t *testing.T) {
+	s := NewStore()
+	first := time.Date(2024, 1, 15, 8, 0, 0, 0, time.UTC)
+	s.UpsertAlert(models.Alert{ID: "a1", Header: "Delays", FirstSeen: first})
+	s.UpsertAlert(models.Alert{ID: "a2", Header: "Reroute", FirstSeen: first})
+	s.UpsertAlert(models.Alert{ID: "a1", Header: "Severe delays", FirstSeen: first.Add(time.Hour)})
+
+	alerts := s.GetServiceAlerts()
+	if len(alerts) != 2 {
+		t.Fatalf("Expected the re-seen alert replaced rather than added, got %+v", alerts)
+	}
+	if alerts[0].ID != "a1" || alerts[0].Header != "Severe delays" || !alerts[0].FirstSeen.Equal(first) {
+		t.Errorf("Expected a1 updated in place with its original FirstSeen, got %+v", alerts[0])
+	}
+}
+
+func TestCounts(t *testing.T) {
+	s := NewStore()
+	if stations, routes, alerts := s.Counts(); stations != 0 || routes != 0 || alerts != 0 {
+		t.Errorf("Expected an empty store to count 0/0/0, got %d/%d/%d", stations, routes, alerts)
+	}
+
+	s.UpdateStations(map[string]*models.Station{
+		"127": {ID: "127", Routes: []string{"1", "2", "3"}},
+		"631": {ID: "631", Routes: []string{"4", "5", "6"}},
+		"635": {ID: "635", Routes: []string{"4", "5", "6", "L"}},
+	})
+	s.UpdateAlerts([]models.Alert{{ID: "a1"}, {ID: "a2"}})
+
+	// Routes shared between stations count once
+	if stations, routes, alerts := s.Counts(); stations != 3 || routes != 7 || alerts != 2 {
+		t.Errorf("Expected 3 stations, 7 routes and 2 alerts, got %d/%d/%d", stations, routes, alerts)
+	}
+
+	s.UpdateStations(map[string]*models.Station{"127": {ID: "127", Routes: []string{"1"}}})
+	if stations, routes, _ := s.Counts(); stations != 1 || routes != 1 {
+		t.Errorf("Expected counts to follow the latest update, got %d stations and %d routes", stations, routes)
+	}
+}
+
+func TestGetRoutesNearby(t *testing.T) {
+	s := NewStore()
+	s.UpdateStations(map[string]*models.Station{
+		"127": {ID: "127", Location: models.Location{Lat: 40.75529, Lon: -73.987495}, Routes: []string{"N", "1", "2"}},
+		"725": {ID: "725", Location: models.Location{Lat: 40.755477, Lon: -73.987691}, Routes: []string{"7", "1"}},
+		"631": {ID: "631", Location: models.Location{Lat: 40.751776, Lon: -73.976848}, Routes: []string{"4", "5", "6"}},
+	})
+
+	routes := s.GetRoutesNearby(40.7553, -73.9875, 0.3)
+	expected := []string{"1", "2", "7", "N"}
+	if !reflect.DeepEqual(routes, expected) {
+		t.Errorf("Expected routes %v, got %v", expected, routes)
+	}
+
+	stations := s.GetStationsWithinRadius(40.7553, -73.9875, 1.5)
+	if len(stations) != 3 || stations[2].ID != "631" {
+		t.Errorf("Expected all 3 stations with 631 farthest, got %v", stations)
+	}
+
+	if routes := s.GetRoutesNearby(40.6, -73.8, 0.3); routes == nil || len(routes) != 0 {
+		t.Errorf("Expected empty non-nil routes, got %#v", routes)
+	}
+}
+
+func TestAllStations(t *testing.T) {
+	s := NewStore()
+	s.UpdateStations(map[string]*models.Station{
+		"635": {ID: "635", Name: "14 St-Union Sq"},
+		"127": {ID: "127", Name: "Times Sq-42 St"},
+		"631": {ID: "631", Name: "Grand Central-42 St"},
+	})
+
+	var ids []string
+	for station := range s.AllStations() {
+		ids = append(ids, station.ID)
+	}
+	if expected := []string{"127", "631", "635"}; !reflect.DeepEqual(ids, expected) {
+		t.Errorf("Expected stations %v, got %v", expected, ids)
+	}
+
+	// Stopping early must not panic or keep yielding
+	count := 0
+	for range s.AllStations() {
+		count++
+		break
+	}
+	if count != 1 {
+		t.Errorf("Expected iteration to stop after 1 station, got %d", count)
+	}
+}
+
+func TestGetStationsByIDPrefix(t *testing.T) {
+	s := NewStore()
+	stations := make(map[string]*models.Station)
+	for _, id := range []string{"R20", "R16", "R17", "127", "A27"} {
+		stations[id] = &models.Station{ID: id, Name: id}
+	}
+	s.UpdateStations(stations)
+
+	tests := []struct {
+		prefix   string
+		expected []string
+	}{
+		{"R1", []string{"R16", "R17"}},
+		{"R", []string{"R16", "R17", "R20"}},
+		{"R16", []string{"R16"}},
+		{"R16N", []string{}}, // Station IDs are parents; directional stop IDs match nothing
+		{"r1", []string{}},   // IDs are case-sensitive
+		{"Z", []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.prefix, func(t *testing.T) {
+			results := s.GetStationsByIDPrefix(tt.prefix)
+			if results == nil {
+				t.Fatal("Expected an empty slice, not nil")
+			}
+			ids := make([]string, len(results))
+			for i, station := range results {
+				ids[i] = station.ID
+			}
+			if fmt.Sprint(ids) != fmt.Sprint(tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, ids)
+			}
+		})
+	}
+}
+
+func TestGetStationsByTrunk(t *testing.T) {
+	s := NewStore()
+	s.UpdateStations(map[string]*models.Station{
+		"R16": {ID: "R16", Routes: []string{"N", "Q", "R", "W"}},
+		"635": {ID: "635", Routes: []string{"4", "5", "6", "N", "Q", "R", "W"}},
+		"127": {ID: "127", Routes: []string{"1", "2", "3"}},
+	})
+
+	stations, err := s.GetStationsByTrunk("Yellow")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	// 635 serves four yellow routes but must appear once
+	if len(stations) != 2 || stations[0].ID != "635" || stations[1].ID != "R16" {
+		t.Errorf("Expected stations 635 and R16, got %v", stations)
+	}
+
+	if _, err := s.GetStationsByTrunk("magenta"); err == nil {
+		t.Error("Expected error for unknown trunk")
+	}
+}
+
+func TestGetArrivalsByRoute(t *testing.T) {
+	now := time.Date(2024, 12, 1, 8, 0, 0, 0, time.UTC)
+	s := NewStore()
+	s.UpdateStations(map[string]*models.Station{
+		"R16": {
+			ID: "R16", Name: "Times Sq-42 St", Routes: []string{"N", "Q", "R"},
+			Trains: models.TrainsByDirection{
+				North: []models.Train{{Route: "N", Time: now.Add(2 * time.Minute)}, {Route: "Q", Time: now.Add(3 * time.Minute)}},
+				South: []models.Train{{Route: "R", Time: now.Add(time.Minute)}, {Route: "N", Time: now.Add(4 * time.Minute)}},
+			},
+		},
+		"R20": {
+			ID: "R20", Name: "14 St-Union Sq", Routes: []string{"N", "R"},
+			Trains: models.TrainsByDirection{
+				North: []models.Train{{Route: "R", Time: now.Add(5 * time.Minute)}},
+			},
+		},
+		"631": {
+			ID: "631", Name: "Grand Central-42 St", Routes: []string{"4", "5", "6"},
+			Trains: models.TrainsByDirection{
+				North: []models.Train{{Route: "6", Time: now.Add(time.Minute)}},
+			},
+		},
+	})
+
+	arrivals, err := s.GetArrivalsByRoute("n")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(arrivals) != 2 {
+		t.Fatalf("Expected 2 stations on route N, got %d", len(arrivals))
+	}
+
+	times := arrivals["R16"]
+	if len(times.North) != 1 || times.North[0].Route != "N" || !times.North[0].Time.Equal(now.Add(2*time.Minute)) {
+		t.Errorf("Expected one northbound N at R16, got %v", times.North)
+	}
+	if len(times.South) != 1 || times.South[0].Route != "N" {
+		t.Errorf("Expected one southbound N at R16, got %v", times.South)
+	}
+
+	// Stations on the route without N arrivals still appear, with empty directions
+	union := arrivals["R20"]
+	if union.North == nil || len(union.North) != 0 || union.South == nil || len(union.South) != 0 {
+		t.Errorf("Expected empty non-nil arrivals at R20, got %+v", union)
+	}
+
+	if _, err := s.GetArrivalsByRoute("X"); err == nil {
+		t.Error("Expected error for non-existent route")
+	}
+}
+
+func TestGetRouteStatus(t *testing.T) {
+	now := time.Date(2024, 12, 1, 8, 0, 0, 0, time.UTC)
+	station := func(id string, routes []string, north, south []models.Train) *models.Station {
+		return &models.Station{ID: id, Name: id, Routes: routes, Trains: models.TrainsByDirection{North: north, South: south}}
+	}
+	in := func(route string, d time.Duration) models.Train {
+		return models.Train{Route: route, Time: now.Add(d), Realtime: true}
+	}
+
+	s := NewStore()
+	s.UpdateStations(map[string]*models.Station{
+		// N waits: 2, 4, 6 and 10 minutes; the soonest of either direction counts
+		"A": station("A", []string{"N", "Q"}, []models.Train{in("N", 2*time.Minute)}, []models.Train{in("N", 5*time.Minute)}),
+		"B": station("B", []string{"N"}, []models.Train{in("N", 4*time.Minute)}, nil),
+		"C": station("C", []string{"N"}, []models.Train{in("N", -30*time.Second), in("N", 6*time.Minute)}, nil), // Just-departed train is skipped
+		"D": station("D", []string{"N"}, nil, []models.Train{in("N", 10*time.Minute)}),
+		// Only other routes and schedule-derived arrivals here, so it has no N wait
+		"E": station("E", []string{"N"}, []models.Train{in("W", time.Minute), {Route: "N", Time: now.Add(time.Minute), Scheduled: true}}, nil),
+		// Q has no current arrivals at all
+		"F": station("F", []string{"Q"}, nil, nil),
+	})
+
+	statuses := s.GetRouteStatus(now)
+	byRoute := make(map[string]models.RouteStatus)
+	for _, status := range statuses {
+		byRoute[status.Route] = status
+	}
+	if len(statuses) != 2 {
+		t.Fatalf("Expected status for N and Q, got %+v", statuses)
+	}
+
+	n := byRoute["N"]
+	if n.Stations != 5 || n.StationsWithTrains != 4 {
+		t.Errorf("Expected 4 of 5 N stations with trains, got %d of %d", n.StationsWithTrains, n.Stations)
+	}
+	// Median of 2, 4, 6 and 10 is 5; p90 falls at rank 2.7, 70% of the way from 6 to 10
+	if n.MedianMinutes == nil || *n.MedianMinutes != 5 {
+		t.Errorf("Expected median 5 minutes, got %v", n.MedianMinutes)
+	}
+	if n.P90Minutes == nil || *n.P90Minutes != 8.8 {
+		t.Errorf("Expected p90 8.8 minutes, got %v", n.P90Minutes)
+	}
+
+	q := byRoute["Q"]
+	if q.Stations != 2 || q.StationsWithTrains != 0 || q.MedianMinutes != nil || q.P90Minutes != nil {
+		t.Errorf("Expected Q listed with no wait statistics, got %+v", q)
+	}
+}
+
+func TestGetCoverage(t *testing.T) {
+	now := time.Date(2024, 12, 1, 8, 0, 0, 0, time.UTC)
+	s := NewStore()
+	s.UpdateStations(map[string]*models.Station{
+		"R16": {
+			ID: "R16", Name: "Times Sq-42 St", Routes: []string{"N", "Q"},
+			Trains: models.TrainsByDirection{North: []models.Train{{Route: "N", Time: now}}},
+		},
+		"R20": {ID: "R20", Name: "14 St-Union Sq", Routes: []string{"N", "R"}},
+		"R11": {
+			ID: "R11", Name: "Lexington Av/59 St", Routes: []string{"N"},
+			Trains: models.TrainsByDirection{South: []models.Train{{Route: "N", Time: now, Scheduled: true}}},
+		},
+		"631": {
+			ID: "631", Name: "Grand Central-42 St", Routes: []string{"6"},
+			Trains: models.TrainsByDirection{South: []models.Train{{Route: "6", Time: now}}},
+		},
+	})
+
+	report := s.GetCoverage()
+
+	if report.TotalStations != 4 {
+		t.Errorf("Expected 4 total stations, got %d", report.TotalStations)
+	}
+	// R11 only has scheduled fallback arrivals, so it still lacks real-time data
+	if report.MissingCount != 2 || fmt.Sprint(report.Missing) != "[R11 R20]" {
+		t.Errorf("Expected missing [R11 R20], got %d %v", report.MissingCount, report.Missing)
+	}
+	if got := fmt.Sprint(report.MissingByRoute["N"]); got != "[R11 R20]" {
+		t.Errorf("Expected route N missing [R11 R20], got %s", got)
+	}
+	if got := fmt.Sprint(report.MissingByRoute["R"]); got != "[R20]" {
+		t.Errorf("Expected route R missing [R20], got %s", got)
+	}
+	if _, ok := report.MissingByRoute["6"]; ok {
+		t.Error("Expected no entry for fully covered route 6")
+	}
+}
+
+func TestGetAlertsInRange(t *testing.T) {
+	at := func(day, hour int) *time.Time {
+		t := time.Date(2024, 12, day, hour, 0, 0, 0, time.UTC)
+		return &t
+	}
+
+	s := NewStore()
+	s.UpdateAlerts([]models.Alert{
+		{ID: "friday-night", ActivePeriods: []models.TimePeriod{{Start: at(6, 22), End: at(7, 6)}}},
+		{ID: "saturday", ActivePeriods: []models.TimePeriod{{Start: at(7, 9), End: at(7, 17)}}},
+		{ID: "next-week", ActivePeriods: []models.TimePeriod{{Start: at(14, 6), End: at(14, 12)}}},
+		{ID: "multi", ActivePeriods: []models.TimePeriod{
+			{Start: at(2, 6), End: at(2, 12)},
+			{Start: at(8, 20), End: at(9, 2)},
+		}},
+		{ID: "open-start", ActivePeriods: []models.TimePeriod{{End: at(7, 7)}}},
+		{ID: "open-end", ActivePeriods: []models.TimePeriod{{Start: at(30, 0)}}},
+		{ID: "no-periods"},
+	})
+
+	tests := []struct {
+		name     string
+		start    *time.Time
+		end      *time.Time
+		expected []string
+	}{
+		// Saturday 6am to Sunday midnight
+		{"weekend", at(7, 6), at(8, 0), []string{"saturday", "open-start", "no-periods"}},
+		// Ranges are half-open, so periods touching either edge don't count
+		{"adjacent", at(7, 6), at(7, 9), []string{"open-start", "no-periods"}},
+		{"overlapping second period", at(8, 23), at(9, 0), []string{"multi", "no-periods"}},
+		{"disjoint", at(20, 0), at(21, 0), []string{"no-periods"}},
+		{"far future", at(31, 0), at(31, 1), []string{"open-end", "no-periods"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ids []string
+			for _, alert := range s.GetAlertsInRange(*tt.start, *tt.end) {
+				ids = append(ids, alert.ID)
+			}
+			if !reflect.DeepEqual(ids, tt.expected) {
+				t.Errorf("Expected alerts %v, got %v", tt.expected, ids)
+			}
+		})
+	}
+}
+
 func TestDistance(t *testing.T) {
 	// Verify Haversine distance calculation accuracy
 	// Real-world distance: Times Square to Grand Central
@@ -130,3 +832,212 @@ func TestDistance(t *testing.T) {
 		t.Errorf("Expected distance 0, got %.2f", dist)
 	}
 }
+
+// benchStations builds n stations spread across a handful of routes
+func benchStations(n int) map[string]*models.Station {
+	routes := []string{"1", "2", "3", "A", "C", "E", "N", "Q", "R", "W"}
+	stations := make(map[string]*models.Station, n)
+	for i := 0; i < n; i++ {
+		id := fmt.Sprintf("S%03d", i)
+		stations[id] = &models.Station{
+			ID:       id,
+			Name:     fmt.Sprintf("Station %03d", i),
+			Location: models.Location{Lat: 40.5 + float64(i)*0.001, Lon: -74.0 + float64(i)*0.001},
+			Routes:   []string{routes[i%len(routes)], routes[(i+3)%len(routes)]},
+			Trains: models.TrainsByDirection{
+				North: make([]models.Train, 10),
+				South: make([]models.Train, 10),
+			},
+		}
+	}
+	return stations
+}
+
+// TestConcurrentReadsAndUpdates is meant to be run with -race
+func TestConcurrentReadsAndUpdates(t *testing.T) {
+	s := NewStore()
+	s.UpdateStations(benchStations(100))
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 50; i++ {
+			s.UpdateStations(benchStations(100))
+			s.UpdateAlerts([]models.Alert{{ID: "a", Routes: []string{"N"}}})
+		}
+		close(done)
+	}()
+
+	for r := 0; r < 4; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				if _, err := s.GetStationsByRoute("N"); err != nil {
+					t.Errorf("Unexpected error: %v", err)
+					return
+				}
+				s.GetStationsByLocation(40.6, -73.9, 5)
+				s.GetStationsByLocationMerged(40.6, -73.9, 5, 0.2)
+				s.GetStationsByIDs([]string{"S001", "S050"})
+				s.SearchStations("station 04", false, 10)
+				s.GetAllStations()
+				s.GetRoutes()
+				s.GetRouteInfo("N")
+				s.GetLastUpdate()
+			}
+		}()
+	}
+
+	wg.Wait()
+}
+
+func BenchmarkGetStationsByRouteDuringUpdates(b *testing.B) {
+	s := NewStore()
+	s.UpdateStations(benchStations(500))
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				s.UpdateStations(benchStations(500))
+			}
+		}
+	}()
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if _, err := s.GetStationsByRoute("N"); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.StopTimer()
+
+	close(stop)
+	wg.Wait()
+}
+
+// BenchmarkStationsByRoute compares copying whole stations with building summaries for a route's list view
+// Both make one allocation per call; summaries are half the size of stations, so the bytes halve
+func BenchmarkStationsByRoute(b *testing.B) {
+	s := NewStore()
+	s.UpdateStations(benchStations(500))
+
+	b.Run("full", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := s.GetStationsByRoute("N"); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("summary", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := s.GetStationSummariesByRoute("N"); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+// BenchmarkStationsForUpdate compares seeding a real-time cycle's station map route by route,
+// as updateRealTimeData used to, with the single pass over the snapshot
+func BenchmarkStationsForUpdate(b *testing.B) {
+	s := NewStore()
+	s.UpdateStations(benchStations(500))
+
+	b.Run("per route", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			stations := make(map[string]*models.Station)
+			for _, route := range s.GetRoutes() {
+				routeStations, err := s.GetStationsByRoute(route)
+				if err != nil {
+					b.Fatal(err)
+				}
+				for _, station := range routeStations {
+					if _, exists := stations[station.ID]; !exists {
+						stationCopy := station
+						stationCopy.Trains = models.TrainsByDirection{North: []models.Train{}, South: []models.Train{}}
+						stations[station.ID] = &stationCopy
+					}
+				}
+			}
+		}
+	})
+	b.Run("single pass", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			s.StationsForUpdate()
+		}
+	})
+}
+
+// BenchmarkReadLatencyDuringUpdates reports tail read latency while stations are replaced continuously
+// Index rebuilding happens before the atomic snapshot swap, so p99 should stay close to p50;
+// a lock held across the rebuild would push p99 out to the length of an update
+func BenchmarkReadLatencyDuringUpdates(b *testing.B) {
+	s := NewStore()
+	s.UpdateStations(benchStations(500))
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				s.UpdateStations(benchStations(500))
+			}
+		}
+	}()
+
+	var mu sync.Mutex
+	var latencies []time.Duration
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		var local []time.Duration
+		for pb.Next() {
+			start := time.Now()
+			if _, err := s.GetStationsByRoute("N"); err != nil {
+				b.Fatal(err)
+			}
+			s.GetRoutes()
+			local = append(local, time.Since(start))
+		}
+		mu.Lock()
+		latencies = append(latencies, local...)
+		mu.Unlock()
+	})
+	b.StopTimer()
+
+	close(stop)
+	wg.Wait()
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	if n := len(latencies); n > 0 {
+		b.ReportMetric(float64(latencies[n/2].Nanoseconds()), "p50-ns")
+		b.ReportMetric(float64(latencies[n*99/100].Nanoseconds()), "p99-ns")
+	}
+}
@@ -1,8 +1,10 @@
 package mta
 
 import (
+	"iter"
 	"time"
 
+	"github.com/jusunglee/mta-go/internal/feed"
 	"github.com/jusunglee/mta-go/internal/models"
 )
 
@@ -10,30 +12,128 @@ import (
 // Abstracts different data sources (local vs remote) behind common interface
 type Client interface {
 	GetStationsByLocation(lat, lon float64, limit int) ([]models.Station, error)
+	GetStationsByLocationMerged(lat, lon float64, limit int, radiusKm float64) ([]models.Station, error)
+	GetStationsWithinRadius(lat, lon, radiusKm float64) ([]models.Station, error)
 	GetStationsByRoute(route string) ([]models.Station, error)
+	GetStationSummariesByRoute(route string) ([]models.StationSummary, error)
+	GetStationsByTrunk(trunk string) ([]models.Station, error)
 	GetStationsByIDs(ids []string) ([]models.Station, error)
+	FindStationsByIDs(ids []string) ([]models.Station, []string, error)
+	GetStationsByIDPrefix(prefix string) ([]models.Station, error)
+	GetAllStations() ([]models.Station, error)
+	AllStations() iter.Seq[models.Station]
+	GetStationsInBounds(minLat, minLon, maxLat, maxLon float64) ([]models.Station, error)
+	GetBounds() (models.Bounds, bool)
+	SearchStations(query string, fuzzy bool, limit int) ([]models.Station, error)
 
 	GetRoutes() ([]string, error)
+	GetRouteInfo(route string) (models.RouteInfo, error)
+	GetRoutesNearby(lat, lon, radiusKm float64) ([]string, error)
+	GetRouteShape(route string) ([][]models.Location, error)
+	GetFares(route, zone string) ([]models.Fare, error)
+	GetArrivalsByRoute(route string) (map[string]models.TrainsByDirection, error)
+	GetRouteStatus(now time.Time) ([]models.RouteStatus, error)
+	GetTripProgress(tripID string) (models.TripProgress, error)
 
 	GetServiceAlerts() ([]models.Alert, error)
+	GetAlertsInRange(start, end time.Time) ([]models.Alert, error)
+
+	GetFeedLatencies() []models.FeedLatency
+	GetStats() models.Stats
+	Counts() (stations, routes, alerts int)
+	GetCoverage() (models.CoverageReport, error)
 
 	GetLastUpdate() time.Time
 	GetLastStaticUpdate() time.Time
 }
 
+// DefaultMergeRadiusKm is the distance within which nearby stations are treated
+// as one transfer complex by GetStationsByLocationMerged
+// Wide enough to join Times Sq's 1/2/3, N/Q/R/W, 7 and S platforms
+const DefaultMergeRadiusKm = 0.2
+
+// DefaultNearbyRadiusKm is the search radius for GetRoutesNearby when the caller doesn't pick one
+// Roughly a five-minute walk
+const DefaultNearbyRadiusKm = 0.4
+
 // Config holds configuration for the MTA client
 // APIKey required for accessing MTA's GTFS-RT feeds
+// FeedGroups optionally limits real-time polling to feed groups like "ace" or "nqrw"; empty means all
+// StaticUpdateInterval controls the static GTFS refresh independently of real-time polling
+// DuplicateStopPolicy is "last-wins" (default), "first-wins" or "error" for repeated stop_ids in stops.txt
+// DedupStrategy is "trip" (default) to collapse duplicate arrivals by trip ID, or "route-time" to match on route and time only
+// ArrivalRetention is how many arrivals per direction the store keeps; zero uses DefaultArrivalRetention
+// PastArrivalCutoff is how long after arriving a train stays listed; zero uses DefaultPastArrivalCutoff
+// MaxAlertAge drops alerts this long after they were first seen, even without an end time; zero uses DefaultMaxAlertAge
+// MergeStaticGTFS downloads both the regular and supplemented static feeds and merges them, supplemented service IDs overriding regular ones
+// ScheduleFallback fills directions with no real-time arrivals from the static timetable, flagged as scheduled
+// ServiceDayCutoff maps schedule times before it (like 4h) to the previous service day, for feeds that don't use 24:00:00+ times
+// GTFSDataDir is where static GTFS is downloaded; empty uses DefaultGTFSDataDir
+// StaticGTFSSource loads static GTFS from a local zip or extracted directory (a path or file:// URL) instead of downloading it
+// ExpectedRealtimeVersion is the gtfs_realtime_version feeds should declare; others are logged and flagged in feed info
+// BreakerThreshold consecutive fetch failures from a feed host skip its fetches for BreakerCooldown; zero uses the defaults, a negative threshold disables
+// StationOverridesFile optionally names a JSON file of per-station name, location or route corrections
+// APIKeyHeader and APIKeyQueryParam move the API key if the MTA changes its auth convention; default is the x-api-key header
+// AuditLog logs a summary of every station and alert update (counts, added and removed IDs, route changes)
+// ServiceArea is "minLat,minLon,maxLat,maxLon" that static stops must fall in; empty uses feed.DefaultServiceArea (NYC), "off" disables
 type Config struct {
-	APIKey         string
-	UpdateInterval time.Duration
-	StationsFile   string
+	APIKey                  string
+	UpdateInterval          time.Duration
+	StaticUpdateInterval    time.Duration
+	StationsFile            string
+	GTFSDataDir             string
+	StaticGTFSSource        string
+	StationOverridesFile    string
+	FeedGroups              []string
+	DuplicateStopPolicy     string
+	DedupStrategy           string
+	ArrivalRetention        int
+	PastArrivalCutoff       time.Duration
+	MaxAlertAge             time.Duration
+	ScheduleFallback        bool
+	MergeStaticGTFS         bool
+	ExpectedRealtimeVersion string
+	ServiceDayCutoff        time.Duration
+	BreakerThreshold        int
+	BreakerCooldown         time.Duration
+	APIKeyHeader            string
+	APIKeyQueryParam        string
+	ServiceArea             string
+	AuditLog                bool
 }
 
+// DefaultStaticUpdateInterval is used when Config.StaticUpdateInterval is zero
+// Static schedules change rarely, so a few refreshes a day is plenty
+const DefaultStaticUpdateInterval = feed.DefaultStaticUpdateInterval
+
+// DefaultGTFSDataDir is used when Config.GTFSDataDir is empty
+const DefaultGTFSDataDir = feed.DefaultGTFSDataDir
+
+// DefaultArrivalRetention is used when Config.ArrivalRetention is zero
+// Responses show fewer; the rest is kept for headway and schedule comparisons
+const DefaultArrivalRetention = feed.DefaultArrivalRetention
+
+// DefaultPastArrivalCutoff is used when Config.PastArrivalCutoff is zero
+const DefaultPastArrivalCutoff = feed.DefaultPastArrivalCutoff
+
+// DefaultMaxAlertAge is used when Config.MaxAlertAge is zero
+const DefaultMaxAlertAge = feed.DefaultMaxAlertAge
+
+// DefaultGTFSRealtimeVersion is used when Config.ExpectedRealtimeVersion is empty
+const DefaultGTFSRealtimeVersion = feed.DefaultGTFSRealtimeVersion
+
+// DefaultBreakerThreshold is used when Config.BreakerThreshold is zero
+const DefaultBreakerThreshold = feed.DefaultBreakerThreshold
+
+// DefaultBreakerCooldown is used when Config.BreakerCooldown is zero
+const DefaultBreakerCooldown = feed.DefaultBreakerCooldown
+
 // DefaultConfig returns default configuration
 // 60-second update interval balances freshness with API rate limits
 func DefaultConfig() Config {
 	return Config{
-		UpdateInterval: 60 * time.Second,
-		StationsFile:   "data/stations.json",
+		UpdateInterval:       60 * time.Second,
+		StaticUpdateInterval: DefaultStaticUpdateInterval,
+		StationsFile:         "data/stations.json",
 	}
 }
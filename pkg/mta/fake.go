@@ -0,0 +1,311 @@
+package mta
+
+import (
+	"context"
+	"iter"
+	"sync"
+	"time"
+
+	"github.com/jusunglee/mta-go/internal/models"
+	"github.com/jusunglee/mta-go/internal/store"
+)
+
+// FakeClient is an in-memory Client for tests of code that embeds one
+// Seeded data is served through the same store LocalClient uses, so ordering, case-insensitive
+// route matching and not-found errors behave alike, but nothing is ever fetched
+type FakeClient struct {
+	store *store.Store
+
+	mu               sync.Mutex
+	ctx              context.Context
+	latencies        []models.FeedLatency
+	stats            models.Stats
+	lastStaticUpdate time.Time
+}
+
+// NewFakeClient returns a FakeClient with no data, as a LocalClient is before its first load
+func NewFakeClient() *FakeClient {
+	return &FakeClient{store: store.NewStore(), ctx: context.Background()}
+}
+
+// SetContext ties the fake to ctx; once it is done every method that can fail returns ctx.Err()
+// and AllStations yields nothing, for testing callers that shut down or time out mid-request
+func (c *FakeClient) SetContext(ctx context.Context) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ctx = ctx
+}
+
+// SetStations replaces every station; the route list is derived from their Routes
+// GetLastUpdate reports the newest station LastUpdate so tests stay deterministic
+func (c *FakeClient) SetStations(stations []models.Station) {
+	byID := make(map[string]*models.Station, len(stations))
+	var newest time.Time
+	for _, station := range stations {
+		station := station
+		byID[station.ID] = &station
+		if station.LastUpdate.After(newest) {
+			newest = station.LastUpdate
+		}
+	}
+	c.store.UpdateStationsAt(byID, newest)
+}
+
+// SetRouteInfo replaces the static route metadata keyed by route short name
+// GetRoutes only lists routes some station serves, as with real data
+func (c *FakeClient) SetRouteInfo(routes map[string]models.RouteInfo) {
+	c.store.UpdateRouteInfo(routes)
+}
+
+// SetRouteShapes replaces the route polylines keyed by route short name
+func (c *FakeClient) SetRouteShapes(shapes map[string][][]models.Location) {
+	c.store.UpdateRouteShapes(shapes)
+}
+
+// SetAlerts replaces the service alerts
+func (c *FakeClient) SetAlerts(alerts []models.Alert) {
+	c.store.UpdateAlerts(alerts)
+}
+
+// SetFares replaces the fares
+func (c *FakeClient) SetFares(fares []models.Fare) {
+	c.store.UpdateFares(fares)
+}
+
+// SetTrips replaces the trip progress keyed by trip ID
+func (c *FakeClient) SetTrips(trips map[string]models.TripProgress) {
+	c.store.UpdateTrips(trips)
+}
+
+// SetFeedLatencies replaces what GetFeedLatencies reports
+func (c *FakeClient) SetFeedLatencies(latencies []models.FeedLatency) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.latencies = latencies
+}
+
+// SetStats replaces what GetStats reports
+func (c *FakeClient) SetStats(stats models.Stats) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stats = stats
+}
+
+// SetLastStaticUpdate replaces what GetLastStaticUpdate reports
+func (c *FakeClient) SetLastStaticUpdate(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lastStaticUpdate = t
+}
+
+// err returns the context's error once it is done
+func (c *FakeClient) err() error {
+	c.mu.Lock()
+	ctx := c.ctx
+	c.mu.Unlock()
+	return ctx.Err()
+}
+
+func (c *FakeClient) GetStationsByLocation(lat, lon float64, limit int) ([]models.Station, error) {
+	if err := c.err(); err != nil {
+		return nil, err
+	}
+	return c.store.GetStationsByLocation(lat, lon, limit), nil
+}
+
+func (c *FakeClient) GetStationsByLocationMerged(lat, lon float64, limit int, radiusKm float64) ([]models.Station, error) {
+	if err := c.err(); err != nil {
+		return nil, err
+	}
+	return c.store.GetStationsByLocationMerged(lat, lon, limit, radiusKm), nil
+}
+
+func (c *FakeClient) GetStationsWithinRadius(lat, lon, radiusKm float64) ([]models.Station, error) {
+	if err := c.err(); err != nil {
+		return nil, err
+	}
+	return c.store.GetStationsWithinRadius(lat, lon, radiusKm), nil
+}
+
+func (c *FakeClient) GetStationsByRoute(route string) ([]models.Station, error) {
+	if err := c.err(); err != nil {
+		return nil, err
+	}
+	return c.store.GetStationsByRoute(route)
+}
+
+func (c *FakeClient) GetStationSummariesByRoute(route string) ([]models.StationSummary, error) {
+	if err := c.err(); err != nil {
+		return nil, err
+	}
+	return c.store.GetStationSummariesByRoute(route)
+}
+
+func (c *FakeClient) GetStationsByTrunk(trunk string) ([]models.Station, error) {
+	if err := c.err(); err != nil {
+		return nil, err
+	}
+	return c.store.GetStationsByTrunk(trunk)
+}
+
+func (c *FakeClient) GetStationsByIDs(ids []string) ([]models.Station, error) {
+	if err := c.err(); err != nil {
+		return nil, err
+	}
+	return c.store.GetStationsByIDs(ids)
+}
+
+func (c *FakeClient) FindStationsByIDs(ids []string) ([]models.Station, []string, error) {
+	if err := c.err(); err != nil {
+		return nil, nil, err
+	}
+	stations, missing := c.store.FindStationsByIDs(ids)
+	return stations, missing, nil
+}
+
+func (c *FakeClient) GetStationsByIDPrefix(prefix string) ([]models.Station, error) {
+	if err := c.err(); err != nil {
+		return nil, err
+	}
+	return c.store.GetStationsByIDPrefix(prefix), nil
+}
+
+func (c *FakeClient) GetAllStations() ([]models.Station, error) {
+	if err := c.err(); err != nil {
+		return nil, err
+	}
+	return c.store.GetAllStations(), nil
+}
+
+// AllStations iterates over every station ordered by ID, stopping early if the context is done
+func (c *FakeClient) AllStations() iter.Seq[models.Station] {
+	return func(yield func(models.Station) bool) {
+		for station := range c.store.AllStations() {
+			if c.err() != nil || !yield(station) {
+				return
+			}
+		}
+	}
+}
+
+func (c *FakeClient) GetStationsInBounds(minLat, minLon, maxLat, maxLon float64) ([]models.Station, error) {
+	if err := c.err(); err != nil {
+		return nil, err
+	}
+	return c.store.GetStationsInBounds(minLat, minLon, maxLat, maxLon), nil
+}
+
+func (c *FakeClient) GetBounds() (models.Bounds, bool) {
+	minLat, minLon, maxLat, maxLon, ok := c.store.GetBounds()
+	return models.Bounds{MinLat: minLat, MinLon: minLon, MaxLat: maxLat, MaxLon: maxLon}, ok
+}
+
+func (c *FakeClient) SearchStations(query string, fuzzy bool, limit int) ([]models.Station, error) {
+	if err := c.err(); err != nil {
+		return nil, err
+	}
+	return c.store.SearchStations(query, fuzzy, limit), nil
+}
+
+func (c *FakeClient) GetRoutes() ([]string, error) {
+	if err := c.err(); err != nil {
+		return nil, err
+	}
+	return c.store.GetRoutes(), nil
+}
+
+func (c *FakeClient) GetRouteInfo(route string) (models.RouteInfo, error) {
+	if err := c.err(); err != nil {
+		return models.RouteInfo{}, err
+	}
+	return c.store.GetRouteInfo(route)
+}
+
+func (c *FakeClient) GetRoutesNearby(lat, lon, radiusKm float64) ([]string, error) {
+	if err := c.err(); err != nil {
+		return nil, err
+	}
+	return c.store.GetRoutesNearby(lat, lon, radiusKm), nil
+}
+
+func (c *FakeClient) GetRouteShape(route string) ([][]models.Location, error) {
+	if err := c.err(); err != nil {
+		return nil, err
+	}
+	return c.store.GetRouteShape(route)
+}
+
+func (c *FakeClient) GetFares(route, zone string) ([]models.Fare, error) {
+	if err := c.err(); err != nil {
+		return nil, err
+	}
+	return c.store.GetFares(route, zone), nil
+}
+
+func (c *FakeClient) GetArrivalsByRoute(route string) (map[string]models.TrainsByDirection, error) {
+	if err := c.err(); err != nil {
+		return nil, err
+	}
+	return c.store.GetArrivalsByRoute(route)
+}
+
+func (c *FakeClient) GetRouteStatus(now time.Time) ([]models.RouteStatus, error) {
+	if err := c.err(); err != nil {
+		return nil, err
+	}
+	return c.store.GetRouteStatus(now), nil
+}
+
+func (c *FakeClient) GetTripProgress(tripID string) (models.TripProgress, error) {
+	if err := c.err(); err != nil {
+		return models.TripProgress{}, err
+	}
+	return c.store.GetTripProgress(tripID)
+}
+
+func (c *FakeClient) GetServiceAlerts() ([]models.Alert, error) {
+	if err := c.err(); err != nil {
+		return nil, err
+	}
+	return c.store.GetServiceAlerts(), nil
+}
+
+func (c *FakeClient) GetAlertsInRange(start, end time.Time) ([]models.Alert, error) {
+	if err := c.err(); err != nil {
+		return nil, err
+	}
+	return c.store.GetAlertsInRange(start, end), nil
+}
+
+func (c *FakeClient) GetFeedLatencies() []models.FeedLatency {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]models.FeedLatency(nil), c.latencies...)
+}
+
+func (c *FakeClient) GetStats() models.Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stats
+}
+
+func (c *FakeClient) Counts() (stations, routes, alerts int) {
+	return c.store.Counts()
+}
+
+func (c *FakeClient) GetCoverage() (models.CoverageReport, error) {
+	if err := c.err(); err != nil {
+		return models.CoverageReport{}, err
+	}
+	return c.store.GetCoverage(), nil
+}
+
+func (c *FakeClient) GetLastUpdate() time.Time {
+	return c.store.GetLastUpdate()
+}
+
+func (c *FakeClient) GetLastStaticUpdate() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lastStaticUpdate
+}
@@ -0,0 +1,87 @@
+package mta
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jusunglee/mta-go/internal/models"
+)
+
+func TestFakeClient(t *testing.T) {
+	updated := time.Date(2024, 12, 1, 8, 0, 0, 0, time.UTC)
+	fake := NewFakeClient()
+	fake.SetStations([]models.Station{
+		{ID: "127", Name: "Times Sq-42 St", Location: models.Location{Lat: 40.75529, Lon: -73.987495}, Routes: []string{"1", "2", "3"}, LastUpdate: updated.Add(-time.Minute)},
+		{ID: "631", Name: "Grand Central-42 St", Location: models.Location{Lat: 40.751776, Lon: -73.976848}, Routes: []string{"4", "5", "6"}, LastUpdate: updated},
+	})
+	fake.SetRouteInfo(map[string]models.RouteInfo{"6": {LongName: "Lexington Avenue Local", Color: "00933C"}})
+	fake.SetAlerts([]models.Alert{{ID: "a1", Header: "6 trains delayed", Routes: []string{"6"}}})
+
+	// Everything below goes through the interface, as a downstream test would
+	var client Client = fake
+
+	t.Run("stations", func(t *testing.T) {
+		stations, err := client.GetStationsByRoute("6")
+		if err != nil || len(stations) != 1 || stations[0].ID != "631" {
+			t.Errorf("Expected Grand Central on the 6, got %v (err %v)", stations, err)
+		}
+		nearest, err := client.GetStationsByLocation(40.7553, -73.9875, 1)
+		if err != nil || len(nearest) != 1 || nearest[0].ID != "127" {
+			t.Errorf("Expected Times Sq nearest, got %v (err %v)", nearest, err)
+		}
+		if _, err := client.GetStationsByRoute("Z"); err == nil {
+			t.Error("Expected an error for an unknown route")
+		}
+		if got := client.GetLastUpdate(); !got.Equal(updated) {
+			t.Errorf("Expected last update %v from the newest station, got %v", updated, got)
+		}
+	})
+
+	t.Run("routes", func(t *testing.T) {
+		routes, err := client.GetRoutes()
+		if err != nil || !reflect.DeepEqual(routes, []string{"1", "2", "3", "4", "5", "6"}) {
+			t.Errorf("Expected routes derived from stations, got %v (err %v)", routes, err)
+		}
+		info, err := client.GetRouteInfo("6")
+		if err != nil || info.LongName != "Lexington Avenue Local" || info.StationCount != 1 || info.ActiveAlertCount != 1 {
+			t.Errorf("Unexpected route info %+v (err %v)", info, err)
+		}
+	})
+
+	t.Run("alerts", func(t *testing.T) {
+		alerts, err := client.GetServiceAlerts()
+		if err != nil || len(alerts) != 1 || alerts[0].ID != "a1" {
+			t.Errorf("Expected the seeded alert, got %v (err %v)", alerts, err)
+		}
+	})
+
+	t.Run("counts", func(t *testing.T) {
+		if stations, routes, alerts := client.Counts(); stations != 2 || routes != 6 || alerts != 1 {
+			t.Errorf("Expected 2 stations, 6 routes and 1 alert, got %d, %d, %d", stations, routes, alerts)
+		}
+	})
+
+	t.Run("context cancellation", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		fake.SetContext(ctx)
+		defer fake.SetContext(context.Background())
+
+		if _, err := client.GetAllStations(); err != nil {
+			t.Fatalf("Unexpected error before cancelling: %v", err)
+		}
+		cancel()
+
+		if _, err := client.GetStationsByRoute("6"); !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected context.Canceled, got %v", err)
+		}
+		if _, err := client.GetServiceAlerts(); !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected context.Canceled, got %v", err)
+		}
+		for station := range client.AllStations() {
+			t.Errorf("Expected no stations after cancelling, got %s", station.ID)
+		}
+	})
+}
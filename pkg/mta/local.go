@@ -1,6 +1,10 @@
 package mta
 
 import (
+	"errors"
+	"iter"
+	"log/slog"
+	"sync"
 	"time"
 
 	"github.com/jusunglee/mta-go/internal/feed"
@@ -11,59 +15,365 @@ import (
 // LocalClient implements the Client interface for local usage
 // Manages in-memory data store and background feed updates
 type LocalClient struct {
-	store       *store.Store
+	store *store.Store
+
+	lifecycle   sync.Mutex // Serializes Restart and Close, and guards closed
+	closed      bool
+	mu          sync.RWMutex // Guards feedManager, which Restart swaps while queries read it
 	feedManager *feed.Manager
+	fetcher     feed.Fetcher // Replaces the network fetcher of every feed manager when set; see newLocal
 }
 
 // NewLocal creates a new local MTA client
 // Starts background feed manager for automatic data updates
 func NewLocal(config Config) (*LocalClient, error) {
+	c, err := newLocal(config, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.feedManager.PrepareGTFSDataDir(); err != nil {
+		return nil, err
+	}
+	c.feedManager.Start()
+	return c, nil
+}
+
+// newLocal builds a configured but unstarted client so configuration can be verified without network access
+// A non-nil fetcher replaces the network one, for this client's manager and those Restart swaps in
+func newLocal(config Config, fetcher feed.Fetcher) (*LocalClient, error) {
 	s := store.NewStore()
+	s.SetAuditLogger(auditLogger(config))
 
 	// TODO: Support the ability to load static station data from stations.json file
 	// without relying on the feed manager to populate station data dynamically.
 	// Currently relies on feed manager to populate station data dynamically,
 	// but there's some second order side effects that need to be thought out more.
 
-	fm := feed.NewManager(config.APIKey, s, config.UpdateInterval)
-	fm.Start()
-
+	fm, err := newFeedManager(config, s, fetcher)
+	if err != nil {
+		return nil, err
+	}
 	return &LocalClient{
 		store:       s,
 		feedManager: fm,
+		fetcher:     fetcher,
 	}, nil
 }
 
+// auditLogger returns the store audit logger config asks for, nil when auditing is off
+func auditLogger(config Config) *slog.Logger {
+	if !config.AuditLog {
+		return nil
+	}
+	return slog.Default()
+}
+
+// newFeedManager builds an unstarted feed manager configured from config that updates s
+// It fetches with fetcher when that is non-nil, and over the network otherwise
+func newFeedManager(config Config, s *store.Store, fetcher feed.Fetcher) (*feed.Manager, error) {
+	fm := feed.NewManager(config.APIKey, s, config.UpdateInterval)
+	if fetcher != nil {
+		fm.SetFetcher(fetcher)
+	}
+	if len(config.FeedGroups) > 0 {
+		if err := fm.SetFeedGroups(config.FeedGroups); err != nil {
+			return nil, err
+		}
+	}
+
+	if config.APIKeyHeader != "" || config.APIKeyQueryParam != "" {
+		auth := feed.APIKeyAuth{Header: config.APIKeyHeader, QueryParam: config.APIKeyQueryParam}
+		if err := fm.SetAPIKeyAuth(auth); err != nil {
+			return nil, err
+		}
+	}
+
+	policy, err := feed.ParseDuplicateStopPolicy(config.DuplicateStopPolicy)
+	if err != nil {
+		return nil, err
+	}
+	fm.SetDuplicateStopPolicy(policy)
+
+	area, err := feed.ParseServiceArea(config.ServiceArea)
+	if err != nil {
+		return nil, err
+	}
+	fm.SetServiceArea(area)
+	dedup, err := feed.ParseDedupStrategy(config.DedupStrategy)
+	if err != nil {
+		return nil, err
+	}
+	fm.SetDedupStrategy(dedup)
+	fm.SetArrivalRetention(config.ArrivalRetention)
+	fm.SetPastArrivalCutoff(config.PastArrivalCutoff)
+	fm.SetMaxAlertAge(config.MaxAlertAge)
+	fm.SetExpectedRealtimeVersion(config.ExpectedRealtimeVersion)
+	fm.SetScheduleFallback(config.ScheduleFallback)
+	fm.SetMergeStaticGTFS(config.MergeStaticGTFS)
+	fm.SetServiceDayCutoff(config.ServiceDayCutoff)
+	fm.SetCircuitBreaker(config.BreakerThreshold, config.BreakerCooldown)
+	if config.GTFSDataDir != "" {
+		fm.SetGTFSDataDir(config.GTFSDataDir)
+	}
+	if err := fm.SetStaticGTFSSource(config.StaticGTFSSource); err != nil {
+		return nil, err
+	}
+	if config.StationOverridesFile != "" {
+		overrides, err := feed.LoadStationOverrides(config.StationOverridesFile)
+		if err != nil {
+			return nil, err
+		}
+		fm.SetStationOverrides(overrides)
+	}
+
+	staticInterval := config.StaticUpdateInterval
+	if staticInterval == 0 {
+		staticInterval = DefaultStaticUpdateInterval
+	}
+	fm.SetStaticUpdateInterval(staticInterval)
+	return fm, nil
+}
+
+// ValidationReport summarizes a static GTFS directory checked by ValidateStaticGTFS
+type ValidationReport = feed.ValidationReport
+
+// ValidateServiceArea reports whether s is a usable Config.ServiceArea, for checking settings before starting a client
+func ValidateServiceArea(s string) error {
+	_, err := feed.ParseServiceArea(s)
+	return err
+}
+
+// ValidateStaticGTFS parses an extracted static GTFS directory and reports counts and warnings
+// Runs without an API key or store so a new data release can be checked before it goes live
+func ValidateStaticGTFS(dir string) (*ValidationReport, error) {
+	fm := feed.NewManager("", store.NewStore(), 0)
+	return fm.ValidateStaticGTFS(dir)
+}
+
+// StationGraph is the station adjacency graph built by BuildStationGraph
+type StationGraph = feed.StationGraph
+
+// GraphStation and GraphEdge are the nodes and edges of a StationGraph
+type (
+	GraphStation = feed.GraphStation
+	GraphEdge    = feed.GraphEdge
+)
+
+// BuildStationGraph reads an extracted static GTFS directory into stations joined by the routes between them
+// Like ValidateStaticGTFS it needs no API key or store
+func BuildStationGraph(dir string) (*StationGraph, error) {
+	fm := feed.NewManager("", store.NewStore(), 0)
+	return fm.BuildStationGraph(dir)
+}
+
+// LoadStatus reports whether a LocalClient has usable data
+type LoadStatus = feed.LoadStatus
+
+const (
+	LoadStatusLoading  = feed.LoadStatusLoading
+	LoadStatusReady    = feed.LoadStatusReady
+	LoadStatusDegraded = feed.LoadStatusDegraded
+	LoadStatusFailed   = feed.LoadStatusFailed
+)
+
+// LoadStatus reports the static and real-time load state as of the latest update
+// Loading until the initial update finishes; Degraded when some feeds or a static refresh failed
+func (c *LocalClient) LoadStatus() LoadStatus {
+	return c.manager().LoadStatus()
+}
+
+// Ready returns a channel closed once the initial data load has finished, successfully or not
+// Embedders wait on it instead of sleeping after NewLocal, then check LoadStatus
+func (c *LocalClient) Ready() <-chan struct{} {
+	return c.manager().Ready()
+}
+
+// manager returns the feed manager currently updating the store
+func (c *LocalClient) manager() *feed.Manager {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.feedManager
+}
+
+// Restart stops background updates and resumes them with a feed manager built from config,
+// for applying a reloaded config without recreating the client. The store is kept, so every
+// station, alert and arrival keeps being served while the new manager makes its first load;
+// Ready and LoadStatus then follow the new manager. An invalid config leaves the client running
+// as it was. Safe to call concurrently with queries; a closed client can't be restarted
+func (c *LocalClient) Restart(config Config) error {
+	fm, err := newFeedManager(config, c.store, c.fetcher)
+	if err != nil {
+		return err
+	}
+	if err := fm.PrepareGTFSDataDir(); err != nil {
+		return err
+	}
+
+	c.lifecycle.Lock()
+	defer c.lifecycle.Unlock()
+	if c.closed {
+		return errors.New("client is closed")
+	}
+	// The old manager finishes any update in flight before the new one starts writing to the store
+	c.manager().Stop()
+	c.mu.Lock()
+	c.feedManager = fm
+	c.mu.Unlock()
+	c.store.SetAuditLogger(auditLogger(config))
+	fm.Start()
+	return nil
+}
+
 // Close gracefully shuts down the local client
-// Must be called to stop background goroutines and prevent leaks
+// Must be called to stop background goroutines and prevent leaks; later calls do nothing
 func (c *LocalClient) Close() {
-	c.feedManager.Stop()
+	c.lifecycle.Lock()
+	defer c.lifecycle.Unlock()
+	if c.closed {
+		return
+	}
+	c.manager().Stop()
+	c.closed = true
+}
+
+// SaveSnapshot writes the loaded stations and route metadata to path, gzip-compressed when it ends in .gz
+func (c *LocalClient) SaveSnapshot(path string) error {
+	return c.store.SaveSnapshot(path)
+}
+
+// LoadSnapshot replaces the stations and route metadata with a snapshot from SaveSnapshot, plain or gzipped
+// The next static GTFS load replaces them again
+func (c *LocalClient) LoadSnapshot(path string) error {
+	return c.store.LoadSnapshot(path)
 }
 
 func (c *LocalClient) GetStationsByLocation(lat, lon float64, limit int) ([]models.Station, error) {
 	return c.store.GetStationsByLocation(lat, lon, limit), nil
 }
 
+func (c *LocalClient) GetStationsByLocationMerged(lat, lon float64, limit int, radiusKm float64) ([]models.Station, error) {
+	return c.store.GetStationsByLocationMerged(lat, lon, limit, radiusKm), nil
+}
+
+func (c *LocalClient) GetStationsWithinRadius(lat, lon, radiusKm float64) ([]models.Station, error) {
+	return c.store.GetStationsWithinRadius(lat, lon, radiusKm), nil
+}
+
 func (c *LocalClient) GetStationsByRoute(route string) ([]models.Station, error) {
 	return c.store.GetStationsByRoute(route)
 }
 
+func (c *LocalClient) GetStationSummariesByRoute(route string) ([]models.StationSummary, error) {
+	return c.store.GetStationSummariesByRoute(route)
+}
+
+func (c *LocalClient) GetStationsByTrunk(trunk string) ([]models.Station, error) {
+	return c.store.GetStationsByTrunk(trunk)
+}
+
 func (c *LocalClient) GetStationsByIDs(ids []string) ([]models.Station, error) {
 	return c.store.GetStationsByIDs(ids)
 }
 
+// FindStationsByIDs returns matching stations plus the IDs that matched nothing
+func (c *LocalClient) FindStationsByIDs(ids []string) ([]models.Station, []string, error) {
+	stations, missing := c.store.FindStationsByIDs(ids)
+	return stations, missing, nil
+}
+
+// GetStationsByIDPrefix returns stations whose ID starts with prefix; no match is not an error
+func (c *LocalClient) GetStationsByIDPrefix(prefix string) ([]models.Station, error) {
+	return c.store.GetStationsByIDPrefix(prefix), nil
+}
+
+func (c *LocalClient) GetAllStations() ([]models.Station, error) {
+	return c.store.GetAllStations(), nil
+}
+
+// AllStations iterates over every station ordered by ID, for streaming the full list
+func (c *LocalClient) AllStations() iter.Seq[models.Station] {
+	return c.store.AllStations()
+}
+
+func (c *LocalClient) GetStationsInBounds(minLat, minLon, maxLat, maxLon float64) ([]models.Station, error) {
+	return c.store.GetStationsInBounds(minLat, minLon, maxLat, maxLon), nil
+}
+
+// GetBounds returns the bounding box of all stations; ok is false before any station data is loaded
+func (c *LocalClient) GetBounds() (models.Bounds, bool) {
+	minLat, minLon, maxLat, maxLon, ok := c.store.GetBounds()
+	return models.Bounds{MinLat: minLat, MinLon: minLon, MaxLat: maxLat, MaxLon: maxLon}, ok
+}
+
+func (c *LocalClient) SearchStations(query string, fuzzy bool, limit int) ([]models.Station, error) {
+	return c.store.SearchStations(query, fuzzy, limit), nil
+}
+
 func (c *LocalClient) GetRoutes() ([]string, error) {
 	return c.store.GetRoutes(), nil
 }
 
+func (c *LocalClient) GetRoutesNearby(lat, lon, radiusKm float64) ([]string, error) {
+	return c.store.GetRoutesNearby(lat, lon, radiusKm), nil
+}
+
+// GetRouteShape returns a route's polylines from shapes.txt; empty when the feed has no shapes
+func (c *LocalClient) GetRouteShape(route string) ([][]models.Location, error) {
+	return c.store.GetRouteShape(route)
+}
+
+// GetFares returns the static GTFS fares applying to route or zone; empty arguments match any
+func (c *LocalClient) GetFares(route, zone string) ([]models.Fare, error) {
+	return c.store.GetFares(route, zone), nil
+}
+
+func (c *LocalClient) GetRouteInfo(route string) (models.RouteInfo, error) {
+	return c.store.GetRouteInfo(route)
+}
+
+func (c *LocalClient) GetArrivalsByRoute(route string) (map[string]models.TrainsByDirection, error) {
+	return c.store.GetArrivalsByRoute(route)
+}
+
+// GetRouteStatus returns next-train wait statistics for every route as of now
+func (c *LocalClient) GetRouteStatus(now time.Time) ([]models.RouteStatus, error) {
+	return c.store.GetRouteStatus(now), nil
+}
+
+// GetTripProgress returns a trip's current position and upcoming stops from the latest real-time update
+func (c *LocalClient) GetTripProgress(tripID string) (models.TripProgress, error) {
+	return c.store.GetTripProgress(tripID)
+}
+
 func (c *LocalClient) GetServiceAlerts() ([]models.Alert, error) {
 	return c.store.GetServiceAlerts(), nil
 }
 
+// GetAlertsInRange returns alerts active at any point in [start, end); a zero bound is unbounded
+func (c *LocalClient) GetAlertsInRange(start, end time.Time) ([]models.Alert, error) {
+	return c.store.GetAlertsInRange(start, end), nil
+}
+
 func (c *LocalClient) GetLastUpdate() time.Time {
 	return c.store.GetLastUpdate()
 }
 
+func (c *LocalClient) GetFeedLatencies() []models.FeedLatency {
+	return c.manager().GetFeedLatencies()
+}
+
+func (c *LocalClient) GetStats() models.Stats {
+	return c.manager().Stats()
+}
+
+func (c *LocalClient) Counts() (stations, routes, alerts int) {
+	return c.store.Counts()
+}
+
+func (c *LocalClient) GetCoverage() (models.CoverageReport, error) {
+	return c.store.GetCoverage(), nil
+}
+
 func (c *LocalClient) GetLastStaticUpdate() time.Time {
-	return c.feedManager.GetLastStaticUpdate()
+	return c.manager().GetLastStaticUpdate()
 }
@@ -0,0 +1,126 @@
+package mta
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jusunglee/mta-go/internal/gtfsrt"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestNewLocalStaticUpdateInterval(t *testing.T) {
+	t.Run("custom interval applied", func(t *testing.T) {
+		config := DefaultConfig()
+		config.StaticUpdateInterval = 90 * time.Minute
+
+		c, err := newLocal(config, nil)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if got := c.feedManager.GetStaticUpdateInterval(); got != 90*time.Minute {
+			t.Errorf("Expected static interval 90m, got %v", got)
+		}
+	})
+
+	t.Run("zero defaults to 6h", func(t *testing.T) {
+		c, err := newLocal(Config{UpdateInterval: time.Minute}, nil)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if got := c.feedManager.GetStaticUpdateInterval(); got != DefaultStaticUpdateInterval {
+			t.Errorf("Expected default static interval %v, got %v", DefaultStaticUpdateInterval, got)
+		}
+	})
+
+	t.Run("unknown feed group rejected", func(t *testing.T) {
+		config := DefaultConfig()
+		config.FeedGroups = []string{"xyz"}
+		if _, err := newLocal(config, nil); err == nil {
+			t.Error("Expected error for unknown feed group")
+		}
+	})
+}
+
+// emptyFeedFetcher answers every real-time feed with a valid feed that has no trains
+type emptyFeedFetcher struct{}
+
+func (emptyFeedFetcher) Fetch(ctx context.Context, url string) ([]byte, error) {
+	return proto.Marshal(&gtfsrt.FeedMessage{Header: &gtfsrt.FeedHeader{GtfsRealtimeVersion: proto.String("1.0")}})
+}
+
+func TestLocalClientRestart(t *testing.T) {
+	gtfsDir := t.TempDir()
+	for name, content := range map[string]string{
+		"stops.txt": "stop_id,stop_name,stop_lat,stop_lon,location_type,parent_station\n" +
+			"127,Times Sq-42 St,40.75529,-73.987495,1,\n" +
+			"127N,Times Sq-42 St,40.75529,-73.987495,,127\n",
+		"routes.txt":     "route_id,route_short_name,route_long_name\n1,1,Broadway - 7 Avenue Local\n",
+		"trips.txt":      "route_id,trip_id\n1,T1\n",
+		"stop_times.txt": "trip_id,stop_id\nT1,127N\n",
+	} {
+		if err := os.WriteFile(filepath.Join(gtfsDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	config := DefaultConfig()
+	config.StaticGTFSSource = gtfsDir
+	config.GTFSDataDir = t.TempDir()
+	config.FeedGroups = []string{"1234567"}
+
+	c, err := newLocal(config, emptyFeedFetcher{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	c.feedManager.Start()
+	defer c.Close()
+
+	waitReady := func() {
+		t.Helper()
+		select {
+		case <-c.Ready():
+		case <-time.After(5 * time.Second):
+			t.Fatal("Timed out waiting for the initial load")
+		}
+		if status := c.LoadStatus(); status != LoadStatusReady {
+			t.Fatalf("Expected status ready, got %v", status)
+		}
+	}
+	waitReady()
+
+	t.Run("invalid config keeps the client running", func(t *testing.T) {
+		bad := config
+		bad.FeedGroups = []string{"xyz"}
+		if err := c.Restart(bad); err == nil {
+			t.Error("Expected error for unknown feed group")
+		}
+		if _, err := c.GetStationsByIDs([]string{"127"}); err != nil {
+			t.Errorf("Expected stations still served: %v", err)
+		}
+	})
+
+	config.UpdateInterval = 30 * time.Second
+	if err := c.Restart(config); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	// Served data survives the restart, before the new manager has loaded anything
+	if _, err := c.GetStationsByIDs([]string{"127"}); err != nil {
+		t.Errorf("Expected stations served across the restart: %v", err)
+	}
+	waitReady()
+	if c.GetLastStaticUpdate().IsZero() {
+		t.Error("Expected the new manager to load static data")
+	}
+	routes, err := c.GetRoutes()
+	if err != nil || len(routes) != 1 || routes[0] != "1" {
+		t.Errorf("Expected route 1 after restart, got %v, %v", routes, err)
+	}
+
+	c.Close()
+	if err := c.Restart(config); err == nil {
+		t.Error("Expected restarting a closed client to fail")
+	}
+}